@@ -0,0 +1,210 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/btassone/obtura/internal/bundle"
+	"github.com/btassone/obtura/internal/config"
+	"github.com/btassone/obtura/internal/database"
+	"github.com/btassone/obtura/internal/models"
+	"github.com/btassone/obtura/internal/server"
+	"github.com/btassone/obtura/pkg/plugin"
+)
+
+func runConfig() {
+	if len(os.Args) > 2 {
+		switch os.Args[2] {
+		case "migrate":
+			runConfigMigrate()
+			return
+		case "rotate-key":
+			runConfigRotateKey()
+			return
+		case "export":
+			runConfigExport()
+			return
+		case "import":
+			runConfigImport()
+			return
+		}
+	}
+
+	fmt.Println("Usage:")
+	fmt.Println("  obtura config migrate --from file --to db   Copy plugin configs between storages")
+	fmt.Println("  obtura config rotate-key                    Encrypt all secrets with APP_KEY")
+	fmt.Println("  obtura config export [--output site.yaml]   Export site configuration as a bundle")
+	fmt.Println("  obtura config import [--dry-run] <bundle>   Validate and apply a bundle")
+	os.Exit(1)
+}
+
+// openSite opens the database and builds the plugin registry a bundle is exported from or applied to
+func openSite() (*bundle.Site, func()) {
+	dbManager, err := database.NewManager()
+	if err != nil {
+		log.Fatalf("Failed to initialize database: %v", err)
+	}
+	registry, err := server.NewPluginRegistry(dbManager.DB())
+	if err != nil {
+		dbManager.Close()
+		log.Fatalf("Failed to load plugins: %v", err)
+	}
+	return bundle.NewSite(registry, models.NewSiteRepository(dbManager.DB())), func() { dbManager.Close() }
+}
+
+// runConfigExport writes the site's configuration bundle to a file or stdout
+func runConfigExport() {
+	exportCmd := flag.NewFlagSet("config export", flag.ExitOnError)
+	output := exportCmd.String("output", "", "File to write; stdout if empty")
+	format := exportCmd.String("format", "", "Bundle format (json, yaml); defaults to the output file's extension, or json")
+	includeSecrets := exportCmd.Bool("include-secrets", false, "Export secret config fields in plain text")
+	exportCmd.Parse(os.Args[3:])
+
+	if *format == "" {
+		*format = bundle.FormatFromPath(*output)
+	}
+
+	site, closeSite := openSite()
+	defer closeSite()
+
+	b, err := site.Export(bundle.ExportOptions{IncludeSecrets: *includeSecrets})
+	if err != nil {
+		log.Fatalf("Export failed: %v", err)
+	}
+	data, err := b.Encode(*format)
+	if err != nil {
+		log.Fatalf("Export failed: %v", err)
+	}
+
+	if *output == "" {
+		os.Stdout.Write(data)
+		return
+	}
+	if err := os.WriteFile(*output, data, 0600); err != nil {
+		log.Fatalf("Failed to write bundle: %v", err)
+	}
+	fmt.Printf("Exported %d plugin config(s) and %d setting(s) to %s.\n", len(b.Plugins), len(b.Settings), *output)
+}
+
+// runConfigImport shows what a bundle changes and applies it unless --dry-run is given
+func runConfigImport() {
+	importCmd := flag.NewFlagSet("config import", flag.ExitOnError)
+	dryRun := importCmd.Bool("dry-run", false, "Show the changes without applying them")
+	author := importCmd.String("author", "cli", "Author recorded in plugin config history")
+	importCmd.Parse(os.Args[3:])
+
+	if importCmd.NArg() != 1 {
+		log.Fatalf("Usage: obtura config import [--dry-run] <bundle>")
+	}
+	data, err := os.ReadFile(importCmd.Arg(0))
+	if err != nil {
+		log.Fatalf("Failed to read bundle: %v", err)
+	}
+	b, err := bundle.Decode(data)
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+
+	site, closeSite := openSite()
+	defer closeSite()
+
+	plan, err := site.Plan(b)
+	if err != nil {
+		log.Fatalf("Bundle is not valid for this site:\n%v", err)
+	}
+	if plan.Empty() {
+		fmt.Println("Nothing to change.")
+		return
+	}
+	fmt.Print(plan)
+	if *dryRun {
+		fmt.Printf("Dry run: %d change(s) not applied.\n", len(plan.Changes))
+		return
+	}
+
+	if _, err := site.Apply(context.Background(), b, *author); err != nil {
+		log.Fatalf("Import failed: %v", err)
+	}
+	fmt.Printf("Applied %d change(s).\n", len(plan.Changes))
+}
+
+func runConfigMigrate() {
+	defaults := config.GetPluginConfigStorageConfig()
+	migrateCmd := flag.NewFlagSet("config migrate", flag.ExitOnError)
+	from := migrateCmd.String("from", plugin.ConfigStorageFile, "Storage to copy configs from (file, db)")
+	to := migrateCmd.String("to", plugin.ConfigStorageDB, "Storage to copy configs to (file, db)")
+	path := migrateCmd.String("path", defaults.Path, "Directory used by file storage")
+	migrateCmd.Parse(os.Args[3:])
+
+	if *from == *to {
+		log.Fatalf("Source and destination storage are both %q", *from)
+	}
+
+	dbManager, err := database.NewManager()
+	if err != nil {
+		log.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer dbManager.Close()
+
+	src, err := plugin.NewConfigStorage(*from, *path, dbManager.DB())
+	if err != nil {
+		log.Fatalf("Failed to open %s config storage: %v", *from, err)
+	}
+	dst, err := plugin.NewConfigStorage(*to, *path, dbManager.DB())
+	if err != nil {
+		log.Fatalf("Failed to open %s config storage: %v", *to, err)
+	}
+
+	copied, err := plugin.CopyConfigs(src, dst)
+	if err != nil {
+		log.Fatalf("Config migration failed after %d plugin(s): %v", copied, err)
+	}
+	fmt.Printf("Copied %d plugin config(s) from %s to %s storage.\n", copied, *from, *to)
+}
+
+// runConfigRotateKey re-encrypts stored secrets with APP_KEY and encrypts secrets stored in plaintext.
+// Old keys must be listed in APP_PREVIOUS_KEYS so secrets encrypted with them can be decrypted.
+func runConfigRotateKey() {
+	cfg := config.GetPluginConfigStorageConfig()
+	rotateCmd := flag.NewFlagSet("config rotate-key", flag.ExitOnError)
+	storageKind := rotateCmd.String("storage", cfg.Storage, "Config storage to rotate (file, db)")
+	path := rotateCmd.String("path", cfg.Path, "Directory used by file storage")
+	rotateCmd.Parse(os.Args[3:])
+
+	if cfg.SecretKey == "" {
+		log.Fatalf("APP_KEY must be set to the new key")
+	}
+	cipher, err := plugin.NewSecretCipher(cfg.SecretKey, cfg.PreviousSecretKeys...)
+	if err != nil {
+		log.Fatalf("Invalid key: %v", err)
+	}
+
+	dbManager, err := database.NewManager()
+	if err != nil {
+		log.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer dbManager.Close()
+
+	storage, err := plugin.NewConfigStorage(*storageKind, *path, dbManager.DB())
+	if err != nil {
+		log.Fatalf("Failed to open %s config storage: %v", *storageKind, err)
+	}
+
+	secrets, err := server.PluginSecretPaths(dbManager.DB())
+	if err != nil {
+		log.Fatalf("Failed to load plugins: %v", err)
+	}
+
+	rotated, err := plugin.RotateSecrets(storage, cipher, secrets)
+	if err != nil {
+		log.Fatalf("Key rotation failed after %d secret(s): %v", rotated, err)
+	}
+	if len(cfg.PreviousSecretKeys) > 0 {
+		fmt.Printf("Encrypted %d secret(s) with APP_KEY. APP_PREVIOUS_KEYS can now be removed.\n", rotated)
+	} else {
+		fmt.Printf("Encrypted %d secret(s) with APP_KEY.\n", rotated)
+	}
+}
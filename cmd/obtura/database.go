@@ -1,6 +1,7 @@
 package main
 
 import (
+	"errors"
 	"flag"
 	"fmt"
 	"log"
@@ -8,15 +9,39 @@ import (
 	"strings"
 
 	"github.com/btassone/obtura/internal/database"
+	"github.com/btassone/obtura/internal/server"
+	pkgdatabase "github.com/btassone/obtura/pkg/database"
 )
 
 func runMigrate() {
+	if len(os.Args) > 2 && os.Args[2] == "verify" {
+		runMigrateVerify()
+		return
+	}
+
+	migrateCmd := flag.NewFlagSet("migrate", flag.ExitOnError)
+	allowDrift := migrateCmd.Bool("allow-drift", false, "Run pending migrations even if applied ones have changed")
+	migrateCmd.Parse(os.Args[2:])
+
 	dbManager, err := database.NewManager()
 	if err != nil {
 		log.Fatalf("Failed to initialize database: %v", err)
 	}
 	defer dbManager.Close()
+	dbManager.AllowMigrationDrift(*allowDrift)
+
+	// Plugins are set up against the core tables, so their migrations run after the core ones
+	ran := migrate(dbManager)
+	addPluginMigrations(dbManager)
+	ran += migrate(dbManager)
+
+	if ran == 0 {
+		fmt.Println("No migrations to run.")
+	}
+}
 
+// migrate runs the manager's pending migrations, listing them first, and returns how many ran
+func migrate(dbManager *database.Manager) int {
 	// Get migration status
 	status, err := dbManager.MigrationStatus()
 	if err != nil {
@@ -32,8 +57,7 @@ func runMigrate() {
 	}
 
 	if len(pending) == 0 {
-		fmt.Println("No migrations to run.")
-		return
+		return 0
 	}
 
 	fmt.Printf("Found %d pending migration(s):\n", len(pending))
@@ -43,8 +67,56 @@ func runMigrate() {
 
 	// Run migrations
 	if err := dbManager.Migrate(); err != nil {
+		var drift *pkgdatabase.DriftError
+		if errors.As(err, &drift) {
+			printDrift(drift.Drifted)
+			log.Fatalf("Migration refused: applied migrations have changed; restore them, or run with --allow-drift")
+		}
 		log.Fatalf("Migration failed: %v", err)
 	}
+	return len(pending)
+}
+
+// addPluginMigrations adds the migrations the core plugins ship to the manager's,
+// without saving plugin configs
+func addPluginMigrations(dbManager *database.Manager) {
+	migrations, err := server.PluginMigrations(dbManager.DB())
+	if err != nil {
+		log.Fatalf("Failed to load plugins: %v", err)
+	}
+	if err := dbManager.AddPluginMigrations(migrations); err != nil {
+		log.Fatalf("Failed to load plugin migrations: %v", err)
+	}
+}
+
+// runMigrateVerify checks applied migrations against their recorded checksums, exiting with
+// status 1 if any have changed, for use in CI
+func runMigrateVerify() {
+	dbManager, err := database.NewManager()
+	if err != nil {
+		log.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer dbManager.Close()
+	addPluginMigrations(dbManager)
+
+	drifted, err := dbManager.VerifyMigrations()
+	if err != nil {
+		log.Fatalf("Failed to verify migrations: %v", err)
+	}
+	if len(drifted) > 0 {
+		printDrift(drifted)
+		dbManager.Close()
+		os.Exit(1)
+	}
+	fmt.Println("Applied migrations match their checksums.")
+}
+
+// printDrift lists migrations that have changed since they were applied
+func printDrift(drifted []pkgdatabase.MigrationDrift) {
+	fmt.Printf("%d applied migration(s) have changed since they were applied:\n", len(drifted))
+	for _, d := range drifted {
+		fmt.Printf("  - %s (applied %s, now %s)\n", d.Version, d.AppliedChecksum, d.Checksum)
+	}
 }
 
 func runRollback() {
@@ -59,6 +131,9 @@ func runRollback() {
 	}
 	defer dbManager.Close()
 
+	// Plugin migrations may be the latest applied
+	addPluginMigrations(dbManager)
+
 	// Run rollback
 	if err := dbManager.Rollback(*steps); err != nil {
 		log.Fatalf("Rollback failed: %v", err)
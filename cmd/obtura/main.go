@@ -1,12 +1,17 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"log"
 	"os"
+	"os/signal"
+	"syscall"
 
+	"github.com/btassone/obtura/internal/config"
 	"github.com/btassone/obtura/internal/server"
+	"github.com/btassone/obtura/pkg/logging"
 
 	// Import migrations and seeders to register them
 	_ "github.com/btassone/obtura/internal/database/migrations"
@@ -14,6 +19,11 @@ import (
 )
 
 func main() {
+	// Configure structured logging from LOG_LEVEL and LOG_FORMAT
+	if _, err := logging.Setup(config.GetLoggingConfig()); err != nil {
+		log.Fatalf("Invalid logging configuration: %v", err)
+	}
+	
 	// Handle database commands first (before flag parsing)
 	if len(os.Args) > 1 {
 		switch os.Args[1] {
@@ -26,27 +36,40 @@ func main() {
 		case "rollback":
 			runRollback()
 			return
+		case "config":
+			runConfig()
+			return
 		}
 	}
 
+	defaults := server.DefaultOptions()
 	var (
-		port = flag.String("port", "8080", "Server port")
-		mode = flag.String("mode", "dev", "Run mode (dev/prod)")
+		port              = flag.String("port", "8080", "Server port")
+		mode              = flag.String("mode", "dev", "Run mode (dev/prod)")
+		shutdownTimeout   = flag.Duration("shutdown-timeout", defaults.ShutdownTimeout, "Time allowed for in-flight requests to drain on shutdown")
+		pluginStopTimeout = flag.Duration("plugin-stop-timeout", defaults.PluginStopTimeout, "Time allowed for plugins to stop on shutdown")
 	)
-	flag.Parse()
+	
+	// Allow flags after the "serve" command
+	args := os.Args[1:]
+	if len(args) > 0 && args[0] == "serve" {
+		args = args[1:]
+	}
+	flag.CommandLine.Parse(args)
 
 	if len(os.Args) > 1 && os.Args[1] == "serve" {
-		srv, err := server.New(*port, *mode)
+		options := defaults
+		options.ShutdownTimeout = *shutdownTimeout
+		options.PluginStopTimeout = *pluginStopTimeout
+		
+		srv, err := server.NewWithOptions(*port, *mode, options)
 		if err != nil {
 			log.Fatalf("Failed to initialize server: %v", err)
 		}
 		
-		// Handle graceful shutdown
-		defer func() {
-			if err := srv.Close(); err != nil {
-				log.Printf("Error closing server: %v", err)
-			}
-		}()
+		// Shut down gracefully on SIGINT/SIGTERM
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
 		
 		if *mode == "dev" {
 			log.Printf("Starting Obtura development server on port %s", *port)
@@ -56,7 +79,7 @@ func main() {
 			log.Printf("Starting Obtura server on port %s in %s mode", *port, *mode)
 		}
 		
-		if err := srv.Start(); err != nil {
+		if err := srv.Run(ctx); err != nil {
 			log.Fatalf("Server failed: %v", err)
 		}
 		return
@@ -65,9 +88,11 @@ func main() {
 	fmt.Println("Obtura - A modular web framework")
 	fmt.Println("\nUsage:")
 	fmt.Println("  obtura serve      Start the web server")
-	fmt.Println("  obtura migrate    Run database migrations")
+	fmt.Println("  obtura migrate    Run database migrations (--allow-drift to run despite changed migrations)")
+	fmt.Println("  obtura migrate verify   Check applied migrations haven't changed")
 	fmt.Println("  obtura rollback   Rollback database migrations")
 	fmt.Println("  obtura seed       Run database seeders")
+	fmt.Println("  obtura config     Manage plugin configuration storage")
 	fmt.Println("  obtura generate   Generate components (coming soon)")
 	fmt.Println("  obtura build      Build for production (coming soon)")
 }
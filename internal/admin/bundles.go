@@ -0,0 +1,118 @@
+package admin
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/a-h/templ"
+	"github.com/btassone/obtura/internal/bundle"
+	adminpages "github.com/btassone/obtura/web/templates/admin/pages"
+	"github.com/go-chi/chi/v5"
+)
+
+// maxBundleSize limits the size of an uploaded configuration bundle
+const maxBundleSize = 1 << 20
+
+// SetupBundleRoutes configures exporting and importing the site's configuration bundle
+func SetupBundleRoutes(r chi.Router, site *bundle.Site) {
+	r.Get("/settings/export", handleBundleExport(site))
+	r.Post("/settings/import", handleBundleImport(site))
+}
+
+// handleBundleExport downloads the site's configuration, without secrets
+func handleBundleExport(site *bundle.Site) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		format := r.URL.Query().Get("format")
+		if format == "" {
+			format = bundle.FormatJSON
+		}
+
+		b, err := site.Export(bundle.ExportOptions{})
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		data, err := b.Encode(format)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		contentType := "application/json"
+		if format == bundle.FormatYAML {
+			contentType = "application/yaml"
+		}
+		filename := fmt.Sprintf("obtura-config-%s.%s", time.Now().Format("20060102-150405"), format)
+		w.Header().Set("Content-Type", contentType)
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+		w.Write(data)
+	}
+}
+
+// handleBundleImport previews the changes an uploaded bundle makes, and applies them when confirmed
+func handleBundleImport(site *bundle.Site) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		r.Body = http.MaxBytesReader(w, r.Body, 2*maxBundleSize)
+		data, err := readBundle(r)
+		if err != nil {
+			renderBundleImport(w, r, nil, "", false, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		b, err := bundle.Decode([]byte(data))
+		if err != nil {
+			renderBundleImport(w, r, nil, "", false, err.Error(), http.StatusUnprocessableEntity)
+			return
+		}
+
+		apply := r.FormValue("apply") == "1"
+		var plan *bundle.Plan
+		if apply {
+			plan, err = site.Apply(r.Context(), b, configAuthor(r))
+		} else {
+			plan, err = site.Plan(b)
+		}
+		if err != nil {
+			renderBundleImport(w, r, plan, data, false, err.Error(), http.StatusUnprocessableEntity)
+			return
+		}
+
+		renderBundleImport(w, r, plan, data, apply, "", http.StatusOK)
+	}
+}
+
+// readBundle reads a bundle from an uploaded file, or from the form when applying a previewed bundle
+func readBundle(r *http.Request) (string, error) {
+	if err := r.ParseMultipartForm(maxBundleSize); err != nil && err != http.ErrNotMultipart {
+		return "", fmt.Errorf("failed to read upload: %w", err)
+	}
+
+	file, _, err := r.FormFile("bundle")
+	if err == http.ErrMissingFile || err == http.ErrNotMultipart {
+		if data := r.FormValue("data"); data != "" {
+			return data, nil
+		}
+		return "", fmt.Errorf("no bundle uploaded")
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read upload: %w", err)
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, maxBundleSize+1))
+	if err != nil {
+		return "", fmt.Errorf("failed to read upload: %w", err)
+	}
+	if len(data) > maxBundleSize {
+		return "", fmt.Errorf("bundle is larger than %d bytes", maxBundleSize)
+	}
+	return string(data), nil
+}
+
+// renderBundleImport renders the import preview or result
+func renderBundleImport(w http.ResponseWriter, r *http.Request, plan *bundle.Plan, data string, applied bool, formError string, status int) {
+	component := adminpages.SettingsImport(getUser(r), plan, data, applied, formError)
+	templ.Handler(component, templ.WithStatus(status)).ServeHTTP(w, r)
+}
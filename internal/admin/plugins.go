@@ -1,10 +1,15 @@
 package admin
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
+	"strconv"
 
 	"github.com/a-h/templ"
 	"github.com/btassone/obtura/internal/types"
+	"github.com/btassone/obtura/pkg/logging"
 	"github.com/btassone/obtura/pkg/plugin"
 	adminpages "github.com/btassone/obtura/web/templates/admin/pages"
 )
@@ -30,7 +35,7 @@ func handlePluginsListWithRegistry(registry *plugin.Registry) http.HandlerFunc {
 				Description: p.Description(),
 				Version:     p.Version(),
 				Author:      p.Author(),
-				IsActive:    true, // All registered plugins are active
+				IsActive:    registry.IsEnabled(p.ID()),
 				IsCore:      isCore(p.ID()),
 				HasConfig:   hasConfig(p),
 			}
@@ -46,17 +51,62 @@ func handlePluginsListWithRegistry(registry *plugin.Registry) http.HandlerFunc {
 // handlePluginToggle toggles a plugin on/off
 func handlePluginToggleWithRegistry(registry *plugin.Registry) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		// TODO: Implement plugin enable/disable
-		// For now, just redirect back
+		pluginID := r.PathValue("id")
+		if isCore(pluginID) {
+			http.Error(w, "Core plugins cannot be disabled", http.StatusBadRequest)
+			return
+		}
+		
+		status, err := registry.Status(pluginID)
+		if err != nil {
+			http.Error(w, "Plugin not found", http.StatusNotFound)
+			return
+		}
+		
+		if status.State == plugin.StateDisabled {
+			err = registry.Enable(r.Context(), pluginID)
+		} else {
+			err = registry.Disable(r.Context(), pluginID)
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		
 		http.Redirect(w, r, "/admin/plugins", http.StatusSeeOther)
 	}
 }
 
+// handlePluginStatusList returns the lifecycle status of all plugins as JSON
+func handlePluginStatusListWithRegistry(registry *plugin.Registry) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, registry.Statuses())
+	}
+}
+
+// handlePluginStatus returns the lifecycle status of a single plugin as JSON
+func handlePluginStatusWithRegistry(registry *plugin.Registry) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		status, err := registry.Status(r.PathValue("id"))
+		if err != nil {
+			writeJSON(w, http.StatusNotFound, map[string]string{"error": err.Error()})
+			return
+		}
+		writeJSON(w, http.StatusOK, status)
+	}
+}
+
+// writeJSON writes a JSON response with the given status code
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(v)
+}
+
 // handlePluginConfig handles plugin configuration page
 func handlePluginConfigWithRegistry(registry *plugin.Registry) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		pluginID := r.PathValue("id")
-		user := getUser(r)
 		
 		p, err := registry.Get(pluginID)
 		if err != nil {
@@ -64,18 +114,15 @@ func handlePluginConfigWithRegistry(registry *plugin.Registry) http.HandlerFunc
 			return
 		}
 		
-		// Get config schema and current config
+		// Get config schema and the effective config, including overrides
 		schema, _ := registry.GetConfigManager().GetSchema(pluginID)
-		config, _ := registry.GetConfig(pluginID)
-		
-		// Convert config to map for template
-		configMap, ok := config.(map[string]interface{})
-		if !ok {
-			configMap = make(map[string]interface{})
+		configMap, _, err := registry.GetConfigManager().EffectiveConfig(pluginID)
+		if err != nil {
+			renderPluginConfig(w, r, registry, p, schema, map[string]interface{}{}, err.Error(), http.StatusInternalServerError)
+			return
 		}
 		
-		component := adminpages.PluginConfig(user, p, schema, configMap)
-		templ.Handler(component).ServeHTTP(w, r)
+		renderPluginConfig(w, r, registry, p, schema, configMap, "", http.StatusOK)
 	}
 }
 
@@ -84,23 +131,76 @@ func handlePluginConfigUpdateWithRegistry(registry *plugin.Registry) http.Handle
 	return func(w http.ResponseWriter, r *http.Request) {
 		pluginID := r.PathValue("id")
 		
-		// TODO: Parse form data and update config
+		p, err := registry.Get(pluginID)
+		if err != nil {
+			http.Error(w, "Plugin not found", http.StatusNotFound)
+			return
+		}
+		
 		if err := r.ParseForm(); err != nil {
 			http.Error(w, "Invalid form data", http.StatusBadRequest)
 			return
 		}
 		
-		// Convert form data to config
+		// Convert form data to config, typed by the schema when there is one
+		schema, _ := registry.GetConfigManager().GetSchema(pluginID)
 		config := make(map[string]interface{})
-		for key, values := range r.Form {
-			if len(values) > 0 {
-				config[key] = values[0]
+		if schema != nil {
+			config, err = schema.ParseForm(r.Form)
+			if current, ok := registry.GetConfigManager().GetConfigMap(pluginID); ok && err == nil {
+				schema.KeepSecrets(config, current)
+			}
+		} else {
+			for key, values := range r.Form {
+				if len(values) > 0 {
+					config[key] = values[0]
+				}
 			}
 		}
 		
+		// Fields locked by the config file or environment keep their stored values
+		if err == nil {
+			err = registry.GetConfigManager().RestoreOverridden(pluginID, config)
+		}
+		
 		// Update config
-		if err := registry.SetConfig(pluginID, config); err != nil {
-			http.Error(w, err.Error(), http.StatusBadRequest)
+		if err == nil {
+			err = registry.SetConfigWithAuthor(pluginID, config, configAuthor(r))
+		}
+		if err != nil {
+			renderPluginConfig(w, r, registry, p, schema, config, err.Error(), http.StatusUnprocessableEntity)
+			return
+		}
+		
+		http.Redirect(w, r, "/admin/plugins/"+pluginID+"/config", http.StatusSeeOther)
+	}
+}
+
+// handlePluginConfigRollback restores a plugin's configuration from an earlier revision
+func handlePluginConfigRollbackWithRegistry(registry *plugin.Registry) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		pluginID := r.PathValue("id")
+		
+		p, err := registry.Get(pluginID)
+		if err != nil {
+			http.Error(w, "Plugin not found", http.StatusNotFound)
+			return
+		}
+		
+		version, err := strconv.Atoi(r.FormValue("version"))
+		if err != nil {
+			http.Error(w, "Invalid revision", http.StatusBadRequest)
+			return
+		}
+		
+		if err := registry.RollbackConfig(pluginID, version, configAuthor(r)); err != nil {
+			if errors.Is(err, plugin.ErrRevisionNotFound) {
+				http.Error(w, err.Error(), http.StatusNotFound)
+				return
+			}
+			schema, _ := registry.GetConfigManager().GetSchema(pluginID)
+			configMap, _, _ := registry.GetConfigManager().EffectiveConfig(pluginID)
+			renderPluginConfig(w, r, registry, p, schema, configMap, fmt.Sprintf("rollback to revision %d failed: %v", version, err), http.StatusUnprocessableEntity)
 			return
 		}
 		
@@ -108,6 +208,28 @@ func handlePluginConfigUpdateWithRegistry(registry *plugin.Registry) http.Handle
 	}
 }
 
+// renderPluginConfig renders the plugin configuration form and its revision history
+func renderPluginConfig(w http.ResponseWriter, r *http.Request, registry *plugin.Registry, p plugin.Plugin, schema *plugin.ConfigSchema, config map[string]interface{}, formError string, status int) {
+	history, err := registry.GetConfigManager().History(p.ID())
+	if err != nil && !errors.Is(err, plugin.ErrRevisionsUnsupported) {
+		logging.FromContext(r.Context()).Warn("Failed to load config history", "plugin", p.ID(), "error", err)
+	}
+	
+	// Origins mark fields overridden by the config file or environment as locked
+	_, origins, _ := registry.GetConfigManager().EffectiveConfig(p.ID())
+	
+	component := adminpages.PluginConfig(getUser(r), p, schema, config, origins, history, formError)
+	templ.Handler(component, templ.WithStatus(status)).ServeHTTP(w, r)
+}
+
+// configAuthor identifies the admin making a config change
+func configAuthor(r *http.Request) string {
+	if user := getUser(r); user != nil {
+		return user.Email
+	}
+	return ""
+}
+
 // isCore checks if a plugin is a core plugin
 func isCore(pluginID string) bool {
 	corePlugins := []string{
@@ -43,9 +43,12 @@ func SetupRoutesWithPlugin(r chi.Router, registry *plugin.Registry) {
 	// Plugins management
 	r.Route("/plugins", func(r chi.Router) {
 		r.Get("/", handlePluginsListWithRegistry(registry))
+		r.Get("/status", handlePluginStatusListWithRegistry(registry))
+		r.Get("/{id}/status", handlePluginStatusWithRegistry(registry))
 		r.Post("/{id}/toggle", handlePluginToggleWithRegistry(registry))
 		r.Get("/{id}/config", handlePluginConfigWithRegistry(registry))
 		r.Post("/{id}/config", handlePluginConfigUpdateWithRegistry(registry))
+		r.Post("/{id}/config/rollback", handlePluginConfigRollbackWithRegistry(registry))
 	})
 	
 	// Users management
@@ -0,0 +1,49 @@
+package admin
+
+import (
+	"encoding/hex"
+	"net/http"
+
+	"github.com/a-h/templ"
+	"github.com/btassone/obtura/pkg/tracing"
+	adminpages "github.com/btassone/obtura/web/templates/admin/pages"
+	"github.com/go-chi/chi/v5"
+)
+
+// SetupTraceRoutes configures the trace viewer backed by an in-memory span buffer
+func SetupTraceRoutes(r chi.Router, buffer *tracing.RingBuffer) {
+	r.Route("/traces", func(r chi.Router) {
+		r.Get("/", handleTracesList(buffer))
+		r.Get("/{id}", handleTraceDetail(buffer))
+	})
+}
+
+// handleTracesList lists the buffered traces, most recent first
+func handleTracesList(buffer *tracing.RingBuffer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		component := adminpages.TracesList(getUser(r), buffer.Traces())
+		templ.Handler(component).ServeHTTP(w, r)
+	}
+}
+
+// handleTraceDetail shows a single trace as a waterfall
+func handleTraceDetail(buffer *tracing.RingBuffer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var id tracing.TraceID
+		raw, err := hex.DecodeString(r.PathValue("id"))
+		if err != nil || len(raw) != len(id) {
+			http.Error(w, "Invalid trace ID", http.StatusBadRequest)
+			return
+		}
+		copy(id[:], raw)
+
+		trace, ok := buffer.Trace(id)
+		if !ok {
+			http.Error(w, "Trace not found", http.StatusNotFound)
+			return
+		}
+
+		component := adminpages.TraceDetail(getUser(r), trace)
+		templ.Handler(component).ServeHTTP(w, r)
+	}
+}
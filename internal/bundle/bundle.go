@@ -0,0 +1,84 @@
+// Package bundle exports a site's configuration to a portable file and imports it elsewhere,
+// such as when moving a configured site from staging to production.
+package bundle
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// FormatVersion is the bundle format version written by Export
+const FormatVersion = 1
+
+// Bundle file formats
+const (
+	FormatJSON = "json"
+	FormatYAML = "yaml"
+)
+
+// Bundle is a site's configuration: plugin configs, settings, the active theme and the enabled plugins.
+// Plugin configs leave out secret fields unless exported with IncludeSecrets.
+type Bundle struct {
+	Version        int                               `json:"version" yaml:"version"`
+	ExportedAt     time.Time                         `json:"exported_at" yaml:"exported_at"`
+	Theme          string                            `json:"theme,omitempty" yaml:"theme,omitempty"`
+	EnabledPlugins []string                          `json:"enabled_plugins" yaml:"enabled_plugins"`
+	Settings       map[string]string                 `json:"settings,omitempty" yaml:"settings,omitempty"`
+	Plugins        map[string]map[string]interface{} `json:"plugins,omitempty" yaml:"plugins,omitempty"`
+}
+
+// Encode writes the bundle in the given format
+func (b *Bundle) Encode(format string) ([]byte, error) {
+	switch format {
+	case FormatJSON:
+		return json.MarshalIndent(b, "", "  ")
+	case FormatYAML:
+		return yaml.Marshal(b)
+	}
+	return nil, fmt.Errorf("unsupported bundle format: %s", format)
+}
+
+// Decode reads a JSON or YAML bundle
+func Decode(data []byte) (*Bundle, error) {
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 {
+		return nil, fmt.Errorf("bundle is empty")
+	}
+
+	// YAML is converted to JSON so values decode to the same types whatever the format
+	if data[0] != '{' {
+		var doc interface{}
+		if err := yaml.Unmarshal(data, &doc); err != nil {
+			return nil, fmt.Errorf("invalid bundle: %w", err)
+		}
+		converted, err := json.Marshal(doc)
+		if err != nil {
+			return nil, fmt.Errorf("invalid bundle: %w", err)
+		}
+		data = converted
+	}
+
+	var b Bundle
+	if err := json.Unmarshal(data, &b); err != nil {
+		return nil, fmt.Errorf("invalid bundle: %w", err)
+	}
+	if b.Version < 1 || b.Version > FormatVersion {
+		return nil, fmt.Errorf("unsupported bundle version %d", b.Version)
+	}
+	return &b, nil
+}
+
+// FormatFromPath returns the format for a file name: YAML for .yaml and .yml, JSON otherwise
+func FormatFromPath(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		return FormatYAML
+	}
+	return FormatJSON
+}
@@ -0,0 +1,291 @@
+package bundle
+
+import (
+	"context"
+	"testing"
+
+	"github.com/btassone/obtura/internal/models"
+	"github.com/btassone/obtura/pkg/database"
+	"github.com/btassone/obtura/pkg/plugin"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testConfig struct {
+	Title  string `json:"title" default:"Site"`
+	Limit  int    `json:"limit" default:"10" min:"1"`
+	APIKey string `json:"api_key" secret:"true"`
+}
+
+// testPlugin is a configurable plugin with a secret field
+type testPlugin struct {
+	id     string
+	config *testConfig
+}
+
+func (p *testPlugin) ID() string                        { return p.id }
+func (p *testPlugin) Name() string                      { return p.id }
+func (p *testPlugin) Version() string                   { return "1.0.0" }
+func (p *testPlugin) Description() string               { return "Test plugin" }
+func (p *testPlugin) Author() string                    { return "Test Author" }
+func (p *testPlugin) Init(ctx context.Context) error    { return nil }
+func (p *testPlugin) Start(ctx context.Context) error   { return nil }
+func (p *testPlugin) Stop(ctx context.Context) error    { return nil }
+func (p *testPlugin) Destroy(ctx context.Context) error { return nil }
+func (p *testPlugin) Dependencies() []string            { return nil }
+func (p *testPlugin) Config() interface{}               { return p.config }
+func (p *testPlugin) ValidateConfig() error             { return nil }
+func (p *testPlugin) DefaultConfig() interface{} {
+	return &testConfig{Title: "Site", Limit: 10}
+}
+
+func newTestDB(t *testing.T) *database.DB {
+	t.Helper()
+	db, err := database.New(&database.Config{
+		Driver:       "sqlite",
+		SQLitePath:   ":memory:",
+		MaxOpenConns: 1,
+		MaxIdleConns: 1,
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	_, err = db.Exec(`
+		CREATE TABLE settings (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			key VARCHAR(255) NOT NULL UNIQUE,
+			value TEXT,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		);
+		CREATE TABLE themes (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			name VARCHAR(255) NOT NULL UNIQUE,
+			version VARCHAR(50) NOT NULL,
+			active BOOLEAN DEFAULT false
+		);
+		CREATE TABLE plugins (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			name VARCHAR(255) NOT NULL UNIQUE,
+			version VARCHAR(50) NOT NULL,
+			active BOOLEAN DEFAULT true,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		);
+		INSERT INTO settings (key, value) VALUES ('site_name', 'Obtura'), ('active_theme', 'default');
+		INSERT INTO themes (name, version, active) VALUES ('default', '1.0.0', true), ('dark', '1.0.0', false);
+	`)
+	require.NoError(t, err)
+	return db
+}
+
+// newTestSite creates a site with two test plugins, the first with a stored config
+func newTestSite(t *testing.T) (*Site, *plugin.Registry, *database.DB) {
+	t.Helper()
+	db := newTestDB(t)
+	repo := models.NewSiteRepository(db)
+
+	registry := plugin.NewRegistry(chi.NewRouter(), plugin.WithConfigStorage(plugin.NewMemoryConfigStorage()))
+	require.NoError(t, registry.SetActivationStore(repo))
+	require.NoError(t, registry.Register(&testPlugin{id: "test.one", config: &testConfig{}}))
+	require.NoError(t, registry.Register(&testPlugin{id: "test.two", config: &testConfig{}}))
+	require.NoError(t, registry.SetConfig("test.one", map[string]interface{}{"title": "One", "limit": 5, "api_key": "secret"}))
+
+	return NewSite(registry, repo), registry, db
+}
+
+func TestBundle_EncodeDecode(t *testing.T) {
+	site, _, _ := newTestSite(t)
+	b, err := site.Export(ExportOptions{})
+	require.NoError(t, err)
+
+	assert.Equal(t, "default", b.Theme)
+	assert.Equal(t, map[string]string{"site_name": "Obtura"}, b.Settings)
+	assert.Equal(t, []string{"test.one", "test.two"}, b.EnabledPlugins)
+	assert.Equal(t, map[string]interface{}{"title": "One", "limit": float64(5)}, b.Plugins["test.one"])
+
+	for _, format := range []string{FormatJSON, FormatYAML} {
+		t.Run(format, func(t *testing.T) {
+			data, err := b.Encode(format)
+			require.NoError(t, err)
+
+			decoded, err := Decode(data)
+			require.NoError(t, err)
+			assert.Equal(t, b.Theme, decoded.Theme)
+			assert.Equal(t, b.Settings, decoded.Settings)
+			assert.Equal(t, b.EnabledPlugins, decoded.EnabledPlugins)
+			assert.Equal(t, b.Plugins, decoded.Plugins)
+		})
+	}
+
+	// An empty plugin list survives encoding, as it disables every plugin where nil leaves them be
+	b.EnabledPlugins = []string{}
+	for _, format := range []string{FormatJSON, FormatYAML} {
+		data, err := b.Encode(format)
+		require.NoError(t, err)
+		decoded, err := Decode(data)
+		require.NoError(t, err)
+		assert.NotNil(t, decoded.EnabledPlugins, format)
+		assert.Empty(t, decoded.EnabledPlugins, format)
+	}
+
+	_, err = b.Encode("toml")
+	assert.Error(t, err)
+	_, err = Decode([]byte(`{"version": 2}`))
+	assert.Error(t, err)
+	assert.Equal(t, FormatYAML, FormatFromPath("site.yml"))
+	assert.Equal(t, FormatJSON, FormatFromPath("site.json"))
+}
+
+func TestSite_ExportIncludeSecrets(t *testing.T) {
+	site, _, _ := newTestSite(t)
+	b, err := site.Export(ExportOptions{IncludeSecrets: true})
+	require.NoError(t, err)
+	assert.Equal(t, "secret", b.Plugins["test.one"]["api_key"])
+}
+
+func TestSite_Plan(t *testing.T) {
+	site, _, _ := newTestSite(t)
+
+	t.Run("changes", func(t *testing.T) {
+		plan, err := site.Plan(&Bundle{
+			Version:        FormatVersion,
+			Theme:          "dark",
+			EnabledPlugins: []string{"test.one"},
+			Settings:       map[string]string{"site_name": "Renamed", "tagline": "Hello"},
+			Plugins: map[string]map[string]interface{}{
+				"test.one": {"title": "One", "limit": float64(7)},
+			},
+		})
+		require.NoError(t, err)
+
+		assert.Equal(t, []Change{
+			{Scope: "test.one", ConfigChange: plugin.ConfigChange{Path: "limit", Kind: plugin.ChangeModified, Old: float64(5), New: float64(7)}},
+			{Scope: ScopeSettings, ConfigChange: plugin.ConfigChange{Path: "site_name", Kind: plugin.ChangeModified, Old: "Obtura", New: "Renamed"}},
+			{Scope: ScopeSettings, ConfigChange: plugin.ConfigChange{Path: "tagline", Kind: plugin.ChangeAdded, New: "Hello"}},
+			{Scope: ScopeTheme, ConfigChange: plugin.ConfigChange{Path: models.ActiveThemeSetting, Kind: plugin.ChangeModified, Old: "default", New: "dark"}},
+			{Scope: ScopePlugins, ConfigChange: plugin.ConfigChange{Path: "test.two", Kind: plugin.ChangeModified, Old: true, New: false}},
+		}, plan.Changes)
+		assert.Contains(t, plan.String(), `~ settings site_name: "Obtura" -> "Renamed"`)
+
+		// The secret the bundle leaves out is kept
+		assert.Equal(t, "secret", plan.configs["test.one"]["api_key"])
+	})
+
+	t.Run("unchanged", func(t *testing.T) {
+		b, err := site.Export(ExportOptions{})
+		require.NoError(t, err)
+		plan, err := site.Plan(b)
+		require.NoError(t, err)
+		assert.True(t, plan.Empty())
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		_, err := site.Plan(&Bundle{
+			Version:        FormatVersion,
+			EnabledPlugins: []string{"test.missing"},
+			Settings:       map[string]string{models.ActiveThemeSetting: "dark"},
+			Plugins: map[string]map[string]interface{}{
+				"test.one":   {"limit": float64(0)},
+				"test.other": {},
+			},
+		})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "plugin test.one")
+		assert.Contains(t, err.Error(), "plugin test.other is not installed")
+		assert.Contains(t, err.Error(), "plugin test.missing is not installed")
+		assert.Contains(t, err.Error(), "setting active_theme must be set with theme")
+	})
+}
+
+func TestSite_Apply(t *testing.T) {
+	site, registry, db := newTestSite(t)
+	repo := models.NewSiteRepository(db)
+
+	plan, err := site.Apply(context.Background(), &Bundle{
+		Version:        FormatVersion,
+		Theme:          "dark",
+		EnabledPlugins: []string{"test.one"},
+		Settings:       map[string]string{"tagline": "Hello"},
+		Plugins: map[string]map[string]interface{}{
+			"test.one": {"title": "Imported", "limit": float64(5)},
+		},
+	}, "importer")
+	require.NoError(t, err)
+	assert.False(t, plan.Empty())
+
+	config, _ := registry.GetConfigManager().GetConfigMap("test.one")
+	assert.Equal(t, "Imported", config["title"])
+	assert.Equal(t, "secret", config["api_key"])
+
+	history, err := registry.GetConfigManager().History("test.one")
+	require.NoError(t, err)
+	assert.Equal(t, "importer", history[0].Author)
+
+	settings, err := repo.Settings()
+	require.NoError(t, err)
+	assert.Equal(t, "Hello", settings["tagline"])
+	theme, err := repo.ActiveTheme()
+	require.NoError(t, err)
+	assert.Equal(t, "dark", theme)
+
+	var active bool
+	require.NoError(t, db.QueryRow("SELECT active FROM themes WHERE name = ?", "dark").Scan(&active))
+	assert.True(t, active)
+
+	disabled, err := repo.DisabledPlugins()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"test.two"}, disabled)
+	assert.False(t, registry.IsEnabled("test.two"))
+}
+
+func TestSite_ApplyRestoresConfigsOnFailure(t *testing.T) {
+	site, registry, db := newTestSite(t)
+
+	// Without a themes table the transaction fails after the configs are saved
+	_, err := db.Exec("DROP TABLE themes")
+	require.NoError(t, err)
+
+	_, err = site.Apply(context.Background(), &Bundle{
+		Version:  FormatVersion,
+		Theme:    "dark",
+		Settings: map[string]string{"tagline": "Hello"},
+		Plugins: map[string]map[string]interface{}{
+			"test.one": {"title": "Imported", "limit": float64(5)},
+		},
+	}, "importer")
+	require.Error(t, err)
+
+	config, _ := registry.GetConfigManager().GetConfigMap("test.one")
+	assert.Equal(t, "One", config["title"])
+	assert.Equal(t, "secret", config["api_key"])
+
+	settings, err := models.NewSiteRepository(db).Settings()
+	require.NoError(t, err)
+	assert.NotContains(t, settings, "tagline")
+}
+
+func TestSite_ApplyRemovesNewConfigsOnFailure(t *testing.T) {
+	site, registry, db := newTestSite(t)
+	require.NoError(t, registry.DeleteConfig("test.two"))
+
+	// Without a themes table the transaction fails after the configs are saved
+	_, err := db.Exec("DROP TABLE themes")
+	require.NoError(t, err)
+
+	_, err = site.Apply(context.Background(), &Bundle{
+		Version: FormatVersion,
+		Theme:   "dark",
+		Plugins: map[string]map[string]interface{}{
+			"test.two": {"title": "Imported", "limit": float64(5)},
+		},
+	}, "importer")
+	require.Error(t, err)
+
+	_, ok := registry.GetConfigManager().GetConfigMap("test.two")
+	assert.False(t, ok, "a plugin without a config before the import has none after it")
+	p, err := registry.Get("test.two")
+	require.NoError(t, err)
+	assert.Equal(t, "Site", p.Config().(*testConfig).Title)
+}
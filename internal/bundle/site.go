@@ -0,0 +1,335 @@
+package bundle
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/btassone/obtura/internal/models"
+	"github.com/btassone/obtura/pkg/plugin"
+)
+
+// Change scopes other than plugin IDs, which scope plugin config changes
+const (
+	ScopeSettings = "settings"
+	ScopeTheme    = "theme"
+	ScopePlugins  = "plugins" // Enabled plugins
+)
+
+// Change is a single difference between a site and a bundle
+type Change struct {
+	Scope string
+	plugin.ConfigChange
+}
+
+// Plan lists what applying a bundle would change
+type Plan struct {
+	Changes []Change
+
+	// Configs to save, with secrets the bundle leaves out kept from the current configs
+	configs map[string]map[string]interface{}
+}
+
+// Empty reports whether applying the bundle would change nothing
+func (p *Plan) Empty() bool {
+	return len(p.Changes) == 0
+}
+
+// String describes the changes one per line, as in a diff
+func (p *Plan) String() string {
+	var b strings.Builder
+	for _, c := range p.Changes {
+		switch c.Kind {
+		case plugin.ChangeAdded:
+			fmt.Fprintf(&b, "+ %s %s: %s\n", c.Scope, c.Path, FormatValue(c.New))
+		case plugin.ChangeRemoved:
+			fmt.Fprintf(&b, "- %s %s: %s\n", c.Scope, c.Path, FormatValue(c.Old))
+		default:
+			fmt.Fprintf(&b, "~ %s %s: %s -> %s\n", c.Scope, c.Path, FormatValue(c.Old), FormatValue(c.New))
+		}
+	}
+	return b.String()
+}
+
+// FormatValue formats a changed value for display
+func FormatValue(value interface{}) string {
+	if value == nil {
+		return "-"
+	}
+	if s, ok := value.(string); ok {
+		return fmt.Sprintf("%q", s)
+	}
+	return fmt.Sprint(value)
+}
+
+// Site is the configuration a bundle is exported from and applied to
+type Site struct {
+	registry *plugin.Registry
+	repo     *models.SiteRepository
+}
+
+// NewSite creates a site over a plugin registry and the site tables
+func NewSite(registry *plugin.Registry, repo *models.SiteRepository) *Site {
+	return &Site{registry: registry, repo: repo}
+}
+
+// ExportOptions control what Export includes
+type ExportOptions struct {
+	// IncludeSecrets exports secret config fields in plain text
+	IncludeSecrets bool
+}
+
+// Export creates a bundle of the site's current configuration.
+// Plugin configs are exported as stored, without config file or environment overrides.
+func (s *Site) Export(opts ExportOptions) (*Bundle, error) {
+	settings, err := s.repo.Settings()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read settings: %w", err)
+	}
+	theme := settings[models.ActiveThemeSetting]
+	delete(settings, models.ActiveThemeSetting)
+
+	b := &Bundle{
+		Version:        FormatVersion,
+		ExportedAt:     time.Now().UTC(),
+		Theme:          theme,
+		EnabledPlugins: []string{},
+		Settings:       settings,
+		Plugins:        make(map[string]map[string]interface{}),
+	}
+
+	cm := s.registry.GetConfigManager()
+	for _, p := range s.sortedPlugins() {
+		if s.enabled(p.ID()) {
+			b.EnabledPlugins = append(b.EnabledPlugins, p.ID())
+		}
+		config, ok := cm.GetConfigMap(p.ID())
+		if !ok {
+			continue
+		}
+		if !opts.IncludeSecrets {
+			cm.StripSecrets(p.ID(), config)
+		}
+		if config, err = normalizeMap(config); err != nil {
+			return nil, fmt.Errorf("failed to export config of plugin %s: %w", p.ID(), err)
+		}
+		b.Plugins[p.ID()] = config
+	}
+	return b, nil
+}
+
+// Plan validates a bundle against the site's plugins and their config schemas, and lists what
+// applying it would change. Plugin configs in the bundle replace the current ones, keeping secrets
+// the bundle leaves out; settings and plugin configs missing from the bundle are left as they are.
+func (s *Site) Plan(b *Bundle) (*Plan, error) {
+	plan := &Plan{configs: make(map[string]map[string]interface{})}
+	var errs []error
+
+	// Plugin configs
+	cm := s.registry.GetConfigManager()
+	for _, id := range sortedKeys(b.Plugins) {
+		if _, err := s.registry.Get(id); err != nil {
+			errs = append(errs, fmt.Errorf("plugin %s is not installed", id))
+			continue
+		}
+		current, ok := cm.GetConfigMap(id)
+		if !ok {
+			current = make(map[string]interface{})
+		}
+		config := copyMap(b.Plugins[id])
+		cm.KeepSecrets(id, config, current)
+		if err := cm.ValidateConfig(id, config); err != nil {
+			errs = append(errs, fmt.Errorf("plugin %s: %w", id, err))
+			continue
+		}
+
+		changes := cm.MaskSecretChanges(id, plugin.DiffConfig(current, config))
+		if len(changes) > 0 {
+			plan.configs[id] = config
+		}
+		for _, change := range changes {
+			plan.Changes = append(plan.Changes, Change{Scope: id, ConfigChange: change})
+		}
+	}
+
+	// Settings
+	current, err := s.repo.Settings()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read settings: %w", err)
+	}
+	for _, key := range sortedKeys(b.Settings) {
+		if key == models.ActiveThemeSetting {
+			errs = append(errs, fmt.Errorf("setting %s must be set with theme", key))
+			continue
+		}
+		value := b.Settings[key]
+		old, exists := current[key]
+		switch {
+		case !exists:
+			plan.Changes = append(plan.Changes, Change{Scope: ScopeSettings, ConfigChange: plugin.ConfigChange{Path: key, Kind: plugin.ChangeAdded, New: value}})
+		case old != value:
+			plan.Changes = append(plan.Changes, Change{Scope: ScopeSettings, ConfigChange: plugin.ConfigChange{Path: key, Kind: plugin.ChangeModified, Old: old, New: value}})
+		}
+	}
+
+	// Theme
+	if theme := current[models.ActiveThemeSetting]; b.Theme != "" && b.Theme != theme {
+		plan.Changes = append(plan.Changes, Change{Scope: ScopeTheme, ConfigChange: plugin.ConfigChange{Path: models.ActiveThemeSetting, Kind: plugin.ChangeModified, Old: theme, New: b.Theme}})
+	}
+
+	// Enabled plugins; a bundle without the list leaves them as they are
+	if b.EnabledPlugins != nil {
+		enable := make(map[string]bool, len(b.EnabledPlugins))
+		for _, id := range b.EnabledPlugins {
+			if _, err := s.registry.Get(id); err != nil {
+				errs = append(errs, fmt.Errorf("plugin %s is not installed", id))
+				continue
+			}
+			enable[id] = true
+		}
+		for _, p := range s.sortedPlugins() {
+			if was := s.enabled(p.ID()); was != enable[p.ID()] {
+				plan.Changes = append(plan.Changes, Change{Scope: ScopePlugins, ConfigChange: plugin.ConfigChange{Path: p.ID(), Kind: plugin.ChangeModified, Old: was, New: enable[p.ID()]}})
+			}
+		}
+	}
+
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return plan, nil
+}
+
+// Apply validates a bundle and applies it all-or-nothing. Plugin configs are saved first, then settings,
+// the theme and plugin activation are written in one transaction; if anything fails, the configs
+// already saved are restored, or removed if the plugin had none. If the registry is running, plugins are then enabled or disabled to match.
+func (s *Site) Apply(ctx context.Context, b *Bundle, author string) (*Plan, error) {
+	plan, err := s.Plan(b)
+	if err != nil {
+		return nil, err
+	}
+	if plan.Empty() {
+		return plan, nil
+	}
+
+	// Configs are saved outside the transaction, as config storage may use the same database
+	cm := s.registry.GetConfigManager()
+	previous := make(map[string]map[string]interface{}, len(plan.configs))
+	var saved []string
+	for _, id := range sortedKeys(plan.configs) {
+		if config, ok := cm.GetConfigMap(id); ok {
+			previous[id] = config
+		}
+		if err = s.registry.ReplaceConfig(id, plan.configs[id], author, "Imported from bundle"); err != nil {
+			err = fmt.Errorf("failed to apply config of plugin %s: %w", id, err)
+			break
+		}
+		saved = append(saved, id)
+	}
+
+	if err == nil {
+		err = s.repo.Transaction(ctx, func(repo *models.SiteRepository) error {
+			for _, c := range plan.Changes {
+				var err error
+				switch c.Scope {
+				case ScopeSettings:
+					err = repo.SetSetting(c.Path, c.New.(string))
+				case ScopeTheme:
+					err = repo.SetActiveTheme(c.New.(string))
+				case ScopePlugins:
+					err = repo.SetPluginEnabled(c.Path, c.New.(bool))
+				}
+				if err != nil {
+					return fmt.Errorf("failed to apply %s %s: %w", c.Scope, c.Path, err)
+				}
+			}
+			return nil
+		})
+	}
+
+	if err != nil {
+		// Restore in reverse order of saving, removing configs that didn't exist before
+		for i := len(saved) - 1; i >= 0; i-- {
+			id := saved[i]
+			var restoreErr error
+			if config, ok := previous[id]; ok {
+				restoreErr = s.registry.ReplaceConfig(id, config, author, "Restored after failed import")
+			} else {
+				restoreErr = s.registry.DeleteConfig(id)
+			}
+			if restoreErr != nil {
+				err = errors.Join(err, fmt.Errorf("failed to restore config of plugin %s: %w", id, restoreErr))
+			}
+		}
+		return nil, err
+	}
+
+	if s.registry.Running() {
+		var errs []error
+		for _, c := range plan.Changes {
+			if c.Scope != ScopePlugins {
+				continue
+			}
+			if c.New.(bool) {
+				errs = append(errs, s.registry.Enable(ctx, c.Path))
+			} else {
+				errs = append(errs, s.registry.Disable(ctx, c.Path))
+			}
+		}
+		if err := errors.Join(errs...); err != nil {
+			return plan, fmt.Errorf("bundle applied, but plugins could not be switched until restart: %w", err)
+		}
+	}
+	return plan, nil
+}
+
+// enabled reports whether a plugin is enabled, whether or not it is running
+func (s *Site) enabled(pluginID string) bool {
+	status, err := s.registry.Status(pluginID)
+	return err == nil && status.State != plugin.StateDisabled
+}
+
+// sortedPlugins returns the registered plugins ordered by ID
+func (s *Site) sortedPlugins() []plugin.Plugin {
+	plugins := s.registry.List()
+	sort.Slice(plugins, func(i, j int) bool {
+		return plugins[i].ID() < plugins[j].ID()
+	})
+	return plugins
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// normalizeMap converts a config to the types it decodes to from a bundle file
+func normalizeMap(config map[string]interface{}) (map[string]interface{}, error) {
+	data, err := json.Marshal(config)
+	if err != nil {
+		return nil, err
+	}
+	var normalized map[string]interface{}
+	err = json.Unmarshal(data, &normalized)
+	return normalized, err
+}
+
+// copyMap copies a decoded config, including nested objects
+func copyMap(config map[string]interface{}) map[string]interface{} {
+	copied := make(map[string]interface{}, len(config))
+	for key, value := range config {
+		if nested, ok := value.(map[string]interface{}); ok {
+			value = copyMap(nested)
+		}
+		copied[key] = value
+	}
+	return copied
+}
@@ -24,6 +24,7 @@ func GetDatabaseConfig() *database.Config {
 			MaxOpenConns:    25,
 			MaxIdleConns:    5,
 			ConnMaxLifetime: 5 * time.Minute,
+			QueryTimeout:    queryTimeout(),
 		}
 	}
 
@@ -40,11 +41,17 @@ func GetDatabaseConfig() *database.Config {
 		MaxOpenConns:    getEnvAsInt("DB_MAX_OPEN_CONNS", 25),
 		MaxIdleConns:    getEnvAsInt("DB_MAX_IDLE_CONNS", 5),
 		ConnMaxLifetime: time.Duration(getEnvAsInt("DB_CONN_MAX_LIFETIME_MIN", 5)) * time.Minute,
+		QueryTimeout:    queryTimeout(),
 	}
 
 	return config
 }
 
+// queryTimeout returns the default statement timeout; 0 disables it
+func queryTimeout() time.Duration {
+	return time.Duration(getEnvAsInt("DB_QUERY_TIMEOUT_SEC", 30)) * time.Second
+}
+
 // getDefaultPort returns the default port for a database driver
 func getDefaultPort(driver string) int {
 	switch driver {
@@ -0,0 +1,21 @@
+package config
+
+import (
+	"os"
+
+	"github.com/btassone/obtura/pkg/logging"
+)
+
+// GetLoggingConfig returns logging configuration based on environment
+func GetLoggingConfig() *logging.Config {
+	config := logging.DefaultConfig()
+
+	// Production defaults to JSON for log aggregation
+	if env := os.Getenv("APP_ENV"); env != "" && env != "development" {
+		config.Format = logging.FormatJSON
+	}
+
+	config.Level = getEnv("LOG_LEVEL", config.Level)
+	config.Format = getEnv("LOG_FORMAT", config.Format)
+	return config
+}
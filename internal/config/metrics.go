@@ -0,0 +1,33 @@
+package config
+
+import (
+	"strings"
+)
+
+// MetricsConfig configures the metrics endpoint
+type MetricsConfig struct {
+	Enabled    bool
+	Path       string
+	Token      string
+	AllowedIPs []string
+}
+
+// GetMetricsConfig returns metrics configuration based on environment.
+// The endpoint is off unless METRICS_ENABLED is "true".
+func GetMetricsConfig() *MetricsConfig {
+	config := &MetricsConfig{
+		Enabled: getEnv("METRICS_ENABLED", "false") == "true",
+		Path:    getEnv("METRICS_PATH", "/metrics"),
+		Token:   getEnv("METRICS_TOKEN", ""),
+	}
+
+	if ips := getEnv("METRICS_ALLOWED_IPS", ""); ips != "" {
+		for _, ip := range strings.Split(ips, ",") {
+			if ip = strings.TrimSpace(ip); ip != "" {
+				config.AllowedIPs = append(config.AllowedIPs, ip)
+			}
+		}
+	}
+
+	return config
+}
@@ -0,0 +1,46 @@
+package config
+
+import (
+	"os"
+	"strings"
+)
+
+// pluginConfigFiles are looked for in the working directory when OBTURA_CONFIG_FILE is unset
+var pluginConfigFiles = []string{"obtura.yaml", "obtura.yml", "obtura.toml"}
+
+// PluginConfigStorageConfig selects where plugin configurations are stored
+type PluginConfigStorageConfig struct {
+	Storage            string   // file, db or memory
+	Path               string   // Directory for file storage
+	SecretKey          string   // Encrypts secret config fields; secrets are stored in plaintext when empty
+	PreviousSecretKeys []string // Older keys still accepted for decryption until secrets are rotated
+	OverrideFile       string   // YAML or TOML file overriding stored plugin config; optional
+}
+
+// GetPluginConfigStorageConfig returns plugin config storage settings based on environment
+func GetPluginConfigStorageConfig() *PluginConfigStorageConfig {
+	var previous []string
+	for _, key := range strings.Split(getEnv("APP_PREVIOUS_KEYS", ""), ",") {
+		if key = strings.TrimSpace(key); key != "" {
+			previous = append(previous, key)
+		}
+	}
+
+	return &PluginConfigStorageConfig{
+		Storage:            getEnv("PLUGIN_CONFIG_STORAGE", "file"),
+		Path:               getEnv("PLUGIN_CONFIG_PATH", "./configs/plugins"),
+		SecretKey:          getEnv("APP_KEY", ""),
+		PreviousSecretKeys: previous,
+		OverrideFile:       getEnv("OBTURA_CONFIG_FILE", findPluginConfigFile()),
+	}
+}
+
+// findPluginConfigFile returns the first plugin config file present in the working directory
+func findPluginConfigFile() string {
+	for _, name := range pluginConfigFiles {
+		if _, err := os.Stat(name); err == nil {
+			return name
+		}
+	}
+	return ""
+}
@@ -0,0 +1,19 @@
+package config
+
+// TracingConfig configures request tracing
+type TracingConfig struct {
+	Enabled     bool
+	BufferSize  int
+	OTLPFile    string
+	ServiceName string
+}
+
+// GetTracingConfig returns tracing configuration based on environment
+func GetTracingConfig() *TracingConfig {
+	return &TracingConfig{
+		Enabled:     getEnv("TRACING_ENABLED", "true") == "true",
+		BufferSize:  getEnvAsInt("TRACING_BUFFER_SIZE", 2000),
+		OTLPFile:    getEnv("TRACING_OTLP_FILE", ""),
+		ServiceName: getEnv("TRACING_SERVICE_NAME", "obtura"),
+	}
+}
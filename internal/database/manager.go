@@ -2,6 +2,8 @@ package database
 
 import (
 	"fmt"
+	"io/fs"
+	"log/slog"
 	"os"
 	"path/filepath"
 
@@ -16,6 +18,7 @@ type Manager struct {
 	db              *database.DB
 	migrationRunner *database.MigrationRunner
 	seederRunner    *database.SeederRunner
+	allowDrift      bool
 }
 
 // NewManager creates a new database manager
@@ -40,11 +43,15 @@ func NewManager() (*Manager, error) {
 	// Set driver name for migrations
 	migrations.SetDriverName(dbConfig.Driver)
 
-	// Create migration runner
+	// Create migration runner with the core SQL and Go migrations
 	migrationRunner := database.NewMigrationRunner(db)
 	for _, migration := range migrations.GetMigrations() {
 		migrationRunner.AddMigration(migration)
 	}
+	if err := migrationRunner.AddMigrationsFS(migrations.FS); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to load migrations: %w", err)
+	}
 
 	// Create seeder runner
 	seederRunner := database.NewSeederRunner(db)
@@ -64,26 +71,65 @@ func (m *Manager) DB() *database.DB {
 	return m.db
 }
 
+// AddPluginMigrations adds the SQL migrations plugins ship, keyed by plugin ID, to those Migrate
+// runs. Each plugin's versions are prefixed with its ID, as in "hello/001_create_greetings",
+// so they can't clash with the core migrations or another plugin's.
+func (m *Manager) AddPluginMigrations(pluginFS map[string]fs.FS) error {
+	for pluginID, fsys := range pluginFS {
+		loaded, err := database.LoadMigrations(fsys, m.db.Driver())
+		if err != nil {
+			return fmt.Errorf("failed to load migrations of plugin %s: %w", pluginID, err)
+		}
+		for _, migration := range loaded {
+			migration.Version = pluginID + "/" + migration.Version
+			m.migrationRunner.AddMigration(migration)
+		}
+	}
+	return nil
+}
+
+// AllowMigrationDrift sets whether Migrate runs pending migrations even though applied ones
+// have changed since they were applied
+func (m *Manager) AllowMigrationDrift(allow bool) {
+	m.migrationRunner.AllowDrift(allow)
+	m.allowDrift = allow
+}
+
 // Migrate runs all pending migrations
 func (m *Manager) Migrate() error {
-	fmt.Println("Running database migrations...")
+	if m.allowDrift {
+		drifted, err := m.migrationRunner.Verify()
+		if err != nil {
+			return fmt.Errorf("migration failed: %w", err)
+		}
+		for _, d := range drifted {
+			slog.Warn("Applied migration has changed", "version", d.Version)
+		}
+	}
+
+	slog.Info("Running database migrations")
 	if err := m.migrationRunner.Run(); err != nil {
 		return fmt.Errorf("migration failed: %w", err)
 	}
-	fmt.Println("Migrations completed successfully!")
+	slog.Info("Migrations completed")
 	return nil
 }
 
 // Rollback rolls back migrations
 func (m *Manager) Rollback(steps int) error {
-	fmt.Printf("Rolling back %d migration(s)...\n", steps)
+	slog.Info("Rolling back migrations", "steps", steps)
 	if err := m.migrationRunner.Rollback(steps); err != nil {
 		return fmt.Errorf("rollback failed: %w", err)
 	}
-	fmt.Println("Rollback completed successfully!")
+	slog.Info("Rollback completed")
 	return nil
 }
 
+// VerifyMigrations returns the applied migrations that have changed since they were applied
+func (m *Manager) VerifyMigrations() ([]database.MigrationDrift, error) {
+	return m.migrationRunner.Verify()
+}
+
 // MigrationStatus returns the status of all migrations
 func (m *Manager) MigrationStatus() ([]database.MigrationStatus, error) {
 	return m.migrationRunner.Status()
@@ -92,15 +138,15 @@ func (m *Manager) MigrationStatus() ([]database.MigrationStatus, error) {
 // Seed runs database seeders
 func (m *Manager) Seed(specific ...string) error {
 	if len(specific) > 0 {
-		fmt.Printf("Running specific seeders: %v\n", specific)
+		slog.Info("Running specific seeders", "seeders", specific)
 		return m.seederRunner.RunSpecific(specific...)
 	}
 
-	fmt.Println("Running all seeders...")
+	slog.Info("Running all seeders")
 	if err := m.seederRunner.Run(); err != nil {
 		return fmt.Errorf("seeding failed: %w", err)
 	}
-	fmt.Println("Seeding completed successfully!")
+	slog.Info("Seeding completed")
 	return nil
 }
 
@@ -4,6 +4,7 @@ import (
 	"database/sql"
 	"testing"
 
+	"github.com/btassone/obtura/internal/database/migrations"
 	"github.com/btassone/obtura/pkg/database"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -53,6 +54,7 @@ func TestManager_Migrate(t *testing.T) {
 	migrationRunner.AddMigration(database.Migration{
 		Version:     "001_create_test_table",
 		Description: "Create test table",
+		Content:     "CREATE TABLE test_table (id, name)",
 		Up: func(tx *sql.Tx) error {
 			query := `CREATE TABLE IF NOT EXISTS test_table (
 				id INTEGER PRIMARY KEY,
@@ -160,3 +162,21 @@ func TestManager_Query(t *testing.T) {
 }
 
 
+
+func TestCoreMigrations(t *testing.T) {
+	// Every dialect has an up migration for each version
+	for _, driver := range []string{"sqlite3", "mysql", "postgres"} {
+		loaded, err := database.LoadMigrations(migrations.FS, driver)
+		require.NoError(t, err, driver)
+		assert.Len(t, loaded, 8, driver)
+	}
+
+	db, err := database.New(&database.Config{Driver: "sqlite", SQLitePath: ":memory:", MaxOpenConns: 1, MaxIdleConns: 1})
+	require.NoError(t, err)
+	defer db.Close()
+
+	runner := database.NewMigrationRunner(db)
+	require.NoError(t, runner.AddMigrationsFS(migrations.FS))
+	require.NoError(t, runner.Run())
+	require.NoError(t, runner.Rollback(8))
+}
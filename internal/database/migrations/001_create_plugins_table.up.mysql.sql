@@ -0,0 +1,11 @@
+CREATE TABLE plugins (
+	id INT AUTO_INCREMENT PRIMARY KEY,
+	name VARCHAR(255) NOT NULL UNIQUE,
+	version VARCHAR(50) NOT NULL,
+	description TEXT,
+	author VARCHAR(255),
+	active BOOLEAN DEFAULT true,
+	settings JSON,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP
+);
@@ -0,0 +1,11 @@
+CREATE TABLE plugins (
+	id SERIAL PRIMARY KEY,
+	name VARCHAR(255) NOT NULL UNIQUE,
+	version VARCHAR(50) NOT NULL,
+	description TEXT,
+	author VARCHAR(255),
+	active BOOLEAN DEFAULT true,
+	settings JSONB,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1,11 @@
+CREATE TABLE plugins (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	name VARCHAR(255) NOT NULL UNIQUE,
+	version VARCHAR(50) NOT NULL,
+	description TEXT,
+	author VARCHAR(255),
+	active BOOLEAN DEFAULT true,
+	settings TEXT, -- JSON data
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
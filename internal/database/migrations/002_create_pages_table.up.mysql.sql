@@ -0,0 +1,20 @@
+CREATE TABLE pages (
+	id INT AUTO_INCREMENT PRIMARY KEY,
+	title VARCHAR(255) NOT NULL,
+	slug VARCHAR(255) NOT NULL UNIQUE,
+	content TEXT,
+	excerpt TEXT,
+	status VARCHAR(50) DEFAULT 'draft',
+	layout VARCHAR(100) DEFAULT 'default',
+	parent_id INT,
+	menu_order INT DEFAULT 0,
+	meta_title VARCHAR(255),
+	meta_description TEXT,
+	meta_keywords TEXT,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+	published_at TIMESTAMP NULL,
+	FOREIGN KEY (parent_id) REFERENCES pages(id) ON DELETE SET NULL,
+	INDEX idx_slug (slug),
+	INDEX idx_status (status)
+);
@@ -0,0 +1,18 @@
+CREATE TABLE pages (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	title VARCHAR(255) NOT NULL,
+	slug VARCHAR(255) NOT NULL UNIQUE,
+	content TEXT,
+	excerpt TEXT,
+	status VARCHAR(50) DEFAULT 'draft',
+	layout VARCHAR(100) DEFAULT 'default',
+	parent_id INTEGER,
+	menu_order INTEGER DEFAULT 0,
+	meta_title VARCHAR(255),
+	meta_description TEXT,
+	meta_keywords TEXT,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	published_at TIMESTAMP,
+	FOREIGN KEY (parent_id) REFERENCES pages(id) ON DELETE SET NULL
+);
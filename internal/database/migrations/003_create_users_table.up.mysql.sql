@@ -0,0 +1,17 @@
+CREATE TABLE users (
+	id INT AUTO_INCREMENT PRIMARY KEY,
+	name VARCHAR(255) NOT NULL,
+	email VARCHAR(255) NOT NULL UNIQUE,
+	password VARCHAR(255) NOT NULL,
+	role VARCHAR(50) DEFAULT 'user',
+	avatar VARCHAR(255),
+	bio TEXT,
+	active BOOLEAN DEFAULT true,
+	email_verified_at TIMESTAMP NULL,
+	remember_token VARCHAR(100),
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+	deleted_at TIMESTAMP NULL,
+	INDEX idx_email (email),
+	INDEX idx_role (role)
+);
@@ -0,0 +1,13 @@
+CREATE TABLE settings (
+	id INT AUTO_INCREMENT PRIMARY KEY,
+	`key` VARCHAR(255) NOT NULL UNIQUE,
+	value TEXT,
+	type VARCHAR(50) DEFAULT 'string',
+	group_name VARCHAR(100) DEFAULT 'general',
+	description TEXT,
+	options JSON,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+	INDEX idx_key (`key`),
+	INDEX idx_group (group_name)
+);
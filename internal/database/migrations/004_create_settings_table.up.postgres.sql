@@ -0,0 +1,14 @@
+CREATE TABLE settings (
+	id SERIAL PRIMARY KEY,
+	key VARCHAR(255) NOT NULL UNIQUE,
+	value TEXT,
+	type VARCHAR(50) DEFAULT 'string',
+	group_name VARCHAR(100) DEFAULT 'general',
+	description TEXT,
+	options JSONB,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_settings_key ON settings(key);
+CREATE INDEX idx_settings_group ON settings(group_name);
@@ -0,0 +1,11 @@
+CREATE TABLE settings (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	key VARCHAR(255) NOT NULL UNIQUE,
+	value TEXT,
+	type VARCHAR(50) DEFAULT 'string',
+	group_name VARCHAR(100) DEFAULT 'general',
+	description TEXT,
+	options TEXT, -- JSON array for select/multiselect types
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
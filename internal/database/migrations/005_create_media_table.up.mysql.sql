@@ -0,0 +1,19 @@
+CREATE TABLE media (
+	id INT AUTO_INCREMENT PRIMARY KEY,
+	filename VARCHAR(255) NOT NULL,
+	original_name VARCHAR(255) NOT NULL,
+	mime_type VARCHAR(100) NOT NULL,
+	size BIGINT NOT NULL,
+	path VARCHAR(500) NOT NULL,
+	url VARCHAR(500) NOT NULL,
+	alt_text VARCHAR(255),
+	title VARCHAR(255),
+	description TEXT,
+	dimensions VARCHAR(50),
+	user_id INT,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+	FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE SET NULL,
+	INDEX idx_mime_type (mime_type),
+	INDEX idx_user_id (user_id)
+);
@@ -0,0 +1,20 @@
+CREATE TABLE media (
+	id SERIAL PRIMARY KEY,
+	filename VARCHAR(255) NOT NULL,
+	original_name VARCHAR(255) NOT NULL,
+	mime_type VARCHAR(100) NOT NULL,
+	size BIGINT NOT NULL,
+	path VARCHAR(500) NOT NULL,
+	url VARCHAR(500) NOT NULL,
+	alt_text VARCHAR(255),
+	title VARCHAR(255),
+	description TEXT,
+	dimensions VARCHAR(50),
+	user_id INTEGER,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE SET NULL
+);
+
+CREATE INDEX idx_media_mime_type ON media(mime_type);
+CREATE INDEX idx_media_user_id ON media(user_id);
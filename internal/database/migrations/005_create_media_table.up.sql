@@ -0,0 +1,17 @@
+CREATE TABLE media (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	filename VARCHAR(255) NOT NULL,
+	original_name VARCHAR(255) NOT NULL,
+	mime_type VARCHAR(100) NOT NULL,
+	size INTEGER NOT NULL,
+	path VARCHAR(500) NOT NULL,
+	url VARCHAR(500) NOT NULL,
+	alt_text VARCHAR(255),
+	title VARCHAR(255),
+	description TEXT,
+	dimensions VARCHAR(50), -- e.g., "1920x1080"
+	user_id INTEGER,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE SET NULL
+);
@@ -0,0 +1,14 @@
+CREATE TABLE themes (
+	id INT AUTO_INCREMENT PRIMARY KEY,
+	name VARCHAR(255) NOT NULL UNIQUE,
+	version VARCHAR(50) NOT NULL,
+	description TEXT,
+	author VARCHAR(255),
+	screenshot VARCHAR(500),
+	active BOOLEAN DEFAULT false,
+	settings JSON,
+	constraints JSON,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+	INDEX idx_active (active)
+);
@@ -0,0 +1,15 @@
+CREATE TABLE themes (
+	id SERIAL PRIMARY KEY,
+	name VARCHAR(255) NOT NULL UNIQUE,
+	version VARCHAR(50) NOT NULL,
+	description TEXT,
+	author VARCHAR(255),
+	screenshot VARCHAR(500),
+	active BOOLEAN DEFAULT false,
+	settings JSONB,
+	constraints JSONB,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_themes_active ON themes(active);
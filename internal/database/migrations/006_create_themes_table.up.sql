@@ -0,0 +1,13 @@
+CREATE TABLE themes (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	name VARCHAR(255) NOT NULL UNIQUE,
+	version VARCHAR(50) NOT NULL,
+	description TEXT,
+	author VARCHAR(255),
+	screenshot VARCHAR(500),
+	active BOOLEAN DEFAULT false,
+	settings TEXT, -- JSON data
+	constraints TEXT, -- JSON data for theme constraints
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
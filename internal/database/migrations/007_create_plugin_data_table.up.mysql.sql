@@ -0,0 +1,8 @@
+CREATE TABLE plugin_data (
+	plugin_id VARCHAR(191) NOT NULL,
+	item_key VARCHAR(191) NOT NULL,
+	value TEXT NOT NULL,
+	expires_at TIMESTAMP NULL DEFAULT NULL,
+	PRIMARY KEY (plugin_id, item_key),
+	INDEX idx_plugin_data_expires_at (expires_at)
+);
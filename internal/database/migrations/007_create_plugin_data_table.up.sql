@@ -0,0 +1,9 @@
+CREATE TABLE plugin_data (
+	plugin_id VARCHAR(255) NOT NULL,
+	item_key VARCHAR(255) NOT NULL,
+	value TEXT NOT NULL,
+	expires_at TIMESTAMP NULL,
+	PRIMARY KEY (plugin_id, item_key)
+);
+
+CREATE INDEX idx_plugin_data_expires_at ON plugin_data(expires_at);
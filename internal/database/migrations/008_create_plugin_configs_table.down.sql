@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS plugin_config_revisions;
+DROP TABLE IF EXISTS plugin_configs;
@@ -0,0 +1,16 @@
+-- Description: Create plugin config and config revision tables
+CREATE TABLE plugin_configs (
+	plugin_id VARCHAR(191) NOT NULL PRIMARY KEY,
+	config LONGTEXT NOT NULL,
+	updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE plugin_config_revisions (
+	plugin_id VARCHAR(191) NOT NULL,
+	version INT NOT NULL,
+	author VARCHAR(255) NOT NULL DEFAULT '',
+	message TEXT NOT NULL,
+	config LONGTEXT NOT NULL,
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY (plugin_id, version)
+);
@@ -0,0 +1,16 @@
+-- Description: Create plugin config and config revision tables
+CREATE TABLE plugin_configs (
+	plugin_id VARCHAR(255) NOT NULL PRIMARY KEY,
+	config TEXT NOT NULL,
+	updated_at TIMESTAMP NOT NULL
+);
+
+CREATE TABLE plugin_config_revisions (
+	plugin_id VARCHAR(255) NOT NULL,
+	version INTEGER NOT NULL,
+	author VARCHAR(255) NOT NULL DEFAULT '',
+	message TEXT NOT NULL,
+	config TEXT NOT NULL,
+	created_at TIMESTAMP NOT NULL,
+	PRIMARY KEY (plugin_id, version)
+);
@@ -1,23 +1,32 @@
 package migrations
 
 import (
+	"embed"
+
 	"github.com/btassone/obtura/pkg/database"
 )
 
+// FS holds the core SQL migrations: NNN_name.up.sql files with their .down.sql rollbacks and
+// .sqlite.sql, .mysql.sql or .postgres.sql variants, see database.LoadMigrations.
+// Migrations registered with RegisterMigration run with them, ordered by version.
+//
+//go:embed *.sql
+var FS embed.FS
+
 var (
-	// migrations holds all registered migrations
+	// migrations holds the registered Go migrations
 	migrations []database.Migration
 	
-	// DriverName is set by the migration runner to indicate which database is being used
+	// DriverName is set by the migration runner so Go migrations can tell which database is used
 	DriverName string
 )
 
-// RegisterMigration adds a migration to the registry
+// RegisterMigration adds a Go migration to the registry, for changes SQL can't express
 func RegisterMigration(migration *database.Migration) {
 	migrations = append(migrations, *migration)
 }
 
-// GetMigrations returns all registered migrations
+// GetMigrations returns the registered Go migrations
 func GetMigrations() []database.Migration {
 	return migrations
 }
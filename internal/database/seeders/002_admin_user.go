@@ -1,7 +1,7 @@
 package seeders
 
 import (
-	"fmt"
+	"log/slog"
 	"time"
 
 	"github.com/btassone/obtura/pkg/database"
@@ -21,7 +21,7 @@ func init() {
 			}
 
 			if count > 0 {
-				fmt.Println("Admin user already exists, skipping...")
+				slog.Info("Admin user already exists, skipping")
 				return nil
 			}
 
@@ -51,12 +51,10 @@ func init() {
 				return err
 			}
 
-			fmt.Printf("\n=================================\n")
-			fmt.Printf("Admin user created successfully!\n")
-			fmt.Printf("Email: admin@example.com\n")
-			fmt.Printf("Password: %s\n", defaultPassword)
-			fmt.Printf("Please change this password immediately!\n")
-			fmt.Printf("=================================\n\n")
+			slog.Warn("Admin user created; change this password immediately",
+				"email", "admin@example.com",
+				"password", defaultPassword,
+			)
 
 			return nil
 		},
@@ -0,0 +1,45 @@
+package models
+
+import (
+	"time"
+
+	"github.com/btassone/obtura/pkg/database"
+)
+
+// Media represents an uploaded file
+type Media struct {
+	ID           int64     `db:"id"`
+	Filename     string    `db:"filename"`
+	OriginalName string    `db:"original_name"`
+	MimeType     string    `db:"mime_type"`
+	Size         int64     `db:"size"`
+	Path         string    `db:"path"`
+	URL          string    `db:"url"`
+	AltText      *string   `db:"alt_text"`
+	Title        *string   `db:"title"`
+	Description  *string   `db:"description"`
+	Dimensions   *string   `db:"dimensions"`
+	UserID       *int64    `db:"user_id"`
+	CreatedAt    time.Time `db:"created_at"`
+	UpdatedAt    time.Time `db:"updated_at"`
+
+	// Loaded with the user relation
+	User *User
+}
+
+// TableName returns the table name
+func (m *Media) TableName() string {
+	return "media"
+}
+
+// PrimaryKey returns the primary key field
+func (m *Media) PrimaryKey() string {
+	return "id"
+}
+
+// Relations declares the user who uploaded the file
+func (m *Media) Relations() database.Relations {
+	return database.Relations{
+		"user": database.BelongsTo("User", "user_id"),
+	}
+}
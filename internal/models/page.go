@@ -0,0 +1,48 @@
+package models
+
+import (
+	"time"
+
+	"github.com/btassone/obtura/pkg/database"
+)
+
+// Page represents a content page, which may be nested under a parent page
+type Page struct {
+	ID              int64      `db:"id"`
+	Title           string     `db:"title"`
+	Slug            string     `db:"slug"`
+	Content         *string    `db:"content"`
+	Excerpt         *string    `db:"excerpt"`
+	Status          string     `db:"status"`
+	Layout          string     `db:"layout"`
+	ParentID        *int64     `db:"parent_id"`
+	MenuOrder       int        `db:"menu_order"`
+	MetaTitle       *string    `db:"meta_title"`
+	MetaDescription *string    `db:"meta_description"`
+	MetaKeywords    *string    `db:"meta_keywords"`
+	CreatedAt       time.Time  `db:"created_at"`
+	UpdatedAt       time.Time  `db:"updated_at"`
+	PublishedAt     *time.Time `db:"published_at"`
+
+	// Loaded with the parent and children relations
+	Parent   *Page
+	Children []*Page
+}
+
+// TableName returns the table name
+func (p *Page) TableName() string {
+	return "pages"
+}
+
+// PrimaryKey returns the primary key field
+func (p *Page) PrimaryKey() string {
+	return "id"
+}
+
+// Relations declares the page's parent and child pages
+func (p *Page) Relations() database.Relations {
+	return database.Relations{
+		"parent":   database.BelongsTo("Parent", "parent_id"),
+		"children": database.HasMany("Children", "parent_id"),
+	}
+}
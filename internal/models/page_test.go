@@ -0,0 +1,71 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/btassone/obtura/pkg/database"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPageAndMediaRelations(t *testing.T) {
+	db := newUserTestDB(t)
+	_, err := db.Exec(`
+		CREATE TABLE pages (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			title VARCHAR(255) NOT NULL,
+			slug VARCHAR(255) NOT NULL UNIQUE,
+			content TEXT,
+			excerpt TEXT,
+			status VARCHAR(50) DEFAULT 'draft',
+			layout VARCHAR(100) DEFAULT 'default',
+			parent_id INTEGER,
+			menu_order INTEGER DEFAULT 0,
+			meta_title VARCHAR(255),
+			meta_description TEXT,
+			meta_keywords TEXT,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			published_at TIMESTAMP
+		);
+		CREATE TABLE media (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			filename VARCHAR(255) NOT NULL,
+			original_name VARCHAR(255) NOT NULL,
+			mime_type VARCHAR(100) NOT NULL,
+			size INTEGER NOT NULL,
+			path VARCHAR(500) NOT NULL,
+			url VARCHAR(500) NOT NULL,
+			alt_text VARCHAR(255),
+			title VARCHAR(255),
+			description TEXT,
+			dimensions VARCHAR(50),
+			user_id INTEGER,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		)
+	`)
+	require.NoError(t, err)
+
+	pages := database.NewRepository[*Page](db)
+	about := &Page{Title: "About", Slug: "about", Status: "published", Layout: "default"}
+	require.NoError(t, pages.Create(about))
+	for _, slug := range []string{"team", "history"} {
+		require.NoError(t, pages.Create(&Page{Title: slug, Slug: slug, Status: "draft", Layout: "default", ParentID: &about.ID}))
+	}
+
+	page, err := pages.With("children.parent").Where("slug", "about").First()
+	require.NoError(t, err)
+	require.Len(t, page.Children, 2)
+	assert.Equal(t, "about", page.Children[0].Parent.Slug)
+
+	user := &User{Name: "Ada", Email: "ada@example.com", Password: "secret", Role: "admin", Active: true}
+	require.NoError(t, NewUserRepository(db).Create(user))
+	media := database.NewRepository[*Media](db)
+	upload := &Media{Filename: "a.png", OriginalName: "a.png", MimeType: "image/png", Size: 10, Path: "/a.png", URL: "/a.png", UserID: &user.ID}
+	require.NoError(t, media.Create(upload))
+
+	require.NoError(t, media.Load([]*Media{upload}, "user.media"))
+	assert.Equal(t, "Ada", upload.User.Name)
+	assert.Len(t, upload.User.Media, 1)
+}
@@ -0,0 +1,141 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/btassone/obtura/pkg/database"
+)
+
+// ActiveThemeSetting is the settings key holding the active theme's name
+const ActiveThemeSetting = "active_theme"
+
+// SiteRepository handles site-wide state: settings, the active theme and which plugins are enabled
+type SiteRepository struct {
+	db  *database.DB
+	ctx context.Context
+}
+
+// NewSiteRepository creates a new site repository
+func NewSiteRepository(db *database.DB) *SiteRepository {
+	return &SiteRepository{db: db, ctx: context.Background()}
+}
+
+// WithContext returns a repository that runs its queries with ctx
+func (r *SiteRepository) WithContext(ctx context.Context) *SiteRepository {
+	return &SiteRepository{db: r.db, ctx: ctx}
+}
+
+// Transaction runs fn with a repository whose changes are committed together, or not at all
+func (r *SiteRepository) Transaction(ctx context.Context, fn func(*SiteRepository) error) error {
+	return r.db.Transaction(ctx, func(tx *database.Tx) error {
+		return fn(r.WithContext(tx.Context()))
+	})
+}
+
+// keyColumn returns the quoted settings key column; key is a reserved word in MySQL
+func (r *SiteRepository) keyColumn() string {
+	return r.db.Quote("key")
+}
+
+// Settings returns all settings values by key
+func (r *SiteRepository) Settings() (map[string]string, error) {
+	column := r.keyColumn()
+	rows, err := r.db.QueryContext(r.ctx, "SELECT "+column+", value FROM settings ORDER BY "+column)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	settings := make(map[string]string)
+	for rows.Next() {
+		var key string
+		var value sql.NullString
+		if err := rows.Scan(&key, &value); err != nil {
+			return nil, err
+		}
+		settings[key] = value.String
+	}
+	return settings, rows.Err()
+}
+
+// SetSetting updates a setting's value, creating the setting if it doesn't exist
+func (r *SiteRepository) SetSetting(key, value string) error {
+	now := time.Now()
+	column := r.keyColumn()
+	result, err := r.db.ExecContext(r.ctx, "UPDATE settings SET value = ?, updated_at = ? WHERE "+column+" = ?", value, now, key)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err != nil || n > 0 {
+		return err
+	}
+
+	_, err = r.db.ExecContext(
+		r.ctx,
+		"INSERT INTO settings ("+column+", value, created_at, updated_at) VALUES (?, ?, ?, ?)",
+		key, value, now, now,
+	)
+	return err
+}
+
+// ActiveTheme returns the name of the active theme, or "" if none is set
+func (r *SiteRepository) ActiveTheme() (string, error) {
+	var name sql.NullString
+	err := r.db.QueryRowContext(r.ctx, "SELECT value FROM settings WHERE "+r.keyColumn()+" = ?", ActiveThemeSetting).Scan(&name)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	return name.String, err
+}
+
+// SetActiveTheme records the active theme in settings and marks it active in the themes table
+func (r *SiteRepository) SetActiveTheme(name string) error {
+	if err := r.SetSetting(ActiveThemeSetting, name); err != nil {
+		return err
+	}
+	if _, err := r.db.ExecContext(r.ctx, "UPDATE themes SET active = ? WHERE name <> ?", false, name); err != nil {
+		return err
+	}
+	_, err := r.db.ExecContext(r.ctx, "UPDATE themes SET active = ? WHERE name = ?", true, name)
+	return err
+}
+
+// DisabledPlugins returns the IDs of plugins an administrator has disabled
+func (r *SiteRepository) DisabledPlugins() ([]string, error) {
+	rows, err := r.db.QueryContext(r.ctx, "SELECT name FROM plugins WHERE active = ? ORDER BY name", false)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// SetPluginEnabled records whether a plugin is enabled
+func (r *SiteRepository) SetPluginEnabled(pluginID string, enabled bool) error {
+	now := time.Now()
+	result, err := r.db.ExecContext(r.ctx, "UPDATE plugins SET active = ?, updated_at = ? WHERE name = ?", enabled, now, pluginID)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err != nil || n > 0 {
+		return err
+	}
+
+	_, err = r.db.ExecContext(
+		r.ctx,
+		"INSERT INTO plugins (name, version, active, created_at, updated_at) VALUES (?, ?, ?, ?, ?)",
+		pluginID, "", enabled, now, now,
+	)
+	return err
+}
@@ -1,6 +1,7 @@
 package models
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"time"
@@ -24,6 +25,9 @@ type User struct {
 	CreatedAt       time.Time  `db:"created_at"`
 	UpdatedAt       time.Time  `db:"updated_at"`
 	DeletedAt       *time.Time `db:"deleted_at"`
+	
+	// Loaded with the media relation
+	Media []*Media
 }
 
 // TableName returns the table name
@@ -36,6 +40,13 @@ func (u *User) PrimaryKey() string {
 	return "id"
 }
 
+// Relations declares the media the user has uploaded
+func (u *User) Relations() database.Relations {
+	return database.Relations{
+		"media": database.HasMany("Media", "user_id"),
+	}
+}
+
 // UserRepository handles user database operations
 type UserRepository struct {
 	db *database.DB
@@ -48,76 +59,51 @@ func NewUserRepository(db *database.DB) *UserRepository {
 
 // FindByEmail finds a user by email
 func (r *UserRepository) FindByEmail(email string) (*User, error) {
-	user := &User{}
-	query := `
-		SELECT id, name, email, password, role, avatar, bio, active, 
-		       email_verified_at, remember_token, created_at, updated_at, deleted_at
-		FROM users 
-		WHERE email = ? AND deleted_at IS NULL
-		LIMIT 1
-	`
-	
-	row := r.db.QueryRow(query, email)
-	err := row.Scan(
-		&user.ID,
-		&user.Name,
-		&user.Email,
-		&user.Password,
-		&user.Role,
-		&user.Avatar,
-		&user.Bio,
-		&user.Active,
-		&user.EmailVerifiedAt,
-		&user.RememberToken,
-		&user.CreatedAt,
-		&user.UpdatedAt,
-		&user.DeletedAt,
-	)
-	
-	if err == sql.ErrNoRows {
-		return nil, errors.New("user not found")
-	}
-	
-	return user, err
+	return r.FindByEmailContext(context.Background(), email)
+}
+
+// FindByEmailContext finds a user by email, querying with ctx
+func (r *UserRepository) FindByEmailContext(ctx context.Context, email string) (*User, error) {
+	return r.findBy(ctx, "email", email)
 }
 
 // FindByID finds a user by ID
 func (r *UserRepository) FindByID(id int64) (*User, error) {
+	return r.FindByIDContext(context.Background(), id)
+}
+
+// FindByIDContext finds a user by ID, querying with ctx
+func (r *UserRepository) FindByIDContext(ctx context.Context, id int64) (*User, error) {
+	return r.findBy(ctx, "id", id)
+}
+
+// findBy finds a user that hasn't been deleted by the value of a column
+func (r *UserRepository) findBy(ctx context.Context, column string, value interface{}) (*User, error) {
 	user := &User{}
-	query := `
-		SELECT id, name, email, password, role, avatar, bio, active, 
-		       email_verified_at, remember_token, created_at, updated_at, deleted_at
-		FROM users 
-		WHERE id = ? AND deleted_at IS NULL
-		LIMIT 1
-	`
-	
-	row := r.db.QueryRow(query, id)
-	err := row.Scan(
-		&user.ID,
-		&user.Name,
-		&user.Email,
-		&user.Password,
-		&user.Role,
-		&user.Avatar,
-		&user.Bio,
-		&user.Active,
-		&user.EmailVerifiedAt,
-		&user.RememberToken,
-		&user.CreatedAt,
-		&user.UpdatedAt,
-		&user.DeletedAt,
-	)
+	err := database.NewQuery(r.db).
+		WithContext(ctx).
+		Table("users").
+		Where(column, value).
+		WhereNull("deleted_at").
+		First(user)
 	
 	if err == sql.ErrNoRows {
 		return nil, errors.New("user not found")
 	}
+	if err != nil {
+		return nil, err
+	}
 	
-	return user, err
+	return user, nil
 }
 
 // Create creates a new user
 func (r *UserRepository) Create(user *User) error {
+	return r.CreateContext(context.Background(), user)
+}
+
+// CreateContext creates a new user, querying with ctx
+func (r *UserRepository) CreateContext(ctx context.Context, user *User) error {
 	// Hash the password
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(user.Password), bcrypt.DefaultCost)
 	if err != nil {
@@ -135,8 +121,10 @@ func (r *UserRepository) Create(user *User) error {
 		VALUES (?, ?, ?, ?, ?, ?, ?)
 	`
 	
-	result, err := r.db.Exec(
+	id, err := r.db.InsertID(
+		ctx,
 		query,
+		"id",
 		user.Name,
 		user.Email,
 		user.Password,
@@ -150,17 +138,17 @@ func (r *UserRepository) Create(user *User) error {
 		return err
 	}
 	
-	id, err := result.LastInsertId()
-	if err != nil {
-		return err
-	}
-	
 	user.ID = id
 	return nil
 }
 
 // Update updates a user
 func (r *UserRepository) Update(user *User) error {
+	return r.UpdateContext(context.Background(), user)
+}
+
+// UpdateContext updates a user, querying with ctx
+func (r *UserRepository) UpdateContext(ctx context.Context, user *User) error {
 	user.UpdatedAt = time.Now()
 	
 	query := `
@@ -170,7 +158,8 @@ func (r *UserRepository) Update(user *User) error {
 		WHERE id = ? AND deleted_at IS NULL
 	`
 	
-	_, err := r.db.Exec(
+	_, err := r.db.ExecContext(
+		ctx,
 		query,
 		user.Name,
 		user.Email,
@@ -187,6 +176,11 @@ func (r *UserRepository) Update(user *User) error {
 
 // UpdatePassword updates a user's password
 func (r *UserRepository) UpdatePassword(userID int64, newPassword string) error {
+	return r.UpdatePasswordContext(context.Background(), userID, newPassword)
+}
+
+// UpdatePasswordContext updates a user's password, querying with ctx
+func (r *UserRepository) UpdatePasswordContext(ctx context.Context, userID int64, newPassword string) error {
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
 	if err != nil {
 		return err
@@ -198,7 +192,7 @@ func (r *UserRepository) UpdatePassword(userID int64, newPassword string) error
 		WHERE id = ? AND deleted_at IS NULL
 	`
 	
-	_, err = r.db.Exec(query, hashedPassword, time.Now(), userID)
+	_, err = r.db.ExecContext(ctx, query, hashedPassword, time.Now(), userID)
 	return err
 }
 
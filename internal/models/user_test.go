@@ -9,19 +9,81 @@ import (
 	"golang.org/x/crypto/bcrypt"
 )
 
+// newUserTestDB creates an in-memory database with the users table
+func newUserTestDB(t *testing.T) *database.DB {
+	t.Helper()
+	db, err := database.New(&database.Config{
+		Driver:       "sqlite",
+		SQLitePath:   ":memory:",
+		MaxOpenConns: 1,
+		MaxIdleConns: 1,
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	_, err = db.Exec(`
+		CREATE TABLE users (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			name VARCHAR(255) NOT NULL,
+			email VARCHAR(255) NOT NULL UNIQUE,
+			password VARCHAR(255) NOT NULL,
+			role VARCHAR(50) DEFAULT 'user',
+			avatar VARCHAR(255),
+			bio TEXT,
+			active BOOLEAN DEFAULT true,
+			email_verified_at TIMESTAMP,
+			remember_token VARCHAR(100),
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			deleted_at TIMESTAMP
+		)
+	`)
+	require.NoError(t, err)
+	return db
+}
+
 func TestUserRepository_Create(t *testing.T) {
-	// Skip this test as it requires database mocking
-	t.Skip("Requires database mocking implementation")
+	repo := NewUserRepository(newUserTestDB(t))
+
+	user := &User{Name: "Ada", Email: "ada@example.com", Password: "secret", Role: "admin", Active: true}
+	require.NoError(t, repo.Create(user))
+	assert.Equal(t, int64(1), user.ID)
+	assert.True(t, user.CheckPassword("secret"))
 }
 
 func TestUserRepository_FindByID(t *testing.T) {
-	// Skip this test as it requires database mocking
-	t.Skip("Requires database mocking implementation")
+	repo := NewUserRepository(newUserTestDB(t))
+	user := &User{Name: "Ada", Email: "ada@example.com", Password: "secret", Role: "admin", Active: true}
+	require.NoError(t, repo.Create(user))
+
+	found, err := repo.FindByID(user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Ada", found.Name)
+	assert.Equal(t, "admin", found.Role)
+	assert.True(t, found.Active)
+	assert.Nil(t, found.Avatar)
+	assert.Nil(t, found.DeletedAt)
+
+	_, err = repo.FindByID(42)
+	assert.EqualError(t, err, "user not found")
 }
 
 func TestUserRepository_FindByEmail(t *testing.T) {
-	// Skip this test as it requires database mocking
-	t.Skip("Requires database mocking implementation")
+	db := newUserTestDB(t)
+	repo := NewUserRepository(db)
+	user := &User{Name: "Ada", Email: "ada@example.com", Password: "secret", Role: "admin", Active: true}
+	require.NoError(t, repo.Create(user))
+
+	found, err := repo.FindByEmail("ada@example.com")
+	require.NoError(t, err)
+	assert.Equal(t, user.ID, found.ID)
+	assert.True(t, found.CheckPassword("secret"))
+
+	// Deleted users are not found
+	_, err = db.Exec("UPDATE users SET deleted_at = CURRENT_TIMESTAMP WHERE id = ?", user.ID)
+	require.NoError(t, err)
+	_, err = repo.FindByEmail("ada@example.com")
+	assert.EqualError(t, err, "user not found")
 }
 
 func TestUserRepository_Update(t *testing.T) {
@@ -0,0 +1,119 @@
+package server
+
+import (
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+
+	"github.com/btassone/obtura/internal/config"
+	"github.com/btassone/obtura/internal/models"
+	"github.com/btassone/obtura/pkg/database"
+	"github.com/btassone/obtura/pkg/metrics"
+	"github.com/btassone/obtura/pkg/plugin"
+	authPlugin "github.com/btassone/obtura/plugins/auth"
+	docsPlugin "github.com/btassone/obtura/plugins/docs"
+	helloPlugin "github.com/btassone/obtura/plugins/hello"
+	hubPlugin "github.com/btassone/obtura/plugins/hub"
+)
+
+// NewPluginRegistry creates the plugin registry with storage, encryption and overrides set up from
+// the environment and the core plugins registered. Plugins are not initialized or started, and the
+// registry has no router yet, so routes are registered once the server sets one.
+func NewPluginRegistry(db *database.DB) (*plugin.Registry, error) {
+	// Store plugin configs where PLUGIN_CONFIG_STORAGE says, before plugins load theirs
+	storageConfig := config.GetPluginConfigStorageConfig()
+	configStorage, err := plugin.NewConfigStorage(storageConfig.Storage, storageConfig.Path, db)
+	if err != nil {
+		return nil, fmt.Errorf("failed to set up plugin config storage: %w", err)
+	}
+	registry := plugin.NewRegistryWithMetrics(nil, metrics.Default(), plugin.WithConfigStorage(configStorage))
+
+	// Back plugin data stores with the database
+	registry.SetStoreProvider(plugin.NewDBStoreProvider(db))
+
+	// Let plugins react to model writes, such as to update a search index
+	registry.PublishModelEvents(db)
+
+	// Keep plugins disabled by an administrator disabled across restarts
+	if err := registry.SetActivationStore(models.NewSiteRepository(db)); err != nil {
+		slog.Warn("Plugin activation will not persist", "error", err)
+	}
+
+	// Encrypt secret config fields at rest with APP_KEY
+	if storageConfig.SecretKey != "" {
+		cipher, err := plugin.NewSecretCipher(storageConfig.SecretKey, storageConfig.PreviousSecretKeys...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to set up config encryption: %w", err)
+		}
+		registry.GetConfigManager().SetCipher(cipher)
+	} else {
+		slog.Warn("APP_KEY is not set, secret plugin config fields are stored unencrypted")
+	}
+
+	// Layer obtura.yaml/.toml and OBTURA_PLUGIN_* environment variables over stored configs
+	if storageConfig.OverrideFile != "" {
+		if err := registry.GetConfigManager().LoadConfigFile(storageConfig.OverrideFile); err != nil {
+			return nil, err
+		}
+	}
+	registry.GetConfigManager().LoadEnvOverrides(os.Environ())
+
+	if err := registerCorePlugins(registry, db); err != nil {
+		return nil, err
+	}
+	return registry, nil
+}
+
+// PluginMigrations returns the SQL migrations the core plugins ship, keyed by plugin ID
+func PluginMigrations(db *database.DB) (map[string]fs.FS, error) {
+	registry, err := newInspectionRegistry(db)
+	if err != nil {
+		return nil, err
+	}
+	return registry.MigrationFS(), nil
+}
+
+// PluginSecretPaths returns a function listing the secret config paths of each core plugin
+func PluginSecretPaths(db *database.DB) (func(pluginID string) []string, error) {
+	registry, err := newInspectionRegistry(db)
+	if err != nil {
+		return nil, err
+	}
+	return registry.GetConfigManager().SecretPaths, nil
+}
+
+// newInspectionRegistry registers the core plugins with in-memory config storage, so commands can
+// inspect them without writing configs to the configured storage
+func newInspectionRegistry(db *database.DB) (*plugin.Registry, error) {
+	registry := plugin.NewRegistry(nil, plugin.WithConfigStorage(plugin.NewMemoryConfigStorage()))
+	if err := registerCorePlugins(registry, db); err != nil {
+		return nil, err
+	}
+	return registry, nil
+}
+
+// registerCorePlugins registers the plugins that ship with obtura
+func registerCorePlugins(registry *plugin.Registry, db *database.DB) error {
+	// Register core plugins
+	if err := registry.Register(authPlugin.NewPlugin(db)); err != nil {
+		return fmt.Errorf("failed to register auth plugin: %w", err)
+	}
+
+	// Register documentation plugin
+	if err := registry.Register(docsPlugin.NewPlugin()); err != nil {
+		return fmt.Errorf("failed to register docs plugin: %w", err)
+	}
+
+	// Register hello plugin (example)
+	if err := registry.Register(helloPlugin.NewPlugin()); err != nil {
+		return fmt.Errorf("failed to register hello plugin: %w", err)
+	}
+
+	// Register plugin hub - must be last so it can see all other plugins
+	if err := registry.Register(hubPlugin.NewPlugin(registry)); err != nil {
+		return fmt.Errorf("failed to register hub plugin: %w", err)
+	}
+
+	return nil
+}
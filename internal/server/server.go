@@ -2,17 +2,23 @@ package server
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"log/slog"
 	"net/http"
+	"time"
 
 	"github.com/a-h/templ"
 	"github.com/btassone/obtura/internal/admin"
+	"github.com/btassone/obtura/internal/bundle"
+	"github.com/btassone/obtura/internal/config"
 	"github.com/btassone/obtura/internal/database"
+	"github.com/btassone/obtura/internal/models"
+	"github.com/btassone/obtura/pkg/logging"
+	"github.com/btassone/obtura/pkg/metrics"
 	"github.com/btassone/obtura/pkg/plugin"
+	"github.com/btassone/obtura/pkg/tracing"
 	authPlugin "github.com/btassone/obtura/plugins/auth"
-	docsPlugin "github.com/btassone/obtura/plugins/docs"
-	helloPlugin "github.com/btassone/obtura/plugins/hello"
-	hubPlugin "github.com/btassone/obtura/plugins/hub"
 	"github.com/btassone/obtura/web/templates/pages"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
@@ -21,66 +27,128 @@ import (
 type Server struct {
 	port     string
 	mode     string
+	options  Options
 	router   *chi.Mux
 	db       *database.Manager
 	registry *plugin.Registry
+	http     *http.Server
+	
+	// Tracing exporters, nil when tracing is disabled
+	traces    *tracing.RingBuffer
+	traceFile *tracing.OTLPFileExporter
+}
+
+// Options configures HTTP timeouts and shutdown deadlines
+type Options struct {
+	ReadTimeout       time.Duration
+	ReadHeaderTimeout time.Duration
+	WriteTimeout      time.Duration
+	IdleTimeout       time.Duration
+
+	// ShutdownTimeout bounds how long in-flight requests may drain
+	ShutdownTimeout time.Duration
+	// PluginStopTimeout bounds how long plugins may take to stop
+	PluginStopTimeout time.Duration
+}
+
+// DefaultOptions returns the default server options
+func DefaultOptions() Options {
+	return Options{
+		ReadTimeout:       15 * time.Second,
+		ReadHeaderTimeout: 5 * time.Second,
+		WriteTimeout:      30 * time.Second,
+		IdleTimeout:       60 * time.Second,
+		ShutdownTimeout:   30 * time.Second,
+		PluginStopTimeout: 10 * time.Second,
+	}
 }
 
 func New(port, mode string) (*Server, error) {
+	return NewWithOptions(port, mode, DefaultOptions())
+}
+
+// withDefaults fills any unset option with its default value
+func (o Options) withDefaults() Options {
+	d := DefaultOptions()
+	if o.ReadTimeout == 0 {
+		o.ReadTimeout = d.ReadTimeout
+	}
+	if o.ReadHeaderTimeout == 0 {
+		o.ReadHeaderTimeout = d.ReadHeaderTimeout
+	}
+	if o.WriteTimeout == 0 {
+		o.WriteTimeout = d.WriteTimeout
+	}
+	if o.IdleTimeout == 0 {
+		o.IdleTimeout = d.IdleTimeout
+	}
+	if o.ShutdownTimeout == 0 {
+		o.ShutdownTimeout = d.ShutdownTimeout
+	}
+	if o.PluginStopTimeout == 0 {
+		o.PluginStopTimeout = d.PluginStopTimeout
+	}
+	return o
+}
+
+// NewWithOptions creates a server with custom timeouts and shutdown deadlines
+func NewWithOptions(port, mode string, options Options) (_ *Server, err error) {
 	// Initialize database
 	dbManager, err := database.NewManager()
 	if err != nil {
 		return nil, fmt.Errorf("failed to initialize database: %w", err)
 	}
 
-	// Run migrations automatically in development
-	if mode == "dev" {
-		if err := dbManager.Migrate(); err != nil {
-			return nil, fmt.Errorf("failed to run migrations: %w", err)
-		}
-	}
-
 	// Create router
 	router := chi.NewRouter()
 
 	s := &Server{
 		port:     port,
 		mode:     mode,
+		options:  options.withDefaults(),
 		router:   router,
 		db:       dbManager,
 	}
 	
+	// If setup fails, stop the plugins started so far and close the database
+	defer func() {
+		if err != nil {
+			s.Close()
+		}
+	}()
+
+	// Run core migrations automatically in development, before the plugin registry needs them
+	if mode == "dev" {
+		if err := dbManager.Migrate(); err != nil {
+			return nil, fmt.Errorf("failed to run migrations: %w", err)
+		}
+	}
+	
+	// Setup tracing before middleware so every request is traced
+	if err := s.setupTracing(config.GetTracingConfig()); err != nil {
+		return nil, fmt.Errorf("failed to set up tracing: %w", err)
+	}
+	
 	// Setup middleware FIRST
 	s.setupMiddleware()
 	
 	// Create plugin registry WITHOUT router (to avoid early route registration)
-	registry := plugin.NewRegistry(nil)
-	s.registry = registry
-
-	// Register core plugins
-	authPlug := authPlugin.NewPlugin(dbManager.DB())
-	if err := registry.Register(authPlug); err != nil {
-		return nil, fmt.Errorf("failed to register auth plugin: %w", err)
+	registry, err := NewPluginRegistry(dbManager.DB())
+	if err != nil {
+		return nil, err
 	}
+	s.registry = registry
 	
-	// Register documentation plugin
-	docsPlug := docsPlugin.NewPlugin()
-	if err := registry.Register(docsPlug); err != nil {
-		return nil, fmt.Errorf("failed to register docs plugin: %w", err)
+	// Run the migrations plugins ship, which may build on the core tables
+	if err := dbManager.AddPluginMigrations(registry.MigrationFS()); err != nil {
+		return nil, err
 	}
-	
-	// Register hello plugin (example)
-	helloPlug := helloPlugin.NewPlugin()
-	if err := registry.Register(helloPlug); err != nil {
-		return nil, fmt.Errorf("failed to register hello plugin: %w", err)
+	if mode == "dev" {
+		if err := dbManager.Migrate(); err != nil {
+			return nil, fmt.Errorf("failed to run plugin migrations: %w", err)
+		}
 	}
 	
-	// Register plugin hub - must be last so it can see all other plugins
-	hubPlug := hubPlugin.NewPlugin(registry)
-	if err := registry.Register(hubPlug); err != nil {
-		return nil, fmt.Errorf("failed to register hub plugin: %w", err)
-	}
-
 	// Initialize plugins
 	ctx := context.Background()
 	if err := registry.Initialize(ctx); err != nil {
@@ -92,6 +160,11 @@ func New(port, mode string) (*Server, error) {
 		return nil, fmt.Errorf("failed to start plugins: %w", err)
 	}
 
+	// Expose metrics
+	if err := s.setupMetrics(config.GetMetricsConfig()); err != nil {
+		return nil, fmt.Errorf("failed to set up metrics: %w", err)
+	}
+	
 	// Setup routes LAST
 	s.setupRoutes()
 	
@@ -103,8 +176,14 @@ func New(port, mode string) (*Server, error) {
 
 func (s *Server) setupMiddleware() {
 	s.router.Use(middleware.RequestID)
+	// Record the peer before RealIP trusts forwarded headers, for the metrics allowlist
+	s.router.Use(metrics.PeerAddr)
 	s.router.Use(middleware.RealIP)
-	s.router.Use(middleware.Logger)
+	if s.traces != nil {
+		s.router.Use(tracing.Middleware(tracing.Default()))
+	}
+	s.router.Use(logging.RequestLogger(logging.Default().Logger()))
+	s.router.Use(metrics.HTTPMiddleware(metrics.Default()))
 	s.router.Use(middleware.Recoverer)
 
 	if s.mode == "dev" {
@@ -112,6 +191,52 @@ func (s *Server) setupMiddleware() {
 	}
 }
 
+// setupTracing installs the default tracer with a ring buffer and an optional OTLP/JSON file exporter
+func (s *Server) setupTracing(cfg *config.TracingConfig) error {
+	if !cfg.Enabled {
+		return nil
+	}
+	
+	s.traces = tracing.NewRingBuffer(cfg.BufferSize)
+	tracer := tracing.NewTracer(s.traces)
+	
+	if cfg.OTLPFile != "" {
+		exporter, err := tracing.NewOTLPFileExporter(cfg.OTLPFile, cfg.ServiceName)
+		if err != nil {
+			return err
+		}
+		s.traceFile = exporter
+		tracer.AddExporter(exporter)
+	}
+	
+	tracer.OnError(func(err error) {
+		slog.Warn("Trace export failed", "error", err)
+	})
+	tracing.SetDefault(tracer)
+	return nil
+}
+
+// setupMetrics records database pool stats and mounts the metrics endpoint
+func (s *Server) setupMetrics(cfg *config.MetricsConfig) error {
+	if s.db != nil {
+		metrics.RegisterDBStats(metrics.Default(), s.db.DB().Stats)
+	}
+	
+	if !cfg.Enabled {
+		return nil
+	}
+	
+	handler, err := metrics.Handler(metrics.Default(), metrics.HandlerOptions{
+		Token:      cfg.Token,
+		AllowedIPs: cfg.AllowedIPs,
+	})
+	if err != nil {
+		return err
+	}
+	s.router.Handle(cfg.Path, handler)
+	return nil
+}
+
 func (s *Server) setupRoutes() {
 	// Static files
 	fileServer := http.FileServer(http.Dir("web/static"))
@@ -132,6 +257,12 @@ func (s *Server) setupRoutes() {
 				
 				// Setup admin routes
 				admin.SetupRoutesWithPlugin(r, s.registry)
+				if s.db != nil {
+					admin.SetupBundleRoutes(r, bundle.NewSite(s.registry, models.NewSiteRepository(s.db.DB())))
+				}
+				if s.traces != nil {
+					admin.SetupTraceRoutes(r, s.traces)
+				}
 			}
 		}
 	})
@@ -142,21 +273,142 @@ func (s *Server) handleHome(w http.ResponseWriter, r *http.Request) {
 	templ.Handler(component).ServeHTTP(w, r)
 }
 
+// Start serves HTTP until the server is shut down
 func (s *Server) Start() error {
-	addr := fmt.Sprintf(":%s", s.port)
-	return http.ListenAndServe(addr, s.router)
+	s.http = s.newHTTPServer()
+	if err := s.http.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
 }
 
-func (s *Server) Close() error {
-	// Stop plugins
-	ctx := context.Background()
-	if s.registry != nil {
-		s.registry.Stop(ctx)
+// Run serves HTTP until ctx is cancelled (e.g. by SIGINT/SIGTERM), then shuts down gracefully
+func (s *Server) Run(ctx context.Context) error {
+	s.http = s.newHTTPServer()
+	
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- s.http.ListenAndServe()
+	}()
+	
+	select {
+	case err := <-serveErr:
+		// Server failed before a shutdown was requested
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			s.shutdownPlugins()
+			s.closeDatabase()
+			s.closeTracing()
+			return err
+		}
+		return nil
+	case <-ctx.Done():
+		slog.Info("Shutdown signal received")
 	}
 	
-	// Close database
-	if s.db != nil {
-		return s.db.Close()
+	return s.Shutdown()
+}
+
+// newHTTPServer builds the http.Server with configured timeouts
+func (s *Server) newHTTPServer() *http.Server {
+	options := s.options.withDefaults()
+	
+	return &http.Server{
+		Addr:              fmt.Sprintf(":%s", s.port),
+		Handler:           s.router,
+		ReadTimeout:       options.ReadTimeout,
+		ReadHeaderTimeout: options.ReadHeaderTimeout,
+		WriteTimeout:      options.WriteTimeout,
+		IdleTimeout:       options.IdleTimeout,
 	}
+}
+
+// Shutdown stops accepting requests, drains in-flight ones, then stops plugins and closes the database
+func (s *Server) Shutdown() error {
+	var errs []error
+	options := s.options.withDefaults()
+	
+	// Phase 1: stop accepting connections and drain in-flight requests
+	if s.http != nil {
+		slog.Info("Draining in-flight requests", "timeout", options.ShutdownTimeout)
+		ctx, cancel := context.WithTimeout(context.Background(), options.ShutdownTimeout)
+		err := s.http.Shutdown(ctx)
+		cancel()
+		if err != nil {
+			slog.Warn("Drain deadline exceeded, closing remaining connections", "error", err)
+			s.http.Close()
+			errs = append(errs, fmt.Errorf("failed to drain requests: %w", err))
+		} else {
+			slog.Info("HTTP server stopped")
+		}
+	}
+	
+	// Phase 2: stop plugins
+	if err := s.shutdownPlugins(); err != nil {
+		errs = append(errs, err)
+	}
+	
+	// Phase 3: close the database
+	if err := s.closeDatabase(); err != nil {
+		errs = append(errs, err)
+	}
+	
+	// Phase 4: flush trace exporters
+	if err := s.closeTracing(); err != nil {
+		errs = append(errs, err)
+	}
+	
+	slog.Info("Shutdown complete")
+	return errors.Join(errs...)
+}
+
+// shutdownPlugins stops all plugins within the plugin stop deadline
+func (s *Server) shutdownPlugins() error {
+	if s.registry == nil {
+		return nil
+	}
+	
+	timeout := s.options.withDefaults().PluginStopTimeout
+	slog.Info("Stopping plugins", "timeout", timeout)
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	
+	if err := s.registry.Stop(ctx); err != nil {
+		slog.Error("Error stopping plugins", "error", err)
+		return err
+	}
+	slog.Info("Plugins stopped")
 	return nil
 }
+
+// closeDatabase closes the database connection
+func (s *Server) closeDatabase() error {
+	if s.db == nil {
+		return nil
+	}
+	
+	slog.Info("Closing database")
+	if err := s.db.Close(); err != nil {
+		slog.Error("Error closing database", "error", err)
+		return err
+	}
+	slog.Info("Database closed")
+	return nil
+}
+
+// closeTracing closes the OTLP/JSON trace file, if any
+func (s *Server) closeTracing() error {
+	if s.traceFile == nil {
+		return nil
+	}
+	
+	if err := s.traceFile.Close(); err != nil {
+		slog.Error("Error closing trace file", "error", err)
+		return err
+	}
+	return nil
+}
+
+// Close releases server resources without waiting for the HTTP server to drain
+func (s *Server) Close() error {
+	return errors.Join(s.shutdownPlugins(), s.closeDatabase(), s.closeTracing())
+}
@@ -1,13 +1,20 @@
 package server
 
 import (
+	"context"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"os"
 	"testing"
+	"time"
 
+	"github.com/btassone/obtura/internal/config"
+	"github.com/btassone/obtura/pkg/database"
 	"github.com/btassone/obtura/pkg/plugin"
 	"github.com/go-chi/chi/v5"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 // Mock database manager for testing
@@ -36,7 +43,7 @@ func TestServerNew(t *testing.T) {
 
 func TestServer_setupRoutes(t *testing.T) {
 	router := chi.NewRouter()
-	registry := plugin.NewRegistry(nil)
+	registry := plugin.NewRegistry(nil, plugin.WithConfigStorage(plugin.NewMemoryConfigStorage()))
 
 	s := &Server{
 		port:     "8080",
@@ -78,7 +85,7 @@ func TestServer_setupRoutes(t *testing.T) {
 
 func TestServer_handleHome(t *testing.T) {
 	router := chi.NewRouter()
-	registry := plugin.NewRegistry(nil)
+	registry := plugin.NewRegistry(nil, plugin.WithConfigStorage(plugin.NewMemoryConfigStorage()))
 	
 	s := &Server{
 		router:   router,
@@ -120,6 +127,88 @@ func TestServer_setupMiddleware(t *testing.T) {
 	assert.Equal(t, http.StatusOK, rec.Code)
 }
 
+func TestServer_MetricsAllowlistIgnoresForwardedHeaders(t *testing.T) {
+	s := &Server{router: chi.NewRouter(), mode: "test"}
+	s.setupMiddleware()
+	require.NoError(t, s.setupMetrics(&config.MetricsConfig{Enabled: true, Path: "/metrics", AllowedIPs: []string{"127.0.0.1"}}))
+
+	req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	req.RemoteAddr = "203.0.113.9:1234"
+	req.Header.Set("X-Real-IP", "127.0.0.1")
+	rec := httptest.NewRecorder()
+	s.router.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+
+	req = httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	req.RemoteAddr = "127.0.0.1:1234"
+	rec = httptest.NewRecorder()
+	s.router.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestServer_ShutdownDrainsInFlightRequests(t *testing.T) {
+	router := chi.NewRouter()
+	router.Get("/slow", func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(100 * time.Millisecond)
+		w.WriteHeader(http.StatusOK)
+	})
+
+	s := &Server{
+		router:   router,
+		registry: plugin.NewRegistry(nil, plugin.WithConfigStorage(plugin.NewMemoryConfigStorage())),
+		options:  DefaultOptions(),
+	}
+	s.http = s.newHTTPServer()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go s.http.Serve(ln)
+
+	// Start a request that is still in flight when shutdown begins
+	result := make(chan int, 1)
+	go func() {
+		resp, err := http.Get("http://" + ln.Addr().String() + "/slow")
+		if err != nil {
+			result <- 0
+			return
+		}
+		resp.Body.Close()
+		result <- resp.StatusCode
+	}()
+	time.Sleep(20 * time.Millisecond)
+
+	require.NoError(t, s.Shutdown())
+	assert.Equal(t, http.StatusOK, <-result)
+
+	// New connections are refused after shutdown
+	_, err = http.Get("http://" + ln.Addr().String() + "/slow")
+	assert.Error(t, err)
+}
+
+func TestServer_RunStopsOnContextCancel(t *testing.T) {
+	s := &Server{
+		port:     "0",
+		router:   chi.NewRouter(),
+		registry: plugin.NewRegistry(nil, plugin.WithConfigStorage(plugin.NewMemoryConfigStorage())),
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- s.Run(ctx)
+	}()
+
+	time.Sleep(20 * time.Millisecond)
+	cancel()
+
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run did not return after context cancellation")
+	}
+}
+
 func TestServer_withRecovery(t *testing.T) {
 	// Create a handler that panics
 	panicHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -151,4 +240,21 @@ func withRecovery(next http.Handler) http.Handler {
 		}()
 		next.ServeHTTP(w, r)
 	})
-}
\ No newline at end of file
+}
+func TestPluginMigrations_DoesNotSaveConfigs(t *testing.T) {
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+	require.NoError(t, os.Chdir(t.TempDir()))
+	t.Cleanup(func() { os.Chdir(wd) })
+	t.Setenv("PLUGIN_CONFIG_STORAGE", "file")
+
+	db, err := database.New(&database.Config{Driver: "sqlite", SQLitePath: ":memory:", MaxOpenConns: 1, MaxIdleConns: 1})
+	require.NoError(t, err)
+	defer db.Close()
+
+	_, err = PluginMigrations(db)
+	require.NoError(t, err)
+
+	_, err = os.Stat("configs")
+	assert.True(t, os.IsNotExist(err), "collecting migrations leaves the config storage alone")
+}
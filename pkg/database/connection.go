@@ -1,10 +1,13 @@
 package database
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
+	"strings"
 	"time"
 
+	"github.com/btassone/obtura/pkg/tracing"
 	_ "github.com/go-sql-driver/mysql"
 	_ "github.com/lib/pq"
 	_ "github.com/mattn/go-sqlite3"
@@ -15,6 +18,10 @@ type DB struct {
 	*sql.DB
 	config     *Config
 	driverName string
+	dialect    Dialect
+
+	// modelEvents receives committed model writes, see OnModelEvent
+	modelEvents func(ModelEvent)
 }
 
 // Config holds database configuration
@@ -31,6 +38,10 @@ type Config struct {
 	MaxIdleConns    int
 	ConnMaxLifetime time.Duration
 	
+	// QueryTimeout bounds each statement whose context has no deadline of its own, unless the
+	// context opts out with WithoutTimeout; 0 means no timeout
+	QueryTimeout time.Duration
+	
 	// SQLite specific
 	SQLitePath string
 }
@@ -79,6 +90,7 @@ func New(config *Config) (*DB, error) {
 		DB:         db,
 		config:     config,
 		driverName: config.Driver,
+		dialect:    DialectFor(config.Driver),
 	}, nil
 }
 
@@ -108,39 +120,202 @@ func (db *DB) Driver() string {
 	return db.driverName
 }
 
-// Transaction executes a function within a database transaction
-func (db *DB) Transaction(fn func(*sql.Tx) error) error {
-	tx, err := db.Begin()
+// Dialect returns the SQL dialect of the database driver
+func (db *DB) Dialect() Dialect {
+	if db.dialect == nil {
+		return DialectFor(db.driverName)
+	}
+	return db.dialect
+}
+
+// Rebind converts the ? placeholders in a query to the driver's bind parameters.
+// Queries run through DB and Tx are rebound automatically.
+func (db *DB) Rebind(query string) string {
+	return Rebind(db.Dialect(), query)
+}
+
+// Quote quotes a table or column name, which may be qualified with its table
+func (db *DB) Quote(name string) string {
+	return QuoteIdentifier(db.Dialect(), name)
+}
+
+// Transaction executes a function within a database transaction. The transaction is carried by
+// its context, tx.Context(), so DB queries made with that context run in the transaction, and a
+// Transaction started with it joins the transaction instead of beginning another.
+func (db *DB) Transaction(ctx context.Context, fn func(*Tx) error) error {
+	if tx, ok := TxFromContext(ctx); ok && tx.db == db {
+		return fn(tx)
+	}
+
+	sqlTx, err := db.BeginTx(ctx, nil)
 	if err != nil {
 		return err
 	}
+	tx := &Tx{Tx: sqlTx, db: db}
+	tx.ctx = context.WithValue(ctx, txContextKey{}, tx)
 
 	defer func() {
 		if p := recover(); p != nil {
-			_ = tx.Rollback()
+			_ = sqlTx.Rollback()
 			panic(p)
 		}
 	}()
 
 	if err := fn(tx); err != nil {
-		_ = tx.Rollback()
+		_ = sqlTx.Rollback()
+		return err
+	}
+
+	if err := sqlTx.Commit(); err != nil {
 		return err
 	}
+	for _, fn := range tx.committed {
+		fn()
+	}
+	return nil
+}
 
-	return tx.Commit()
+// InsertID runs an INSERT and returns the generated value of its id column.
+// Drivers without LastInsertId support, such as PostgreSQL, get the value with RETURNING.
+func (db *DB) InsertID(ctx context.Context, query, idColumn string, args ...interface{}) (int64, error) {
+	return insertID(ctx, db, db.Dialect(), query, idColumn, args...)
 }
 
 // Exec executes a query without returning any rows
 func (db *DB) Exec(query string, args ...interface{}) (sql.Result, error) {
-	return db.DB.Exec(query, args...)
+	return db.ExecContext(context.Background(), query, args...)
 }
 
 // Query executes a query that returns rows
-func (db *DB) Query(query string, args ...interface{}) (*sql.Rows, error) {
-	return db.DB.Query(query, args...)
+func (db *DB) Query(query string, args ...interface{}) (*Rows, error) {
+	return db.QueryContext(context.Background(), query, args...)
 }
 
 // QueryRow executes a query that is expected to return at most one row
-func (db *DB) QueryRow(query string, args ...interface{}) *sql.Row {
-	return db.DB.QueryRow(query, args...)
+func (db *DB) QueryRow(query string, args ...interface{}) *Row {
+	return db.QueryRowContext(context.Background(), query, args...)
+}
+
+// ExecContext executes a query without returning any rows, recording a span.
+// It runs in the transaction carried by ctx, if there is one.
+func (db *DB) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	if tx, ok := TxFromContext(ctx); ok && tx.db == db {
+		return tx.ExecContext(ctx, query, args...)
+	}
+
+	ctx, span := db.startSpan(ctx, query)
+	defer span.End()
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	result, err := db.DB.ExecContext(ctx, db.Rebind(query), args...)
+	span.RecordError(err)
+	return result, err
+}
+
+// QueryContext executes a query that returns rows, recording a span.
+// The span covers executing the query, not iterating the rows, and the query's timeout lasts
+// until the rows are closed. It runs in the transaction carried by ctx, if there is one.
+func (db *DB) QueryContext(ctx context.Context, query string, args ...interface{}) (*Rows, error) {
+	if tx, ok := TxFromContext(ctx); ok && tx.db == db {
+		return tx.QueryContext(ctx, query, args...)
+	}
+
+	ctx, span := db.startSpan(ctx, query)
+	defer span.End()
+	ctx, cancel := db.withTimeout(ctx)
+
+	rows, err := db.DB.QueryContext(ctx, db.Rebind(query), args...)
+	span.RecordError(err)
+	return newRows(rows, cancel, err)
+}
+
+// QueryRowContext executes a query that is expected to return at most one row, recording a span.
+// The query's timeout lasts until the row is scanned. It runs in the transaction carried by ctx,
+// if there is one.
+func (db *DB) QueryRowContext(ctx context.Context, query string, args ...interface{}) *Row {
+	if tx, ok := TxFromContext(ctx); ok && tx.db == db {
+		return tx.QueryRowContext(ctx, query, args...)
+	}
+
+	ctx, span := db.startSpan(ctx, query)
+	defer span.End()
+	ctx, cancel := db.withTimeout(ctx)
+
+	row := db.DB.QueryRowContext(ctx, db.Rebind(query), args...)
+	span.RecordError(row.Err())
+	return &Row{Row: row, cancel: cancel}
+}
+
+// noTimeoutKey is the context key marking statements exempt from QueryTimeout
+type noTimeoutKey struct{}
+
+// WithoutTimeout returns a context whose statements are not bounded by QueryTimeout, for long
+// running work such as migrations. A deadline of the context's own still applies.
+func WithoutTimeout(ctx context.Context) context.Context {
+	return context.WithValue(ctx, noTimeoutKey{}, true)
+}
+
+// withTimeout applies the configured QueryTimeout to a context without a deadline
+func (db *DB) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if db.config == nil || db.config.QueryTimeout <= 0 {
+		return ctx, func() {}
+	}
+	if exempt, _ := ctx.Value(noTimeoutKey{}).(bool); exempt {
+		return ctx, func() {}
+	}
+	if _, ok := ctx.Deadline(); ok {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, db.config.QueryTimeout)
+}
+
+// Rows is the result of a query. Closing it also releases the query's timeout, which bounds
+// reading the rows as well as running the query.
+type Rows struct {
+	*sql.Rows
+	cancel context.CancelFunc
+}
+
+// newRows wraps the rows of a query, releasing its timeout straight away if it failed
+func newRows(rows *sql.Rows, cancel context.CancelFunc, err error) (*Rows, error) {
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	return &Rows{Rows: rows, cancel: cancel}, nil
+}
+
+// Close closes the rows and releases the query's timeout
+func (r *Rows) Close() error {
+	err := r.Rows.Close()
+	r.cancel()
+	return err
+}
+
+// Row is the result of a query for a single row. Scanning it also releases the query's timeout.
+type Row struct {
+	*sql.Row
+	cancel context.CancelFunc
+}
+
+// Scan copies the row's columns into dest, as sql.Row.Scan does, and releases the query's timeout
+func (r *Row) Scan(dest ...interface{}) error {
+	defer r.cancel()
+	return r.Row.Scan(dest...)
+}
+
+// startSpan starts a client span for a query, named after its SQL operation
+func (db *DB) startSpan(ctx context.Context, query string) (context.Context, *tracing.Span) {
+	operation := strings.TrimSpace(query)
+	if i := strings.IndexAny(operation, " \t\n"); i > 0 {
+		operation = operation[:i]
+	}
+	operation = strings.ToUpper(operation)
+
+	return tracing.StartKind(ctx, "db "+operation, tracing.KindClient,
+		tracing.Attr("db.system", db.driverName),
+		tracing.Attr("db.operation", operation),
+		tracing.Attr("db.statement", strings.TrimSpace(query)),
+	)
 }
\ No newline at end of file
@@ -0,0 +1,121 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTransaction_CarriedByContext(t *testing.T) {
+	db := newRepoDB(t)
+	notes := NewRepository[*repoNote](db)
+	var events []string
+	db.OnModelEvent(func(e ModelEvent) { events = append(events, e.Name()) })
+
+	// With a single connection, a query that left the transaction would wait for it to finish
+	check := func(ctx context.Context) int64 {
+		ctx, cancel := context.WithTimeout(ctx, time.Second)
+		defer cancel()
+		count, err := NewQuery(db).WithContext(ctx).Table("notes").Count()
+		require.NoError(t, err)
+		return count
+	}
+
+	errRollback := errors.New("roll back")
+	err := db.Transaction(context.Background(), func(tx *Tx) error {
+		require.NoError(t, notes.WithContext(tx.Context()).Create(&repoNote{Slug: "a", Body: "a"}))
+		assert.Equal(t, int64(1), check(tx.Context()))
+
+		// A nested transaction joins this one
+		require.NoError(t, db.Transaction(tx.Context(), func(inner *Tx) error {
+			assert.Same(t, tx, inner)
+			_, err := db.ExecContext(inner.Context(), "INSERT INTO notes (slug, body) VALUES (?, ?)", "b", "b")
+			return err
+		}))
+		assert.Equal(t, int64(2), check(tx.Context()))
+		assert.Empty(t, events, "events wait for the commit")
+		return errRollback
+	})
+	assert.ErrorIs(t, err, errRollback)
+	assert.Equal(t, int64(0), check(context.Background()))
+	assert.Empty(t, events, "a rolled back write publishes nothing")
+
+	require.NoError(t, db.Transaction(context.Background(), func(tx *Tx) error {
+		return notes.WithContext(tx.Context()).Create(&repoNote{Slug: "c", Body: "c"})
+	}))
+	assert.Equal(t, []string{"model.notes.created"}, events)
+	assert.Equal(t, int64(1), check(context.Background()))
+}
+
+func TestDB_QueryTimeout(t *testing.T) {
+	db, err := New(&Config{Driver: "sqlite", SQLitePath: ":memory:", MaxOpenConns: 1, MaxIdleConns: 1, QueryTimeout: 50 * time.Millisecond})
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	slow := "WITH RECURSIVE c(x) AS (SELECT 1 UNION ALL SELECT x + 1 FROM c WHERE x < 1000000000) SELECT COUNT(*) FROM c"
+
+	start := time.Now()
+	var count int64
+	err = db.QueryRow(slow).Scan(&count)
+	assert.Error(t, err)
+	assert.Less(t, time.Since(start), 5*time.Second)
+
+	// A context's own deadline takes precedence over the default timeout
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	rows, err := db.QueryContext(ctx, "SELECT 1")
+	require.NoError(t, err)
+	time.Sleep(100 * time.Millisecond)
+	require.True(t, rows.Next(), "rows outlive the default timeout")
+	require.NoError(t, rows.Close())
+
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = db.ExecContext(canceled, "SELECT 1")
+	assert.ErrorIs(t, err, context.Canceled)
+	_, err = NewQuery(db).WithContext(canceled).Table("sqlite_master").Count()
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestDB_QueryTimeoutReleased(t *testing.T) {
+	db, err := New(&Config{Driver: "sqlite", SQLitePath: ":memory:", MaxOpenConns: 1, MaxIdleConns: 1, QueryTimeout: time.Minute})
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	// released counts the calls to a query's cancel func
+	released := 0
+	track := func(cancel context.CancelFunc) context.CancelFunc {
+		return func() {
+			released++
+			cancel()
+		}
+	}
+
+	rows, err := db.Query("SELECT 1")
+	require.NoError(t, err)
+	rows.cancel = track(rows.cancel)
+	require.True(t, rows.Next())
+	assert.Equal(t, 0, released, "rows being read keep their timeout")
+	require.NoError(t, rows.Close())
+	assert.Equal(t, 1, released, "closing rows releases their timeout")
+
+	var one int
+	row := db.QueryRow("SELECT 1")
+	row.cancel = track(row.cancel)
+	require.NoError(t, row.Scan(&one))
+	assert.Equal(t, 2, released, "scanning a row releases its timeout")
+
+	require.NoError(t, db.Transaction(context.Background(), func(tx *Tx) error {
+		rows, err := tx.Query("SELECT 1")
+		if err != nil {
+			return err
+		}
+		rows.cancel = track(rows.cancel)
+		return rows.Close()
+	}))
+	assert.Equal(t, 3, released)
+}
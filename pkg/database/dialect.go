@@ -0,0 +1,180 @@
+package database
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Dialect describes how SQL differs between database drivers.
+// Queries are written with ? placeholders and unquoted identifiers; the dialect adapts them.
+type Dialect interface {
+	// Name returns the dialect name: sqlite, mysql or postgres
+	Name() string
+	// Placeholder returns the bind parameter for the nth argument, counting from 1
+	Placeholder(n int) string
+	// QuoteIdent quotes a single identifier, such as a table or column name
+	QuoteIdent(name string) string
+	// SupportsReturning reports whether inserts return generated keys with RETURNING
+	// instead of LastInsertId
+	SupportsReturning() bool
+}
+
+// DialectFor returns the dialect of a driver name, defaulting to SQLite for unknown drivers
+func DialectFor(driver string) Dialect {
+	switch driver {
+	case "mysql":
+		return mysqlDialect{}
+	case "postgres", "postgresql":
+		return postgresDialect{}
+	default:
+		return sqliteDialect{}
+	}
+}
+
+type sqliteDialect struct{}
+
+func (sqliteDialect) Name() string                  { return "sqlite" }
+func (sqliteDialect) Placeholder(n int) string      { return "?" }
+func (sqliteDialect) QuoteIdent(name string) string { return quoteWith(name, '"') }
+func (sqliteDialect) SupportsReturning() bool       { return false }
+
+type mysqlDialect struct{}
+
+func (mysqlDialect) Name() string                  { return "mysql" }
+func (mysqlDialect) Placeholder(n int) string      { return "?" }
+func (mysqlDialect) QuoteIdent(name string) string { return quoteWith(name, '`') }
+func (mysqlDialect) SupportsReturning() bool       { return false }
+
+type postgresDialect struct{}
+
+func (postgresDialect) Name() string                  { return "postgres" }
+func (postgresDialect) Placeholder(n int) string      { return "$" + strconv.Itoa(n) }
+func (postgresDialect) QuoteIdent(name string) string { return quoteWith(name, '"') }
+func (postgresDialect) SupportsReturning() bool       { return true }
+
+// quoteWith quotes an identifier, doubling any quote characters inside it
+func quoteWith(name string, quote rune) string {
+	q := string(quote)
+	return q + strings.ReplaceAll(name, q, q+q) + q
+}
+
+// Rebind converts the ? placeholders in a query to the dialect's bind parameters.
+// Question marks inside quoted strings, quoted identifiers and comments are left alone.
+func Rebind(d Dialect, query string) string {
+	if d.Placeholder(1) == "?" || !strings.Contains(query, "?") {
+		return query
+	}
+
+	var b strings.Builder
+	b.Grow(len(query) + 8)
+	n := 0
+	for i := 0; i < len(query); i++ {
+		c := query[i]
+		switch {
+		case c == '\'' || c == '"' || c == '`':
+			// Copy the quoted section; a doubled quote is an escaped quote and continues it
+			end := i + 1
+			for end < len(query) {
+				if query[end] == c {
+					if end+1 < len(query) && query[end+1] == c {
+						end += 2
+						continue
+					}
+					break
+				}
+				end++
+			}
+			if end >= len(query) {
+				end = len(query) - 1
+			}
+			b.WriteString(query[i : end+1])
+			i = end
+		case c == '-' && i+1 < len(query) && query[i+1] == '-':
+			end := strings.IndexByte(query[i:], '\n')
+			if end < 0 {
+				end = len(query) - i - 1
+			}
+			b.WriteString(query[i : i+end+1])
+			i += end
+		case c == '?':
+			n++
+			b.WriteString(d.Placeholder(n))
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+// QuoteIdentifier quotes a possibly qualified identifier, such as users.email or users.*.
+// Anything else, such as an expression or an aliased column, is returned unchanged.
+func QuoteIdentifier(d Dialect, name string) string {
+	if name == "*" {
+		return name
+	}
+	parts := strings.Split(name, ".")
+	for i, part := range parts {
+		if part == "*" && i == len(parts)-1 && i > 0 {
+			continue
+		}
+		if !isIdentifier(part) {
+			return name
+		}
+	}
+	for i, part := range parts {
+		if part != "*" {
+			parts[i] = d.QuoteIdent(part)
+		}
+	}
+	return strings.Join(parts, ".")
+}
+
+// isIdentifier reports whether s is a plain unquoted identifier
+func isIdentifier(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i, c := range s {
+		switch {
+		case c == '_', c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z':
+		case c >= '0' && c <= '9' && i > 0:
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// ValidIdentifier reports whether name is a plain or qualified identifier, such as email,
+// users.email or users.*, which the query builder accepts as a table or column name.
+// With allowAlias, it may also have an alias, as in users.email AS contact.
+func ValidIdentifier(name string, allowAlias bool) bool {
+	if column, _, ok := splitAlias(name); ok {
+		if !allowAlias {
+			return false
+		}
+		name = column
+	}
+	if name == "*" {
+		return true
+	}
+	parts := strings.Split(name, ".")
+	for i, part := range parts {
+		if part == "*" && i == len(parts)-1 && i > 0 {
+			continue
+		}
+		if !isIdentifier(part) {
+			return false
+		}
+	}
+	return true
+}
+
+// splitAlias splits "name AS alias" into its name and alias
+func splitAlias(s string) (name, alias string, ok bool) {
+	fields := strings.Fields(s)
+	if len(fields) != 3 || !strings.EqualFold(fields[1], "AS") || !isIdentifier(fields[2]) {
+		return "", "", false
+	}
+	return fields[0], fields[2], true
+}
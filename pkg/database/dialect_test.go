@@ -0,0 +1,183 @@
+package database
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// dialectDB returns a DB that builds queries for a driver without connecting to it
+func dialectDB(driver string) *DB {
+	return &DB{driverName: driver, dialect: DialectFor(driver)}
+}
+
+func TestDialectFor(t *testing.T) {
+	assert.Equal(t, "sqlite", DialectFor("sqlite").Name())
+	assert.Equal(t, "sqlite", DialectFor("sqlite3").Name())
+	assert.Equal(t, "mysql", DialectFor("mysql").Name())
+	assert.Equal(t, "postgres", DialectFor("postgres").Name())
+	assert.Equal(t, "postgres", DialectFor("postgresql").Name())
+	assert.True(t, DialectFor("postgres").SupportsReturning())
+	assert.False(t, DialectFor("mysql").SupportsReturning())
+}
+
+func TestRebind(t *testing.T) {
+	tests := []struct {
+		name     string
+		query    string
+		postgres string
+	}{
+		{"placeholders", "SELECT * FROM users WHERE id = ? AND role = ?", "SELECT * FROM users WHERE id = $1 AND role = $2"},
+		{"string literal", "SELECT * FROM users WHERE name = 'who?' AND id = ?", "SELECT * FROM users WHERE name = 'who?' AND id = $1"},
+		{"escaped quote", "SELECT 'it''s ?' FROM users WHERE id = ?", "SELECT 'it''s ?' FROM users WHERE id = $1"},
+		{"quoted identifier", `SELECT "odd?" FROM users WHERE id = ?`, `SELECT "odd?" FROM users WHERE id = $1`},
+		{"comment", "SELECT 1 -- why?\nWHERE id = ?", "SELECT 1 -- why?\nWHERE id = $1"},
+		{"no placeholders", "SELECT 1", "SELECT 1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.query, Rebind(DialectFor("sqlite"), tt.query))
+			assert.Equal(t, tt.query, Rebind(DialectFor("mysql"), tt.query))
+			assert.Equal(t, tt.postgres, Rebind(DialectFor("postgres"), tt.query))
+		})
+	}
+}
+
+func TestQuoteIdentifier(t *testing.T) {
+	tests := []struct {
+		name     string
+		sqlite   string
+		mysql    string
+		postgres string
+	}{
+		{"users", `"users"`, "`users`", `"users"`},
+		{"users.email", `"users"."email"`, "`users`.`email`", `"users"."email"`},
+		{"users.*", `"users".*`, "`users`.*", `"users".*`},
+		{"*", "*", "*", "*"},
+		{"COUNT(*) as count", "COUNT(*) as count", "COUNT(*) as count", "COUNT(*) as count"},
+		{"LOWER(email)", "LOWER(email)", "LOWER(email)", "LOWER(email)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.sqlite, QuoteIdentifier(DialectFor("sqlite"), tt.name))
+			assert.Equal(t, tt.mysql, QuoteIdentifier(DialectFor("mysql"), tt.name))
+			assert.Equal(t, tt.postgres, QuoteIdentifier(DialectFor("postgres"), tt.name))
+		})
+	}
+
+	assert.Equal(t, `"a""b"`, DialectFor("postgres").QuoteIdent(`a"b`))
+	assert.Equal(t, "`a``b`", DialectFor("mysql").QuoteIdent("a`b"))
+}
+
+func TestQuery_DialectMatrix(t *testing.T) {
+	type want struct {
+		sqlite, mysql, postgres string
+	}
+	tests := []struct {
+		name  string
+		build func(db *DB) (string, []interface{}, error)
+		want  want
+		args  []interface{}
+	}{
+		{
+			name: "select",
+			build: func(db *DB) (string, []interface{}, error) {
+				q := NewQuery(db).Table("users").
+					Select("users.*").
+					SelectRaw("COUNT(*) as count").
+					LeftJoin("posts", "posts.user_id", "=", "users.id").
+					Where("role", "admin").
+					OrWhere("age", ">", 18).
+					WhereIn("id", []interface{}{1, 2}).
+					WhereNull("deleted_at").
+					GroupBy("users.id").
+					OrderBy("name", "desc").
+					Limit(10).
+					Offset(20)
+				query, args := q.ToSQL()
+				return query, args, q.Err()
+			},
+			want: want{
+				sqlite: `SELECT "users".*, COUNT(*) as count FROM "users" LEFT JOIN "posts" ON "posts"."user_id" = "users"."id"` +
+					` WHERE "role" = ? OR "age" > ? AND "id" IN (?, ?) AND "deleted_at" IS NULL GROUP BY "users"."id" ORDER BY "name" DESC LIMIT 10 OFFSET 20`,
+				mysql: "SELECT `users`.*, COUNT(*) as count FROM `users` LEFT JOIN `posts` ON `posts`.`user_id` = `users`.`id`" +
+					" WHERE `role` = ? OR `age` > ? AND `id` IN (?, ?) AND `deleted_at` IS NULL GROUP BY `users`.`id` ORDER BY `name` DESC LIMIT 10 OFFSET 20",
+				postgres: `SELECT "users".*, COUNT(*) as count FROM "users" LEFT JOIN "posts" ON "posts"."user_id" = "users"."id"` +
+					` WHERE "role" = $1 OR "age" > $2 AND "id" IN ($3, $4) AND "deleted_at" IS NULL GROUP BY "users"."id" ORDER BY "name" DESC LIMIT 10 OFFSET 20`,
+			},
+			args: []interface{}{"admin", 18, 1, 2},
+		},
+		{
+			name: "update",
+			build: func(db *DB) (string, []interface{}, error) {
+				// Placeholders are rebound when the statement runs
+				query, args, err := NewQuery(db).Table("users").Where("id", 7).updateSQL(map[string]interface{}{"role": "editor", "active": true})
+				return db.Rebind(query), args, err
+			},
+			want: want{
+				sqlite:   `UPDATE "users" SET "active" = ?, "role" = ? WHERE "id" = ?`,
+				mysql:    "UPDATE `users` SET `active` = ?, `role` = ? WHERE `id` = ?",
+				postgres: `UPDATE "users" SET "active" = $1, "role" = $2 WHERE "id" = $3`,
+			},
+			args: []interface{}{true, "editor", 7},
+		},
+		{
+			name: "delete",
+			build: func(db *DB) (string, []interface{}, error) {
+				query, args, err := NewQuery(db).Table("users").Where("id", 7).WhereNotNull("deleted_at").deleteSQL()
+				return db.Rebind(query), args, err
+			},
+			want: want{
+				sqlite:   `DELETE FROM "users" WHERE "id" = ? AND "deleted_at" IS NOT NULL`,
+				mysql:    "DELETE FROM `users` WHERE `id` = ? AND `deleted_at` IS NOT NULL",
+				postgres: `DELETE FROM "users" WHERE "id" = $1 AND "deleted_at" IS NOT NULL`,
+			},
+			args: []interface{}{7},
+		},
+	}
+
+	for _, tt := range tests {
+		for driver, expected := range map[string]string{"sqlite": tt.want.sqlite, "mysql": tt.want.mysql, "postgres": tt.want.postgres} {
+			t.Run(tt.name+"/"+driver, func(t *testing.T) {
+				query, args, err := tt.build(dialectDB(driver))
+				require.NoError(t, err)
+				assert.Equal(t, expected, query)
+				assert.Equal(t, tt.args, args)
+			})
+		}
+	}
+}
+
+func TestDB_RebindsQueries(t *testing.T) {
+	db, err := New(&Config{Driver: "sqlite", SQLitePath: ":memory:", MaxOpenConns: 1, MaxIdleConns: 1})
+	require.NoError(t, err)
+	defer db.Close()
+
+	_, err = db.Exec(`CREATE TABLE items (id INTEGER PRIMARY KEY AUTOINCREMENT, name TEXT NOT NULL)`)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	id, err := db.InsertID(ctx, "INSERT INTO items (name) VALUES (?)", "id", "first")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), id)
+
+	require.NoError(t, db.Transaction(context.Background(), func(tx *Tx) error {
+		id, err = tx.InsertID(ctx, "INSERT INTO items (name) VALUES (?)", "id", "second")
+		if err != nil {
+			return err
+		}
+		_, err := tx.Exec("UPDATE items SET name = ? WHERE id = ?", "renamed", id)
+		return err
+	}))
+	assert.Equal(t, int64(2), id)
+
+	var name string
+	require.NoError(t, NewQuery(db).Table("items").Select("name").Where("id", 2).First(&name))
+	assert.Equal(t, "renamed", name)
+
+	count, err := NewQuery(db).Table("items").WhereIn("id", []interface{}{1, 2}).Count()
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), count)
+}
@@ -0,0 +1,140 @@
+package database
+
+import "context"
+
+// Models opt into lifecycle hooks by implementing any of the interfaces below. BaseModel and
+// Repository run a write's hooks and the write itself in one transaction, in this order:
+//
+//	BeforeCreate / BeforeUpdate / BeforeDelete
+//	Validate (creates and updates only)
+//	the INSERT, UPDATE or DELETE
+//	AfterCreate / AfterUpdate / AfterDelete
+//
+// A hook that returns an error rolls the transaction back, and the error is returned unwrapped
+// so callers can inspect it. Hooks should run their own queries on tx, not on the DB.
+
+// Validator is implemented by models that check themselves before they are saved
+type Validator interface {
+	Validate() error
+}
+
+// BeforeCreateHook is implemented by models that run code before they are inserted
+type BeforeCreateHook interface {
+	BeforeCreate(tx *Tx) error
+}
+
+// AfterCreateHook is implemented by models that run code after they are inserted
+type AfterCreateHook interface {
+	AfterCreate(tx *Tx) error
+}
+
+// BeforeUpdateHook is implemented by models that run code before they are updated
+type BeforeUpdateHook interface {
+	BeforeUpdate(tx *Tx) error
+}
+
+// AfterUpdateHook is implemented by models that run code after they are updated
+type AfterUpdateHook interface {
+	AfterUpdate(tx *Tx) error
+}
+
+// BeforeDeleteHook is implemented by models that run code before they are deleted
+type BeforeDeleteHook interface {
+	BeforeDelete(tx *Tx) error
+}
+
+// AfterDeleteHook is implemented by models that run code after they are deleted
+type AfterDeleteHook interface {
+	AfterDelete(tx *Tx) error
+}
+
+// Model event actions
+const (
+	ModelCreated = "created"
+	ModelUpdated = "updated"
+	ModelDeleted = "deleted"
+)
+
+// ModelEvent describes a model write that has been committed
+type ModelEvent struct {
+	Table  string
+	Action string // ModelCreated, ModelUpdated or ModelDeleted
+	Model  Model
+}
+
+// Name returns the event name, such as model.posts.created
+func (e ModelEvent) Name() string {
+	return "model." + e.Table + "." + e.Action
+}
+
+// OnModelEvent sets a function that is called after each model write made through BaseModel
+// or Repository commits. Soft deletes are reported as deleted. Set it before the database is used.
+func (db *DB) OnModelEvent(fn func(ModelEvent)) {
+	db.modelEvents = fn
+}
+
+// writeModel runs a model write and its hooks in a transaction, joining the one ctx carries if
+// there is one, and publishes the model event once the transaction commits
+func (db *DB) writeModel(ctx context.Context, model Model, action string, write func(tx *Tx) error) error {
+	return db.Transaction(ctx, func(tx *Tx) error {
+		if err := beforeHook(model, action, tx); err != nil {
+			return err
+		}
+		if v, ok := model.(Validator); ok && action != ModelDeleted {
+			if err := v.Validate(); err != nil {
+				return err
+			}
+		}
+		if err := write(tx); err != nil {
+			return err
+		}
+		if err := afterHook(model, action, tx); err != nil {
+			return err
+		}
+
+		if db.modelEvents != nil {
+			tx.afterCommit(func() {
+				db.modelEvents(ModelEvent{Table: model.TableName(), Action: action, Model: model})
+			})
+		}
+		return nil
+	})
+}
+
+// beforeHook runs a model's hook for the start of a write, if it has one
+func beforeHook(model Model, action string, tx *Tx) error {
+	switch action {
+	case ModelCreated:
+		if h, ok := model.(BeforeCreateHook); ok {
+			return h.BeforeCreate(tx)
+		}
+	case ModelUpdated:
+		if h, ok := model.(BeforeUpdateHook); ok {
+			return h.BeforeUpdate(tx)
+		}
+	case ModelDeleted:
+		if h, ok := model.(BeforeDeleteHook); ok {
+			return h.BeforeDelete(tx)
+		}
+	}
+	return nil
+}
+
+// afterHook runs a model's hook for the end of a write, if it has one
+func afterHook(model Model, action string, tx *Tx) error {
+	switch action {
+	case ModelCreated:
+		if h, ok := model.(AfterCreateHook); ok {
+			return h.AfterCreate(tx)
+		}
+	case ModelUpdated:
+		if h, ok := model.(AfterUpdateHook); ok {
+			return h.AfterUpdate(tx)
+		}
+	case ModelDeleted:
+		if h, ok := model.(AfterDeleteHook); ok {
+			return h.AfterDelete(tx)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,130 @@
+package database
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// hookedNote records the hooks it runs and can fail any of them
+type hookedNote struct {
+	ID   int    `db:"id"`
+	Slug string `db:"slug"`
+	Body string `db:"body"`
+	Timestamps
+	SoftDeletes
+
+	calls  []string
+	failOn string
+}
+
+func (n *hookedNote) TableName() string  { return "notes" }
+func (n *hookedNote) PrimaryKey() string { return "id" }
+
+func (n *hookedNote) hook(name string, tx *Tx) error {
+	n.calls = append(n.calls, name)
+	if name == n.failOn {
+		return errors.New(name + " failed")
+	}
+	if tx == nil {
+		return errors.New("no transaction")
+	}
+	return nil
+}
+
+func (n *hookedNote) Validate() error {
+	n.calls = append(n.calls, "Validate")
+	if n.Body == "" {
+		return errors.New("body is required")
+	}
+	return nil
+}
+
+func (n *hookedNote) BeforeCreate(tx *Tx) error {
+	if n.Slug == "" {
+		n.Slug = "generated"
+	}
+	return n.hook("BeforeCreate", tx)
+}
+func (n *hookedNote) AfterCreate(tx *Tx) error  { return n.hook("AfterCreate", tx) }
+func (n *hookedNote) BeforeUpdate(tx *Tx) error { return n.hook("BeforeUpdate", tx) }
+func (n *hookedNote) AfterUpdate(tx *Tx) error  { return n.hook("AfterUpdate", tx) }
+func (n *hookedNote) BeforeDelete(tx *Tx) error { return n.hook("BeforeDelete", tx) }
+func (n *hookedNote) AfterDelete(tx *Tx) error  { return n.hook("AfterDelete", tx) }
+
+func TestRepository_Hooks(t *testing.T) {
+	db := newRepoDB(t)
+	var events []string
+	db.OnModelEvent(func(e ModelEvent) { events = append(events, e.Name()) })
+	notes := NewRepository[*hookedNote](db)
+
+	note := &hookedNote{Body: "Hello"}
+	require.NoError(t, notes.Create(note))
+	assert.Equal(t, []string{"BeforeCreate", "Validate", "AfterCreate"}, note.calls)
+	assert.Equal(t, "generated", note.Slug, "changes made by BeforeCreate are saved")
+
+	note.calls = nil
+	require.NoError(t, notes.Update(note))
+	assert.Equal(t, []string{"BeforeUpdate", "Validate", "AfterUpdate"}, note.calls)
+
+	note.calls = nil
+	require.NoError(t, notes.Delete(note))
+	assert.Equal(t, []string{"BeforeDelete", "AfterDelete"}, note.calls)
+
+	assert.Equal(t, []string{"model.notes.created", "model.notes.updated", "model.notes.deleted"}, events)
+}
+
+func TestRepository_HookErrorsRollBack(t *testing.T) {
+	db := newRepoDB(t)
+	var events []string
+	db.OnModelEvent(func(e ModelEvent) { events = append(events, e.Name()) })
+	notes := NewRepository[*hookedNote](db)
+
+	err := notes.Create(&hookedNote{Slug: "invalid"})
+	assert.EqualError(t, err, "body is required")
+
+	err = notes.Create(&hookedNote{Slug: "after", Body: "Hello", failOn: "AfterCreate"})
+	assert.EqualError(t, err, "AfterCreate failed")
+
+	count, err := notes.Count()
+	require.NoError(t, err)
+	assert.Zero(t, count, "failed creates are rolled back")
+
+	note := &hookedNote{Slug: "kept", Body: "Hello"}
+	require.NoError(t, notes.Create(note))
+	note.Body = "Changed"
+	note.failOn = "AfterUpdate"
+	assert.Error(t, notes.Update(note))
+	note.failOn = "AfterDelete"
+	assert.Error(t, notes.Delete(note))
+
+	saved, err := notes.Find(note.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Hello", saved.Body)
+	assert.Equal(t, []string{"model.notes.created"}, events, "events are only published for committed writes")
+}
+
+func TestBaseModel_Hooks(t *testing.T) {
+	db := newRepoDB(t)
+	var published []ModelEvent
+	db.OnModelEvent(func(e ModelEvent) { published = append(published, e) })
+	model := NewBaseModel(db, &hookedNote{})
+
+	note := &hookedNote{Slug: "base", Body: "Hello"}
+	require.NoError(t, model.Create(note))
+	require.NoError(t, model.Update(note))
+	require.NoError(t, model.Delete(note))
+	assert.Equal(t, []string{
+		"BeforeCreate", "Validate", "AfterCreate",
+		"BeforeUpdate", "Validate", "AfterUpdate",
+		"BeforeDelete", "AfterDelete",
+	}, note.calls)
+
+	require.Len(t, published, 3)
+	assert.Equal(t, ModelEvent{Table: "notes", Action: ModelDeleted, Model: note}, published[2])
+
+	assert.EqualError(t, model.Create(&hookedNote{Slug: "empty"}), "body is required")
+	assert.Len(t, published, 3)
+}
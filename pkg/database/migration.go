@@ -1,25 +1,66 @@
 package database
 
 import (
+	"context"
 	"crypto/md5"
 	"database/sql"
 	"fmt"
 	"sort"
+	"strings"
 	"time"
 )
 
-// Migration represents a database migration
+// Migration represents a database migration. The checksum recorded when it is applied covers
+// its UpSQL and Content, so a migration edited after it was applied is detected as drift.
 type Migration struct {
 	Version     string
 	Description string
 	Up          func(*sql.Tx) error
 	Down        func(*sql.Tx) error
+
+	// UpSchema and DownSchema change tables with a Schema compiled to the database's dialect,
+	// run in place of Up and Down when those are nil
+	UpSchema   func(*Schema) error
+	DownSchema func(*Schema) error
+
+	// UpSQL and DownSQL are run in place of Up and Down when those and the schema functions are nil
+	UpSQL   string
+	DownSQL string
+
+	// Content stands in the checksum for what a Go migration's Up does, such as the SQL it runs.
+	// It is required for migrations with an Up function; those with UpSchema are checksummed by
+	// the statements their schema definition compiles to.
+	Content string
 }
 
 // MigrationRunner handles running migrations
 type MigrationRunner struct {
 	db         *DB
 	migrations []Migration
+	allowDrift bool
+}
+
+// MigrationDrift describes an applied migration that has changed since it was applied
+type MigrationDrift struct {
+	Version         string
+	Description     string
+	AppliedChecksum string // The checksum recorded when the migration was applied
+	Checksum        string // The checksum of the migration as it is now
+}
+
+// DriftError is returned by Run when applied migrations have changed since they were applied
+type DriftError struct {
+	Drifted []MigrationDrift
+}
+
+// Error lists the drifted migrations
+func (e *DriftError) Error() string {
+	versions := make([]string, len(e.Drifted))
+	for i, d := range e.Drifted {
+		versions[i] = d.Version
+	}
+	return fmt.Sprintf("%d applied migration(s) changed since they were applied: %s",
+		len(e.Drifted), strings.Join(versions, ", "))
 }
 
 // NewMigrationRunner creates a new migration runner
@@ -35,15 +76,31 @@ func (r *MigrationRunner) AddMigration(migration Migration) {
 	r.migrations = append(r.migrations, migration)
 }
 
+// AllowDrift sets whether Run applies pending migrations even though applied ones have changed
+func (r *MigrationRunner) AllowDrift(allow bool) {
+	r.allowDrift = allow
+}
+
 // Run executes all pending migrations
 func (r *MigrationRunner) Run() error {
+	return r.RunContext(context.Background())
+}
+
+// RunContext executes all pending migrations, stopping with ctx's error if it is canceled.
+// Each migration runs in its own transaction, which is rolled back if ctx is canceled.
+// Migration statements are not bounded by the database's QueryTimeout.
+// If applied migrations have changed, it returns a *DriftError without running any, unless
+// drift is allowed.
+func (r *MigrationRunner) RunContext(ctx context.Context) error {
+	ctx = WithoutTimeout(ctx)
+
 	// Ensure migrations table exists
-	if err := r.createMigrationsTable(); err != nil {
+	if err := r.createMigrationsTable(ctx); err != nil {
 		return fmt.Errorf("failed to create migrations table: %w", err)
 	}
 
 	// Get applied migrations
-	applied, err := r.getAppliedMigrations()
+	applied, err := r.getAppliedMigrations(ctx)
 	if err != nil {
 		return fmt.Errorf("failed to get applied migrations: %w", err)
 	}
@@ -53,13 +110,28 @@ func (r *MigrationRunner) Run() error {
 		return r.migrations[i].Version < r.migrations[j].Version
 	})
 
+	// Check applied migrations against their recorded checksums
+	if drifted := r.drift(applied); len(drifted) > 0 && !r.allowDrift {
+		return &DriftError{Drifted: drifted}
+	}
+	if err := r.updateLegacyChecksums(ctx, applied); err != nil {
+		return err
+	}
+
+	// Go code can't be checksummed, so pending Go migrations must describe themselves with Content
+	for _, migration := range r.migrations {
+		if _, ok := applied[migration.Version]; !ok && migration.Up != nil && migration.Content == "" {
+			return fmt.Errorf("migration %s has an Up function but no Content to detect changes with", migration.Version)
+		}
+	}
+
 	// Run pending migrations
 	for _, migration := range r.migrations {
 		if _, ok := applied[migration.Version]; ok {
 			continue // Already applied
 		}
 
-		if err := r.runMigration(migration); err != nil {
+		if err := r.runMigration(ctx, migration); err != nil {
 			return fmt.Errorf("failed to run migration %s: %w", migration.Version, err)
 		}
 	}
@@ -69,7 +141,15 @@ func (r *MigrationRunner) Run() error {
 
 // Rollback rolls back the last n migrations
 func (r *MigrationRunner) Rollback(steps int) error {
-	applied, err := r.getAppliedMigrationsOrdered()
+	return r.RollbackContext(context.Background(), steps)
+}
+
+// RollbackContext rolls back the last n migrations, stopping with ctx's error if it is canceled.
+// Like RunContext, it is not bounded by the database's QueryTimeout.
+func (r *MigrationRunner) RollbackContext(ctx context.Context, steps int) error {
+	ctx = WithoutTimeout(ctx)
+
+	applied, err := r.getAppliedMigrationsOrdered(ctx)
 	if err != nil {
 		return fmt.Errorf("failed to get applied migrations: %w", err)
 	}
@@ -95,7 +175,7 @@ func (r *MigrationRunner) Rollback(steps int) error {
 			return fmt.Errorf("migration %s not found", version)
 		}
 
-		if err := r.rollbackMigration(migration); err != nil {
+		if err := r.rollbackMigration(ctx, migration); err != nil {
 			return fmt.Errorf("failed to rollback migration %s: %w", version, err)
 		}
 	}
@@ -104,7 +184,7 @@ func (r *MigrationRunner) Rollback(steps int) error {
 }
 
 // createMigrationsTable creates the migrations tracking table
-func (r *MigrationRunner) createMigrationsTable() error {
+func (r *MigrationRunner) createMigrationsTable(ctx context.Context) error {
 	var query string
 
 	// Adjust for different databases
@@ -145,36 +225,98 @@ func (r *MigrationRunner) createMigrationsTable() error {
 		`
 	}
 
-	_, err := r.db.Exec(query)
+	_, err := r.db.ExecContext(ctx, query)
 	if err != nil {
 		return fmt.Errorf("failed to create migrations table: %w", err)
 	}
 	return nil
 }
 
-// getAppliedMigrations returns a map of applied migration versions
-func (r *MigrationRunner) getAppliedMigrations() (map[string]bool, error) {
-	rows, err := r.db.Query("SELECT version FROM migrations")
+// getAppliedMigrations returns the checksums of applied migrations by version
+func (r *MigrationRunner) getAppliedMigrations(ctx context.Context) (map[string]string, error) {
+	rows, err := r.db.QueryContext(ctx, "SELECT version, checksum FROM migrations")
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	applied := make(map[string]bool)
+	applied := make(map[string]string)
 	for rows.Next() {
 		var version string
-		if err := rows.Scan(&version); err != nil {
+		var checksum sql.NullString
+		if err := rows.Scan(&version, &checksum); err != nil {
 			return nil, err
 		}
-		applied[version] = true
+		applied[version] = checksum.String
 	}
 
 	return applied, rows.Err()
 }
 
+// Verify returns the applied migrations that have changed since they were applied
+func (r *MigrationRunner) Verify() ([]MigrationDrift, error) {
+	return r.VerifyContext(context.Background())
+}
+
+// VerifyContext returns the applied migrations that have changed since they were applied,
+// querying with ctx
+func (r *MigrationRunner) VerifyContext(ctx context.Context) ([]MigrationDrift, error) {
+	if err := r.createMigrationsTable(ctx); err != nil {
+		return nil, fmt.Errorf("failed to create migrations table: %w", err)
+	}
+	applied, err := r.getAppliedMigrations(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get applied migrations: %w", err)
+	}
+
+	sort.Slice(r.migrations, func(i, j int) bool {
+		return r.migrations[i].Version < r.migrations[j].Version
+	})
+	return r.drift(applied), nil
+}
+
+// drift returns the applied migrations whose recorded checksum differs from their current one.
+// Checksums recorded before migrations were checksummed by content, or not recorded at all,
+// can't show drift.
+func (r *MigrationRunner) drift(applied map[string]string) []MigrationDrift {
+	var drifted []MigrationDrift
+	for _, m := range r.migrations {
+		stored, ok := applied[m.Version]
+		if !ok || stored == "" || stored == legacyChecksum(m) {
+			continue
+		}
+		if current := calculateChecksum(m); stored != current {
+			drifted = append(drifted, MigrationDrift{
+				Version:         m.Version,
+				Description:     m.Description,
+				AppliedChecksum: stored,
+				Checksum:        current,
+			})
+		}
+	}
+	return drifted
+}
+
+// updateLegacyChecksums replaces checksums that can't show drift with current ones
+func (r *MigrationRunner) updateLegacyChecksums(ctx context.Context, applied map[string]string) error {
+	for _, m := range r.migrations {
+		stored, ok := applied[m.Version]
+		current := calculateChecksum(m)
+		if !ok || stored == current || (stored != "" && stored != legacyChecksum(m)) {
+			continue
+		}
+		_, err := r.db.ExecContext(ctx, "UPDATE migrations SET checksum = ? WHERE version = ?", current, m.Version)
+		if err != nil {
+			return fmt.Errorf("failed to update checksum of migration %s: %w", m.Version, err)
+		}
+		applied[m.Version] = current
+	}
+	return nil
+}
+
 // getAppliedMigrationsOrdered returns a slice of applied migration versions in order
-func (r *MigrationRunner) getAppliedMigrationsOrdered() ([]string, error) {
-	rows, err := r.db.Query("SELECT version FROM migrations ORDER BY version")
+func (r *MigrationRunner) getAppliedMigrationsOrdered(ctx context.Context) ([]string, error) {
+	rows, err := r.db.QueryContext(ctx, "SELECT version FROM migrations ORDER BY version")
 	if err != nil {
 		return nil, err
 	}
@@ -193,10 +335,10 @@ func (r *MigrationRunner) getAppliedMigrationsOrdered() ([]string, error) {
 }
 
 // runMigration executes a single migration
-func (r *MigrationRunner) runMigration(migration Migration) error {
-	return r.db.Transaction(func(tx *sql.Tx) error {
+func (r *MigrationRunner) runMigration(ctx context.Context, migration Migration) error {
+	return r.db.Transaction(ctx, func(tx *Tx) error {
 		// Run migration
-		if err := migration.Up(tx); err != nil {
+		if err := runStep(tx, migration.Up, migration.UpSchema, migration.UpSQL); err != nil {
 			return err
 		}
 
@@ -213,14 +355,14 @@ func (r *MigrationRunner) runMigration(migration Migration) error {
 }
 
 // rollbackMigration rolls back a single migration
-func (r *MigrationRunner) rollbackMigration(migration Migration) error {
-	if migration.Down == nil {
+func (r *MigrationRunner) rollbackMigration(ctx context.Context, migration Migration) error {
+	if migration.Down == nil && migration.DownSchema == nil && migration.DownSQL == "" {
 		return fmt.Errorf("migration %s does not support rollback", migration.Version)
 	}
 
-	return r.db.Transaction(func(tx *sql.Tx) error {
+	return r.db.Transaction(ctx, func(tx *Tx) error {
 		// Run rollback
-		if err := migration.Down(tx); err != nil {
+		if err := runStep(tx, migration.Down, migration.DownSchema, migration.DownSQL); err != nil {
 			return err
 		}
 
@@ -230,15 +372,59 @@ func (r *MigrationRunner) rollbackMigration(migration Migration) error {
 	})
 }
 
-// calculateChecksum calculates a checksum for a migration
+// runStep runs one direction of a migration, as a Go function, schema changes or SQL, which
+// may hold several statements
+func runStep(tx *Tx, fn func(*sql.Tx) error, schema func(*Schema) error, query string) error {
+	if fn != nil {
+		return fn(tx.Tx)
+	}
+	if schema != nil {
+		return schema(NewSchema(tx.db).WithContext(tx.Context()))
+	}
+	if tx.Dialect().Name() != "mysql" {
+		_, err := tx.Tx.ExecContext(tx.Context(), query)
+		return err
+	}
+	for _, stmt := range splitStatements(query) {
+		if _, err := tx.Tx.ExecContext(tx.Context(), stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// calculateChecksum calculates a checksum for a migration, covering its content
 func calculateChecksum(migration Migration) string {
+	content := migration.Content
+	if content == "" && migration.Up == nil && migration.UpSchema != nil {
+		if statements, err := recordSchema(migration.UpSchema); err == nil {
+			content = strings.Join(statements, ";\n")
+		}
+	}
+	if migration.UpSQL == "" && content == "" {
+		return legacyChecksum(migration)
+	}
+	data := strings.Join([]string{migration.Version, migration.Description, migration.UpSQL, content}, "\x00")
+	return fmt.Sprintf("%x", md5.Sum([]byte(data)))
+}
+
+// legacyChecksum is the checksum recorded before checksums covered a migration's content
+func legacyChecksum(migration Migration) string {
 	data := fmt.Sprintf("%s:%s", migration.Version, migration.Description)
 	return fmt.Sprintf("%x", md5.Sum([]byte(data)))
 }
 
 // Status returns the status of migrations
 func (r *MigrationRunner) Status() ([]MigrationStatus, error) {
-	applied, err := r.getAppliedMigrations()
+	return r.StatusContext(context.Background())
+}
+
+// StatusContext returns the status of migrations, querying with ctx
+func (r *MigrationRunner) StatusContext(ctx context.Context) ([]MigrationStatus, error) {
+	if err := r.createMigrationsTable(ctx); err != nil {
+		return nil, fmt.Errorf("failed to create migrations table: %w", err)
+	}
+	applied, err := r.getAppliedMigrations(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -248,12 +434,19 @@ func (r *MigrationRunner) Status() ([]MigrationStatus, error) {
 		return r.migrations[i].Version < r.migrations[j].Version
 	})
 
+	drifted := make(map[string]bool)
+	for _, d := range r.drift(applied) {
+		drifted[d.Version] = true
+	}
+
 	var status []MigrationStatus
 	for _, m := range r.migrations {
+		_, ok := applied[m.Version]
 		s := MigrationStatus{
 			Version:     m.Version,
 			Description: m.Description,
-			Applied:     applied[m.Version],
+			Applied:     ok,
+			Drifted:     drifted[m.Version],
 		}
 		status = append(status, s)
 	}
@@ -267,4 +460,5 @@ type MigrationStatus struct {
 	Description string
 	Applied     bool
 	AppliedAt   *time.Time
+	Drifted     bool // Applied, but changed since
 }
\ No newline at end of file
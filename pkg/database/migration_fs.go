@@ -0,0 +1,183 @@
+package database
+
+import (
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// migrationFilePattern matches migration file names: NNN_name.up.sql or NNN_name.down.sql,
+// optionally with a dialect before the extension, such as NNN_name.up.postgres.sql
+var migrationFilePattern = regexp.MustCompile(`^(\d+_[A-Za-z0-9_]+)\.(up|down)(?:\.(sqlite|mysql|postgres))?\.sql$`)
+
+// descriptionPrefix starts a comment on an up file's first line giving the migration's
+// description, in place of the one derived from its name
+const descriptionPrefix = "-- Description:"
+
+// migrationFiles holds the SQL of one migration's files, keyed by dialect, "" for the default
+type migrationFiles struct {
+	up   map[string]string
+	down map[string]string
+}
+
+// LoadMigrations reads SQL migrations from the top level of fsys for a database driver.
+// Each migration is a NNN_name.up.sql file, with an optional NNN_name.down.sql to roll it back.
+// A file such as NNN_name.up.postgres.sql replaces the default on that dialect ("sqlite",
+// "mysql" or "postgres"). The version is the NNN_name part and the description is derived from
+// the name, unless the up file starts with a "-- Description: ..." comment. Other files are
+// ignored.
+func LoadMigrations(fsys fs.FS, driver string) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations: %w", err)
+	}
+
+	files := make(map[string]*migrationFiles)
+	for _, entry := range entries {
+		if entry.IsDir() || path.Ext(entry.Name()) != ".sql" {
+			continue
+		}
+		match := migrationFilePattern.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("invalid migration file name %s: want NNN_name.up.sql or NNN_name.down.sql", entry.Name())
+		}
+		data, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return nil, fmt.Errorf("failed to read migration %s: %w", entry.Name(), err)
+		}
+
+		version, direction, dialect := match[1], match[2], match[3]
+		f, ok := files[version]
+		if !ok {
+			f = &migrationFiles{up: make(map[string]string), down: make(map[string]string)}
+			files[version] = f
+		}
+		if direction == "up" {
+			f.up[dialect] = string(data)
+		} else {
+			f.down[dialect] = string(data)
+		}
+	}
+
+	dialect := DialectFor(driver).Name()
+	migrations := make([]Migration, 0, len(files))
+	for version, f := range files {
+		up, ok := forDialect(f.up, dialect)
+		if !ok {
+			return nil, fmt.Errorf("migration %s has no up file for %s", version, dialect)
+		}
+		down, _ := forDialect(f.down, dialect)
+		migrations = append(migrations, Migration{
+			Version:     version,
+			Description: migrationDescription(version, up),
+			UpSQL:       up,
+			DownSQL:     down,
+		})
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+	return migrations, nil
+}
+
+// AddMigrationsFS loads SQL migrations from fsys for the runner's database, see LoadMigrations,
+// and adds them to those it runs. Use os.DirFS to load them from a directory.
+func (r *MigrationRunner) AddMigrationsFS(fsys fs.FS) error {
+	migrations, err := LoadMigrations(fsys, r.db.Driver())
+	if err != nil {
+		return err
+	}
+
+	versions := make(map[string]bool, len(r.migrations))
+	for _, m := range r.migrations {
+		versions[m.Version] = true
+	}
+	for _, m := range migrations {
+		if versions[m.Version] {
+			return fmt.Errorf("duplicate migration version %s", m.Version)
+		}
+	}
+
+	r.migrations = append(r.migrations, migrations...)
+	return nil
+}
+
+// forDialect returns a dialect's variant of a migration file, or the default one
+func forDialect(variants map[string]string, dialect string) (string, bool) {
+	if sql, ok := variants[dialect]; ok {
+		return sql, true
+	}
+	sql, ok := variants[""]
+	return sql, ok
+}
+
+// migrationDescription returns the description given by an up file's first line, or one
+// derived from the version, so "003_create_users_table" is "Create users table"
+func migrationDescription(version, up string) string {
+	firstLine, _, _ := strings.Cut(strings.TrimSpace(up), "\n")
+	if desc, ok := strings.CutPrefix(strings.TrimSpace(firstLine), descriptionPrefix); ok {
+		return strings.TrimSpace(desc)
+	}
+
+	_, name, _ := strings.Cut(version, "_")
+	desc := strings.ReplaceAll(name, "_", " ")
+	if desc == "" {
+		return version
+	}
+	return strings.ToUpper(desc[:1]) + desc[1:]
+}
+
+// splitStatements splits MySQL SQL into its statements on semicolons outside quotes and
+// comments, as the MySQL driver runs one statement per Exec. Statements of only comments are
+// dropped.
+func splitStatements(query string) []string {
+	var statements []string
+	start, code := 0, false // code is whether the statement so far is more than comments
+	add := func(end int) {
+		if code {
+			statements = append(statements, strings.TrimSpace(query[start:end]))
+		}
+	}
+
+	for i := 0; i < len(query); i++ {
+		switch c := query[i]; {
+		case c == '\'' || c == '"' || c == '`':
+			code = true
+			// Skip the quoted string, where a backslash or a doubled quote escapes a quote
+			for i++; i < len(query); i++ {
+				if query[i] == '\\' && c != '`' {
+					i++
+				} else if query[i] == c {
+					if i+1 < len(query) && query[i+1] == c {
+						i++
+						continue
+					}
+					break
+				}
+			}
+		case c == '-' && strings.HasPrefix(query[i:], "--"), c == '#':
+			if end := strings.IndexByte(query[i:], '\n'); end >= 0 {
+				i += end
+			} else {
+				i = len(query)
+			}
+		case c == '/' && strings.HasPrefix(query[i:], "/*"):
+			if end := strings.Index(query[i+2:], "*/"); end >= 0 {
+				i += end + 3
+			} else {
+				i = len(query)
+			}
+		case c == ';':
+			add(i)
+			start, code = i+1, false
+		case c != ' ' && c != '\t' && c != '\r' && c != '\n':
+			code = true
+		}
+	}
+	add(len(query))
+	return statements
+}
@@ -0,0 +1,96 @@
+package database
+
+import (
+	"database/sql"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadMigrations(t *testing.T) {
+	fsys := fstest.MapFS{
+		"001_create_notes.up.sql":           {Data: []byte("CREATE TABLE notes (id INTEGER PRIMARY KEY);")},
+		"001_create_notes.up.mysql.sql":     {Data: []byte("CREATE TABLE notes (id INT AUTO_INCREMENT PRIMARY KEY);")},
+		"001_create_notes.down.sql":         {Data: []byte("DROP TABLE notes;")},
+		"002_add_tags.up.postgres.sql":      {Data: []byte("-- Description: Add note tags\nCREATE TABLE tags (name TEXT);")},
+		"002_add_tags.up.sql":               {Data: []byte("-- Description: Add note tags\nCREATE TABLE tags (name TEXT);")},
+		"README.md":                         {Data: []byte("not a migration")},
+		"nested/003_ignored.up.sql":         {Data: []byte("SELECT 1")},
+		"004_postgres_only.up.postgres.sql": {Data: []byte("SELECT 1")},
+	}
+
+	_, err := LoadMigrations(fsys, "sqlite3")
+	assert.ErrorContains(t, err, "004_postgres_only has no up file for sqlite")
+
+	migrations, err := LoadMigrations(fsys, "postgresql")
+	require.NoError(t, err)
+	require.Len(t, migrations, 3)
+	assert.Equal(t, Migration{
+		Version:     "001_create_notes",
+		Description: "Create notes",
+		UpSQL:       "CREATE TABLE notes (id INTEGER PRIMARY KEY);",
+		DownSQL:     "DROP TABLE notes;",
+	}, migrations[0])
+	assert.Equal(t, "Add note tags", migrations[1].Description)
+	assert.Empty(t, migrations[1].DownSQL)
+
+	delete(fsys, "004_postgres_only.up.postgres.sql")
+	migrations, err = LoadMigrations(fsys, "mysql")
+	require.NoError(t, err)
+	require.Len(t, migrations, 2)
+	assert.Equal(t, "CREATE TABLE notes (id INT AUTO_INCREMENT PRIMARY KEY);", migrations[0].UpSQL)
+	assert.Equal(t, "DROP TABLE notes;", migrations[0].DownSQL)
+
+	fsys["5_bad-name.up.sql"] = &fstest.MapFile{Data: []byte("SELECT 1")}
+	_, err = LoadMigrations(fsys, "sqlite")
+	assert.ErrorContains(t, err, "invalid migration file name 5_bad-name.up.sql")
+}
+
+func TestMigrationRunner_AddMigrationsFS(t *testing.T) {
+	db := newMigrationDB(t)
+	fsys := fstest.MapFS{
+		"001_create_notes.up.sql":   {Data: []byte("CREATE TABLE notes (id INTEGER PRIMARY KEY, body TEXT);\nCREATE INDEX idx_notes_body ON notes(body);")},
+		"001_create_notes.down.sql": {Data: []byte("DROP TABLE notes;")},
+		"003_create_tags.up.sql":    {Data: []byte("CREATE TABLE tags (note_id INTEGER REFERENCES notes(id), name TEXT);")},
+	}
+
+	// A Go migration runs between SQL ones, in version order
+	var order []string
+	runner := NewMigrationRunner(db)
+	runner.AddMigration(Migration{Version: "002_seed_notes", Description: "Seed notes", Content: "INSERT INTO notes (body) VALUES ('hello')", Up: func(tx *sql.Tx) error {
+		order = append(order, "002_seed_notes")
+		_, err := tx.Exec("INSERT INTO notes (body) VALUES ('hello')")
+		return err
+	}})
+	require.NoError(t, runner.AddMigrationsFS(fsys))
+	require.NoError(t, runner.Run())
+	assert.Equal(t, []string{"002_seed_notes"}, order)
+
+	_, err := db.Exec("INSERT INTO tags (note_id, name) VALUES (1, 'go')")
+	require.NoError(t, err)
+	var index string
+	require.NoError(t, db.QueryRow("SELECT name FROM sqlite_master WHERE type = 'index' AND name = 'idx_notes_body'").Scan(&index))
+
+	status, err := runner.Status()
+	require.NoError(t, err)
+	require.Len(t, status, 3)
+	assert.Equal(t, "Create tags", status[2].Description)
+	assert.True(t, status[2].Applied)
+
+	assert.ErrorContains(t, runner.AddMigrationsFS(fsys), "duplicate migration version 001_create_notes")
+}
+
+func TestSplitStatements(t *testing.T) {
+	query := `
+		-- Notes; with a comment
+		CREATE TABLE notes (body TEXT DEFAULT 'a;b', ` + "`semi;colon`" + ` INT);
+		/* block; comment */ INSERT INTO notes (body) VALUES ('it''s; \'quoted\'');
+		# trailing;
+	`
+	assert.Equal(t, []string{
+		"-- Notes; with a comment\n\t\tCREATE TABLE notes (body TEXT DEFAULT 'a;b', `semi;colon` INT)",
+		`/* block; comment */ INSERT INTO notes (body) VALUES ('it''s; \'quoted\'')`,
+	}, splitStatements(query))
+}
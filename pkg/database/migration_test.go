@@ -0,0 +1,120 @@
+package database
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newMigrationDB(t *testing.T) *DB {
+	t.Helper()
+	db, err := New(&Config{Driver: "sqlite", SQLitePath: ":memory:", MaxOpenConns: 1, MaxIdleConns: 1})
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func noopMigration(*sql.Tx) error { return nil }
+
+func TestMigrationRunner_Drift(t *testing.T) {
+	db := newMigrationDB(t)
+	notes := Migration{
+		Version:     "001_create_notes",
+		Description: "Create notes",
+		UpSQL:       "CREATE TABLE notes (id INTEGER PRIMARY KEY, body TEXT)",
+		DownSQL:     "DROP TABLE notes",
+	}
+	runner := NewMigrationRunner(db)
+	runner.AddMigration(notes)
+	require.NoError(t, runner.Run())
+
+	// Editing an applied migration is drift, which stops later migrations from running
+	edited := notes
+	edited.UpSQL = "CREATE TABLE notes (id INTEGER PRIMARY KEY, body TEXT NOT NULL)"
+	tags := Migration{Version: "002_create_tags", Description: "Create tags", UpSQL: "CREATE TABLE tags (name TEXT)", DownSQL: "DROP TABLE tags"}
+	runner = NewMigrationRunner(db)
+	runner.AddMigration(edited)
+	runner.AddMigration(tags)
+
+	drifted, err := runner.Verify()
+	require.NoError(t, err)
+	require.Len(t, drifted, 1)
+	assert.Equal(t, "001_create_notes", drifted[0].Version)
+	assert.Equal(t, calculateChecksum(notes), drifted[0].AppliedChecksum)
+	assert.Equal(t, calculateChecksum(edited), drifted[0].Checksum)
+
+	err = runner.Run()
+	var driftErr *DriftError
+	require.ErrorAs(t, err, &driftErr)
+	assert.Equal(t, drifted, driftErr.Drifted)
+	assert.Contains(t, err.Error(), "001_create_notes")
+
+	status, err := runner.Status()
+	require.NoError(t, err)
+	assert.Equal(t, []MigrationStatus{
+		{Version: "001_create_notes", Description: "Create notes", Applied: true, Drifted: true},
+		{Version: "002_create_tags", Description: "Create tags"},
+	}, status)
+
+	runner.AllowDrift(true)
+	require.NoError(t, runner.Run())
+	_, err = db.Exec("INSERT INTO tags (name) VALUES ('go')")
+	require.NoError(t, err)
+
+	// Changing a Go migration's content is drift too, but changing its code can't be seen
+	goMigration := Migration{Version: "003_go", Description: "Go", Content: "v1", Up: noopMigration}
+	runner = NewMigrationRunner(db)
+	runner.AddMigration(notes)
+	runner.AddMigration(goMigration)
+	require.NoError(t, runner.Run())
+	goMigration.Content = "v2"
+	runner = NewMigrationRunner(db)
+	runner.AddMigration(goMigration)
+	drifted, err = runner.Verify()
+	require.NoError(t, err)
+	require.Len(t, drifted, 1)
+	assert.Equal(t, "003_go", drifted[0].Version)
+
+	// SQL migrations roll back with DownSQL
+	runner = NewMigrationRunner(db)
+	runner.AddMigration(notes)
+	runner.AddMigration(tags)
+	runner.AddMigration(Migration{Version: "003_go", Description: "Go", Content: "v1", Up: noopMigration, Down: noopMigration})
+	require.NoError(t, runner.Rollback(2))
+	_, err = db.Exec("INSERT INTO tags (name) VALUES ('go')")
+	assert.Error(t, err)
+}
+
+func TestMigrationRunner_LegacyChecksums(t *testing.T) {
+	db := newMigrationDB(t)
+	legacy := Migration{Version: "001_create_notes", Description: "Create notes", Up: noopMigration}
+	runner := NewMigrationRunner(db)
+	require.NoError(t, runner.Run())
+	_, err := db.Exec("INSERT INTO migrations (version, description, checksum) VALUES (?, ?, ?)", legacy.Version, legacy.Description, legacyChecksum(legacy))
+	require.NoError(t, err)
+
+	// The migration gains content; its recorded checksum predates content checksums
+	withSQL := legacy
+	withSQL.Up = nil
+	withSQL.UpSQL = "CREATE TABLE notes (id INTEGER PRIMARY KEY)"
+	runner = NewMigrationRunner(db)
+	runner.AddMigration(withSQL)
+	drifted, err := runner.Verify()
+	require.NoError(t, err)
+	assert.Empty(t, drifted)
+
+	require.NoError(t, runner.Run())
+	var checksum string
+	require.NoError(t, db.QueryRow("SELECT checksum FROM migrations WHERE version = ?", legacy.Version).Scan(&checksum))
+	assert.Equal(t, calculateChecksum(withSQL), checksum, "Run records the content checksum")
+
+	// From then on, edits are drift
+	withSQL.UpSQL += " -- edited"
+	runner = NewMigrationRunner(db)
+	runner.AddMigration(withSQL)
+	drifted, err = runner.Verify()
+	require.NoError(t, err)
+	assert.Len(t, drifted, 1)
+}
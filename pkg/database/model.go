@@ -1,7 +1,9 @@
 package database
 
 import (
-	"database/sql"
+	"context"
+	"database/sql/driver"
+	"encoding/json"
 	"fmt"
 	"reflect"
 	"strings"
@@ -29,6 +31,7 @@ type SoftDeletes struct {
 type BaseModel struct {
 	db    *DB
 	model Model
+	ctx   context.Context // Set by WithContext
 }
 
 // NewBaseModel creates a new BaseModel instance
@@ -39,17 +42,35 @@ func NewBaseModel(db *DB, model Model) *BaseModel {
 	}
 }
 
+// WithContext returns a copy of the model that runs its queries and writes with ctx,
+// joining the transaction ctx carries if there is one
+func (m *BaseModel) WithContext(ctx context.Context) *BaseModel {
+	model := *m
+	model.ctx = ctx
+	return &model
+}
+
 // Find finds a record by primary key
 func (m *BaseModel) Find(id interface{}, dest Model) error {
 	query := fmt.Sprintf("SELECT * FROM %s WHERE %s = ? LIMIT 1", 
-		dest.TableName(), dest.PrimaryKey())
+		m.db.Quote(dest.TableName()), m.db.Quote(dest.PrimaryKey()))
 	
-	row := m.db.QueryRow(query, id)
-	return scanStruct(row, dest)
+	rows, err := m.db.QueryContext(m.context(), query, id)
+	if err != nil {
+		return err
+	}
+	return ScanOne(rows, dest)
 }
 
-// Create inserts a new record
+// Create inserts a new record, running the model's create hooks in the same transaction
 func (m *BaseModel) Create(model Model) error {
+	return m.db.writeModel(m.context(), model, ModelCreated, func(tx *Tx) error {
+		return m.create(tx, model)
+	})
+}
+
+// create builds and runs the INSERT for a model, after its before hooks have run
+func (m *BaseModel) create(tx *Tx, model Model) error {
 	fields, values := getFieldsAndValues(model, true)
 	
 	// Handle timestamps
@@ -65,25 +86,31 @@ func (m *BaseModel) Create(model Model) error {
 	}
 	
 	query := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)",
-		model.TableName(),
-		strings.Join(fields, ", "),
+		m.db.Quote(model.TableName()),
+		strings.Join(m.quoteAll(fields), ", "),
 		strings.Join(placeholders, ", "))
 	
-	result, err := m.db.Exec(query, values...)
+	// Set auto-increment ID, returned by the insert on drivers without LastInsertId
+	id, err := tx.InsertID(tx.Context(), query, model.PrimaryKey(), values...)
 	if err != nil {
 		return err
 	}
-	
-	// Set auto-increment ID if applicable
-	if id, err := result.LastInsertId(); err == nil && id > 0 {
+	if id > 0 {
 		setFieldValue(model, model.PrimaryKey(), id)
 	}
 	
 	return nil
 }
 
-// Update updates a record
+// Update updates a record, running the model's update hooks in the same transaction
 func (m *BaseModel) Update(model Model) error {
+	return m.db.writeModel(m.context(), model, ModelUpdated, func(tx *Tx) error {
+		return m.update(tx, model)
+	})
+}
+
+// update builds and runs the UPDATE for a model, after its before hooks have run
+func (m *BaseModel) update(tx *Tx, model Model) error {
 	fields, values := getFieldsAndValues(model, false)
 	
 	// Handle timestamps
@@ -95,7 +122,7 @@ func (m *BaseModel) Update(model Model) error {
 	// Build SET clause
 	setClauses := make([]string, len(fields))
 	for i, field := range fields {
-		setClauses[i] = field + " = ?"
+		setClauses[i] = m.db.Quote(field) + " = ?"
 	}
 	
 	// Add primary key to values
@@ -103,64 +130,81 @@ func (m *BaseModel) Update(model Model) error {
 	values = append(values, pkValue)
 	
 	query := fmt.Sprintf("UPDATE %s SET %s WHERE %s = ?",
-		model.TableName(),
+		m.db.Quote(model.TableName()),
 		strings.Join(setClauses, ", "),
-		model.PrimaryKey())
+		m.db.Quote(model.PrimaryKey()))
 	
-	_, err := m.db.Exec(query, values...)
+	_, err := tx.Exec(query, values...)
 	return err
 }
 
-// Delete deletes a record
+// Delete deletes a record, running the model's delete hooks in the same transaction
 func (m *BaseModel) Delete(model Model) error {
+	return m.db.writeModel(m.context(), model, ModelDeleted, func(tx *Tx) error {
+		return m.delete(tx, model)
+	})
+}
+
+// delete soft deletes or removes a model
+func (m *BaseModel) delete(tx *Tx, model Model) error {
 	// Check for soft deletes
 	if hasSoftDeletes(model) {
 		now := time.Now()
-		query := fmt.Sprintf("UPDATE %s SET deleted_at = ? WHERE %s = ?",
-			model.TableName(), model.PrimaryKey())
+		query := fmt.Sprintf("UPDATE %s SET %s = ? WHERE %s = ?",
+			m.db.Quote(model.TableName()), m.db.Quote("deleted_at"), m.db.Quote(model.PrimaryKey()))
 		
 		pkValue := getFieldValue(model, model.PrimaryKey())
-		_, err := m.db.Exec(query, now, pkValue)
+		_, err := tx.Exec(query, now, pkValue)
 		return err
 	}
 	
 	// Hard delete
 	pkValue := getFieldValue(model, model.PrimaryKey())
 	query := fmt.Sprintf("DELETE FROM %s WHERE %s = ?", 
-		model.TableName(), model.PrimaryKey())
+		m.db.Quote(model.TableName()), m.db.Quote(model.PrimaryKey()))
 	
-	_, err := m.db.Exec(query, pkValue)
+	_, err := tx.Exec(query, pkValue)
 	return err
 }
 
 // Where starts a new query
 func (m *BaseModel) Where(field string, value interface{}) *Query {
-	q := &Query{
-		db:    m.db,
-		table: m.model.TableName(),
-		wheres: []whereClause{
-			{field: field, operator: "=", value: value},
-		},
-	}
-	return q
+	return NewQuery(m.db).WithContext(m.context()).Table(m.model.TableName()).Where(field, value)
 }
 
 // All retrieves all records
 func (m *BaseModel) All(dest interface{}) error {
-	query := fmt.Sprintf("SELECT * FROM %s", m.model.TableName())
+	query := fmt.Sprintf("SELECT * FROM %s", m.db.Quote(m.model.TableName()))
 	
 	// Handle soft deletes
 	if hasSoftDeletes(m.model) {
-		query += " WHERE deleted_at IS NULL"
+		query += fmt.Sprintf(" WHERE %s IS NULL", m.db.Quote("deleted_at"))
 	}
 	
-	rows, err := m.db.Query(query)
+	rows, err := m.db.QueryContext(m.context(), query)
 	if err != nil {
 		return err
 	}
 	defer rows.Close()
 	
-	return scanStructs(rows, dest)
+	return ScanAll(rows, dest)
+}
+
+// context returns the context the model runs with
+func (m *BaseModel) context() context.Context {
+	if m.ctx == nil {
+		return context.Background()
+	}
+	return m.ctx
+}
+
+// quoteAll quotes column names
+func (m *BaseModel) quoteAll(columns []string) []string {
+	quoted := make([]string, len(columns))
+	for i, column := range columns {
+		quoted[i] = m.db.Quote(column)
+	}
+	return quoted
 }
 
 // Helper functions
@@ -180,28 +224,27 @@ func getFieldsAndValues(model interface{}, excludePK bool) ([]string, []interfac
 	var values []interface{}
 	
 	v := reflect.ValueOf(model).Elem()
-	t := v.Type()
+	info := structColumns(v.Type())
 	
-	for i := 0; i < t.NumField(); i++ {
-		field := t.Field(i)
-		tag := field.Tag.Get("db")
-		
-		if tag == "" || tag == "-" {
-			continue
-		}
-		
+	for _, name := range info.names {
 		// Skip primary key if requested
-		if excludePK && tag == model.(Model).PrimaryKey() {
+		if excludePK && name == model.(Model).PrimaryKey() {
 			continue
 		}
 		
 		// Skip timestamps and soft deletes fields
-		if tag == "created_at" || tag == "updated_at" || tag == "deleted_at" {
+		if name == "created_at" || name == "updated_at" || name == "deleted_at" {
 			continue
 		}
 		
-		fields = append(fields, tag)
-		values = append(values, v.Field(i).Interface())
+		col := info.columns[name]
+		value := v.FieldByIndex(col.index).Interface()
+		if col.json {
+			value = jsonValue{value}
+		}
+		
+		fields = append(fields, name)
+		values = append(values, value)
 	}
 	
 	return fields, values
@@ -209,15 +252,8 @@ func getFieldsAndValues(model interface{}, excludePK bool) ([]string, []interfac
 
 func getFieldValue(model interface{}, fieldName string) interface{} {
 	v := reflect.ValueOf(model).Elem()
-	t := v.Type()
-	
-	for i := 0; i < t.NumField(); i++ {
-		field := t.Field(i)
-		tag := field.Tag.Get("db")
-		
-		if tag == fieldName {
-			return v.Field(i).Interface()
-		}
+	if col, ok := structColumns(v.Type()).columns[fieldName]; ok {
+		return v.FieldByIndex(col.index).Interface()
 	}
 	
 	return nil
@@ -225,27 +261,43 @@ func getFieldValue(model interface{}, fieldName string) interface{} {
 
 func setFieldValue(model interface{}, fieldName string, value interface{}) {
 	v := reflect.ValueOf(model).Elem()
-	t := v.Type()
+	col, ok := structColumns(v.Type()).columns[fieldName]
+	if !ok {
+		return
+	}
 	
-	for i := 0; i < t.NumField(); i++ {
-		field := t.Field(i)
-		tag := field.Tag.Get("db")
-		
-		if tag == fieldName {
-			v.Field(i).Set(reflect.ValueOf(value))
-			return
+	// Generated IDs are int64; convert them to the field's integer type
+	field := v.FieldByIndex(col.index)
+	val := reflect.ValueOf(value)
+	switch field.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if val.CanConvert(field.Type()) {
+			field.Set(val.Convert(field.Type()))
+		}
+	default:
+		if val.Type().AssignableTo(field.Type()) {
+			field.Set(val)
 		}
 	}
 }
 
-func scanStruct(row *sql.Row, dest interface{}) error {
-	// This is a simplified version - in production you'd use a library like sqlx
-	// For now, we'll just return an error
-	return fmt.Errorf("scanStruct not fully implemented - use sqlx for production")
+// jsonValue writes a value to a JSON column
+type jsonValue struct {
+	value interface{}
 }
 
-func scanStructs(rows *sql.Rows, dest interface{}) error {
-	// This is a simplified version - in production you'd use a library like sqlx
-	// For now, we'll just return an error
-	return fmt.Errorf("scanStructs not fully implemented - use sqlx for production")
-}
\ No newline at end of file
+// Value implements driver.Valuer
+func (j jsonValue) Value() (driver.Value, error) {
+	if j.value == nil {
+		return nil, nil
+	}
+	if v := reflect.ValueOf(j.value); (v.Kind() == reflect.Map || v.Kind() == reflect.Slice || v.Kind() == reflect.Pointer) && v.IsNil() {
+		return nil, nil
+	}
+	data, err := json.Marshal(j.value)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
@@ -0,0 +1,267 @@
+package database
+
+import (
+	"database/sql/driver"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// ErrInvalidCursor is returned for a cursor that wasn't issued for the query's ordering
+var ErrInvalidCursor = errors.New("invalid pagination cursor")
+
+// Pagination describes one page of an offset-paginated query
+type Pagination struct {
+	Page    int   // The current page, counting from 1
+	PerPage int   // The number of items per page
+	Total   int64 // The number of items on all pages
+	Pages   int   // The number of pages, at least 1
+}
+
+// HasPrev reports whether there is a page before this one
+func (p *Pagination) HasPrev() bool {
+	return p.Page > 1
+}
+
+// HasNext reports whether there is a page after this one
+func (p *Pagination) HasNext() bool {
+	return p.Page < p.Pages
+}
+
+// PrevPage returns the previous page number, or 0 on the first page
+func (p *Pagination) PrevPage() int {
+	if !p.HasPrev() {
+		return 0
+	}
+	return p.Page - 1
+}
+
+// NextPage returns the next page number, or 0 on the last page
+func (p *Pagination) NextPage() int {
+	if !p.HasNext() {
+		return 0
+	}
+	return p.Page + 1
+}
+
+// Paginate scans one page of results into dest, a pointer to a slice, and returns the
+// page's position among all results. Pages count from 1; page and perPage below 1 are
+// treated as 1.
+func (q *Query) Paginate(page, perPage int, dest interface{}) (*Pagination, error) {
+	if page < 1 {
+		page = 1
+	}
+	if perPage < 1 {
+		perPage = 1
+	}
+
+	total, err := q.Count()
+	if err != nil {
+		return nil, err
+	}
+	pages := int((total + int64(perPage) - 1) / int64(perPage))
+	if pages < 1 {
+		pages = 1
+	}
+
+	// Save current paging
+	oldLimit, oldOffset := q.limit, q.offset
+	q.limit, q.offset = perPage, (page-1)*perPage
+
+	rows, err := q.Get()
+
+	// Restore paging
+	q.limit, q.offset = oldLimit, oldOffset
+
+	if err != nil {
+		return nil, err
+	}
+	if err := ScanAll(rows, dest); err != nil {
+		return nil, err
+	}
+
+	return &Pagination{Page: page, PerPage: perPage, Total: total, Pages: pages}, nil
+}
+
+// CursorPage describes one page of a cursor-paginated query
+type CursorPage struct {
+	Limit      int    // The maximum number of items per page
+	NextCursor string // The cursor of the next page, empty on the last page
+}
+
+// HasNext reports whether there is a page after this one
+func (p *CursorPage) HasNext() bool {
+	return p.NextCursor != ""
+}
+
+// CursorPaginate scans the page of results after cursor into dest, a pointer to a slice of
+// structs or struct pointers, using keyset pagination: instead of skipping rows with an
+// offset, it selects the rows that sort after the cursor, which stays fast on large tables.
+// An empty cursor starts at the first page.
+//
+// The query must be ordered with OrderBy by columns that are never NULL and, together, unique
+// to a row, such as created_at then id, and each must map to a field of the struct. Cursors
+// are opaque strings holding the ordering values of a page's last row.
+func (q *Query) CursorPaginate(cursor string, limit int, dest interface{}) (*CursorPage, error) {
+	if limit < 1 {
+		limit = 1
+	}
+	if len(q.orderBys) == 0 {
+		return nil, errors.New("cursor pagination requires the query to be ordered")
+	}
+	for _, order := range q.orderBys {
+		if order.raw != nil {
+			return nil, errors.New("cursor pagination can't order by raw expressions")
+		}
+	}
+
+	v := reflect.ValueOf(dest)
+	if v.Kind() != reflect.Pointer || v.IsNil() || v.Elem().Kind() != reflect.Slice {
+		return nil, fmt.Errorf("cursor pagination destination must be a pointer to a slice, got %T", dest)
+	}
+
+	// Save current conditions and paging. The cursor condition is a scope, so the query's
+	// OR conditions can't bypass it.
+	oldScopes, oldLimit, oldOffset := q.scopes, q.limit, q.offset
+	if cursor != "" {
+		values, err := decodeCursor(cursor, len(q.orderBys))
+		if err != nil {
+			return nil, err
+		}
+		q.scopes = append(append([]whereClause(nil), q.scopes...), q.afterCursor(values))
+	}
+	// Read one more row than the page holds to learn whether another page follows
+	q.limit, q.offset = limit+1, 0
+
+	rows, err := q.Get()
+
+	// Restore conditions and paging
+	q.scopes, q.limit, q.offset = oldScopes, oldLimit, oldOffset
+
+	if err != nil {
+		return nil, err
+	}
+	if err := ScanAll(rows, dest); err != nil {
+		return nil, err
+	}
+
+	page := &CursorPage{Limit: limit}
+	slice := v.Elem()
+	if slice.Len() > limit {
+		slice.Set(slice.Slice(0, limit))
+		next, err := q.cursorFor(slice.Index(limit - 1))
+		if err != nil {
+			return nil, err
+		}
+		page.NextCursor = next
+	}
+	return page, nil
+}
+
+// afterCursor returns the condition selecting the rows that sort after the cursor's values:
+// (a > ?) OR (a = ? AND b > ?) OR ... for columns a, b, ..., with < for descending columns
+func (q *Query) afterCursor(values []interface{}) whereClause {
+	group := &Query{db: q.db}
+	for i, order := range q.orderBys {
+		group.OrWhereGroup(func(g *Query) {
+			for j := 0; j < i; j++ {
+				g.Where(q.orderBys[j].field, values[j])
+			}
+			op := ">"
+			if order.direction == "DESC" {
+				op = "<"
+			}
+			g.Where(order.field, op, values[i])
+		})
+	}
+	return whereClause{operator: "GROUP", value: group, boolean: "AND"}
+}
+
+// cursorFor encodes the ordering values of a scanned row
+func (q *Query) cursorFor(row reflect.Value) (string, error) {
+	if row.Kind() == reflect.Pointer {
+		row = row.Elem()
+	}
+	if !isStruct(row.Type()) {
+		return "", fmt.Errorf("cursor pagination needs struct results, got %s", row.Type())
+	}
+
+	columns := structColumns(row.Type()).columns
+	values := make([]cursorValue, len(q.orderBys))
+	for i, order := range q.orderBys {
+		// Ordering may be qualified with the table, as in pages.id
+		var err error
+		name := order.field[strings.LastIndex(order.field, ".")+1:]
+		col, ok := columns[name]
+		if !ok {
+			return "", fmt.Errorf("cursor pagination orders by %s, which %s has no field for", order.field, row.Type())
+		}
+		value := row.FieldByIndex(col.index).Interface()
+		if valuer, ok := value.(driver.Valuer); ok {
+			if value, err = valuer.Value(); err != nil {
+				return "", fmt.Errorf("failed to read cursor value of %s: %w", order.field, err)
+			}
+		}
+		if t, ok := value.(time.Time); ok {
+			values[i] = cursorValue{Time: &t}
+		} else {
+			values[i] = cursorValue{Value: value}
+		}
+	}
+
+	data, err := json.Marshal(values)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode cursor: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// cursorValue is an ordering value in a cursor. Times are kept apart from other values so
+// they are bound as times again, and compare the way the driver stored them.
+type cursorValue struct {
+	Time  *time.Time  `json:"t,omitempty"`
+	Value interface{} `json:"v,omitempty"`
+}
+
+// decodeCursor decodes a cursor's ordering values
+func decodeCursor(cursor string, columns int) ([]interface{}, error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	decoder := json.NewDecoder(strings.NewReader(string(data)))
+	decoder.UseNumber()
+	var encoded []cursorValue
+	if err := decoder.Decode(&encoded); err != nil || len(encoded) != columns {
+		return nil, ErrInvalidCursor
+	}
+
+	values := make([]interface{}, len(encoded))
+	for i, value := range encoded {
+		switch v := value.Value.(type) {
+		case json.Number:
+			if n, err := v.Int64(); err == nil {
+				values[i] = n
+			} else if f, err := v.Float64(); err == nil {
+				values[i] = f
+			} else {
+				return nil, ErrInvalidCursor
+			}
+		case string, bool:
+			values[i] = v
+		case nil:
+			if value.Time == nil {
+				return nil, ErrInvalidCursor
+			}
+			values[i] = *value.Time
+		default:
+			return nil, ErrInvalidCursor
+		}
+	}
+	return values, nil
+}
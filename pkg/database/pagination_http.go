@@ -0,0 +1,92 @@
+package database
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Paginated endpoints read these query parameters
+const (
+	PageParam    = "page"
+	PerPageParam = "per_page"
+	CursorParam  = "cursor"
+	LimitParam   = "limit"
+)
+
+// PageParams reads the page and per_page query parameters of a request. Missing or invalid
+// values fall back to page 1 and defaultPerPage, and per_page is capped at maxPerPage.
+func PageParams(r *http.Request, defaultPerPage, maxPerPage int) (page, perPage int) {
+	query := r.URL.Query()
+	page = positiveParam(query.Get(PageParam), 1)
+	perPage = positiveParam(query.Get(PerPageParam), defaultPerPage)
+	if maxPerPage > 0 && perPage > maxPerPage {
+		perPage = maxPerPage
+	}
+	return page, perPage
+}
+
+// CursorParams reads the cursor and limit query parameters of a request. A missing or invalid
+// limit falls back to defaultLimit, and limit is capped at maxLimit.
+func CursorParams(r *http.Request, defaultLimit, maxLimit int) (cursor string, limit int) {
+	query := r.URL.Query()
+	limit = positiveParam(query.Get(LimitParam), defaultLimit)
+	if maxLimit > 0 && limit > maxLimit {
+		limit = maxLimit
+	}
+	return query.Get(CursorParam), limit
+}
+
+// SetLinkHeader sets the Link header of a response to the first, prev, next and last pages,
+// as links to the request's URL with the page parameters replaced
+func (p *Pagination) SetLinkHeader(w http.ResponseWriter, r *http.Request) {
+	link := func(page int, rel string) string {
+		return pageLink(r, rel, map[string]string{
+			PageParam:    strconv.Itoa(page),
+			PerPageParam: strconv.Itoa(p.PerPage),
+		})
+	}
+
+	links := []string{link(1, "first")}
+	if p.HasPrev() {
+		links = append(links, link(p.PrevPage(), "prev"))
+	}
+	if p.HasNext() {
+		links = append(links, link(p.NextPage(), "next"))
+	}
+	links = append(links, link(p.Pages, "last"))
+	w.Header().Set("Link", strings.Join(links, ", "))
+}
+
+// SetLinkHeader sets the Link header of a response to the next page, if there is one
+func (p *CursorPage) SetLinkHeader(w http.ResponseWriter, r *http.Request) {
+	if !p.HasNext() {
+		w.Header().Del("Link")
+		return
+	}
+	w.Header().Set("Link", pageLink(r, "next", map[string]string{
+		CursorParam: p.NextCursor,
+		LimitParam:  strconv.Itoa(p.Limit),
+	}))
+}
+
+// pageLink formats a link to the request's URL with the given query parameters replaced
+func pageLink(r *http.Request, rel string, params map[string]string) string {
+	u := *r.URL
+	query := u.Query()
+	for name, value := range params {
+		query.Set(name, value)
+	}
+	u.RawQuery = query.Encode()
+	return fmt.Sprintf("<%s>; rel=%q", u.RequestURI(), rel)
+}
+
+// positiveParam parses a positive integer parameter, or returns fallback
+func positiveParam(value string, fallback int) int {
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 1 {
+		return fallback
+	}
+	return n
+}
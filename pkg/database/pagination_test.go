@@ -0,0 +1,159 @@
+package database
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newPagedDB returns a database with notes 1 to 7, where notes 2 to 4 share a creation time
+func newPagedDB(t *testing.T) *DB {
+	t.Helper()
+	db := newRepoDB(t)
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	created := []time.Time{base, base.Add(time.Hour), base.Add(time.Hour), base.Add(time.Hour), base.Add(2 * time.Hour), base.Add(3 * time.Hour), base.Add(4 * time.Hour)}
+	for i, at := range created {
+		_, err := db.Exec("INSERT INTO notes (slug, body, created_at, updated_at) VALUES (?, ?, ?, ?)", string(rune('a'+i)), "body", at, at)
+		require.NoError(t, err)
+	}
+	return db
+}
+
+func TestQuery_Paginate(t *testing.T) {
+	db := newPagedDB(t)
+
+	tests := []struct {
+		page, perPage int
+		want          Pagination
+		slugs         []string
+	}{
+		{1, 3, Pagination{Page: 1, PerPage: 3, Total: 6, Pages: 2}, []string{"b", "c", "d"}},
+		{2, 3, Pagination{Page: 2, PerPage: 3, Total: 6, Pages: 2}, []string{"e", "f", "g"}},
+		{3, 3, Pagination{Page: 3, PerPage: 3, Total: 6, Pages: 2}, []string{}},
+		{0, 0, Pagination{Page: 1, PerPage: 1, Total: 6, Pages: 6}, []string{"b"}},
+	}
+	for _, tt := range tests {
+		q := NewQuery(db).Table("notes").Where("id", ">", 1).OrderBy("id").Limit(1)
+		notes := []repoNote{}
+		pagination, err := q.Paginate(tt.page, tt.perPage, &notes)
+		require.NoError(t, err)
+		assert.Equal(t, tt.want, *pagination)
+
+		slugs := []string{}
+		for _, note := range notes {
+			slugs = append(slugs, note.Slug)
+		}
+		assert.Equal(t, tt.slugs, slugs)
+
+		query, _ := q.ToSQL()
+		assert.Contains(t, query, "LIMIT 1", "the query keeps its own paging")
+	}
+
+	empty, err := NewQuery(db).Table("notes").Where("id", ">", 100).Paginate(1, 10, &[]repoNote{})
+	require.NoError(t, err)
+	assert.Equal(t, Pagination{Page: 1, PerPage: 10, Total: 0, Pages: 1}, *empty)
+	assert.False(t, empty.HasPrev())
+	assert.False(t, empty.HasNext())
+
+	middle := Pagination{Page: 2, PerPage: 10, Total: 25, Pages: 3}
+	assert.Equal(t, 1, middle.PrevPage())
+	assert.Equal(t, 3, middle.NextPage())
+	last := Pagination{Page: 3, PerPage: 10, Total: 25, Pages: 3}
+	assert.Equal(t, 0, last.NextPage())
+}
+
+func TestRepository_CursorPaginate(t *testing.T) {
+	notes := NewRepository[*repoNote](newPagedDB(t))
+
+	// Walk the pages, newest first, with ties on created_at broken by id
+	var slugs []string
+	var cursor string
+	pages := 0
+	for {
+		page, info, err := notes.Query().OrderBy("created_at", "desc").OrderBy("notes.id", "desc").CursorPaginate(cursor, 2)
+		require.NoError(t, err)
+		pages++
+		for _, note := range page {
+			slugs = append(slugs, note.Slug)
+		}
+		if !info.HasNext() {
+			break
+		}
+		cursor = info.NextCursor
+	}
+	assert.Equal(t, []string{"g", "f", "e", "d", "c", "b", "a"}, slugs)
+	assert.Equal(t, 4, pages)
+
+	// A full last page has no next cursor
+	page, info, err := notes.Query().OrderBy("id").CursorPaginate("", 7)
+	require.NoError(t, err)
+	assert.Len(t, page, 7)
+	assert.False(t, info.HasNext())
+
+	// The cursor applies to all of the query's OR conditions
+	q := NewQuery(notes.db).Table("notes").Where("slug", "a").OrWhere("slug", "c").OrWhere("slug", "e").OrderBy("id")
+	var first, second []repoNote
+	next, err := q.CursorPaginate("", 2, &first)
+	require.NoError(t, err)
+	_, err = q.CursorPaginate(next.NextCursor, 2, &second)
+	require.NoError(t, err)
+	require.Len(t, second, 1)
+	assert.Equal(t, "e", second[0].Slug)
+
+	_, _, err = notes.Query().OrderBy("id").CursorPaginate("not a cursor", 2)
+	assert.ErrorIs(t, err, ErrInvalidCursor)
+	_, _, err = notes.Query().OrderBy("created_at").OrderBy("id").CursorPaginate(cursor, 2)
+	assert.NoError(t, err)
+	_, _, err = notes.Query().OrderBy("id").CursorPaginate(cursor, 2)
+	assert.ErrorIs(t, err, ErrInvalidCursor, "the cursor holds two columns")
+	_, _, err = notes.Query().CursorPaginate("", 2)
+	assert.ErrorContains(t, err, "ordered")
+}
+
+func TestPageParams(t *testing.T) {
+	tests := []struct {
+		url           string
+		page, perPage int
+	}{
+		{"/users", 1, 20},
+		{"/users?page=3&per_page=50", 3, 50},
+		{"/users?page=-1&per_page=abc", 1, 20},
+		{"/users?per_page=500", 1, 100},
+	}
+	for _, tt := range tests {
+		page, perPage := PageParams(httptest.NewRequest("GET", tt.url, nil), 20, 100)
+		assert.Equal(t, tt.page, page, tt.url)
+		assert.Equal(t, tt.perPage, perPage, tt.url)
+	}
+
+	cursor, limit := CursorParams(httptest.NewRequest("GET", "/events?cursor=abc&limit=1000", nil), 50, 200)
+	assert.Equal(t, "abc", cursor)
+	assert.Equal(t, 200, limit)
+}
+
+func TestSetLinkHeader(t *testing.T) {
+	r := httptest.NewRequest("GET", "/admin/users?q=ann&page=2", nil)
+
+	w := httptest.NewRecorder()
+	(&Pagination{Page: 2, PerPage: 10, Total: 25, Pages: 3}).SetLinkHeader(w, r)
+	assert.Equal(t, `</admin/users?page=1&per_page=10&q=ann>; rel="first", `+
+		`</admin/users?page=1&per_page=10&q=ann>; rel="prev", `+
+		`</admin/users?page=3&per_page=10&q=ann>; rel="next", `+
+		`</admin/users?page=3&per_page=10&q=ann>; rel="last"`, w.Header().Get("Link"))
+
+	w = httptest.NewRecorder()
+	(&Pagination{Page: 1, PerPage: 10, Total: 5, Pages: 1}).SetLinkHeader(w, r)
+	assert.Equal(t, `</admin/users?page=1&per_page=10&q=ann>; rel="first", `+
+		`</admin/users?page=1&per_page=10&q=ann>; rel="last"`, w.Header().Get("Link"))
+
+	w = httptest.NewRecorder()
+	(&CursorPage{Limit: 50, NextCursor: "eyJ9"}).SetLinkHeader(w, r)
+	assert.Equal(t, `</admin/users?cursor=eyJ9&limit=50&page=2&q=ann>; rel="next"`, w.Header().Get("Link"))
+
+	w = httptest.NewRecorder()
+	(&CursorPage{Limit: 50}).SetLinkHeader(w, r)
+	assert.Empty(t, w.Header().Get("Link"))
+}
@@ -1,22 +1,38 @@
 package database
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"reflect"
+	"sort"
 	"strings"
 )
 
-// Query represents a database query builder
+// Query represents a database query builder.
+//
+// Table and column names must be plain or qualified identifiers, such as email or users.email;
+// selects, tables and joins may also be aliased, as in users AS u. Anything else, such as a
+// function call, makes the query fail when it runs, as user input passed as a column name must
+// not reach the SQL. Expressions are written with Raw and the *Raw methods, and columns named in
+// user input are looked up in a Columns allowlist.
 type Query struct {
 	db       *DB
 	table    string
-	selects  []string
+	distinct bool
+	selects  []interface{} // Column names and Exprs
 	wheres   []whereClause
+	scopes   []whereClause // Conditions every result must meet, whatever the OR conditions in wheres
 	joins    []joinClause
 	orderBys []orderByClause
 	groupBys []string
+	havings  []whereClause
+	unions   []unionClause
 	limit    int
 	offset   int
+	err      error           // The first invalid identifier or operator
+	ctx      context.Context // Set by WithContext
 }
 
 type whereClause struct {
@@ -27,7 +43,7 @@ type whereClause struct {
 }
 
 type joinClause struct {
-	joinType string // INNER, LEFT, RIGHT
+	joinType string // INNER, LEFT, RIGHT, CROSS
 	table    string
 	first    string
 	operator string
@@ -37,13 +53,36 @@ type joinClause struct {
 type orderByClause struct {
 	field     string
 	direction string // ASC or DESC
+	raw       *Expr
+}
+
+type unionClause struct {
+	query *Query
+	all   bool
+}
+
+// Expr is a raw SQL expression with ? placeholders, written into a query as is
+type Expr struct {
+	SQL  string
+	Args []interface{}
+}
+
+// Raw returns a raw SQL expression. Never build its SQL from user input; pass values as args.
+func Raw(sql string, args ...interface{}) Expr {
+	return Expr{SQL: sql, Args: args}
+}
+
+// Operators that Where and Having accept
+var comparisonOperators = map[string]bool{
+	"=": true, "!=": true, "<>": true, "<": true, "<=": true, ">": true, ">=": true,
+	"LIKE": true, "NOT LIKE": true, "ILIKE": true, "NOT ILIKE": true,
 }
 
 // NewQuery creates a new query builder
 func NewQuery(db *DB) *Query {
 	return &Query{
 		db:       db,
-		selects:  []string{"*"},
+		selects:  []interface{}{"*"},
 		wheres:   []whereClause{},
 		joins:    []joinClause{},
 		orderBys: []orderByClause{},
@@ -51,78 +90,158 @@ func NewQuery(db *DB) *Query {
 	}
 }
 
+// Err returns the first invalid identifier or operator given to the query, which is
+// also returned when the query runs
+func (q *Query) Err() error {
+	return q.err
+}
+
+// WithContext sets the context the query runs with, which may carry a transaction
+// started by DB.Transaction. Queries run with context.Background otherwise.
+func (q *Query) WithContext(ctx context.Context) *Query {
+	q.ctx = ctx
+	return q
+}
+
 // Table sets the table for the query
 func (q *Query) Table(table string) *Query {
+	q.checkIdentifier(table, true)
 	q.table = table
 	return q
 }
 
 // Select sets the columns to select
 func (q *Query) Select(columns ...string) *Query {
-	q.selects = columns
+	q.selects = make([]interface{}, len(columns))
+	for i, column := range columns {
+		q.checkIdentifier(column, true)
+		q.selects[i] = column
+	}
 	return q
 }
 
-// Where adds a where condition
-func (q *Query) Where(field string, operatorOrValue interface{}, value ...interface{}) *Query {
-	var op string
-	var val interface{}
-	
-	if len(value) > 0 {
-		op = operatorOrValue.(string)
-		val = value[0]
-	} else {
-		op = "="
-		val = operatorOrValue
+// SelectRaw adds a raw expression to the selected columns, such as COUNT(*) AS total
+func (q *Query) SelectRaw(expr string, args ...interface{}) *Query {
+	if len(q.selects) == 1 && q.selects[0] == "*" {
+		q.selects = nil
 	}
+	q.selects = append(q.selects, Raw(expr, args...))
+	return q
+}
+
+// Distinct selects only distinct rows
+func (q *Query) Distinct() *Query {
+	q.distinct = true
+	return q
+}
+
+// Where adds a where condition. The value may be an Expr.
+func (q *Query) Where(field string, operatorOrValue interface{}, value ...interface{}) *Query {
+	q.wheres = q.addCondition(q.wheres, "AND", field, operatorOrValue, value)
+	return q
+}
+
+// OrWhere adds an OR where condition
+func (q *Query) OrWhere(field string, operatorOrValue interface{}, value ...interface{}) *Query {
+	q.wheres = q.addCondition(q.wheres, "OR", field, operatorOrValue, value)
+	return q
+}
+
+// WhereColumn adds a condition comparing two columns, such as in a correlated subquery
+func (q *Query) WhereColumn(first, operator, second string) *Query {
+	q.checkIdentifier(first, false)
+	q.checkIdentifier(second, false)
+	q.checkOperator(operator)
+	q.wheres = append(q.wheres, whereClause{
+		field:    first,
+		operator: "COLUMN " + strings.ToUpper(operator),
+		value:    second,
+		boolean:  "AND",
+	})
 	
+	return q
+}
+
+// WhereGroup adds a parenthesized group of conditions, built by fn, such as the
+// (b OR c) in a AND (b OR c)
+func (q *Query) WhereGroup(fn func(*Query)) *Query {
+	return q.addGroup("AND", fn)
+}
+
+// OrWhereGroup adds a parenthesized group of conditions, ORed with the others
+func (q *Query) OrWhereGroup(fn func(*Query)) *Query {
+	return q.addGroup("OR", fn)
+}
+
+// WhereRaw adds a raw condition
+func (q *Query) WhereRaw(sql string, args ...interface{}) *Query {
+	q.wheres = append(q.wheres, whereClause{operator: "RAW", value: Raw(sql, args...), boolean: "AND"})
+	return q
+}
+
+// OrWhereRaw adds a raw OR condition
+func (q *Query) OrWhereRaw(sql string, args ...interface{}) *Query {
+	q.wheres = append(q.wheres, whereClause{operator: "RAW", value: Raw(sql, args...), boolean: "OR"})
+	return q
+}
+
+// WhereIn adds a WHERE IN condition
+func (q *Query) WhereIn(field string, values []interface{}) *Query {
+	q.checkIdentifier(field, false)
 	q.wheres = append(q.wheres, whereClause{
 		field:    field,
-		operator: op,
-		value:    val,
+		operator: "IN",
+		value:    values,
 		boolean:  "AND",
 	})
 	
 	return q
 }
 
-// OrWhere adds an OR where condition
-func (q *Query) OrWhere(field string, operatorOrValue interface{}, value ...interface{}) *Query {
-	var op string
-	var val interface{}
-	
-	if len(value) > 0 {
-		op = operatorOrValue.(string)
-		val = value[0]
-	} else {
-		op = "="
-		val = operatorOrValue
-	}
-	
+// WhereNotIn adds a WHERE NOT IN condition
+func (q *Query) WhereNotIn(field string, values []interface{}) *Query {
+	q.checkIdentifier(field, false)
 	q.wheres = append(q.wheres, whereClause{
 		field:    field,
-		operator: op,
-		value:    val,
-		boolean:  "OR",
+		operator: "NOT IN",
+		value:    values,
+		boolean:  "AND",
 	})
 	
 	return q
 }
 
-// WhereIn adds a WHERE IN condition
-func (q *Query) WhereIn(field string, values []interface{}) *Query {
+// WhereInQuery adds a condition that a column's value is among a subquery's results
+func (q *Query) WhereInQuery(field string, sub *Query) *Query {
+	q.checkIdentifier(field, false)
+	q.checkSubquery(sub)
 	q.wheres = append(q.wheres, whereClause{
 		field:    field,
-		operator: "IN",
-		value:    values,
+		operator: "IN QUERY",
+		value:    sub,
 		boolean:  "AND",
 	})
 	
 	return q
 }
 
+// WhereExists adds a condition that a subquery returns rows
+func (q *Query) WhereExists(sub *Query) *Query {
+	q.checkSubquery(sub)
+	q.wheres = append(q.wheres, whereClause{operator: "EXISTS", value: sub, boolean: "AND"})
+	return q
+}
+
+// WhereNotExists adds a condition that a subquery returns no rows
+func (q *Query) WhereNotExists(sub *Query) *Query {
+	q.checkSubquery(sub)
+	q.wheres = append(q.wheres, whereClause{operator: "NOT EXISTS", value: sub, boolean: "AND"})
+	return q
+}
+
 // WhereNull adds a WHERE IS NULL condition
 func (q *Query) WhereNull(field string) *Query {
+	q.checkIdentifier(field, false)
 	q.wheres = append(q.wheres, whereClause{
 		field:    field,
 		operator: "IS NULL",
@@ -135,6 +254,7 @@ func (q *Query) WhereNull(field string) *Query {
 
 // WhereNotNull adds a WHERE IS NOT NULL condition
 func (q *Query) WhereNotNull(field string) *Query {
+	q.checkIdentifier(field, false)
 	q.wheres = append(q.wheres, whereClause{
 		field:    field,
 		operator: "IS NOT NULL",
@@ -147,36 +267,36 @@ func (q *Query) WhereNotNull(field string) *Query {
 
 // Join adds an inner join
 func (q *Query) Join(table, first, operator, second string) *Query {
-	q.joins = append(q.joins, joinClause{
-		joinType: "INNER",
-		table:    table,
-		first:    first,
-		operator: operator,
-		second:   second,
-	})
-	
-	return q
+	return q.addJoin("INNER", table, first, operator, second)
 }
 
 // LeftJoin adds a left join
 func (q *Query) LeftJoin(table, first, operator, second string) *Query {
-	q.joins = append(q.joins, joinClause{
-		joinType: "LEFT",
-		table:    table,
-		first:    first,
-		operator: operator,
-		second:   second,
-	})
-	
+	return q.addJoin("LEFT", table, first, operator, second)
+}
+
+// RightJoin adds a right join
+func (q *Query) RightJoin(table, first, operator, second string) *Query {
+	return q.addJoin("RIGHT", table, first, operator, second)
+}
+
+// CrossJoin adds a cross join
+func (q *Query) CrossJoin(table string) *Query {
+	q.checkIdentifier(table, true)
+	q.joins = append(q.joins, joinClause{joinType: "CROSS", table: table})
 	return q
 }
 
-// OrderBy adds an order by clause
+// OrderBy adds an order by clause. The direction is asc or desc.
 func (q *Query) OrderBy(field string, direction ...string) *Query {
+	q.checkIdentifier(field, false)
 	dir := "ASC"
 	if len(direction) > 0 {
 		dir = strings.ToUpper(direction[0])
 	}
+	if dir != "ASC" && dir != "DESC" {
+		q.setErr(fmt.Errorf("invalid sort direction %q", direction[0]))
+	}
 	
 	q.orderBys = append(q.orderBys, orderByClause{
 		field:     field,
@@ -186,12 +306,59 @@ func (q *Query) OrderBy(field string, direction ...string) *Query {
 	return q
 }
 
+// OrderByRaw adds a raw order by expression
+func (q *Query) OrderByRaw(expr string, args ...interface{}) *Query {
+	raw := Raw(expr, args...)
+	q.orderBys = append(q.orderBys, orderByClause{raw: &raw})
+	return q
+}
+
+// SortBy orders by a column named in user input, such as a sort query parameter, if the
+// allowlist has it, and ignores it otherwise. The direction is desc for descending order,
+// and ascending otherwise.
+func (q *Query) SortBy(input, direction string, allowed Columns) *Query {
+	column, ok := allowed.Lookup(input)
+	if !ok {
+		return q
+	}
+	if strings.EqualFold(direction, "desc") {
+		return q.OrderBy(column, "DESC")
+	}
+	return q.OrderBy(column)
+}
+
 // GroupBy adds a group by clause
 func (q *Query) GroupBy(fields ...string) *Query {
+	for _, field := range fields {
+		q.checkIdentifier(field, false)
+	}
 	q.groupBys = append(q.groupBys, fields...)
 	return q
 }
 
+// Having adds a having condition on a grouped column
+func (q *Query) Having(field string, operatorOrValue interface{}, value ...interface{}) *Query {
+	q.havings = q.addCondition(q.havings, "AND", field, operatorOrValue, value)
+	return q
+}
+
+// HavingRaw adds a raw having condition, such as COUNT(*) > ?
+func (q *Query) HavingRaw(sql string, args ...interface{}) *Query {
+	q.havings = append(q.havings, whereClause{operator: "RAW", value: Raw(sql, args...), boolean: "AND"})
+	return q
+}
+
+// Union appends another query's distinct results. The other query must select the same
+// number of columns and have no order, limit or offset; this query's apply to the union.
+func (q *Query) Union(other *Query) *Query {
+	return q.addUnion(other, false)
+}
+
+// UnionAll appends all of another query's results, as Union
+func (q *Query) UnionAll(other *Query) *Query {
+	return q.addUnion(other, true)
+}
+
 // Limit sets the limit
 func (q *Query) Limit(limit int) *Query {
 	q.limit = limit
@@ -205,31 +372,82 @@ func (q *Query) Offset(offset int) *Query {
 }
 
 // Get executes the query and returns all results
-func (q *Query) Get() (*sql.Rows, error) {
+func (q *Query) Get() (*Rows, error) {
+	if q.err != nil {
+		return nil, q.err
+	}
 	query, args := q.toSQL()
-	return q.db.Query(query, args...)
+	return q.db.QueryContext(q.context(), query, args...)
 }
 
-// First executes the query and returns the first result
-func (q *Query) First() (*sql.Row, error) {
+// First scans the first result into dest, a pointer to a struct or scalar.
+// It returns sql.ErrNoRows if nothing matches.
+func (q *Query) First(dest interface{}) error {
 	q.limit = 1
-	query, args := q.toSQL()
-	return q.db.QueryRow(query, args...), nil
+	rows, err := q.Get()
+	if err != nil {
+		return err
+	}
+	return ScanOne(rows, dest)
 }
 
-// Count returns the count of matching records
-func (q *Query) Count() (int64, error) {
+// Scan scans the results into dest: all rows for a pointer to a slice, or the first row otherwise
+func (q *Query) Scan(dest interface{}) error {
+	if v := reflect.ValueOf(dest); v.Kind() == reflect.Pointer && v.Elem().Kind() == reflect.Slice {
+		rows, err := q.Get()
+		if err != nil {
+			return err
+		}
+		return ScanAll(rows, dest)
+	}
+	return q.First(dest)
+}
+
+// Pluck scans a single column of the results into dest, a pointer to a slice
+func (q *Query) Pluck(column string, dest interface{}) error {
+	q.checkIdentifier(column, true)
+	
 	// Save current selects
 	oldSelects := q.selects
-	q.selects = []string{"COUNT(*) as count"}
+	q.selects = []interface{}{column}
 	
-	query, args := q.toSQL()
+	rows, err := q.Get()
 	
 	// Restore selects
 	q.selects = oldSelects
 	
+	if err != nil {
+		return err
+	}
+	return ScanAll(rows, dest)
+}
+
+// Count returns the count of matching records, ignoring any order, limit and offset
+func (q *Query) Count() (int64, error) {
+	if q.err != nil {
+		return 0, q.err
+	}
+	
+	// Save current selects and paging
+	oldSelects, oldOrderBys, oldLimit, oldOffset := q.selects, q.orderBys, q.limit, q.offset
+	q.orderBys, q.limit, q.offset = nil, 0, 0
+	
+	var query string
+	var args []interface{}
+	if q.distinct || len(q.groupBys) > 0 || len(q.unions) > 0 {
+		// Count the rows the query returns, rather than the rows it reads
+		query, args = q.toSQL()
+		query = fmt.Sprintf("SELECT COUNT(*) FROM (%s) %s", query, q.dialect().QuoteIdent("counted"))
+	} else {
+		q.selects = []interface{}{Raw("COUNT(*) as count")}
+		query, args = q.toSQL()
+	}
+	
+	// Restore selects and paging
+	q.selects, q.orderBys, q.limit, q.offset = oldSelects, oldOrderBys, oldLimit, oldOffset
+	
 	var count int64
-	err := q.db.QueryRow(query, args...).Scan(&count)
+	err := q.db.QueryRowContext(q.context(), query, args...).Scan(&count)
 	return count, err
 }
 
@@ -241,19 +459,54 @@ func (q *Query) Exists() (bool, error) {
 
 // Update updates records
 func (q *Query) Update(updates map[string]interface{}) (sql.Result, error) {
+	query, args, err := q.updateSQL(updates)
+	if err != nil {
+		return nil, err
+	}
+	return q.db.ExecContext(q.context(), query, args...)
+}
+
+// Delete deletes records
+func (q *Query) Delete() (sql.Result, error) {
+	query, args, err := q.deleteSQL()
+	if err != nil {
+		return nil, err
+	}
+	return q.db.ExecContext(q.context(), query, args...)
+}
+
+// ToSQL returns the SELECT statement and its arguments, with identifiers quoted
+// and placeholders written for the database's dialect
+func (q *Query) ToSQL() (string, []interface{}) {
+	query, args := q.toSQL()
+	return Rebind(q.dialect(), query), args
+}
+
+// updateSQL builds the UPDATE statement, setting columns in name order
+func (q *Query) updateSQL(updates map[string]interface{}) (string, []interface{}, error) {
 	if len(updates) == 0 {
-		return nil, fmt.Errorf("no updates provided")
+		return "", nil, fmt.Errorf("no updates provided")
+	}
+	
+	fields := make([]string, 0, len(updates))
+	for field := range updates {
+		q.checkIdentifier(field, false)
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	if q.err != nil {
+		return "", nil, q.err
 	}
 	
 	var setClauses []string
 	var args []interface{}
 	
-	for field, value := range updates {
-		setClauses = append(setClauses, fmt.Sprintf("%s = ?", field))
-		args = append(args, value)
+	for _, field := range fields {
+		setClauses = append(setClauses, fmt.Sprintf("%s = ?", q.quote(field)))
+		args = append(args, updates[field])
 	}
 	
-	query := fmt.Sprintf("UPDATE %s SET %s", q.table, strings.Join(setClauses, ", "))
+	query := fmt.Sprintf("UPDATE %s SET %s", q.quote(q.table), strings.Join(setClauses, ", "))
 	
 	// Add where clauses
 	whereSQL, whereArgs := q.buildWhere()
@@ -262,12 +515,15 @@ func (q *Query) Update(updates map[string]interface{}) (sql.Result, error) {
 		args = append(args, whereArgs...)
 	}
 	
-	return q.db.Exec(query, args...)
+	return query, args, nil
 }
 
-// Delete deletes records
-func (q *Query) Delete() (sql.Result, error) {
-	query := fmt.Sprintf("DELETE FROM %s", q.table)
+// deleteSQL builds the DELETE statement
+func (q *Query) deleteSQL() (string, []interface{}, error) {
+	if q.err != nil {
+		return "", nil, q.err
+	}
+	query := fmt.Sprintf("DELETE FROM %s", q.quote(q.table))
 	
 	// Add where clauses
 	whereSQL, args := q.buildWhere()
@@ -275,39 +531,151 @@ func (q *Query) Delete() (sql.Result, error) {
 		query += " WHERE " + whereSQL
 	}
 	
-	return q.db.Exec(query, args...)
+	return query, args, nil
 }
 
-// toSQL builds the SQL query
-func (q *Query) toSQL() (string, []interface{}) {
-	var args []interface{}
-	
-	// SELECT
-	query := fmt.Sprintf("SELECT %s FROM %s", strings.Join(q.selects, ", "), q.table)
+// context returns the context the query runs with
+func (q *Query) context() context.Context {
+	if q.ctx == nil {
+		return context.Background()
+	}
+	return q.ctx
+}
+
+// dialect returns the dialect queries are built for
+func (q *Query) dialect() Dialect {
+	if q.db == nil {
+		return DialectFor("")
+	}
+	return q.db.Dialect()
+}
+
+// quote quotes a plain or qualified identifier, with its alias if it has one
+func (q *Query) quote(name string) string {
+	if column, alias, ok := splitAlias(name); ok {
+		return QuoteIdentifier(q.dialect(), column) + " AS " + q.dialect().QuoteIdent(alias)
+	}
+	return QuoteIdentifier(q.dialect(), name)
+}
+
+// setErr records the query's first error
+func (q *Query) setErr(err error) {
+	if q.err == nil {
+		q.err = err
+	}
+}
+
+// checkIdentifier records an error if name isn't a valid identifier
+func (q *Query) checkIdentifier(name string, allowAlias bool) {
+	if !ValidIdentifier(name, allowAlias) {
+		q.setErr(fmt.Errorf("invalid identifier %q; use Raw for expressions", name))
+	}
+}
+
+// checkOperator records an error if op isn't a comparison operator
+func (q *Query) checkOperator(op string) {
+	if !comparisonOperators[strings.ToUpper(op)] {
+		q.setErr(fmt.Errorf("invalid operator %q", op))
+	}
+}
+
+// checkSubquery records a subquery's error as the query's own
+func (q *Query) checkSubquery(sub *Query) {
+	if sub == nil {
+		q.setErr(errors.New("subquery is nil"))
+		return
+	}
+	if sub.err != nil {
+		q.setErr(sub.err)
+	}
+}
+
+// addCondition appends a field comparison to a list of conditions
+func (q *Query) addCondition(conditions []whereClause, boolean, field string, operatorOrValue interface{}, value []interface{}) []whereClause {
+	var op string
+	var val interface{}
 	
-	// JOIN
-	for _, join := range q.joins {
-		query += fmt.Sprintf(" %s JOIN %s ON %s %s %s",
-			join.joinType, join.table, join.first, join.operator, join.second)
+	if len(value) > 0 {
+		op, _ = operatorOrValue.(string)
+		val = value[0]
+		q.checkOperator(op)
+	} else {
+		op = "="
+		val = operatorOrValue
 	}
+	q.checkIdentifier(field, false)
 	
-	// WHERE
-	whereSQL, whereArgs := q.buildWhere()
-	if whereSQL != "" {
-		query += " WHERE " + whereSQL
-		args = append(args, whereArgs...)
+	return append(conditions, whereClause{
+		field:    field,
+		operator: strings.ToUpper(op),
+		value:    val,
+		boolean:  boolean,
+	})
+}
+
+// addGroup appends a group of conditions built by fn on a nested query
+func (q *Query) addGroup(boolean string, fn func(*Query)) *Query {
+	group := &Query{db: q.db}
+	fn(group)
+	if group.err != nil {
+		q.setErr(group.err)
 	}
+	q.wheres = append(q.wheres, whereClause{operator: "GROUP", value: group, boolean: boolean})
+	return q
+}
+
+// addJoin appends a join on two columns
+func (q *Query) addJoin(joinType, table, first, operator, second string) *Query {
+	q.checkIdentifier(table, true)
+	q.checkIdentifier(first, false)
+	q.checkIdentifier(second, false)
+	q.checkOperator(operator)
+	q.joins = append(q.joins, joinClause{
+		joinType: joinType,
+		table:    table,
+		first:    first,
+		operator: strings.ToUpper(operator),
+		second:   second,
+	})
 	
-	// GROUP BY
-	if len(q.groupBys) > 0 {
-		query += " GROUP BY " + strings.Join(q.groupBys, ", ")
+	return q
+}
+
+// addUnion appends a query to union with
+func (q *Query) addUnion(other *Query, all bool) *Query {
+	q.checkSubquery(other)
+	if other != nil && (len(other.orderBys) > 0 || other.limit > 0 || other.offset > 0) {
+		q.setErr(errors.New("a unioned query can't have its own order, limit or offset"))
+	}
+	q.unions = append(q.unions, unionClause{query: other, all: all})
+	return q
+}
+
+// toSQL builds the SQL query with ? placeholders
+func (q *Query) toSQL() (string, []interface{}) {
+	query, args := q.selectSQL()
+	
+	// UNION
+	for _, union := range q.unions {
+		unionSQL, unionArgs := union.query.selectSQL()
+		if union.all {
+			query += " UNION ALL " + unionSQL
+		} else {
+			query += " UNION " + unionSQL
+		}
+		args = append(args, unionArgs...)
 	}
 	
 	// ORDER BY
 	if len(q.orderBys) > 0 {
 		var orderClauses []string
 		for _, order := range q.orderBys {
-			orderClauses = append(orderClauses, fmt.Sprintf("%s %s", order.field, order.direction))
+			if order.raw != nil {
+				orderClauses = append(orderClauses, order.raw.SQL)
+				args = append(args, order.raw.Args...)
+				continue
+			}
+			orderClauses = append(orderClauses, fmt.Sprintf("%s %s", q.quote(order.field), order.direction))
 		}
 		query += " ORDER BY " + strings.Join(orderClauses, ", ")
 	}
@@ -325,43 +693,174 @@ func (q *Query) toSQL() (string, []interface{}) {
 	return query, args
 }
 
-// buildWhere builds the WHERE clause
+// selectSQL builds the SELECT statement up to its HAVING clause
+func (q *Query) selectSQL() (string, []interface{}) {
+	var args []interface{}
+	
+	// SELECT
+	selects := make([]string, len(q.selects))
+	for i, column := range q.selects {
+		switch column := column.(type) {
+		case Expr:
+			selects[i] = column.SQL
+			args = append(args, column.Args...)
+		case string:
+			selects[i] = q.quote(column)
+		}
+	}
+	query := "SELECT "
+	if q.distinct {
+		query += "DISTINCT "
+	}
+	query += fmt.Sprintf("%s FROM %s", strings.Join(selects, ", "), q.quote(q.table))
+	
+	// JOIN
+	for _, join := range q.joins {
+		if join.joinType == "CROSS" {
+			query += " CROSS JOIN " + q.quote(join.table)
+			continue
+		}
+		query += fmt.Sprintf(" %s JOIN %s ON %s %s %s",
+			join.joinType, q.quote(join.table), q.quote(join.first), join.operator, q.quote(join.second))
+	}
+	
+	// WHERE
+	whereSQL, whereArgs := q.buildWhere()
+	if whereSQL != "" {
+		query += " WHERE " + whereSQL
+		args = append(args, whereArgs...)
+	}
+	
+	// GROUP BY
+	if len(q.groupBys) > 0 {
+		groupBys := make([]string, len(q.groupBys))
+		for i, field := range q.groupBys {
+			groupBys[i] = q.quote(field)
+		}
+		query += " GROUP BY " + strings.Join(groupBys, ", ")
+	}
+	
+	// HAVING
+	if havingSQL, havingArgs := q.buildConditions(q.havings); havingSQL != "" {
+		query += " HAVING " + havingSQL
+		args = append(args, havingArgs...)
+	}
+	
+	return query, args
+}
+
+// buildWhere builds the WHERE clause. Scope conditions are ANDed with the other
+// conditions, which are grouped so their OR conditions can't bypass the scopes.
 func (q *Query) buildWhere() (string, []interface{}) {
-	if len(q.wheres) == 0 {
+	whereSQL, args := q.buildConditions(q.wheres)
+	if len(q.scopes) == 0 {
+		return whereSQL, args
+	}
+	
+	scopeSQL, scopeArgs := q.buildConditions(q.scopes)
+	if whereSQL == "" {
+		return scopeSQL, scopeArgs
+	}
+	return scopeSQL + " AND (" + whereSQL + ")", append(scopeArgs, args...)
+}
+
+// buildConditions joins where clauses with their boolean operators
+func (q *Query) buildConditions(wheres []whereClause) (string, []interface{}) {
+	if len(wheres) == 0 {
 		return "", nil
 	}
 	
 	var clauses []string
 	var args []interface{}
 	
-	for i, where := range q.wheres {
+	for _, where := range wheres {
 		clause := ""
 		
-		// Add boolean operator for subsequent clauses
-		if i > 0 {
-			clause += where.boolean + " "
-		}
-		
-		switch where.operator {
-		case "IN":
+		switch {
+		case where.operator == "IN", where.operator == "NOT IN":
 			values := where.value.([]interface{})
+			if len(values) == 0 {
+				// IN () is a syntax error; no value is in an empty list
+				if where.operator == "IN" {
+					clause += "1 = 0"
+				} else {
+					clause += "1 = 1"
+				}
+				break
+			}
 			placeholders := make([]string, len(values))
 			for j := range placeholders {
 				placeholders[j] = "?"
 				args = append(args, values[j])
 			}
-			clause += fmt.Sprintf("%s IN (%s)", where.field, strings.Join(placeholders, ", "))
-			
-		case "IS NULL", "IS NOT NULL":
-			clause += fmt.Sprintf("%s %s", where.field, where.operator)
-			
+			clause += fmt.Sprintf("%s %s (%s)", q.quote(where.field), where.operator, strings.Join(placeholders, ", "))
+		
+		case where.operator == "IS NULL", where.operator == "IS NOT NULL":
+			clause += fmt.Sprintf("%s %s", q.quote(where.field), where.operator)
+		
+		case where.operator == "RAW":
+			// Parenthesized, so an OR inside it can't escape the other conditions
+			raw := where.value.(Expr)
+			clause += "(" + raw.SQL + ")"
+			args = append(args, raw.Args...)
+		
+		case where.operator == "GROUP":
+			group := where.value.(*Query)
+			groupSQL, groupArgs := q.buildConditions(group.wheres)
+			if groupSQL == "" {
+				continue
+			}
+			clause += "(" + groupSQL + ")"
+			args = append(args, groupArgs...)
+		
+		case where.operator == "IN QUERY":
+			subSQL, subArgs := where.value.(*Query).toSQL()
+			clause += fmt.Sprintf("%s IN (%s)", q.quote(where.field), subSQL)
+			args = append(args, subArgs...)
+		
+		case where.operator == "EXISTS", where.operator == "NOT EXISTS":
+			subSQL, subArgs := where.value.(*Query).toSQL()
+			clause += fmt.Sprintf("%s (%s)", where.operator, subSQL)
+			args = append(args, subArgs...)
+		
+		case strings.HasPrefix(where.operator, "COLUMN "):
+			clause += fmt.Sprintf("%s %s %s", q.quote(where.field), strings.TrimPrefix(where.operator, "COLUMN "), q.quote(where.value.(string)))
+		
 		default:
-			clause += fmt.Sprintf("%s %s ?", where.field, where.operator)
-			args = append(args, where.value)
+			if raw, ok := where.value.(Expr); ok {
+				clause += fmt.Sprintf("%s %s %s", q.quote(where.field), where.operator, raw.SQL)
+				args = append(args, raw.Args...)
+			} else {
+				clause += fmt.Sprintf("%s %s ?", q.quote(where.field), where.operator)
+				args = append(args, where.value)
+			}
 		}
 		
+		// Add boolean operator for subsequent clauses
+		if len(clauses) > 0 {
+			clause = where.boolean + " " + clause
+		}
 		clauses = append(clauses, clause)
 	}
 	
 	return strings.Join(clauses, " "), args
-}
\ No newline at end of file
+}
+
+// Columns is an allowlist mapping the names user input may use, such as a sort parameter,
+// to the columns they stand for
+type Columns map[string]string
+
+// AllowColumns returns an allowlist of columns that user input names directly
+func AllowColumns(columns ...string) Columns {
+	allowed := make(Columns, len(columns))
+	for _, column := range columns {
+		allowed[column] = column
+	}
+	return allowed
+}
+
+// Lookup returns the column that user input names, and whether the allowlist has it
+func (c Columns) Lookup(input string) (string, bool) {
+	column, ok := c[input]
+	return column, ok
+}
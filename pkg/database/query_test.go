@@ -0,0 +1,222 @@
+package database
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQuery_Clauses(t *testing.T) {
+	tests := []struct {
+		name  string
+		query *Query
+		want  string
+		args  []interface{}
+	}{
+		{
+			name: "grouped conditions",
+			query: NewQuery(dialectDB("postgres")).Table("pages").
+				Where("status", "published").
+				WhereGroup(func(q *Query) {
+					q.Where("title", "LIKE", "%go%").OrWhere("excerpt", "like", "%go%")
+				}).
+				OrWhereGroup(func(q *Query) {
+					q.Where("menu_order", ">", 5).WhereGroup(func(q *Query) {
+						q.WhereNull("parent_id").OrWhere("parent_id", 1)
+					})
+				}).
+				WhereGroup(func(q *Query) {}),
+			want: `SELECT * FROM "pages" WHERE "status" = $1 AND ("title" LIKE $2 OR "excerpt" LIKE $3)` +
+				` OR ("menu_order" > $4 AND ("parent_id" IS NULL OR "parent_id" = $5))`,
+			args: []interface{}{"published", "%go%", "%go%", 5, 1},
+		},
+		{
+			name: "subqueries",
+			query: NewQuery(dialectDB("postgres")).Table("users").
+				Where("active", true).
+				WhereExists(NewQuery(dialectDB("postgres")).Table("media").Select("id").
+					WhereColumn("media.user_id", "=", "users.id").Where("mime_type", "image/png")).
+				WhereInQuery("role", NewQuery(dialectDB("postgres")).Table("roles").Select("name").Where("admin", true)),
+			want: `SELECT * FROM "users" WHERE "active" = $1` +
+				` AND EXISTS (SELECT "id" FROM "media" WHERE "media"."user_id" = "users"."id" AND "mime_type" = $2)` +
+				` AND "role" IN (SELECT "name" FROM "roles" WHERE "admin" = $3)`,
+			args: []interface{}{true, "image/png", true},
+		},
+		{
+			name: "in lists",
+			query: NewQuery(dialectDB("postgres")).Table("users").
+				WhereIn("role", []interface{}{"admin", "editor"}).
+				WhereNotIn("id", []interface{}{1}).
+				WhereNotIn("status", []interface{}{}).
+				OrWhere("active", true),
+			want: `SELECT * FROM "users" WHERE "role" IN ($1, $2) AND "id" NOT IN ($3) AND 1 = 1 OR "active" = $4`,
+			args: []interface{}{"admin", "editor", 1, true},
+		},
+		{
+			name: "having and raw expressions",
+			query: NewQuery(dialectDB("mysql")).Table("media").
+				Select("user_id").
+				SelectRaw("SUM(size) AS total").
+				Where("created_at", ">", Raw("NOW() - INTERVAL ? DAY", 7)).
+				WhereRaw("size > ? OR mime_type = ?", 10, "image/gif").
+				GroupBy("user_id").
+				Having("user_id", "!=", 0).
+				HavingRaw("SUM(size) > ?", 100).
+				OrderByRaw("SUM(size) DESC"),
+			want: "SELECT `user_id`, SUM(size) AS total FROM `media`" +
+				" WHERE `created_at` > NOW() - INTERVAL ? DAY AND (size > ? OR mime_type = ?)" +
+				" GROUP BY `user_id` HAVING `user_id` != ? AND (SUM(size) > ?) ORDER BY SUM(size) DESC",
+			args: []interface{}{7, 10, "image/gif", 0, 100},
+		},
+		{
+			name: "distinct, joins and aliases",
+			query: NewQuery(dialectDB("sqlite")).Table("pages AS p").
+				Distinct().
+				Select("p.title", "u.name AS author").
+				RightJoin("users AS u", "u.id", "=", "p.user_id").
+				CrossJoin("themes"),
+			want: `SELECT DISTINCT "p"."title", "u"."name" AS "author" FROM "pages" AS "p"` +
+				` RIGHT JOIN "users" AS "u" ON "u"."id" = "p"."user_id" CROSS JOIN "themes"`,
+		},
+		{
+			name: "union",
+			query: NewQuery(dialectDB("postgres")).Table("pages").Select("title").Where("status", "draft").
+				UnionAll(NewQuery(dialectDB("postgres")).Table("posts").Select("title").Where("status", "draft")).
+				OrderBy("title").
+				Limit(5),
+			want: `SELECT "title" FROM "pages" WHERE "status" = $1 UNION ALL SELECT "title" FROM "posts" WHERE "status" = $2` +
+				` ORDER BY "title" ASC LIMIT 5`,
+			args: []interface{}{"draft", "draft"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.NoError(t, tt.query.Err())
+			query, args := tt.query.ToSQL()
+			assert.Equal(t, tt.want, query)
+			assert.Equal(t, tt.args, args)
+		})
+	}
+}
+
+func TestQuery_EmptyInLists(t *testing.T) {
+	db := newRepoDB(t)
+	notes := NewRepository[*repoNote](db)
+	require.NoError(t, notes.Create(&repoNote{Slug: "a", Body: "a"}))
+	require.NoError(t, notes.Create(&repoNote{Slug: "b", Body: "b"}))
+
+	count, err := NewQuery(db).Table("notes").WhereIn("slug", []interface{}{}).Count()
+	require.NoError(t, err)
+	assert.Zero(t, count, "nothing is in an empty list")
+
+	count, err = NewQuery(db).Table("notes").WhereNotIn("slug", []interface{}{}).Count()
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), count)
+
+	count, err = NewQuery(db).Table("notes").WhereNotIn("slug", []interface{}{"a"}).Count()
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+}
+
+func TestQuery_RejectsUnsafeInput(t *testing.T) {
+	db := newRepoDB(t)
+	require.NoError(t, NewRepository[*repoNote](db).Create(&repoNote{Slug: "a", Body: "a"}))
+
+	tests := map[string]*Query{
+		"column":         NewQuery(db).Table("notes").Where("slug = 'a' OR 1=1 --", "x"),
+		"operator":       NewQuery(db).Table("notes").Where("slug", "= 'a' OR 1=1 --", "x"),
+		"sort column":    NewQuery(db).Table("notes").OrderBy("(SELECT 1)"),
+		"sort direction": NewQuery(db).Table("notes").OrderBy("slug", "ASC; DROP TABLE notes"),
+		"select":         NewQuery(db).Table("notes").Select("COUNT(*)"),
+		"table":          NewQuery(db).Table("notes; DROP TABLE notes"),
+		"join operator":  NewQuery(db).Table("notes").Join("tags", "tags.name", "= tags.name OR", "notes.slug"),
+		"group":          NewQuery(db).Table("notes").WhereGroup(func(q *Query) { q.Where("1=1", 1) }),
+		"subquery":       NewQuery(db).Table("notes").WhereExists(NewQuery(db).Table("tags").Where("x y", 1)),
+		"unioned order":  NewQuery(db).Table("notes").Union(NewQuery(db).Table("notes").OrderBy("id")),
+	}
+	for name, query := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Error(t, query.Err())
+			_, err := query.Get()
+			assert.Error(t, err)
+			_, err = query.Count()
+			assert.Error(t, err)
+		})
+	}
+
+	_, err := NewQuery(db).Table("notes").Update(map[string]interface{}{"body = 'x', slug": "y"})
+	assert.ErrorContains(t, err, "invalid identifier")
+	_, err = NewQuery(db).Table("notes").Where("id", "IN (1) --", 1).Delete()
+	assert.ErrorContains(t, err, "invalid operator")
+
+	count, err := NewQuery(db).Table("notes").Count()
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), count, "nothing ran")
+}
+
+func TestQuery_SortBy(t *testing.T) {
+	allowed := AllowColumns("title", "created_at")
+	allowed["author"] = "users.name"
+
+	tests := []struct {
+		input, direction, want string
+	}{
+		{"title", "desc", `SELECT * FROM "pages" ORDER BY "title" DESC`},
+		{"created_at", "", `SELECT * FROM "pages" ORDER BY "created_at" ASC`},
+		{"author", "sideways", `SELECT * FROM "pages" ORDER BY "users"."name" ASC`},
+		{"password", "asc", `SELECT * FROM "pages"`},
+		{"title; DROP TABLE pages", "asc", `SELECT * FROM "pages"`},
+	}
+	for _, tt := range tests {
+		q := NewQuery(dialectDB("sqlite")).Table("pages").SortBy(tt.input, tt.direction, allowed)
+		require.NoError(t, q.Err())
+		query, _ := q.ToSQL()
+		assert.Equal(t, tt.want, query, tt.input)
+	}
+}
+
+func TestQuery_RunsClauses(t *testing.T) {
+	db := newRelationsDB(t)
+
+	// Posts by live authors that have comments or tags
+	var titles []string
+	require.NoError(t, NewQuery(db).Table("posts").
+		WhereInQuery("author_id", NewQuery(db).Table("authors").Select("id").WhereNull("deleted_at")).
+		WhereGroup(func(q *Query) {
+			q.WhereExists(NewQuery(db).Table("comments").Select("id").WhereColumn("comments.post_id", "=", "posts.id")).
+				OrWhereRaw("id IN (SELECT post_id FROM post_tags)")
+		}).
+		OrderBy("title").
+		Pluck("title", &titles))
+	assert.Equal(t, []string{"First", "Third"}, titles)
+
+	// Authors with more than one post
+	var authors []int64
+	require.NoError(t, NewQuery(db).Table("posts").Select("author_id").
+		GroupBy("author_id").
+		HavingRaw("COUNT(*) > ?", 1).
+		Pluck("author_id", &authors))
+	assert.Equal(t, []int64{1}, authors)
+
+	union := NewQuery(db).Table("tags").Select("name").
+		Union(NewQuery(db).Table("authors").Select("name")).
+		OrderBy("name").
+		Limit(2).
+		Offset(1)
+	var names []string
+	require.NoError(t, union.Pluck("name", &names))
+	assert.Equal(t, []string{"Bob", "Gone"}, names)
+
+	// Counts ignore paging and count grouped, distinct and unioned rows
+	count, err := union.Count()
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), count)
+	count, err = NewQuery(db).Table("posts").Select("author_id").Distinct().WhereNotNull("author_id").Count()
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), count)
+	count, err = NewQuery(db).Table("comments").Limit(1).Offset(2).Count()
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), count)
+}
@@ -0,0 +1,384 @@
+package database
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Models declare their relationships by implementing RelatedModel. Each relation fills a struct
+// field, which has no db tag, with the related models:
+//
+//	type Page struct {
+//		ID       int64    `db:"id"`
+//		ParentID *int64   `db:"parent_id"`
+//		Parent   *Page    // BelongsTo fills a pointer
+//		Children []*Page  // HasMany and ManyToMany fill a slice
+//	}
+//
+//	func (p *Page) Relations() database.Relations {
+//		return database.Relations{
+//			"parent":   database.BelongsTo("Parent", "parent_id"),
+//			"children": database.HasMany("Children", "parent_id"),
+//		}
+//	}
+//
+// Relations are loaded with Repository.With, which loads each relation of every result in one
+// WHERE IN query, or later with Load. Nested relations are named with dots, such as
+// "children.media". Soft-deleted related models are left out.
+
+// RelatedModel is implemented by models with relationships
+type RelatedModel interface {
+	Model
+	Relations() Relations
+}
+
+// Relations maps relation names to their declarations
+type Relations map[string]Relation
+
+type relationKind int
+
+const (
+	belongsTo relationKind = iota
+	hasMany
+	manyToMany
+)
+
+// Relation declares how a model's field is loaded from another table
+type Relation struct {
+	kind       relationKind
+	field      string
+	foreignKey string
+	pivot      string
+	relatedKey string
+}
+
+// BelongsTo declares a relation to the model whose primary key is held in this model's
+// foreignKey column. field is a pointer to the related model.
+func BelongsTo(field, foreignKey string) Relation {
+	return Relation{kind: belongsTo, field: field, foreignKey: foreignKey}
+}
+
+// HasMany declares a relation to the models whose foreignKey column holds this model's
+// primary key. field is a slice of the related models.
+func HasMany(field, foreignKey string) Relation {
+	return Relation{kind: hasMany, field: field, foreignKey: foreignKey}
+}
+
+// ManyToMany declares a relation through a pivot table, whose foreignKey column holds this
+// model's primary key and whose relatedKey column holds the related model's. field is a
+// slice of the related models.
+func ManyToMany(field, pivot, foreignKey, relatedKey string) Relation {
+	return Relation{kind: manyToMany, field: field, foreignKey: foreignKey, pivot: pivot, relatedKey: relatedKey}
+}
+
+// Load loads relations into already-fetched models. models is a model, or a slice of models
+// or model pointers.
+func Load(db *DB, models interface{}, relations ...string) error {
+	return LoadContext(context.Background(), db, models, relations...)
+}
+
+// LoadContext loads relations into already-fetched models, querying with ctx
+func LoadContext(ctx context.Context, db *DB, models interface{}, relations ...string) error {
+	v := reflect.ValueOf(models)
+	var owners []reflect.Value
+	switch {
+	case v.Kind() == reflect.Pointer && !v.IsNil() && v.Elem().Kind() == reflect.Struct:
+		owners = []reflect.Value{v}
+	case v.Kind() == reflect.Slice:
+		owners = modelPointers(v)
+	case v.Kind() == reflect.Pointer && !v.IsNil() && v.Elem().Kind() == reflect.Slice:
+		owners = modelPointers(v.Elem())
+	default:
+		return fmt.Errorf("cannot load relations into %T", models)
+	}
+	return loadRelations(ctx, db, owners, relations)
+}
+
+// modelPointers returns pointers to the models in a slice of structs or struct pointers
+func modelPointers(slice reflect.Value) []reflect.Value {
+	pointers := make([]reflect.Value, 0, slice.Len())
+	for i := 0; i < slice.Len(); i++ {
+		elem := slice.Index(i)
+		if elem.Kind() != reflect.Pointer {
+			elem = elem.Addr()
+		}
+		if !elem.IsNil() {
+			pointers = append(pointers, elem)
+		}
+	}
+	return pointers
+}
+
+// loadRelations loads relation paths into owners, which are pointers to models of one type
+func loadRelations(ctx context.Context, db *DB, owners []reflect.Value, paths []string) error {
+	if len(owners) == 0 || len(paths) == 0 {
+		return nil
+	}
+
+	// Group nested paths under their first relation, so each relation is loaded once
+	var names []string
+	nested := make(map[string][]string)
+	for _, path := range paths {
+		name, rest, _ := strings.Cut(path, ".")
+		if _, ok := nested[name]; !ok {
+			names = append(names, name)
+			nested[name] = nil
+		}
+		if rest != "" {
+			nested[name] = append(nested[name], rest)
+		}
+	}
+
+	owner, ok := owners[0].Interface().(RelatedModel)
+	if !ok {
+		return fmt.Errorf("%s has no relations", owners[0].Type())
+	}
+	relations := owner.Relations()
+	for _, name := range names {
+		relation, ok := relations[name]
+		if !ok {
+			return fmt.Errorf("%s has no relation %q", owners[0].Type(), name)
+		}
+		related, err := relation.load(ctx, db, owners)
+		if err != nil {
+			return fmt.Errorf("failed to load %s: %w", name, err)
+		}
+		if err := loadRelations(ctx, db, related, nested[name]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// load fills the relation's field on each owner and returns the loaded models
+func (r Relation) load(ctx context.Context, db *DB, owners []reflect.Value) ([]reflect.Value, error) {
+	field, ok := owners[0].Elem().Type().FieldByName(r.field)
+	if !ok {
+		return nil, fmt.Errorf("%s has no field %s", owners[0].Type(), r.field)
+	}
+
+	// BelongsTo fills a model pointer; the others fill a slice of models or model pointers
+	relatedType := field.Type
+	if r.kind != belongsTo {
+		if relatedType.Kind() != reflect.Slice {
+			return nil, fmt.Errorf("field %s must be a slice of models", r.field)
+		}
+		relatedType = relatedType.Elem()
+	}
+	if relatedType.Kind() == reflect.Pointer {
+		relatedType = relatedType.Elem()
+	} else if r.kind == belongsTo {
+		return nil, fmt.Errorf("field %s must be a pointer to a model", r.field)
+	}
+	related, ok := reflect.New(relatedType).Interface().(Model)
+	if !ok || relatedType.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("field %s must hold models, not %s", r.field, relatedType)
+	}
+
+	// Catch misspelled keys, which would otherwise load nothing
+	keyModel := related
+	if r.kind == belongsTo {
+		keyModel = owners[0].Interface().(Model)
+	}
+	if r.kind != manyToMany {
+		if _, ok := structColumns(reflect.TypeOf(keyModel).Elem()).columns[r.foreignKey]; !ok {
+			return nil, fmt.Errorf("%T has no %s column", keyModel, r.foreignKey)
+		}
+	}
+
+	switch r.kind {
+	case belongsTo:
+		return r.loadBelongsTo(ctx, db, owners, related)
+	case hasMany:
+		return r.loadHasMany(ctx, db, owners, related)
+	default:
+		return r.loadManyToMany(ctx, db, owners, related)
+	}
+}
+
+// loadBelongsTo sets each owner's field to the model its foreign key refers to
+func (r Relation) loadBelongsTo(ctx context.Context, db *DB, owners []reflect.Value, related Model) ([]reflect.Value, error) {
+	models, err := fetchRelated(ctx, db, related, related.PrimaryKey(), columnValues(owners, r.foreignKey))
+	if err != nil {
+		return nil, err
+	}
+	byKey := indexModels(models, related.PrimaryKey())
+
+	for _, owner := range owners {
+		field := owner.Elem().FieldByName(r.field)
+		field.SetZero()
+		if key, ok := keyOf(getFieldValue(owner.Interface(), r.foreignKey)); ok {
+			if matches := byKey[key]; len(matches) > 0 {
+				field.Set(matches[0])
+			}
+		}
+	}
+	return models, nil
+}
+
+// loadHasMany sets each owner's field to the models whose foreign key holds its primary key
+func (r Relation) loadHasMany(ctx context.Context, db *DB, owners []reflect.Value, related Model) ([]reflect.Value, error) {
+	pk := owners[0].Interface().(Model).PrimaryKey()
+	models, err := fetchRelated(ctx, db, related, r.foreignKey, columnValues(owners, pk))
+	if err != nil {
+		return nil, err
+	}
+	byKey := indexModels(models, r.foreignKey)
+
+	for _, owner := range owners {
+		key, _ := keyOf(getFieldValue(owner.Interface(), pk))
+		setRelatedSlice(owner.Elem().FieldByName(r.field), byKey[key])
+	}
+	return relatedPointers(owners, r.field), nil
+}
+
+// loadManyToMany sets each owner's field to the models linked to it through the pivot table
+func (r Relation) loadManyToMany(ctx context.Context, db *DB, owners []reflect.Value, related Model) ([]reflect.Value, error) {
+	pk := owners[0].Interface().(Model).PrimaryKey()
+	keys := columnValues(owners, pk)
+
+	// Read the links first, then the models they link to
+	links := make(map[string][]string)
+	var relatedKeys []interface{}
+	seen := make(map[string]bool)
+	if len(keys) > 0 {
+		rows, err := NewQuery(db).WithContext(ctx).Table(r.pivot).Select(r.foreignKey, r.relatedKey).WhereIn(r.foreignKey, keys).Get()
+		if err != nil {
+			return nil, err
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var ownerValue, relatedValue interface{}
+			if err := rows.Scan(&ownerValue, &relatedValue); err != nil {
+				return nil, err
+			}
+			ownerKey, ok := keyOf(ownerValue)
+			relatedKey, relatedOK := keyOf(relatedValue)
+			if !ok || !relatedOK {
+				continue
+			}
+			links[ownerKey] = append(links[ownerKey], relatedKey)
+			if !seen[relatedKey] {
+				seen[relatedKey] = true
+				relatedKeys = append(relatedKeys, relatedValue)
+			}
+		}
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	models, err := fetchRelated(ctx, db, related, related.PrimaryKey(), relatedKeys)
+	if err != nil {
+		return nil, err
+	}
+	byKey := indexModels(models, related.PrimaryKey())
+
+	for _, owner := range owners {
+		key, _ := keyOf(getFieldValue(owner.Interface(), pk))
+		var matches []reflect.Value
+		for _, relatedKey := range links[key] {
+			matches = append(matches, byKey[relatedKey]...)
+		}
+		setRelatedSlice(owner.Elem().FieldByName(r.field), matches)
+	}
+	return relatedPointers(owners, r.field), nil
+}
+
+// fetchRelated reads the models whose column holds one of keys, leaving out soft-deleted ones
+func fetchRelated(ctx context.Context, db *DB, related Model, column string, keys []interface{}) ([]reflect.Value, error) {
+	if len(keys) == 0 {
+		return nil, nil
+	}
+
+	q := NewQuery(db).WithContext(ctx).Table(related.TableName()).WhereIn(column, keys)
+	relatedType := reflect.TypeOf(related).Elem()
+	if _, ok := structColumns(relatedType).columns["deleted_at"]; ok {
+		q.WhereNull("deleted_at")
+	}
+
+	models := reflect.New(reflect.SliceOf(reflect.PointerTo(relatedType)))
+	if err := q.Scan(models.Interface()); err != nil {
+		return nil, err
+	}
+	return modelPointers(models.Elem()), nil
+}
+
+// columnValues returns the distinct non-NULL values of the models' column
+func columnValues(models []reflect.Value, column string) []interface{} {
+	var values []interface{}
+	seen := make(map[string]bool)
+	for _, model := range models {
+		value := getFieldValue(model.Interface(), column)
+		if key, ok := keyOf(value); ok && !seen[key] {
+			seen[key] = true
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+// indexModels groups models by the value of a column
+func indexModels(models []reflect.Value, column string) map[string][]reflect.Value {
+	byKey := make(map[string][]reflect.Value, len(models))
+	for _, model := range models {
+		if key, ok := keyOf(getFieldValue(model.Interface(), column)); ok {
+			byKey[key] = append(byKey[key], model)
+		}
+	}
+	return byKey
+}
+
+// setRelatedSlice sets a slice field to models, which are pointers. An owner without related
+// models gets an empty slice, so loaded relations can be told from unloaded ones.
+func setRelatedSlice(field reflect.Value, models []reflect.Value) {
+	slice := reflect.MakeSlice(field.Type(), 0, len(models))
+	isPtr := field.Type().Elem().Kind() == reflect.Pointer
+	for _, model := range models {
+		if isPtr {
+			slice = reflect.Append(slice, model)
+		} else {
+			slice = reflect.Append(slice, model.Elem())
+		}
+	}
+	field.Set(slice)
+}
+
+// relatedPointers returns pointers to the models now held in each owner's slice field, so
+// nested relations are loaded into the models the owners hold
+func relatedPointers(owners []reflect.Value, field string) []reflect.Value {
+	var models []reflect.Value
+	for _, owner := range owners {
+		models = append(models, modelPointers(owner.Elem().FieldByName(field))...)
+	}
+	return models
+}
+
+// keyOf normalizes a key column's value so values read from different columns and drivers
+// compare equal. It reports false for NULL.
+func keyOf(value interface{}) (string, bool) {
+	if valuer, ok := value.(driver.Valuer); ok {
+		v, err := valuer.Value()
+		if err != nil {
+			return "", false
+		}
+		value = v
+	}
+	v := reflect.ValueOf(value)
+	for v.IsValid() && v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return "", false
+		}
+		v = v.Elem()
+	}
+	if !v.IsValid() {
+		return "", false
+	}
+	if b, ok := v.Interface().([]byte); ok {
+		return string(b), true
+	}
+	return fmt.Sprint(v.Interface()), true
+}
@@ -0,0 +1,156 @@
+package database
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type relAuthor struct {
+	ID   int64  `db:"id"`
+	Name string `db:"name"`
+	SoftDeletes
+
+	Posts []*relPost
+}
+
+func (a *relAuthor) TableName() string  { return "authors" }
+func (a *relAuthor) PrimaryKey() string { return "id" }
+func (a *relAuthor) Relations() Relations {
+	return Relations{"posts": HasMany("Posts", "author_id")}
+}
+
+type relPost struct {
+	ID       int64  `db:"id"`
+	AuthorID *int64 `db:"author_id"`
+	Title    string `db:"title"`
+
+	Author   *relAuthor
+	Tags     []relTag
+	Comments []*relComment
+}
+
+func (p *relPost) TableName() string  { return "posts" }
+func (p *relPost) PrimaryKey() string { return "id" }
+func (p *relPost) Relations() Relations {
+	return Relations{
+		"author":   BelongsTo("Author", "author_id"),
+		"tags":     ManyToMany("Tags", "post_tags", "post_id", "tag_id"),
+		"comments": HasMany("Comments", "post_id"),
+		"broken":   HasMany("Comments", "missing_id"),
+	}
+}
+
+type relTag struct {
+	ID   int64  `db:"id"`
+	Name string `db:"name"`
+}
+
+func (t *relTag) TableName() string  { return "tags" }
+func (t *relTag) PrimaryKey() string { return "id" }
+
+type relComment struct {
+	ID     int64  `db:"id"`
+	PostID int64  `db:"post_id"`
+	Body   string `db:"body"`
+}
+
+func (c *relComment) TableName() string  { return "comments" }
+func (c *relComment) PrimaryKey() string { return "id" }
+
+func newRelationsDB(t *testing.T) *DB {
+	t.Helper()
+	db, err := New(&Config{Driver: "sqlite", SQLitePath: ":memory:", MaxOpenConns: 1, MaxIdleConns: 1})
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	_, err = db.Exec(`
+		CREATE TABLE authors (id INTEGER PRIMARY KEY, name TEXT NOT NULL, deleted_at TIMESTAMP);
+		CREATE TABLE posts (id INTEGER PRIMARY KEY, author_id INTEGER, title TEXT NOT NULL);
+		CREATE TABLE tags (id INTEGER PRIMARY KEY, name TEXT NOT NULL);
+		CREATE TABLE post_tags (post_id INTEGER NOT NULL, tag_id INTEGER NOT NULL);
+		CREATE TABLE comments (id INTEGER PRIMARY KEY, post_id INTEGER NOT NULL, body TEXT NOT NULL);
+
+		INSERT INTO authors (id, name, deleted_at) VALUES (1, 'Ann', NULL), (2, 'Bob', NULL), (3, 'Gone', '2024-01-01 00:00:00');
+		INSERT INTO posts (id, author_id, title) VALUES (1, 1, 'First'), (2, 1, 'Second'), (3, 2, 'Third'), (4, NULL, 'Orphan'), (5, 3, 'Lost');
+		INSERT INTO tags (id, name) VALUES (1, 'go'), (2, 'sql');
+		INSERT INTO post_tags (post_id, tag_id) VALUES (1, 1), (1, 2), (3, 2);
+		INSERT INTO comments (id, post_id, body) VALUES (1, 1, 'Nice'), (2, 1, 'Thanks'), (3, 3, 'Hmm');
+	`)
+	require.NoError(t, err)
+	return db
+}
+
+func TestRepository_With(t *testing.T) {
+	posts := NewRepository[*relPost](newRelationsDB(t))
+
+	all, err := posts.With("author", "tags", "comments").OrderBy("id").Get()
+	require.NoError(t, err)
+	require.Len(t, all, 5)
+
+	first := all[0]
+	require.NotNil(t, first.Author)
+	assert.Equal(t, "Ann", first.Author.Name)
+	assert.Same(t, first.Author, all[1].Author, "models sharing a parent share its instance")
+	assert.Equal(t, []relTag{{ID: 1, Name: "go"}, {ID: 2, Name: "sql"}}, first.Tags)
+	require.Len(t, first.Comments, 2)
+	assert.Equal(t, "Thanks", first.Comments[1].Body)
+
+	assert.Equal(t, "Bob", all[2].Author.Name)
+	assert.Equal(t, []relTag{{ID: 2, Name: "sql"}}, all[2].Tags)
+
+	orphan := all[3]
+	assert.Nil(t, orphan.Author)
+	assert.NotNil(t, orphan.Tags, "loaded relations without models are empty, not nil")
+	assert.Empty(t, orphan.Tags)
+	assert.Empty(t, orphan.Comments)
+
+	assert.Nil(t, all[4].Author, "soft-deleted parents are left out")
+
+	post, err := posts.With("author").Where("id", 3).First()
+	require.NoError(t, err)
+	assert.Equal(t, "Bob", post.Author.Name)
+	assert.Nil(t, post.Tags, "relations that weren't asked for aren't loaded")
+}
+
+func TestRepository_WithNested(t *testing.T) {
+	authors := NewRepository[*relAuthor](newRelationsDB(t))
+
+	all, err := authors.With("posts.comments", "posts.tags").OrderBy("id").Get()
+	require.NoError(t, err)
+	require.Len(t, all, 2)
+
+	ann := all[0]
+	require.Len(t, ann.Posts, 2)
+	assert.Len(t, ann.Posts[0].Comments, 2)
+	assert.Len(t, ann.Posts[0].Tags, 2)
+	assert.Empty(t, ann.Posts[1].Comments)
+	assert.Equal(t, "Hmm", all[1].Posts[0].Comments[0].Body)
+}
+
+func TestLoad(t *testing.T) {
+	db := newRelationsDB(t)
+	posts := NewRepository[*relPost](db)
+
+	post, err := posts.Find(1)
+	require.NoError(t, err)
+	require.NoError(t, Load(db, post, "author.posts"))
+	assert.Equal(t, "Ann", post.Author.Name)
+	assert.Len(t, post.Author.Posts, 2)
+
+	all, err := posts.All()
+	require.NoError(t, err)
+	require.NoError(t, posts.Load(all, "comments"))
+	assert.Len(t, all[0].Comments, 2)
+
+	var values []relPost
+	require.NoError(t, NewQuery(db).Table("posts").OrderBy("id").Scan(&values))
+	require.NoError(t, Load(db, values, "tags"))
+	assert.Len(t, values[0].Tags, 2)
+
+	assert.ErrorContains(t, Load(db, post, "unknown"), `no relation "unknown"`)
+	assert.ErrorContains(t, Load(db, post, "broken"), "no missing_id column")
+	assert.ErrorContains(t, Load(db, &relTag{}, "posts"), "has no relations")
+	assert.Error(t, posts.With("author").Each(func(*relPost) error { return nil }))
+}
@@ -0,0 +1,592 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Repository provides typed CRUD for a model, a pointer to a struct with db tags:
+//
+//	type Note struct {
+//		ID   int64  `db:"id"`
+//		Body string `db:"body"`
+//		database.Timestamps
+//		database.SoftDeletes
+//	}
+//
+//	notes := database.NewRepository[*Note](db)
+//	recent, err := notes.Where("body", "LIKE", "%todo%").OrderBy("id", "desc").Get()
+//
+// Models with created_at and updated_at columns get them set on save. Models with a
+// deleted_at column are soft deleted, and soft-deleted rows are left out of every read.
+type Repository[T Model] struct {
+	db      *DB
+	table   string
+	pk      string
+	columns *structInfo
+	ctx     context.Context // Set by WithContext
+}
+
+// NewRepository creates a repository for a model type, which must be a pointer to a struct
+func NewRepository[T Model](db *DB) *Repository[T] {
+	t := reflect.TypeOf((*T)(nil)).Elem()
+	if t.Kind() != reflect.Pointer || t.Elem().Kind() != reflect.Struct {
+		panic(fmt.Sprintf("database: repository model must be a pointer to a struct, got %s", t))
+	}
+
+	model := reflect.New(t.Elem()).Interface().(Model)
+	return &Repository[T]{
+		db:      db,
+		table:   model.TableName(),
+		pk:      model.PrimaryKey(),
+		columns: structColumns(t.Elem()),
+	}
+}
+
+// WithContext returns a copy of the repository that runs its queries and writes with ctx,
+// joining the transaction ctx carries if there is one:
+//
+//	err := db.Transaction(req.Context(), func(tx *database.Tx) error {
+//		return notes.WithContext(tx.Context()).Create(note)
+//	})
+func (r *Repository[T]) WithContext(ctx context.Context) *Repository[T] {
+	repo := *r
+	repo.ctx = ctx
+	return &repo
+}
+
+// Find returns the model with a primary key, or sql.ErrNoRows
+func (r *Repository[T]) Find(id interface{}) (T, error) {
+	return r.FindBy(r.pk, id)
+}
+
+// FindBy returns the first model with a column value, or sql.ErrNoRows
+func (r *Repository[T]) FindBy(column string, value interface{}) (T, error) {
+	return r.Where(column, value).First()
+}
+
+// All returns every model
+func (r *Repository[T]) All() ([]T, error) {
+	return r.Query().Get()
+}
+
+// Count returns the number of models
+func (r *Repository[T]) Count() (int64, error) {
+	return r.Query().Count()
+}
+
+// Exists reports whether any model matches a column value
+func (r *Repository[T]) Exists(column string, value interface{}) (bool, error) {
+	return r.Where(column, value).Exists()
+}
+
+// Each calls fn with every model in turn, without loading them all at once
+func (r *Repository[T]) Each(fn func(T) error) error {
+	return r.Query().Each(fn)
+}
+
+// With starts a query that loads relations into its results, see RelatedModel
+func (r *Repository[T]) With(relations ...string) *RepositoryQuery[T] {
+	return r.Query().With(relations...)
+}
+
+// Load loads relations into models that have already been fetched
+func (r *Repository[T]) Load(models []T, relations ...string) error {
+	return LoadContext(r.context(), r.db, models, relations...)
+}
+
+// Where starts a query with a condition, as Query.Where
+func (r *Repository[T]) Where(column string, operatorOrValue interface{}, value ...interface{}) *RepositoryQuery[T] {
+	return r.Query().Where(column, operatorOrValue, value...)
+}
+
+// Query starts a query over all models
+func (r *Repository[T]) Query() *RepositoryQuery[T] {
+	q := NewQuery(r.db).WithContext(r.context()).Table(r.table)
+	if r.softDeletes() {
+		q.scopes = append(q.scopes, whereClause{field: "deleted_at", operator: "IS NULL", boolean: "AND"})
+	}
+	return &RepositoryQuery[T]{repo: r, query: q}
+}
+
+// Create inserts a model, setting its timestamps and generated primary key. The model's
+// create hooks run in the same transaction.
+func (r *Repository[T]) Create(model T) error {
+	now := time.Now()
+	r.setTime(model, "created_at", now)
+	r.setTime(model, "updated_at", now)
+
+	return r.db.writeModel(r.context(), model, ModelCreated, func(tx *Tx) error {
+		generateKey := r.pkIsZero(model)
+		columns, values := r.values(model, generateKey)
+		query := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)",
+			r.db.Quote(r.table), strings.Join(r.quoteAll(columns), ", "), placeholders(len(values)))
+
+		if !generateKey {
+			_, err := tx.Exec(query, values...)
+			return err
+		}
+		id, err := tx.InsertID(tx.Context(), query, r.pk, values...)
+		if err != nil {
+			return err
+		}
+		setFieldValue(model, r.pk, id)
+		return nil
+	})
+}
+
+// Update saves all of a model's columns, setting updated_at. The model's update hooks run
+// in the same transaction.
+func (r *Repository[T]) Update(model T) error {
+	r.setTime(model, "updated_at", time.Now())
+
+	return r.db.writeModel(r.context(), model, ModelUpdated, func(tx *Tx) error {
+		columns, values := r.values(model, true)
+		updates := make(map[string]interface{}, len(columns))
+		for i, column := range columns {
+			if column != "created_at" {
+				updates[column] = values[i]
+			}
+		}
+
+		query, args, err := r.Query().Where(r.pk, getFieldValue(model, r.pk)).query.updateSQL(updates)
+		if err != nil {
+			return err
+		}
+		// MySQL reports no affected rows when nothing changed, so a missing row isn't detected here
+		_, err = tx.Exec(query, args...)
+		return err
+	})
+}
+
+// Delete soft deletes a model if it has a deleted_at column, and removes it otherwise.
+// The model's delete hooks run in the same transaction.
+func (r *Repository[T]) Delete(model T) error {
+	if !r.softDeletes() {
+		return r.ForceDelete(model)
+	}
+
+	return r.db.writeModel(r.context(), model, ModelDeleted, func(tx *Tx) error {
+		now := time.Now()
+		query, args, err := r.Query().Where(r.pk, getFieldValue(model, r.pk)).query.updateSQL(map[string]interface{}{"deleted_at": now})
+		if err != nil {
+			return err
+		}
+		result, err := tx.Exec(query, args...)
+		if err != nil {
+			return err
+		}
+		if err := expectAffected(result, r.table); err != nil {
+			return err
+		}
+		r.setTime(model, "deleted_at", now)
+		return nil
+	})
+}
+
+// ForceDelete removes a model, even one that soft deletes. The model's delete hooks run
+// in the same transaction.
+func (r *Repository[T]) ForceDelete(model T) error {
+	return r.db.writeModel(r.context(), model, ModelDeleted, func(tx *Tx) error {
+		query, args, err := NewQuery(r.db).Table(r.table).Where(r.pk, getFieldValue(model, r.pk)).deleteSQL()
+		if err != nil {
+			return err
+		}
+		result, err := tx.Exec(query, args...)
+		if err != nil {
+			return err
+		}
+		return expectAffected(result, r.table)
+	})
+}
+
+// Upsert inserts a model, or updates the row that has the same values in the conflict columns,
+// which must have a unique index. They default to the primary key. The model's primary key
+// is set from the saved row, and a soft-deleted row it updates is restored. As Upsert can't tell
+// whether it will insert or update, it runs Validate but no create or update hooks, and publishes
+// no model event.
+func (r *Repository[T]) Upsert(model T, conflictColumns ...string) error {
+	if v, ok := any(model).(Validator); ok {
+		if err := v.Validate(); err != nil {
+			return err
+		}
+	}
+	if len(conflictColumns) == 0 {
+		conflictColumns = []string{r.pk}
+	}
+
+	now := time.Now()
+	r.setTime(model, "created_at", now)
+	r.setTime(model, "updated_at", now)
+
+	generateKey := r.pkIsZero(model)
+	columns, values := r.values(model, generateKey)
+	if col, ok := r.columns.columns["deleted_at"]; ok {
+		// Writing the model undeletes the row it replaces
+		field := reflect.ValueOf(model).Elem().FieldByIndex(col.index)
+		field.Set(reflect.Zero(field.Type()))
+		columns = append(columns, "deleted_at")
+		values = append(values, nil)
+	}
+	var updates []string
+	for _, column := range columns {
+		if column != "created_at" && !slices.Contains(conflictColumns, column) {
+			updates = append(updates, column)
+		}
+	}
+
+	query := upsertSQL(r.db.Dialect(), r.table, columns, conflictColumns, updates)
+
+	// Without its key, the row can't conflict on it, so it is inserted with a generated key
+	if generateKey && slices.Contains(conflictColumns, r.pk) {
+		id, err := r.db.InsertID(r.context(), query, r.pk, values...)
+		if err != nil {
+			return err
+		}
+		setFieldValue(model, r.pk, id)
+		return nil
+	}
+
+	if _, err := r.db.ExecContext(r.context(), query, values...); err != nil {
+		return err
+	}
+
+	// Read the key back, as drivers differ in what LastInsertId reports for an update
+	col, ok := r.columns.columns[r.pk]
+	if !ok {
+		return nil
+	}
+	q := NewQuery(r.db).WithContext(r.context()).Table(r.table)
+	for _, column := range conflictColumns {
+		q.Where(column, getFieldValue(model, column))
+	}
+	pk := reflect.ValueOf(model).Elem().FieldByIndex(col.index).Addr().Interface()
+	if err := q.Select(r.pk).First(pk); err != nil {
+		return fmt.Errorf("failed to read upserted %s: %w", r.table, err)
+	}
+	return nil
+}
+
+// upsertSQL builds an INSERT that updates the conflicting row instead of failing
+func upsertSQL(d Dialect, table string, columns, conflictColumns, updates []string) string {
+	quote := func(names []string) []string {
+		quoted := make([]string, len(names))
+		for i, name := range names {
+			quoted[i] = QuoteIdentifier(d, name)
+		}
+		return quoted
+	}
+
+	query := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)",
+		QuoteIdentifier(d, table), strings.Join(quote(columns), ", "), placeholders(len(columns)))
+
+	sets := make([]string, len(updates))
+	switch d.Name() {
+	case "mysql":
+		for i, column := range quote(updates) {
+			sets[i] = fmt.Sprintf("%s = VALUES(%s)", column, column)
+		}
+		if len(sets) == 0 {
+			// Assigning the key to itself turns a duplicate into a no-op
+			column := QuoteIdentifier(d, conflictColumns[0])
+			sets = []string{fmt.Sprintf("%s = %s", column, column)}
+		}
+		query += " ON DUPLICATE KEY UPDATE " + strings.Join(sets, ", ")
+	default:
+		for i, column := range quote(updates) {
+			sets[i] = fmt.Sprintf("%s = excluded.%s", column, column)
+		}
+		query += fmt.Sprintf(" ON CONFLICT (%s) ", strings.Join(quote(conflictColumns), ", "))
+		if len(sets) == 0 {
+			query += "DO NOTHING"
+		} else {
+			query += "DO UPDATE SET " + strings.Join(sets, ", ")
+		}
+	}
+	return query
+}
+
+// values returns a model's columns and values, leaving out deleted_at and optionally the primary key
+func (r *Repository[T]) values(model T, excludePK bool) ([]string, []interface{}) {
+	v := reflect.ValueOf(model).Elem()
+
+	var columns []string
+	var values []interface{}
+	for _, name := range r.columns.names {
+		if name == "deleted_at" || (excludePK && name == r.pk) {
+			continue
+		}
+		col := r.columns.columns[name]
+		value := v.FieldByIndex(col.index).Interface()
+		if col.json {
+			value = jsonValue{value}
+		}
+		columns = append(columns, name)
+		values = append(values, value)
+	}
+	return columns, values
+}
+
+// pkIsZero reports whether a model's primary key is unset, so the database generates it
+func (r *Repository[T]) pkIsZero(model T) bool {
+	col, ok := r.columns.columns[r.pk]
+	return !ok || reflect.ValueOf(model).Elem().FieldByIndex(col.index).IsZero()
+}
+
+// softDeletes reports whether the model has a deleted_at column
+func (r *Repository[T]) softDeletes() bool {
+	_, ok := r.columns.columns["deleted_at"]
+	return ok
+}
+
+// setTime sets a time column's field, whether it is a time.Time or a *time.Time
+func (r *Repository[T]) setTime(model T, column string, t time.Time) {
+	col, ok := r.columns.columns[column]
+	if !ok {
+		return
+	}
+	field := reflect.ValueOf(model).Elem().FieldByIndex(col.index)
+	switch field.Type() {
+	case timeType:
+		field.Set(reflect.ValueOf(t))
+	case reflect.PointerTo(timeType):
+		field.Set(reflect.ValueOf(&t))
+	}
+}
+
+// quoteAll quotes column names
+func (r *Repository[T]) quoteAll(columns []string) []string {
+	quoted := make([]string, len(columns))
+	for i, column := range columns {
+		quoted[i] = r.db.Quote(column)
+	}
+	return quoted
+}
+
+// RepositoryQuery is a query that returns models
+type RepositoryQuery[T Model] struct {
+	repo  *Repository[T]
+	query *Query
+	with  []string
+}
+
+// With loads relations into the results of Get and First, with one query per relation
+func (q *RepositoryQuery[T]) With(relations ...string) *RepositoryQuery[T] {
+	q.with = append(q.with, relations...)
+	return q
+}
+
+// Where adds a condition, as Query.Where
+func (q *RepositoryQuery[T]) Where(column string, operatorOrValue interface{}, value ...interface{}) *RepositoryQuery[T] {
+	q.query.Where(column, operatorOrValue, value...)
+	return q
+}
+
+// OrWhere adds an OR condition, as Query.OrWhere
+func (q *RepositoryQuery[T]) OrWhere(column string, operatorOrValue interface{}, value ...interface{}) *RepositoryQuery[T] {
+	q.query.OrWhere(column, operatorOrValue, value...)
+	return q
+}
+
+// WhereGroup adds a parenthesized group of conditions, as Query.WhereGroup
+func (q *RepositoryQuery[T]) WhereGroup(fn func(*Query)) *RepositoryQuery[T] {
+	q.query.WhereGroup(fn)
+	return q
+}
+
+// OrWhereGroup adds a parenthesized group of conditions, ORed with the others
+func (q *RepositoryQuery[T]) OrWhereGroup(fn func(*Query)) *RepositoryQuery[T] {
+	q.query.OrWhereGroup(fn)
+	return q
+}
+
+// WhereRaw adds a raw condition
+func (q *RepositoryQuery[T]) WhereRaw(sql string, args ...interface{}) *RepositoryQuery[T] {
+	q.query.WhereRaw(sql, args...)
+	return q
+}
+
+// WhereExists adds a condition that a subquery returns rows
+func (q *RepositoryQuery[T]) WhereExists(sub *Query) *RepositoryQuery[T] {
+	q.query.WhereExists(sub)
+	return q
+}
+
+// WhereInQuery adds a condition that a column's value is among a subquery's results
+func (q *RepositoryQuery[T]) WhereInQuery(column string, sub *Query) *RepositoryQuery[T] {
+	q.query.WhereInQuery(column, sub)
+	return q
+}
+
+// WhereIn adds a WHERE IN condition
+func (q *RepositoryQuery[T]) WhereIn(column string, values []interface{}) *RepositoryQuery[T] {
+	q.query.WhereIn(column, values)
+	return q
+}
+
+// WhereNotIn adds a WHERE NOT IN condition
+func (q *RepositoryQuery[T]) WhereNotIn(column string, values []interface{}) *RepositoryQuery[T] {
+	q.query.WhereNotIn(column, values)
+	return q
+}
+
+// WhereNull adds a WHERE IS NULL condition
+func (q *RepositoryQuery[T]) WhereNull(column string) *RepositoryQuery[T] {
+	q.query.WhereNull(column)
+	return q
+}
+
+// WhereNotNull adds a WHERE IS NOT NULL condition
+func (q *RepositoryQuery[T]) WhereNotNull(column string) *RepositoryQuery[T] {
+	q.query.WhereNotNull(column)
+	return q
+}
+
+// OrderBy adds an order by clause
+func (q *RepositoryQuery[T]) OrderBy(column string, direction ...string) *RepositoryQuery[T] {
+	q.query.OrderBy(column, direction...)
+	return q
+}
+
+// SortBy orders by a column named in user input if the allowlist has it, as Query.SortBy
+func (q *RepositoryQuery[T]) SortBy(input, direction string, allowed Columns) *RepositoryQuery[T] {
+	q.query.SortBy(input, direction, allowed)
+	return q
+}
+
+// Limit sets the limit
+func (q *RepositoryQuery[T]) Limit(limit int) *RepositoryQuery[T] {
+	q.query.Limit(limit)
+	return q
+}
+
+// Offset sets the offset
+func (q *RepositoryQuery[T]) Offset(offset int) *RepositoryQuery[T] {
+	q.query.Offset(offset)
+	return q
+}
+
+// Get returns the matching models
+func (q *RepositoryQuery[T]) Get() ([]T, error) {
+	models := []T{}
+	if err := q.query.Scan(&models); err != nil {
+		return nil, err
+	}
+	if err := LoadContext(q.repo.context(), q.repo.db, models, q.with...); err != nil {
+		return nil, err
+	}
+	return models, nil
+}
+
+// First returns the first matching model, or sql.ErrNoRows
+func (q *RepositoryQuery[T]) First() (T, error) {
+	model := q.repo.newModel()
+	var zero T
+	if err := q.query.First(model); err != nil {
+		return zero, err
+	}
+	if err := LoadContext(q.repo.context(), q.repo.db, model, q.with...); err != nil {
+		return zero, err
+	}
+	return model, nil
+}
+
+// Count returns the number of matching models
+func (q *RepositoryQuery[T]) Count() (int64, error) {
+	return q.query.Count()
+}
+
+// Exists reports whether any model matches
+func (q *RepositoryQuery[T]) Exists() (bool, error) {
+	return q.query.Exists()
+}
+
+// Paginate returns one page of matching models, as Query.Paginate
+func (q *RepositoryQuery[T]) Paginate(page, perPage int) ([]T, *Pagination, error) {
+	models := []T{}
+	pagination, err := q.query.Paginate(page, perPage, &models)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := LoadContext(q.repo.context(), q.repo.db, models, q.with...); err != nil {
+		return nil, nil, err
+	}
+	return models, pagination, nil
+}
+
+// CursorPaginate returns the page of matching models after cursor, as Query.CursorPaginate
+func (q *RepositoryQuery[T]) CursorPaginate(cursor string, limit int) ([]T, *CursorPage, error) {
+	models := []T{}
+	page, err := q.query.CursorPaginate(cursor, limit, &models)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := LoadContext(q.repo.context(), q.repo.db, models, q.with...); err != nil {
+		return nil, nil, err
+	}
+	return models, page, nil
+}
+
+// Each calls fn with each matching model as it is read, stopping at the first error
+func (q *RepositoryQuery[T]) Each(fn func(T) error) error {
+	// Loading relations needs another connection while the rows are open
+	if len(q.with) > 0 {
+		return fmt.Errorf("cannot load relations while streaming %s", q.repo.table)
+	}
+
+	rows, err := q.query.Get()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+		model := q.repo.newModel()
+		if err := scanInto(rows, columns, reflect.ValueOf(model).Elem()); err != nil {
+			return err
+		}
+		if err := fn(model); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// context returns the context the repository runs with
+func (r *Repository[T]) context() context.Context {
+	if r.ctx == nil {
+		return context.Background()
+	}
+	return r.ctx
+}
+
+// newModel allocates an empty model
+func (r *Repository[T]) newModel() T {
+	return reflect.New(reflect.TypeOf((*T)(nil)).Elem().Elem()).Interface().(T)
+}
+
+// placeholders returns n comma-separated ? placeholders
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?, ", n), ", ")
+}
+
+// expectAffected returns sql.ErrNoRows if a statement changed no rows
+func expectAffected(result sql.Result, table string) error {
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return fmt.Errorf("no %s row to change: %w", table, sql.ErrNoRows)
+	}
+	return nil
+}
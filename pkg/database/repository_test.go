@@ -0,0 +1,234 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type repoNote struct {
+	ID    int             `db:"id"`
+	Slug  string          `db:"slug"`
+	Body  string          `db:"body"`
+	Tags  []string        `db:"tags,json"`
+	Owner *repoNoteOwner  `db:"owner,json"`
+	Score sql.NullFloat64 `db:"score"`
+	Timestamps
+	SoftDeletes
+}
+
+type repoNoteOwner struct {
+	Name string `json:"name"`
+}
+
+func (n *repoNote) TableName() string  { return "notes" }
+func (n *repoNote) PrimaryKey() string { return "id" }
+
+// repoTag has no timestamps or soft deletes
+type repoTag struct {
+	Name  string `db:"name"`
+	Count int    `db:"count"`
+}
+
+func (t *repoTag) TableName() string  { return "tags" }
+func (t *repoTag) PrimaryKey() string { return "name" }
+
+func newRepoDB(t *testing.T) *DB {
+	t.Helper()
+	db, err := New(&Config{Driver: "sqlite", SQLitePath: ":memory:", MaxOpenConns: 1, MaxIdleConns: 1})
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	_, err = db.Exec(`
+		CREATE TABLE notes (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			slug TEXT NOT NULL UNIQUE,
+			body TEXT NOT NULL,
+			tags TEXT,
+			owner TEXT,
+			score REAL,
+			created_at TIMESTAMP,
+			updated_at TIMESTAMP,
+			deleted_at TIMESTAMP
+		);
+		CREATE TABLE tags (
+			name TEXT PRIMARY KEY,
+			count INTEGER NOT NULL
+		);
+	`)
+	require.NoError(t, err)
+	return db
+}
+
+func TestRepository_CRUD(t *testing.T) {
+	notes := NewRepository[*repoNote](newRepoDB(t))
+
+	note := &repoNote{Slug: "first", Body: "Hello", Tags: []string{"a"}, Owner: &repoNoteOwner{Name: "Ann"}}
+	require.NoError(t, notes.Create(note))
+	assert.Equal(t, 1, note.ID)
+	assert.False(t, note.CreatedAt.IsZero())
+	assert.Equal(t, note.CreatedAt, note.UpdatedAt)
+
+	found, err := notes.Find(note.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Hello", found.Body)
+	assert.Equal(t, []string{"a"}, found.Tags)
+	assert.Equal(t, &repoNoteOwner{Name: "Ann"}, found.Owner)
+	assert.False(t, found.Score.Valid)
+
+	found.Body = "Hello again"
+	found.Score = sql.NullFloat64{Float64: 4.5, Valid: true}
+	require.NoError(t, notes.Update(found))
+
+	found, err = notes.FindBy("slug", "first")
+	require.NoError(t, err)
+	assert.Equal(t, "Hello again", found.Body)
+	assert.Equal(t, 4.5, found.Score.Float64)
+
+	_, err = notes.Find(42)
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+}
+
+func TestRepository_SoftDeletes(t *testing.T) {
+	notes := NewRepository[*repoNote](newRepoDB(t))
+	for _, slug := range []string{"a", "b", "c"} {
+		require.NoError(t, notes.Create(&repoNote{Slug: slug, Body: slug}))
+	}
+
+	note, err := notes.FindBy("slug", "b")
+	require.NoError(t, err)
+	require.NoError(t, notes.Delete(note))
+	assert.NotNil(t, note.DeletedAt)
+
+	// Deleted notes are left out, even when an OR condition matches them
+	matching, err := notes.Where("slug", "a").OrWhere("slug", "b").Get()
+	require.NoError(t, err)
+	require.Len(t, matching, 1)
+	assert.Equal(t, "a", matching[0].Slug)
+
+	count, err := notes.Count()
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), count)
+	exists, err := notes.Exists("slug", "b")
+	require.NoError(t, err)
+	assert.False(t, exists)
+	_, err = notes.Find(note.ID)
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+	assert.ErrorIs(t, notes.Delete(note), sql.ErrNoRows)
+
+	require.NoError(t, notes.ForceDelete(note))
+	assert.ErrorIs(t, notes.ForceDelete(note), sql.ErrNoRows)
+}
+
+func TestRepository_QueryAndEach(t *testing.T) {
+	notes := NewRepository[*repoNote](newRepoDB(t))
+	for _, slug := range []string{"a", "b", "c", "d"} {
+		require.NoError(t, notes.Create(&repoNote{Slug: slug, Body: "body " + slug}))
+	}
+
+	page, err := notes.Query().OrderBy("slug", "desc").Limit(2).Offset(1).Get()
+	require.NoError(t, err)
+	require.Len(t, page, 2)
+	assert.Equal(t, "c", page[0].Slug)
+	assert.Equal(t, "b", page[1].Slug)
+
+	none, err := notes.Where("slug", "z").Get()
+	require.NoError(t, err)
+	assert.Empty(t, none)
+	assert.NotNil(t, none)
+
+	var seen []string
+	require.NoError(t, notes.Where("slug", "!=", "b").OrderBy("slug").Each(func(n *repoNote) error {
+		seen = append(seen, n.Slug)
+		return nil
+	}))
+	assert.Equal(t, []string{"a", "c", "d"}, seen)
+
+	stop := errors.New("stop")
+	calls := 0
+	err = notes.Each(func(n *repoNote) error {
+		calls++
+		return stop
+	})
+	assert.ErrorIs(t, err, stop)
+	assert.Equal(t, 1, calls)
+}
+
+func TestRepository_Upsert(t *testing.T) {
+	db := newRepoDB(t)
+	notes := NewRepository[*repoNote](db)
+
+	note := &repoNote{Slug: "first", Body: "v1"}
+	require.NoError(t, notes.Upsert(note, "slug"))
+	assert.Equal(t, 1, note.ID)
+	created, err := notes.Find(1)
+	require.NoError(t, err)
+
+	again := &repoNote{Slug: "first", Body: "v2"}
+	require.NoError(t, notes.Upsert(again, "slug"))
+	assert.Equal(t, 1, again.ID)
+
+	saved, err := notes.Find(1)
+	require.NoError(t, err)
+	assert.Equal(t, "v2", saved.Body)
+	assert.Equal(t, created.CreatedAt, saved.CreatedAt, "created_at is kept on update")
+
+	// A model with no key yet is inserted when the key is the conflict column
+	third := &repoNote{Slug: "third", Body: "v1"}
+	require.NoError(t, notes.Upsert(third))
+	require.NotZero(t, third.ID)
+	id := third.ID
+	third.Body = "v2"
+	require.NoError(t, notes.Upsert(third))
+	assert.Equal(t, id, third.ID)
+	saved, err = notes.Find(id)
+	require.NoError(t, err)
+	assert.Equal(t, "v2", saved.Body)
+
+	// Upserting over a soft-deleted row restores it
+	require.NoError(t, notes.Delete(saved))
+	restored := &repoNote{Slug: "third", Body: "v3"}
+	require.NoError(t, notes.Upsert(restored, "slug"))
+	assert.Equal(t, id, restored.ID)
+	assert.Nil(t, restored.DeletedAt)
+	saved, err = notes.Find(id)
+	require.NoError(t, err)
+	assert.Equal(t, "v3", saved.Body)
+
+	// A model whose key is its only conflict column
+	tags := NewRepository[*repoTag](db)
+	require.NoError(t, tags.Upsert(&repoTag{Name: "go", Count: 1}))
+	require.NoError(t, tags.Upsert(&repoTag{Name: "go", Count: 2}))
+	tag, err := tags.Find("go")
+	require.NoError(t, err)
+	assert.Equal(t, 2, tag.Count)
+
+	require.NoError(t, tags.Delete(tag))
+	count, err := tags.Count()
+	require.NoError(t, err)
+	assert.Zero(t, count)
+}
+
+func TestUpsertSQL(t *testing.T) {
+	columns := []string{"slug", "body"}
+	assert.Equal(t,
+		`INSERT INTO "notes" ("slug", "body") VALUES (?, ?) ON CONFLICT ("slug") DO UPDATE SET "body" = excluded."body"`,
+		upsertSQL(DialectFor("sqlite"), "notes", columns, []string{"slug"}, []string{"body"}))
+	assert.Equal(t,
+		`INSERT INTO "notes" ("slug", "body") VALUES (?, ?) ON CONFLICT ("slug") DO UPDATE SET "body" = excluded."body"`,
+		upsertSQL(DialectFor("postgres"), "notes", columns, []string{"slug"}, []string{"body"}))
+	assert.Equal(t,
+		"INSERT INTO `notes` (`slug`, `body`) VALUES (?, ?) ON DUPLICATE KEY UPDATE `body` = VALUES(`body`)",
+		upsertSQL(DialectFor("mysql"), "notes", columns, []string{"slug"}, []string{"body"}))
+
+	// Nothing to update
+	assert.Equal(t,
+		`INSERT INTO "tags" ("name") VALUES (?) ON CONFLICT ("name") DO NOTHING`,
+		upsertSQL(DialectFor("sqlite"), "tags", []string{"name"}, []string{"name"}, nil))
+	assert.Equal(t,
+		"INSERT INTO `tags` (`name`) VALUES (?) ON DUPLICATE KEY UPDATE `name` = `name`",
+		upsertSQL(DialectFor("mysql"), "tags", []string{"name"}, []string{"name"}, nil))
+}
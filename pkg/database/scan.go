@@ -0,0 +1,244 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Struct fields map to columns with db tags, such as `db:"email"`. Tagging a field `db:"settings,json"`
+// stores it as a JSON column. Fields of embedded structs without a db tag, such as Timestamps,
+// are mapped as if they were declared directly in the struct.
+//
+// Columns without a matching field are skipped, and fields without a matching column keep their
+// zero value. A nullable column should map to a pointer or sql.Null* field; NULL is an error
+// for other types, as with sql.Rows.Scan.
+
+var (
+	scannerType = reflect.TypeOf((*sql.Scanner)(nil)).Elem()
+	timeType    = reflect.TypeOf(time.Time{})
+
+	// columnCache holds the column mapping of each struct type
+	columnCache sync.Map // map[reflect.Type]*structInfo
+)
+
+// column is the struct field a column scans into
+type column struct {
+	index []int
+	json  bool
+}
+
+// structInfo maps a struct type's columns to its fields
+type structInfo struct {
+	columns map[string]column
+	names   []string // Column names in field order
+}
+
+// ScanOne scans the first row into dest, which is a pointer to a struct or, for a single
+// column, a pointer to a scalar. It returns sql.ErrNoRows if there are no rows, and closes rows.
+func ScanOne(rows *Rows, dest interface{}) error {
+	defer rows.Close()
+
+	v := reflect.ValueOf(dest)
+	if v.Kind() != reflect.Pointer || v.IsNil() {
+		return fmt.Errorf("scan destination must be a non-nil pointer, got %T", dest)
+	}
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		return sql.ErrNoRows
+	}
+	if err := scanInto(rows, columns, v.Elem()); err != nil {
+		return err
+	}
+	return rows.Close()
+}
+
+// ScanAll scans every row into dest, which is a pointer to a slice of structs, struct pointers
+// or, for a single column, scalars. It closes rows.
+func ScanAll(rows *Rows, dest interface{}) error {
+	defer rows.Close()
+
+	v := reflect.ValueOf(dest)
+	if v.Kind() != reflect.Pointer || v.IsNil() || v.Elem().Kind() != reflect.Slice {
+		return fmt.Errorf("scan destination must be a pointer to a slice, got %T", dest)
+	}
+	slice := v.Elem()
+	elemType := slice.Type().Elem()
+	isPtr := elemType.Kind() == reflect.Pointer
+	if isPtr {
+		elemType = elemType.Elem()
+	}
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+
+	result := reflect.MakeSlice(slice.Type(), 0, 0)
+	for rows.Next() {
+		elem := reflect.New(elemType)
+		if err := scanInto(rows, columns, elem.Elem()); err != nil {
+			return err
+		}
+		if isPtr {
+			result = reflect.Append(result, elem)
+		} else {
+			result = reflect.Append(result, elem.Elem())
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	slice.Set(result)
+	return nil
+}
+
+// scanInto scans the current row into a struct or scalar value
+func scanInto(rows *Rows, columns []string, v reflect.Value) error {
+	if !isStruct(v.Type()) {
+		if len(columns) != 1 {
+			return fmt.Errorf("cannot scan %d columns into %s", len(columns), v.Type())
+		}
+		return rows.Scan(v.Addr().Interface())
+	}
+
+	fields := structColumns(v.Type()).columns
+	targets := make([]interface{}, len(columns))
+	for i, name := range columns {
+		col, ok := fields[name]
+		if !ok {
+			col, ok = fields[strings.ToLower(name)]
+		}
+		if !ok {
+			targets[i] = new(interface{})
+			continue
+		}
+
+		field := v.FieldByIndex(col.index)
+		if col.json {
+			targets[i] = &jsonColumn{dest: field.Addr().Interface()}
+		} else {
+			targets[i] = field.Addr().Interface()
+		}
+	}
+
+	if err := rows.Scan(targets...); err != nil {
+		return fmt.Errorf("failed to scan into %s: %w", v.Type(), err)
+	}
+	return nil
+}
+
+// isStruct reports whether rows scan into the fields of t, rather than into t itself
+func isStruct(t reflect.Type) bool {
+	return t.Kind() == reflect.Struct && t != timeType && !reflect.PointerTo(t).Implements(scannerType)
+}
+
+// structColumns returns the columns of a struct type
+func structColumns(t reflect.Type) *structInfo {
+	if cached, ok := columnCache.Load(t); ok {
+		return cached.(*structInfo)
+	}
+
+	info := &structInfo{columns: make(map[string]column)}
+	collectColumns(t, nil, info.columns)
+	info.names = orderedColumns(info.columns)
+	columnCache.Store(t, info)
+	return info
+}
+
+// orderedColumns returns column names ordered by their field index
+func orderedColumns(columns map[string]column) []string {
+	names := make([]string, 0, len(columns))
+	for name := range columns {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		a, b := columns[names[i]].index, columns[names[j]].index
+		for k := 0; k < len(a) && k < len(b); k++ {
+			if a[k] != b[k] {
+				return a[k] < b[k]
+			}
+		}
+		return len(a) < len(b)
+	})
+	return names
+}
+
+// collectColumns adds the db-tagged fields of t, including those of untagged embedded structs
+func collectColumns(t reflect.Type, index []int, columns map[string]column) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		fieldIndex := append(append([]int(nil), index...), i)
+
+		if !field.IsExported() {
+			continue
+		}
+		tag := field.Tag.Get("db")
+		if tag == "" && field.Anonymous && field.Type.Kind() == reflect.Struct {
+			collectColumns(field.Type, fieldIndex, columns)
+			continue
+		}
+
+		name, isJSON := parseDBTag(tag)
+		if name == "" {
+			continue
+		}
+		// Fields declared closer to the top level win, as with encoding/json
+		if existing, ok := columns[name]; ok && len(existing.index) <= len(fieldIndex) {
+			continue
+		}
+		columns[name] = column{index: fieldIndex, json: isJSON}
+	}
+}
+
+// parseDBTag splits a db tag into its column name and whether the column holds JSON
+func parseDBTag(tag string) (name string, isJSON bool) {
+	name, opts, _ := strings.Cut(tag, ",")
+	if name == "-" {
+		return "", false
+	}
+	for _, opt := range strings.Split(opts, ",") {
+		if opt == "json" {
+			isJSON = true
+		}
+	}
+	return name, isJSON
+}
+
+// jsonColumn decodes a JSON column into a field
+type jsonColumn struct {
+	dest interface{}
+}
+
+// Scan implements sql.Scanner
+func (c *jsonColumn) Scan(src interface{}) error {
+	var data []byte
+	switch v := src.(type) {
+	case nil:
+		reflect.ValueOf(c.dest).Elem().SetZero()
+		return nil
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return fmt.Errorf("cannot decode JSON column from %T", src)
+	}
+	if len(data) == 0 {
+		reflect.ValueOf(c.dest).Elem().SetZero()
+		return nil
+	}
+	return json.Unmarshal(data, c.dest)
+}
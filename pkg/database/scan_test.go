@@ -0,0 +1,175 @@
+package database
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type scanProfile struct {
+	Theme string   `json:"theme"`
+	Tags  []string `json:"tags"`
+}
+
+type scanArticle struct {
+	ID      int64          `db:"id"`
+	Title   string         `db:"title"`
+	Summary *string        `db:"summary"`
+	Author  sql.NullString `db:"author"`
+	Profile *scanProfile   `db:"profile,json"`
+	Meta    map[string]int `db:"meta,json"`
+	Skipped string         `db:"-"`
+	Extra   string         // No column
+	Timestamps
+	SoftDeletes
+}
+
+func (a *scanArticle) TableName() string  { return "articles" }
+func (a *scanArticle) PrimaryKey() string { return "id" }
+
+func newScanDB(t *testing.T) *DB {
+	t.Helper()
+	db, err := New(&Config{Driver: "sqlite", SQLitePath: ":memory:", MaxOpenConns: 1, MaxIdleConns: 1})
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	_, err = db.Exec(`
+		CREATE TABLE articles (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			title TEXT NOT NULL,
+			summary TEXT,
+			author TEXT,
+			profile TEXT,
+			meta TEXT,
+			views INTEGER DEFAULT 0,
+			created_at TIMESTAMP,
+			updated_at TIMESTAMP,
+			deleted_at TIMESTAMP
+		);
+		INSERT INTO articles (title, summary, author, profile, meta, created_at, updated_at)
+			VALUES ('First', 'Short', 'Ann', '{"theme":"dark","tags":["a","b"]}', '{"likes":3}', '2024-01-02 03:04:05', '2024-01-02 03:04:05');
+		INSERT INTO articles (title, created_at, updated_at)
+			VALUES ('Second', '2024-02-03 04:05:06', '2024-02-03 04:05:06');
+	`)
+	require.NoError(t, err)
+	return db
+}
+
+func TestScanAll(t *testing.T) {
+	db := newScanDB(t)
+
+	rows, err := db.Query("SELECT * FROM articles ORDER BY id")
+	require.NoError(t, err)
+	var articles []scanArticle
+	require.NoError(t, ScanAll(rows, &articles))
+	require.Len(t, articles, 2)
+
+	first := articles[0]
+	assert.Equal(t, int64(1), first.ID)
+	assert.Equal(t, "First", first.Title)
+	require.NotNil(t, first.Summary)
+	assert.Equal(t, "Short", *first.Summary)
+	assert.Equal(t, sql.NullString{String: "Ann", Valid: true}, first.Author)
+	assert.Equal(t, &scanProfile{Theme: "dark", Tags: []string{"a", "b"}}, first.Profile)
+	assert.Equal(t, map[string]int{"likes": 3}, first.Meta)
+	assert.Equal(t, 2024, first.CreatedAt.Year())
+	assert.Nil(t, first.DeletedAt)
+
+	// NULL columns leave pointers, sql.Null* and JSON fields empty
+	second := articles[1]
+	assert.Nil(t, second.Summary)
+	assert.False(t, second.Author.Valid)
+	assert.Nil(t, second.Profile)
+	assert.Nil(t, second.Meta)
+
+	rows, err = db.Query("SELECT id, title FROM articles ORDER BY id")
+	require.NoError(t, err)
+	var pointers []*scanArticle
+	require.NoError(t, ScanAll(rows, &pointers))
+	require.Len(t, pointers, 2)
+	assert.Equal(t, "Second", pointers[1].Title)
+}
+
+func TestScanOne(t *testing.T) {
+	db := newScanDB(t)
+
+	rows, err := db.Query("SELECT id, title, 'unmapped' AS other FROM articles WHERE id = ?", 2)
+	require.NoError(t, err)
+	var article scanArticle
+	require.NoError(t, ScanOne(rows, &article))
+	assert.Equal(t, "Second", article.Title)
+
+	rows, err = db.Query("SELECT * FROM articles WHERE id = ?", 42)
+	require.NoError(t, err)
+	assert.ErrorIs(t, ScanOne(rows, &article), sql.ErrNoRows)
+
+	rows, err = db.Query("SELECT title FROM articles")
+	require.NoError(t, err)
+	assert.Error(t, ScanOne(rows, article), "destination must be a pointer")
+
+	// NULL is an error for fields that can't hold it
+	rows, err = db.Query("SELECT summary AS title FROM articles WHERE id = ?", 2)
+	require.NoError(t, err)
+	assert.Error(t, ScanOne(rows, &article))
+}
+
+func TestQuery_ScanFirstPluck(t *testing.T) {
+	db := newScanDB(t)
+
+	var articles []scanArticle
+	require.NoError(t, NewQuery(db).Table("articles").OrderBy("id", "desc").Scan(&articles))
+	require.Len(t, articles, 2)
+	assert.Equal(t, "Second", articles[0].Title)
+
+	var article scanArticle
+	require.NoError(t, NewQuery(db).Table("articles").Where("title", "First").Scan(&article))
+	assert.Equal(t, int64(1), article.ID)
+
+	var latest scanArticle
+	require.NoError(t, NewQuery(db).Table("articles").OrderBy("id", "desc").First(&latest))
+	assert.Equal(t, "Second", latest.Title)
+	assert.ErrorIs(t, NewQuery(db).Table("articles").Where("id", 42).First(&latest), sql.ErrNoRows)
+
+	var titles []string
+	query := NewQuery(db).Table("articles").OrderBy("title")
+	require.NoError(t, query.Pluck("title", &titles))
+	assert.Equal(t, []string{"First", "Second"}, titles)
+
+	var ids []int64
+	require.NoError(t, query.Pluck("id", &ids))
+	assert.Equal(t, []int64{1, 2}, ids)
+}
+
+func TestBaseModel_CreateFindAll(t *testing.T) {
+	db := newScanDB(t)
+	model := NewBaseModel(db, &scanArticle{})
+
+	summary := "Created"
+	article := &scanArticle{
+		Title:   "Third",
+		Summary: &summary,
+		Profile: &scanProfile{Theme: "light"},
+		Meta:    map[string]int{"likes": 1},
+	}
+	require.NoError(t, model.Create(article))
+	assert.Equal(t, int64(3), article.ID)
+
+	var found scanArticle
+	require.NoError(t, model.Find(article.ID, &found))
+	assert.Equal(t, "Third", found.Title)
+	assert.Equal(t, &scanProfile{Theme: "light"}, found.Profile)
+	assert.Equal(t, map[string]int{"likes": 1}, found.Meta)
+	assert.False(t, found.CreatedAt.IsZero())
+
+	found.Title = "Third, updated"
+	require.NoError(t, model.Update(&found))
+	require.NoError(t, model.Delete(&found))
+
+	var all []scanArticle
+	require.NoError(t, model.All(&all))
+	assert.Len(t, all, 2, "soft-deleted articles are left out")
+
+	assert.ErrorIs(t, model.Find(int64(42), &found), sql.ErrNoRows)
+}
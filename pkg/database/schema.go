@@ -0,0 +1,181 @@
+package database
+
+import (
+	"context"
+	"fmt"
+)
+
+// Schema creates and changes tables with definitions that compile to the database's dialect,
+// so a migration is written once for SQLite, MySQL and Postgres:
+//
+//	schema.Create("pages", func(t *Table) {
+//		t.ID()
+//		t.String("slug").Unique()
+//		t.ForeignID("parent_id").References("pages").NullOnDelete()
+//		t.Timestamps()
+//	})
+//
+// Statements run with the schema's context, so in a transaction when it carries one.
+type Schema struct {
+	db  *DB
+	ctx context.Context
+
+	// recorded collects statements instead of running them, see recordSchema
+	recorded *[]string
+}
+
+// NewSchema creates a schema builder for a database
+func NewSchema(db *DB) *Schema {
+	return &Schema{db: db}
+}
+
+// WithContext returns a copy of the schema builder that runs its statements with ctx
+func (s *Schema) WithContext(ctx context.Context) *Schema {
+	clone := *s
+	clone.ctx = ctx
+	return &clone
+}
+
+// recordSchema runs define against a schema builder that records the statements it would run,
+// compiled for Postgres, which changes tables in place. HasTable and HasColumn report false.
+// The statements are a canonical form of the definition, used to checksum schema migrations.
+func recordSchema(define func(*Schema) error) ([]string, error) {
+	var statements []string
+	s := &Schema{recorded: &statements}
+	if err := define(s); err != nil {
+		return nil, err
+	}
+	return statements, nil
+}
+
+// dialect returns the dialect statements are compiled for
+func (s *Schema) dialect() Dialect {
+	if s.recorded != nil {
+		return postgresDialect{}
+	}
+	return s.db.Dialect()
+}
+
+// context returns the schema builder's context, or the background context
+func (s *Schema) context() context.Context {
+	if s.ctx == nil {
+		return context.Background()
+	}
+	return s.ctx
+}
+
+// grammar returns the DDL grammar of the database's dialect
+func (s *Schema) grammar() schemaGrammar {
+	return schemaGrammar{d: s.dialect()}
+}
+
+// Create creates a table with the columns, indexes and foreign keys define adds
+func (s *Schema) Create(table string, define func(t *Table)) error {
+	t := &Table{name: table}
+	define(t)
+	if err := s.exec(s.grammar().createTable(t, table)...); err != nil {
+		return fmt.Errorf("failed to create table %s: %w", table, err)
+	}
+	return nil
+}
+
+// Alter changes a table: adding columns, changing those marked with Change, renaming and
+// dropping columns, and adding and dropping indexes and foreign keys. SQLite can't make most
+// of these changes in place, so the table is rebuilt with them; see rebuildSQLite.
+func (s *Schema) Alter(table string, define func(t *Table)) error {
+	t := &Table{name: table}
+	define(t)
+
+	var err error
+	if s.dialect().Name() == "sqlite" && needsRebuild(t) {
+		err = s.rebuildSQLite(t)
+	} else {
+		err = s.exec(s.grammar().alterTable(t)...)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to alter table %s: %w", table, err)
+	}
+	return nil
+}
+
+// Rename renames a table
+func (s *Schema) Rename(from, to string) error {
+	if err := s.exec(s.grammar().renameTable(from, to)); err != nil {
+		return fmt.Errorf("failed to rename table %s: %w", from, err)
+	}
+	return nil
+}
+
+// Drop drops a table
+func (s *Schema) Drop(table string) error {
+	if err := s.exec("DROP TABLE " + s.dialect().QuoteIdent(table)); err != nil {
+		return fmt.Errorf("failed to drop table %s: %w", table, err)
+	}
+	return nil
+}
+
+// DropIfExists drops a table if it exists
+func (s *Schema) DropIfExists(table string) error {
+	if err := s.exec("DROP TABLE IF EXISTS " + s.dialect().QuoteIdent(table)); err != nil {
+		return fmt.Errorf("failed to drop table %s: %w", table, err)
+	}
+	return nil
+}
+
+// HasTable reports whether a table exists
+func (s *Schema) HasTable(table string) (bool, error) {
+	if s.recorded != nil {
+		return false, nil
+	}
+	var query string
+	switch s.db.Dialect().Name() {
+	case "mysql":
+		query = "SELECT COUNT(*) FROM information_schema.tables WHERE table_schema = DATABASE() AND table_name = ?"
+	case "postgres":
+		query = "SELECT COUNT(*) FROM information_schema.tables WHERE table_schema = current_schema() AND table_name = ?"
+	default:
+		query = "SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ?"
+	}
+
+	var count int
+	if err := s.db.QueryRowContext(s.context(), query, table).Scan(&count); err != nil {
+		return false, fmt.Errorf("failed to check table %s: %w", table, err)
+	}
+	return count > 0, nil
+}
+
+// HasColumn reports whether a table has a column
+func (s *Schema) HasColumn(table, column string) (bool, error) {
+	if s.recorded != nil {
+		return false, nil
+	}
+	var query string
+	switch s.db.Dialect().Name() {
+	case "mysql":
+		query = "SELECT COUNT(*) FROM information_schema.columns WHERE table_schema = DATABASE() AND table_name = ? AND column_name = ?"
+	case "postgres":
+		query = "SELECT COUNT(*) FROM information_schema.columns WHERE table_schema = current_schema() AND table_name = ? AND column_name = ?"
+	default:
+		query = "SELECT COUNT(*) FROM pragma_table_info(?) WHERE name = ?"
+	}
+
+	var count int
+	if err := s.db.QueryRowContext(s.context(), query, table, column).Scan(&count); err != nil {
+		return false, fmt.Errorf("failed to check column %s.%s: %w", table, column, err)
+	}
+	return count > 0, nil
+}
+
+// exec runs DDL statements in order
+func (s *Schema) exec(statements ...string) error {
+	if s.recorded != nil {
+		*s.recorded = append(*s.recorded, statements...)
+		return nil
+	}
+	for _, stmt := range statements {
+		if _, err := s.db.ExecContext(s.context(), stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
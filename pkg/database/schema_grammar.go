@@ -0,0 +1,229 @@
+package database
+
+import (
+	"fmt"
+	"strings"
+)
+
+// schemaGrammar compiles table definitions to a dialect's DDL
+type schemaGrammar struct {
+	d Dialect
+}
+
+// quote quotes identifiers and joins them with commas
+func (g schemaGrammar) quote(names ...string) string {
+	quoted := make([]string, len(names))
+	for i, name := range names {
+		quoted[i] = g.d.QuoteIdent(name)
+	}
+	return strings.Join(quoted, ", ")
+}
+
+// columnType returns the dialect's type for a column
+func (g schemaGrammar) columnType(c *Column) string {
+	dialect := g.d.Name()
+	switch c.typ {
+	case typeIncrements:
+		return map[string]string{"sqlite": "INTEGER", "mysql": "INT", "postgres": "SERIAL"}[dialect]
+	case typeBigIncrements:
+		return map[string]string{"sqlite": "INTEGER", "mysql": "BIGINT", "postgres": "BIGSERIAL"}[dialect]
+	case typeString:
+		return fmt.Sprintf("VARCHAR(%d)", c.length)
+	case typeText:
+		return "TEXT"
+	case typeLongText:
+		if dialect == "mysql" {
+			return "LONGTEXT"
+		}
+		return "TEXT"
+	case typeInteger:
+		if dialect == "mysql" {
+			return "INT"
+		}
+		return "INTEGER"
+	case typeBigInteger:
+		return "BIGINT"
+	case typeBoolean:
+		return "BOOLEAN"
+	case typeDecimal:
+		return fmt.Sprintf("DECIMAL(%d, %d)", c.length, c.scale)
+	case typeFloat:
+		return map[string]string{"sqlite": "REAL", "mysql": "DOUBLE", "postgres": "DOUBLE PRECISION"}[dialect]
+	case typeDate:
+		return "DATE"
+	case typeTimestamp:
+		return "TIMESTAMP"
+	case typeJSON:
+		return map[string]string{"sqlite": "TEXT", "mysql": "JSON", "postgres": "JSONB"}[dialect]
+	case typeBinary:
+		if dialect == "postgres" {
+			return "BYTEA"
+		}
+		return "BLOB"
+	default:
+		return c.declared
+	}
+}
+
+// columnDef compiles a column definition, as used in CREATE TABLE and ADD COLUMN, declaring it
+// the primary key if inlinePrimary is set
+func (g schemaGrammar) columnDef(c *Column, inlinePrimary bool) string {
+	def := g.d.QuoteIdent(c.name) + " " + g.columnType(c)
+	if c.autoIncrement {
+		switch g.d.Name() {
+		case "sqlite":
+			return def + " PRIMARY KEY AUTOINCREMENT"
+		case "mysql":
+			return def + " NOT NULL AUTO_INCREMENT PRIMARY KEY"
+		default:
+			return def + " PRIMARY KEY"
+		}
+	}
+
+	if c.nullable && !c.primary && !inlinePrimary {
+		def += " NULL"
+	} else {
+		def += " NOT NULL"
+	}
+	if c.def != "" {
+		def += " DEFAULT " + c.def
+	}
+	if inlinePrimary {
+		def += " PRIMARY KEY"
+	}
+	return def
+}
+
+// foreignDef compiles a foreign key constraint
+func (g schemaGrammar) foreignDef(table string, fk *ForeignKey) string {
+	def := fmt.Sprintf("CONSTRAINT %s FOREIGN KEY (%s) REFERENCES %s",
+		g.d.QuoteIdent(fk.foreignName(table)), g.quote(fk.columns...), g.d.QuoteIdent(fk.table))
+	if len(fk.references) > 0 {
+		def += " (" + g.quote(fk.references...) + ")"
+	}
+	if fk.onDelete != "" {
+		def += " ON DELETE " + fk.onDelete
+	}
+	if fk.onUpdate != "" {
+		def += " ON UPDATE " + fk.onUpdate
+	}
+	return def
+}
+
+// createTable compiles the statements creating a table under a name, which is the table's
+// own unless SQLite is rebuilding it, followed by those creating its indexes
+func (g schemaGrammar) createTable(t *Table, name string) []string {
+	primary := t.primary
+	if len(primary) == 0 {
+		for _, c := range t.columns {
+			if c.primary {
+				primary = append(primary, c.name)
+			}
+		}
+	}
+
+	defs := make([]string, 0, len(t.columns)+len(t.foreigns)+1)
+	for _, c := range t.columns {
+		defs = append(defs, g.columnDef(c, len(primary) == 1 && primary[0] == c.name))
+	}
+	if len(primary) > 1 {
+		defs = append(defs, "PRIMARY KEY ("+g.quote(primary...)+")")
+	}
+	for _, fk := range t.foreigns {
+		defs = append(defs, g.foreignDef(t.name, fk))
+	}
+
+	statements := []string{fmt.Sprintf("CREATE TABLE %s (\n\t%s\n)", g.d.QuoteIdent(name), strings.Join(defs, ",\n\t"))}
+	for _, idx := range t.indexes {
+		statements = append(statements, g.createIndex(t.name, idx))
+	}
+	return statements
+}
+
+// createIndex compiles the statement creating an index
+func (g schemaGrammar) createIndex(table string, idx *Index) string {
+	kind := "INDEX"
+	if idx.unique {
+		kind = "UNIQUE INDEX"
+	}
+	return fmt.Sprintf("CREATE %s %s ON %s (%s)",
+		kind, g.d.QuoteIdent(idx.indexName(table)), g.d.QuoteIdent(table), g.quote(idx.columns...))
+}
+
+// dropIndex compiles the statement dropping an index
+func (g schemaGrammar) dropIndex(table, name string) string {
+	if g.d.Name() == "mysql" {
+		return fmt.Sprintf("DROP INDEX %s ON %s", g.d.QuoteIdent(name), g.d.QuoteIdent(table))
+	}
+	return "DROP INDEX " + g.d.QuoteIdent(name)
+}
+
+// alterTable compiles the statements altering a table in place. SQLite supports only renaming,
+// adding plain columns and index changes this way; see needsRebuild.
+func (g schemaGrammar) alterTable(t *Table) []string {
+	table := "ALTER TABLE " + g.d.QuoteIdent(t.name)
+	var statements []string
+
+	for _, r := range t.renames {
+		statements = append(statements, fmt.Sprintf("%s RENAME COLUMN %s TO %s", table, g.d.QuoteIdent(r.from), g.d.QuoteIdent(r.to)))
+	}
+	for _, columns := range t.dropForeigns {
+		name := g.d.QuoteIdent((&ForeignKey{columns: columns}).foreignName(t.name))
+		if g.d.Name() == "mysql" {
+			statements = append(statements, table+" DROP FOREIGN KEY "+name)
+		} else {
+			statements = append(statements, table+" DROP CONSTRAINT "+name)
+		}
+	}
+	for _, name := range t.dropIndexes {
+		statements = append(statements, g.dropIndex(t.name, name))
+	}
+	for _, name := range t.dropColumns {
+		statements = append(statements, table+" DROP COLUMN "+g.d.QuoteIdent(name))
+	}
+	for _, c := range t.columns {
+		switch {
+		case !c.change:
+			statements = append(statements, table+" ADD COLUMN "+g.columnDef(c, c.primary))
+		case g.d.Name() == "mysql":
+			statements = append(statements, table+" MODIFY COLUMN "+g.columnDef(c, false))
+		default:
+			statements = append(statements, g.changeColumn(t.name, c)...)
+		}
+	}
+	if len(t.primary) > 0 {
+		statements = append(statements, table+" ADD PRIMARY KEY ("+g.quote(t.primary...)+")")
+	}
+	for _, idx := range t.indexes {
+		statements = append(statements, g.createIndex(t.name, idx))
+	}
+	for _, fk := range t.foreigns {
+		statements = append(statements, table+" ADD "+g.foreignDef(t.name, fk))
+	}
+	return statements
+}
+
+// changeColumn compiles the Postgres statements changing a column's type, nullability and default
+func (g schemaGrammar) changeColumn(table string, c *Column) []string {
+	alter := fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s", g.d.QuoteIdent(table), g.d.QuoteIdent(c.name))
+	statements := []string{fmt.Sprintf("%s TYPE %s USING %s::%s", alter, g.columnType(c), g.d.QuoteIdent(c.name), g.columnType(c))}
+	if c.nullable {
+		statements = append(statements, alter+" DROP NOT NULL")
+	} else {
+		statements = append(statements, alter+" SET NOT NULL")
+	}
+	if c.def != "" {
+		statements = append(statements, alter+" SET DEFAULT "+c.def)
+	} else {
+		statements = append(statements, alter+" DROP DEFAULT")
+	}
+	return statements
+}
+
+// renameTable compiles the statement renaming a table
+func (g schemaGrammar) renameTable(from, to string) string {
+	if g.d.Name() == "mysql" {
+		return fmt.Sprintf("RENAME TABLE %s TO %s", g.d.QuoteIdent(from), g.d.QuoteIdent(to))
+	}
+	return fmt.Sprintf("ALTER TABLE %s RENAME TO %s", g.d.QuoteIdent(from), g.d.QuoteIdent(to))
+}
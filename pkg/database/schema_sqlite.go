@@ -0,0 +1,320 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// needsRebuild reports whether SQLite must rebuild a table to make an alteration.
+// It can rename columns, add nullable or constant-default columns and change indexes in place.
+func needsRebuild(t *Table) bool {
+	if len(t.dropColumns) > 0 || len(t.dropForeigns) > 0 || len(t.foreigns) > 0 || len(t.primary) > 0 {
+		return true
+	}
+	for _, c := range t.columns {
+		if c.change || c.primary || c.autoIncrement || c.def == "CURRENT_TIMESTAMP" || (!c.nullable && c.def == "") {
+			return true
+		}
+	}
+	return false
+}
+
+// rebuildSQLite alters a table the way SQLite recommends for changes ALTER TABLE can't make:
+// it creates the altered table under a temporary name, copies the rows over, drops the table and
+// renames the new one in its place, then recreates the indexes. CHECK constraints, collations and
+// triggers on the table are not carried over, and partial or expression indexes stop the rebuild.
+//
+// Dropping the table would set off foreign key actions on the rows referencing it, and SQLite
+// can't turn enforcement off inside a transaction, so the rebuild refuses to run while it is on.
+func (s *Schema) rebuildSQLite(alter *Table) error {
+	ctx := s.context()
+	var enforced bool
+	if err := s.db.QueryRowContext(ctx, "PRAGMA foreign_keys").Scan(&enforced); err != nil {
+		return err
+	}
+	if enforced {
+		return errors.New("rebuilding the table needs foreign key enforcement off, which SQLite can't change in a transaction")
+	}
+
+	t, autoIncrement, err := s.sqliteTable(alter.name)
+	if err != nil {
+		return err
+	}
+	copied, err := applyAlter(t, alter)
+	if err != nil {
+		return err
+	}
+
+	g := s.grammar()
+	tmp := "_new_" + t.name
+	create := g.createTable(t, tmp)
+
+	var newColumns, oldColumns []string
+	for _, c := range t.columns {
+		if from, ok := copied[c.name]; ok {
+			newColumns = append(newColumns, c.name)
+			oldColumns = append(oldColumns, from)
+		}
+	}
+
+	statements := []string{
+		create[0],
+		fmt.Sprintf("INSERT INTO %s (%s) SELECT %s FROM %s",
+			g.d.QuoteIdent(tmp), g.quote(newColumns...), g.quote(oldColumns...), g.d.QuoteIdent(t.name)),
+	}
+	if autoIncrement {
+		// Keep the AUTOINCREMENT counter, so IDs of deleted rows aren't reused
+		statements = append(statements,
+			"DELETE FROM sqlite_sequence WHERE name = "+sqlLiteral(tmp),
+			fmt.Sprintf("INSERT INTO sqlite_sequence (name, seq) SELECT %s, seq FROM sqlite_sequence WHERE name = %s",
+				sqlLiteral(tmp), sqlLiteral(t.name)))
+	}
+	statements = append(statements,
+		"DROP TABLE "+g.d.QuoteIdent(t.name),
+		g.renameTable(tmp, t.name))
+	statements = append(statements, create[1:]...)
+	return s.exec(statements...)
+}
+
+// applyAlter applies an alteration to the definition of an existing table, returning the
+// columns whose rows are copied, mapped to the names they had
+func applyAlter(t, alter *Table) (map[string]string, error) {
+	copied := make(map[string]string, len(t.columns))
+	for _, c := range t.columns {
+		copied[c.name] = c.name
+	}
+
+	for _, r := range alter.renames {
+		c := t.column(r.from)
+		if c == nil {
+			return nil, fmt.Errorf("no column %s to rename", r.from)
+		}
+		c.name = r.to
+		copied[r.to] = copied[r.from]
+		delete(copied, r.from)
+		renameIn(t.primary, r.from, r.to)
+		for _, idx := range t.indexes {
+			renameIn(idx.columns, r.from, r.to)
+		}
+		for _, fk := range t.foreigns {
+			renameIn(fk.columns, r.from, r.to)
+		}
+	}
+
+	for _, name := range alter.dropColumns {
+		if t.column(name) == nil {
+			return nil, fmt.Errorf("no column %s to drop", name)
+		}
+		if slices.Contains(t.primary, name) {
+			return nil, fmt.Errorf("can't drop primary key column %s", name)
+		}
+		t.columns = slices.DeleteFunc(t.columns, func(c *Column) bool { return c.name == name })
+		t.indexes = slices.DeleteFunc(t.indexes, func(idx *Index) bool { return slices.Contains(idx.columns, name) })
+		t.foreigns = slices.DeleteFunc(t.foreigns, func(fk *ForeignKey) bool { return slices.Contains(fk.columns, name) })
+		delete(copied, name)
+	}
+
+	for _, name := range alter.dropIndexes {
+		n := len(t.indexes)
+		t.indexes = slices.DeleteFunc(t.indexes, func(idx *Index) bool { return idx.indexName(t.name) == name })
+		if len(t.indexes) == n {
+			return nil, fmt.Errorf("no index %s to drop", name)
+		}
+	}
+
+	for _, columns := range alter.dropForeigns {
+		n := len(t.foreigns)
+		t.foreigns = slices.DeleteFunc(t.foreigns, func(fk *ForeignKey) bool { return slices.Equal(fk.columns, columns) })
+		if len(t.foreigns) == n {
+			return nil, fmt.Errorf("no foreign key on %s to drop", strings.Join(columns, ", "))
+		}
+	}
+
+	for _, c := range alter.columns {
+		existing := slices.IndexFunc(t.columns, func(e *Column) bool { return e.name == c.name })
+		switch {
+		case c.change && existing < 0:
+			return nil, fmt.Errorf("no column %s to change", c.name)
+		case c.change:
+			c.table = t
+			t.columns[existing] = c
+		case existing >= 0:
+			return nil, fmt.Errorf("column %s already exists", c.name)
+		default:
+			c.table = t
+			t.columns = append(t.columns, c)
+		}
+	}
+
+	if len(alter.primary) > 0 {
+		t.primary = alter.primary
+	}
+	t.indexes = append(t.indexes, alter.indexes...)
+	t.foreigns = append(t.foreigns, alter.foreigns...)
+	return copied, nil
+}
+
+// renameIn renames a column in a list of column names
+func renameIn(columns []string, from, to string) {
+	for i, c := range columns {
+		if c == from {
+			columns[i] = to
+		}
+	}
+}
+
+// sqliteTable reads the definition of an existing SQLite table: its columns, primary key,
+// foreign keys and indexes, and whether its key is AUTOINCREMENT
+func (s *Schema) sqliteTable(name string) (*Table, bool, error) {
+	ctx := s.context()
+	t := &Table{name: name}
+
+	var create string
+	err := s.db.QueryRowContext(ctx, "SELECT sql FROM sqlite_master WHERE type = 'table' AND name = ?", name).Scan(&create)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, false, fmt.Errorf("no table %s", name)
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	autoIncrement := strings.Contains(strings.ToUpper(create), "AUTOINCREMENT")
+
+	// Columns, with their positions in the primary key
+	rows, err := s.db.QueryContext(ctx, `SELECT name, type, "notnull", dflt_value, pk FROM pragma_table_info(?) ORDER BY cid`, name)
+	if err != nil {
+		return nil, false, err
+	}
+	pk := make(map[int]string)
+	for rows.Next() {
+		c := &Column{table: t, typ: typeDeclared}
+		var notNull bool
+		var def sql.NullString
+		var position int
+		if err := rows.Scan(&c.name, &c.declared, &notNull, &def, &position); err != nil {
+			rows.Close()
+			return nil, false, err
+		}
+		c.nullable, c.def = !notNull, def.String
+		if position > 0 {
+			pk[position] = c.name
+		}
+		t.columns = append(t.columns, c)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, false, err
+	}
+	for i := 1; i <= len(pk); i++ {
+		t.primary = append(t.primary, pk[i])
+	}
+	if len(t.primary) == 1 {
+		c := t.column(t.primary[0])
+		c.autoIncrement = autoIncrement && strings.EqualFold(c.declared, "INTEGER")
+		c.primary, t.primary = true, nil
+	}
+
+	// Foreign keys, one row per column
+	rows, err = s.db.QueryContext(ctx, `SELECT id, "table", "from", "to", on_update, on_delete FROM pragma_foreign_key_list(?) ORDER BY id, seq`, name)
+	if err != nil {
+		return nil, false, err
+	}
+	byID := make(map[int]*ForeignKey)
+	for rows.Next() {
+		var id int
+		var table, from, onUpdate, onDelete string
+		var to sql.NullString
+		if err := rows.Scan(&id, &table, &from, &to, &onUpdate, &onDelete); err != nil {
+			rows.Close()
+			return nil, false, err
+		}
+		fk, ok := byID[id]
+		if !ok {
+			fk = &ForeignKey{table: table, onUpdate: foreignAction(onUpdate), onDelete: foreignAction(onDelete)}
+			byID[id] = fk
+			t.foreigns = append(t.foreigns, fk)
+		}
+		fk.columns = append(fk.columns, from)
+		if to.Valid {
+			fk.references = append(fk.references, to.String)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, false, err
+	}
+
+	// Indexes, other than the primary key's. Those made by UNIQUE constraints become unique
+	// indexes with default names.
+	rows, err = s.db.QueryContext(ctx, `SELECT name, "unique", origin, partial FROM pragma_index_list(?)`, name)
+	if err != nil {
+		return nil, false, err
+	}
+	var names []string
+	for rows.Next() {
+		idx := &Index{}
+		var indexName, origin string
+		var partial bool
+		if err := rows.Scan(&indexName, &idx.unique, &origin, &partial); err != nil {
+			rows.Close()
+			return nil, false, err
+		}
+		if origin == "pk" {
+			continue
+		}
+		if partial {
+			rows.Close()
+			return nil, false, fmt.Errorf("partial index %s can't be rebuilt", indexName)
+		}
+		if origin == "c" {
+			idx.name = indexName
+		}
+		names = append(names, indexName)
+		t.indexes = append(t.indexes, idx)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, false, err
+	}
+	for i, indexName := range names {
+		columns, err := s.sqliteIndexColumns(indexName)
+		if err != nil {
+			return nil, false, err
+		}
+		t.indexes[i].columns = columns
+	}
+
+	return t, autoIncrement, nil
+}
+
+// sqliteIndexColumns reads the columns of a SQLite index
+func (s *Schema) sqliteIndexColumns(index string) ([]string, error) {
+	rows, err := s.db.QueryContext(s.context(), "SELECT name FROM pragma_index_info(?) ORDER BY seqno", index)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var columns []string
+	for rows.Next() {
+		var column sql.NullString
+		if err := rows.Scan(&column); err != nil {
+			return nil, err
+		}
+		if !column.Valid {
+			return nil, fmt.Errorf("expression index %s can't be rebuilt", index)
+		}
+		columns = append(columns, column.String)
+	}
+	return columns, rows.Err()
+}
+
+// foreignAction returns a foreign key action as declared, "" for the default NO ACTION
+func foreignAction(action string) string {
+	if action == "NO ACTION" {
+		return ""
+	}
+	return action
+}
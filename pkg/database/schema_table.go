@@ -0,0 +1,399 @@
+package database
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// columnType is the portable type of a column, mapped to each dialect's type when compiled
+type columnType int
+
+const (
+	typeIncrements columnType = iota
+	typeBigIncrements
+	typeString
+	typeText
+	typeLongText
+	typeInteger
+	typeBigInteger
+	typeBoolean
+	typeDecimal
+	typeFloat
+	typeDate
+	typeTimestamp
+	typeJSON
+	typeBinary
+	typeDeclared // A type read from an existing SQLite table, compiled as declared
+)
+
+// Table describes a table being created or altered, see Schema.Create and Schema.Alter.
+// Columns are NOT NULL unless marked Nullable.
+type Table struct {
+	name     string
+	columns  []*Column
+	primary  []string
+	indexes  []*Index
+	foreigns []*ForeignKey
+
+	// Changes to an existing table, see Schema.Alter
+	renames      []columnRename
+	dropColumns  []string
+	dropIndexes  []string
+	dropForeigns [][]string
+}
+
+// columnRename renames a column of an existing table
+type columnRename struct {
+	from, to string
+}
+
+// Column defines a column. Its methods set modifiers and return it, so they can be chained.
+type Column struct {
+	table    *Table
+	name     string
+	typ      columnType
+	declared string // The declared type of a typeDeclared column
+	length   int
+	scale    int
+
+	nullable      bool
+	def           string // SQL default expression, "" for none
+	primary       bool
+	autoIncrement bool
+	change        bool
+}
+
+// Index defines an index on one or more columns
+type Index struct {
+	name    string
+	columns []string
+	unique  bool
+}
+
+// ForeignKey defines a foreign key constraint. Its methods set its actions and return it,
+// so they can be chained.
+type ForeignKey struct {
+	name       string
+	columns    []string
+	table      string
+	references []string
+	onDelete   string
+	onUpdate   string
+
+	column *Column // The column it was defined with, see Column.References
+}
+
+// ID adds an auto-incrementing big integer primary key named id
+func (t *Table) ID() *Column {
+	return t.BigIncrements("id")
+}
+
+// Increments adds an auto-incrementing integer primary key
+func (t *Table) Increments(name string) *Column {
+	c := t.addColumn(name, typeIncrements)
+	c.primary, c.autoIncrement = true, true
+	return c
+}
+
+// BigIncrements adds an auto-incrementing big integer primary key
+func (t *Table) BigIncrements(name string) *Column {
+	c := t.addColumn(name, typeBigIncrements)
+	c.primary, c.autoIncrement = true, true
+	return c
+}
+
+// String adds a VARCHAR column, 255 characters long unless a length is given
+func (t *Table) String(name string, length ...int) *Column {
+	c := t.addColumn(name, typeString)
+	c.length = 255
+	if len(length) > 0 {
+		c.length = length[0]
+	}
+	return c
+}
+
+// Text adds a TEXT column
+func (t *Table) Text(name string) *Column {
+	return t.addColumn(name, typeText)
+}
+
+// LongText adds a text column for large values, LONGTEXT on MySQL
+func (t *Table) LongText(name string) *Column {
+	return t.addColumn(name, typeLongText)
+}
+
+// Integer adds an INTEGER column
+func (t *Table) Integer(name string) *Column {
+	return t.addColumn(name, typeInteger)
+}
+
+// BigInteger adds a BIGINT column
+func (t *Table) BigInteger(name string) *Column {
+	return t.addColumn(name, typeBigInteger)
+}
+
+// Boolean adds a BOOLEAN column
+func (t *Table) Boolean(name string) *Column {
+	return t.addColumn(name, typeBoolean)
+}
+
+// Decimal adds a fixed-point DECIMAL column with the given precision and scale
+func (t *Table) Decimal(name string, precision, scale int) *Column {
+	c := t.addColumn(name, typeDecimal)
+	c.length, c.scale = precision, scale
+	return c
+}
+
+// Float adds a double-precision floating-point column
+func (t *Table) Float(name string) *Column {
+	return t.addColumn(name, typeFloat)
+}
+
+// Date adds a DATE column
+func (t *Table) Date(name string) *Column {
+	return t.addColumn(name, typeDate)
+}
+
+// Timestamp adds a TIMESTAMP column
+func (t *Table) Timestamp(name string) *Column {
+	return t.addColumn(name, typeTimestamp)
+}
+
+// JSON adds a column for JSON documents: JSON on MySQL, JSONB on Postgres and TEXT on SQLite
+func (t *Table) JSON(name string) *Column {
+	return t.addColumn(name, typeJSON)
+}
+
+// Binary adds a column for binary data: BLOB, or BYTEA on Postgres
+func (t *Table) Binary(name string) *Column {
+	return t.addColumn(name, typeBinary)
+}
+
+// ForeignID adds a big integer column for a foreign key to an ID column; see Column.References.
+// MySQL needs a key's type to match the column it references, so reference the core tables'
+// INT keys from an Integer column.
+func (t *Table) ForeignID(name string) *Column {
+	return t.addColumn(name, typeBigInteger)
+}
+
+// Timestamps adds nullable created_at and updated_at columns defaulting to the current time
+func (t *Table) Timestamps() {
+	t.Timestamp("created_at").Nullable().DefaultNow()
+	t.Timestamp("updated_at").Nullable().DefaultNow()
+}
+
+// SoftDeletes adds the nullable deleted_at column used by SoftDeletes models
+func (t *Table) SoftDeletes() *Column {
+	return t.Timestamp("deleted_at").Nullable()
+}
+
+// Primary sets the table's primary key to one or more columns
+func (t *Table) Primary(columns ...string) {
+	t.primary = columns
+}
+
+// Index adds an index on one or more columns, named idx_<table>_<columns> unless renamed
+func (t *Table) Index(columns ...string) *Index {
+	idx := &Index{columns: columns}
+	t.indexes = append(t.indexes, idx)
+	return idx
+}
+
+// Unique adds a unique index on one or more columns, named uniq_<table>_<columns> unless renamed
+func (t *Table) Unique(columns ...string) *Index {
+	idx := t.Index(columns...)
+	idx.unique = true
+	return idx
+}
+
+// Foreign adds a foreign key on one or more columns, named fk_<table>_<columns> unless renamed.
+// Set the table and columns it references with References.
+func (t *Table) Foreign(columns ...string) *ForeignKey {
+	fk := &ForeignKey{columns: columns}
+	t.foreigns = append(t.foreigns, fk)
+	return fk
+}
+
+// RenameColumn renames a column of the table being altered
+func (t *Table) RenameColumn(from, to string) {
+	t.renames = append(t.renames, columnRename{from: from, to: to})
+}
+
+// DropColumn drops columns from the table being altered
+func (t *Table) DropColumn(names ...string) {
+	t.dropColumns = append(t.dropColumns, names...)
+}
+
+// DropIndex drops an index, unique or not, from the table being altered
+func (t *Table) DropIndex(name string) {
+	t.dropIndexes = append(t.dropIndexes, name)
+}
+
+// DropForeign drops the foreign key on columns from the table being altered
+func (t *Table) DropForeign(columns ...string) {
+	t.dropForeigns = append(t.dropForeigns, columns)
+}
+
+// addColumn appends a column definition
+func (t *Table) addColumn(name string, typ columnType) *Column {
+	c := &Column{table: t, name: name, typ: typ}
+	t.columns = append(t.columns, c)
+	return c
+}
+
+// column returns the column definition with a name, or nil
+func (t *Table) column(name string) *Column {
+	for _, c := range t.columns {
+		if c.name == name {
+			return c
+		}
+	}
+	return nil
+}
+
+// Nullable allows the column to hold NULL
+func (c *Column) Nullable() *Column {
+	c.nullable = true
+	return c
+}
+
+// Default sets the column's default to a string, number, bool or time value
+func (c *Column) Default(value interface{}) *Column {
+	c.def = sqlLiteral(value)
+	return c
+}
+
+// DefaultNow sets the column's default to the current timestamp
+func (c *Column) DefaultNow() *Column {
+	c.def = "CURRENT_TIMESTAMP"
+	return c
+}
+
+// Primary makes the column the table's primary key
+func (c *Column) Primary() *Column {
+	c.primary = true
+	return c
+}
+
+// Unique adds a unique index on the column
+func (c *Column) Unique() *Column {
+	c.table.Unique(c.name)
+	return c
+}
+
+// Index adds an index on the column
+func (c *Column) Index() *Column {
+	c.table.Index(c.name)
+	return c
+}
+
+// Change marks the column as a change to an existing column, when altering a table,
+// replacing its type, nullability and default with this definition
+func (c *Column) Change() *Column {
+	c.change = true
+	return c
+}
+
+// References adds a foreign key on the column to a table's column, id unless one is given
+func (c *Column) References(table string, column ...string) *ForeignKey {
+	fk := c.table.Foreign(c.name).References(table, column...)
+	fk.column = c
+	return fk
+}
+
+// Name sets the index's name
+func (i *Index) Name(name string) *Index {
+	i.name = name
+	return i
+}
+
+// indexName returns the index's name, or the default for its table and columns
+func (i *Index) indexName(table string) string {
+	if i.name != "" {
+		return i.name
+	}
+	prefix := "idx_"
+	if i.unique {
+		prefix = "uniq_"
+	}
+	return prefix + table + "_" + strings.Join(i.columns, "_")
+}
+
+// References sets the table and columns the foreign key references, id unless columns are given
+func (fk *ForeignKey) References(table string, columns ...string) *ForeignKey {
+	if len(columns) == 0 {
+		columns = []string{"id"}
+	}
+	fk.table, fk.references = table, columns
+	return fk
+}
+
+// Name sets the foreign key's constraint name
+func (fk *ForeignKey) Name(name string) *ForeignKey {
+	fk.name = name
+	return fk
+}
+
+// OnDelete sets the action taken when the referenced row is deleted, such as "CASCADE"
+func (fk *ForeignKey) OnDelete(action string) *ForeignKey {
+	fk.onDelete = action
+	return fk
+}
+
+// OnUpdate sets the action taken when the referenced key is updated, such as "CASCADE"
+func (fk *ForeignKey) OnUpdate(action string) *ForeignKey {
+	fk.onUpdate = action
+	return fk
+}
+
+// CascadeOnDelete deletes rows when the row they reference is deleted
+func (fk *ForeignKey) CascadeOnDelete() *ForeignKey {
+	return fk.OnDelete("CASCADE")
+}
+
+// RestrictOnDelete prevents deleting a row that is referenced
+func (fk *ForeignKey) RestrictOnDelete() *ForeignKey {
+	return fk.OnDelete("RESTRICT")
+}
+
+// NullOnDelete sets the foreign key to NULL when the row it references is deleted,
+// making its column nullable
+func (fk *ForeignKey) NullOnDelete() *ForeignKey {
+	if fk.column != nil {
+		fk.column.Nullable()
+	}
+	return fk.OnDelete("SET NULL")
+}
+
+// CascadeOnUpdate updates the foreign key when the key it references changes
+func (fk *ForeignKey) CascadeOnUpdate() *ForeignKey {
+	return fk.OnUpdate("CASCADE")
+}
+
+// foreignName returns the foreign key's name, or the default for its table and columns
+func (fk *ForeignKey) foreignName(table string) string {
+	if fk.name != "" {
+		return fk.name
+	}
+	return "fk_" + table + "_" + strings.Join(fk.columns, "_")
+}
+
+// sqlLiteral renders a default value as a SQL literal
+func sqlLiteral(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "NULL"
+	case string:
+		return "'" + strings.ReplaceAll(v, "'", "''") + "'"
+	case bool:
+		if v {
+			return "TRUE"
+		}
+		return "FALSE"
+	case time.Time:
+		return "'" + v.UTC().Format("2006-01-02 15:04:05") + "'"
+	default:
+		return fmt.Sprint(v)
+	}
+}
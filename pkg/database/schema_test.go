@@ -0,0 +1,297 @@
+package database
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// definePages defines the pages table the schema tests create
+func definePages(t *Table) {
+	t.ID()
+	t.String("slug").Unique()
+	t.String("title", 100).Default("Untitled")
+	t.ForeignID("parent_id").References("pages").NullOnDelete()
+	t.Boolean("published").Default(false).Index()
+	t.Timestamps()
+}
+
+func TestSchemaGrammar_Create(t *testing.T) {
+	pages := &Table{name: "pages"}
+	definePages(pages)
+
+	assert.Equal(t, []string{
+		"CREATE TABLE \"pages\" (\n" +
+			"\t\"id\" INTEGER PRIMARY KEY AUTOINCREMENT,\n" +
+			"\t\"slug\" VARCHAR(255) NOT NULL,\n" +
+			"\t\"title\" VARCHAR(100) NOT NULL DEFAULT 'Untitled',\n" +
+			"\t\"parent_id\" BIGINT NULL,\n" +
+			"\t\"published\" BOOLEAN NOT NULL DEFAULT FALSE,\n" +
+			"\t\"created_at\" TIMESTAMP NULL DEFAULT CURRENT_TIMESTAMP,\n" +
+			"\t\"updated_at\" TIMESTAMP NULL DEFAULT CURRENT_TIMESTAMP,\n" +
+			"\tCONSTRAINT \"fk_pages_parent_id\" FOREIGN KEY (\"parent_id\") REFERENCES \"pages\" (\"id\") ON DELETE SET NULL\n" +
+			")",
+		`CREATE UNIQUE INDEX "uniq_pages_slug" ON "pages" ("slug")`,
+		`CREATE INDEX "idx_pages_published" ON "pages" ("published")`,
+	}, schemaGrammar{d: DialectFor("sqlite")}.createTable(pages, "pages"))
+
+	mysql := schemaGrammar{d: DialectFor("mysql")}.createTable(pages, "pages")
+	assert.Contains(t, mysql[0], "`id` BIGINT NOT NULL AUTO_INCREMENT PRIMARY KEY,")
+	assert.Contains(t, mysql[0], "CONSTRAINT `fk_pages_parent_id` FOREIGN KEY (`parent_id`) REFERENCES `pages` (`id`) ON DELETE SET NULL")
+	assert.Equal(t, "CREATE UNIQUE INDEX `uniq_pages_slug` ON `pages` (`slug`)", mysql[1])
+
+	postgres := schemaGrammar{d: DialectFor("postgres")}.createTable(pages, "pages")
+	assert.Contains(t, postgres[0], `"id" BIGSERIAL PRIMARY KEY,`)
+	assert.Contains(t, postgres[0], `"published" BOOLEAN NOT NULL DEFAULT FALSE,`)
+
+	// A composite primary key is a table constraint
+	revisions := &Table{name: "revisions"}
+	revisions.String("page_slug")
+	revisions.Integer("version")
+	revisions.JSON("data")
+	revisions.Primary("page_slug", "version")
+	assert.Equal(t, "CREATE TABLE `revisions` (\n"+
+		"\t`page_slug` VARCHAR(255) NOT NULL,\n"+
+		"\t`version` INT NOT NULL,\n"+
+		"\t`data` JSON NOT NULL,\n"+
+		"\tPRIMARY KEY (`page_slug`, `version`)\n"+
+		")", schemaGrammar{d: DialectFor("mysql")}.createTable(revisions, "revisions")[0])
+}
+
+func TestSchemaGrammar_Alter(t *testing.T) {
+	alter := &Table{name: "pages"}
+	alter.RenameColumn("title", "heading")
+	alter.DropForeign("parent_id")
+	alter.DropIndex("idx_pages_published")
+	alter.DropColumn("published")
+	alter.Text("body").Nullable()
+	alter.String("heading", 200).Change()
+	alter.ForeignID("author_id").Nullable().References("users").CascadeOnDelete()
+
+	assert.Equal(t, []string{
+		"ALTER TABLE `pages` RENAME COLUMN `title` TO `heading`",
+		"ALTER TABLE `pages` DROP FOREIGN KEY `fk_pages_parent_id`",
+		"DROP INDEX `idx_pages_published` ON `pages`",
+		"ALTER TABLE `pages` DROP COLUMN `published`",
+		"ALTER TABLE `pages` ADD COLUMN `body` TEXT NULL",
+		"ALTER TABLE `pages` MODIFY COLUMN `heading` VARCHAR(200) NOT NULL",
+		"ALTER TABLE `pages` ADD COLUMN `author_id` BIGINT NULL",
+		"ALTER TABLE `pages` ADD CONSTRAINT `fk_pages_author_id` FOREIGN KEY (`author_id`) REFERENCES `users` (`id`) ON DELETE CASCADE",
+	}, schemaGrammar{d: DialectFor("mysql")}.alterTable(alter))
+
+	assert.Equal(t, []string{
+		`ALTER TABLE "pages" RENAME COLUMN "title" TO "heading"`,
+		`ALTER TABLE "pages" DROP CONSTRAINT "fk_pages_parent_id"`,
+		`DROP INDEX "idx_pages_published"`,
+		`ALTER TABLE "pages" DROP COLUMN "published"`,
+		`ALTER TABLE "pages" ADD COLUMN "body" TEXT NULL`,
+		`ALTER TABLE "pages" ALTER COLUMN "heading" TYPE VARCHAR(200) USING "heading"::VARCHAR(200)`,
+		`ALTER TABLE "pages" ALTER COLUMN "heading" SET NOT NULL`,
+		`ALTER TABLE "pages" ALTER COLUMN "heading" DROP DEFAULT`,
+		`ALTER TABLE "pages" ADD COLUMN "author_id" BIGINT NULL`,
+		`ALTER TABLE "pages" ADD CONSTRAINT "fk_pages_author_id" FOREIGN KEY ("author_id") REFERENCES "users" ("id") ON DELETE CASCADE`,
+	}, schemaGrammar{d: DialectFor("postgres")}.alterTable(alter))
+
+	assert.True(t, needsRebuild(alter))
+	inPlace := &Table{name: "pages"}
+	inPlace.RenameColumn("title", "heading")
+	inPlace.Text("body").Nullable().Unique()
+	inPlace.Integer("views").Default(0)
+	inPlace.DropIndex("idx_pages_published")
+	assert.False(t, needsRebuild(inPlace))
+}
+
+func TestSchema_SQLite(t *testing.T) {
+	db := newMigrationDB(t)
+	schema := NewSchema(db)
+
+	require.NoError(t, schema.Create("users", func(t *Table) {
+		t.ID()
+		t.String("email")
+	}))
+	require.NoError(t, schema.Create("pages", definePages))
+	ok, err := schema.HasTable("pages")
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	_, err = db.Exec("INSERT INTO users (email) VALUES ('a@example.com')")
+	require.NoError(t, err)
+	_, err = db.Exec("INSERT INTO pages (slug) VALUES ('home'), ('about'), ('gone')")
+	require.NoError(t, err)
+	_, err = db.Exec("UPDATE pages SET parent_id = 1 WHERE slug = 'about'")
+	require.NoError(t, err)
+	_, err = db.Exec("DELETE FROM pages WHERE slug = 'gone'")
+	require.NoError(t, err)
+	_, err = db.Exec("INSERT INTO pages (slug) VALUES ('home')")
+	assert.Error(t, err, "slug is unique")
+
+	// Renaming and adding a nullable column happen in place
+	require.NoError(t, schema.Alter("pages", func(t *Table) {
+		t.RenameColumn("title", "heading")
+		t.Text("body").Nullable()
+	}))
+
+	// Changing a column, dropping one and adding a foreign key rebuild the table
+	require.NoError(t, schema.Alter("pages", func(t *Table) {
+		t.Text("heading").Default("").Change()
+		t.DropColumn("published")
+		t.ForeignID("author_id").Nullable().References("users").CascadeOnDelete()
+		t.Integer("views").Default(0).Index()
+	}))
+
+	var slug, heading string
+	var parentID *int64
+	require.NoError(t, db.QueryRow("SELECT slug, heading, parent_id FROM pages WHERE id = 2").Scan(&slug, &heading, &parentID))
+	assert.Equal(t, "about", slug)
+	assert.Equal(t, "Untitled", heading)
+	require.NotNil(t, parentID)
+	assert.Equal(t, int64(1), *parentID)
+
+	ok, err = schema.HasColumn("pages", "published")
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	var indexes []string
+	rows, err := db.Query("SELECT name FROM sqlite_master WHERE type = 'index' AND tbl_name = 'pages' AND sql IS NOT NULL ORDER BY name")
+	require.NoError(t, err)
+	for rows.Next() {
+		var name string
+		require.NoError(t, rows.Scan(&name))
+		indexes = append(indexes, name)
+	}
+	require.NoError(t, rows.Close())
+	assert.Equal(t, []string{"idx_pages_views", "uniq_pages_slug"}, indexes, "indexes on dropped columns go with them")
+
+	var foreign []string
+	rows, err = db.Query(`SELECT "from" || '->' || "table" || ':' || on_delete FROM pragma_foreign_key_list('pages') ORDER BY "from"`)
+	require.NoError(t, err)
+	for rows.Next() {
+		var fk string
+		require.NoError(t, rows.Scan(&fk))
+		foreign = append(foreign, fk)
+	}
+	require.NoError(t, rows.Close())
+	assert.Equal(t, []string{"author_id->users:CASCADE", "parent_id->pages:SET NULL"}, foreign)
+
+	// The AUTOINCREMENT counter survives, so the deleted page's ID isn't reused
+	_, err = db.Exec("INSERT INTO pages (slug) VALUES ('new')")
+	require.NoError(t, err)
+	var id int64
+	require.NoError(t, db.QueryRow("SELECT id FROM pages WHERE slug = 'new'").Scan(&id))
+	assert.Equal(t, int64(4), id)
+
+	require.NoError(t, schema.Alter("pages", func(t *Table) {
+		t.DropForeign("author_id")
+		t.DropIndex("idx_pages_views")
+	}))
+	require.NoError(t, db.QueryRow("SELECT COUNT(*) FROM pragma_foreign_key_list('pages')").Scan(&id))
+	assert.Equal(t, int64(1), id)
+
+	assert.ErrorContains(t, schema.Alter("pages", func(t *Table) { t.DropColumn("missing") }), "no column missing to drop")
+	require.NoError(t, schema.Rename("pages", "posts"))
+	require.NoError(t, schema.Drop("posts"))
+	require.NoError(t, schema.DropIfExists("posts"))
+}
+
+func TestSchema_RebuildNeedsForeignKeysOff(t *testing.T) {
+	db, err := New(&Config{Driver: "sqlite", SQLitePath: "file::memory:?_foreign_keys=on", MaxOpenConns: 1, MaxIdleConns: 1})
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	schema := NewSchema(db)
+	require.NoError(t, schema.Create("pages", definePages))
+	err = schema.Alter("pages", func(t *Table) { t.DropColumn("title") })
+	assert.ErrorContains(t, err, "foreign key enforcement")
+}
+
+func TestMigrationRunner_Schema(t *testing.T) {
+	db := newMigrationDB(t)
+	runner := NewMigrationRunner(db)
+	runner.AddMigration(Migration{
+		Version:     "001_create_pages",
+		Description: "Create pages",
+		UpSchema:    func(s *Schema) error { return s.Create("pages", definePages) },
+		DownSchema:  func(s *Schema) error { return s.Drop("pages") },
+	})
+	runner.AddMigration(Migration{
+		Version:     "002_add_page_body",
+		Description: "Add page body",
+		UpSchema: func(s *Schema) error {
+			return s.Alter("pages", func(t *Table) { t.Text("body").Default("") })
+		},
+		DownSchema: func(s *Schema) error {
+			return s.Alter("pages", func(t *Table) { t.DropColumn("body") })
+		},
+	})
+	require.NoError(t, runner.Run())
+
+	schema := NewSchema(db).WithContext(context.Background())
+	ok, err := schema.HasColumn("pages", "body")
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	require.NoError(t, runner.Rollback(1))
+	ok, err = schema.HasColumn("pages", "body")
+	require.NoError(t, err)
+	assert.False(t, ok)
+	require.NoError(t, runner.Rollback(1))
+	ok, err = schema.HasTable("pages")
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func TestMigrationRunner_SchemaDrift(t *testing.T) {
+	db := newMigrationDB(t)
+	migration := Migration{
+		Version:     "001_create_pages",
+		Description: "Create pages",
+		UpSchema:    func(s *Schema) error { return s.Create("pages", definePages) },
+	}
+	runner := NewMigrationRunner(db)
+	runner.AddMigration(migration)
+	require.NoError(t, runner.Run())
+
+	drifted, err := runner.Verify()
+	require.NoError(t, err)
+	assert.Empty(t, drifted)
+
+	// Editing the schema definition is drift
+	migration.UpSchema = func(s *Schema) error {
+		return s.Create("pages", func(t *Table) {
+			definePages(t)
+			t.Text("body")
+		})
+	}
+	runner = NewMigrationRunner(db)
+	runner.AddMigration(migration)
+	drifted, err = runner.Verify()
+	require.NoError(t, err)
+	require.Len(t, drifted, 1)
+	assert.Equal(t, "001_create_pages", drifted[0].Version)
+}
+
+func TestMigrationRunner_RequiresContentForGoMigrations(t *testing.T) {
+	db := newMigrationDB(t)
+	runner := NewMigrationRunner(db)
+	runner.AddMigration(Migration{Version: "001_go", Description: "Go", Up: noopMigration})
+	assert.ErrorContains(t, runner.Run(), "no Content")
+}
+
+func TestMigrationRunner_IgnoresQueryTimeout(t *testing.T) {
+	db, err := New(&Config{Driver: "sqlite", SQLitePath: ":memory:", MaxOpenConns: 1, MaxIdleConns: 1, QueryTimeout: time.Nanosecond})
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	runner := NewMigrationRunner(db)
+	runner.AddMigration(Migration{
+		Version:     "001_create_pages",
+		Description: "Create pages",
+		UpSchema:    func(s *Schema) error { return s.Create("pages", definePages) },
+		DownSchema:  func(s *Schema) error { return s.Drop("pages") },
+	})
+	require.NoError(t, runner.Run(), "migrations outlive the statement timeout")
+	require.NoError(t, runner.Rollback(1))
+}
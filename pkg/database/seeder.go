@@ -2,6 +2,7 @@ package database
 
 import (
 	"fmt"
+	"log/slog"
 	"sort"
 )
 
@@ -39,13 +40,13 @@ func (r *SeederRunner) Run() error {
 	})
 
 	for _, seeder := range r.seeders {
-		fmt.Printf("Running seeder: %s\n", seeder.Description)
+		slog.Info("Running seeder", "seeder", seeder.Name, "description", seeder.Description)
 		
 		if err := seeder.Run(r.db); err != nil {
 			return fmt.Errorf("failed to run seeder %s: %w", seeder.Name, err)
 		}
 		
-		fmt.Printf("Seeder %s completed successfully\n", seeder.Name)
+		slog.Info("Seeder completed", "seeder", seeder.Name)
 	}
 
 	return nil
@@ -64,13 +65,13 @@ func (r *SeederRunner) RunSpecific(names ...string) error {
 			return fmt.Errorf("seeder %s not found", name)
 		}
 
-		fmt.Printf("Running seeder: %s\n", seeder.Description)
+		slog.Info("Running seeder", "seeder", seeder.Name, "description", seeder.Description)
 		
 		if err := seeder.Run(r.db); err != nil {
 			return fmt.Errorf("failed to run seeder %s: %w", name, err)
 		}
 		
-		fmt.Printf("Seeder %s completed successfully\n", name)
+		slog.Info("Seeder completed", "seeder", name)
 	}
 
 	return nil
@@ -0,0 +1,124 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// Tx is a transaction whose queries are rebound to the database's dialect and traced like DB queries
+type Tx struct {
+	*sql.Tx
+	db  *DB
+	ctx context.Context // Carries the transaction, see Context
+
+	// committed holds the functions to call once the transaction commits
+	committed []func()
+}
+
+// txContextKey is the context key of the transaction a context carries
+type txContextKey struct{}
+
+// TxFromContext returns the transaction carried by a context, if there is one
+func TxFromContext(ctx context.Context) (*Tx, bool) {
+	tx, ok := ctx.Value(txContextKey{}).(*Tx)
+	return tx, ok
+}
+
+// Context returns the context the transaction was started with, carrying the transaction.
+// Queries made with it through the DB, a Query or a Repository run in the transaction.
+func (tx *Tx) Context() context.Context {
+	if tx.ctx == nil {
+		return context.WithValue(context.Background(), txContextKey{}, tx)
+	}
+	return tx.ctx
+}
+
+// Dialect returns the SQL dialect of the transaction's database
+func (tx *Tx) Dialect() Dialect {
+	return tx.db.Dialect()
+}
+
+// afterCommit calls fn once the transaction commits. A transaction that rolls back never calls it.
+func (tx *Tx) afterCommit(fn func()) {
+	tx.committed = append(tx.committed, fn)
+}
+
+// Exec executes a query without returning any rows, with the transaction's context
+func (tx *Tx) Exec(query string, args ...interface{}) (sql.Result, error) {
+	return tx.ExecContext(tx.Context(), query, args...)
+}
+
+// Query executes a query that returns rows, with the transaction's context
+func (tx *Tx) Query(query string, args ...interface{}) (*Rows, error) {
+	return tx.QueryContext(tx.Context(), query, args...)
+}
+
+// QueryRow executes a query that is expected to return at most one row, with the transaction's context
+func (tx *Tx) QueryRow(query string, args ...interface{}) *Row {
+	return tx.QueryRowContext(tx.Context(), query, args...)
+}
+
+// ExecContext executes a query without returning any rows, recording a span
+func (tx *Tx) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	ctx, span := tx.db.startSpan(ctx, query)
+	defer span.End()
+	ctx, cancel := tx.db.withTimeout(ctx)
+	defer cancel()
+
+	result, err := tx.Tx.ExecContext(ctx, tx.db.Rebind(query), args...)
+	span.RecordError(err)
+	return result, err
+}
+
+// QueryContext executes a query that returns rows, recording a span
+func (tx *Tx) QueryContext(ctx context.Context, query string, args ...interface{}) (*Rows, error) {
+	ctx, span := tx.db.startSpan(ctx, query)
+	defer span.End()
+	ctx, cancel := tx.db.withTimeout(ctx)
+
+	rows, err := tx.Tx.QueryContext(ctx, tx.db.Rebind(query), args...)
+	span.RecordError(err)
+	return newRows(rows, cancel, err)
+}
+
+// QueryRowContext executes a query that is expected to return at most one row, recording a span
+func (tx *Tx) QueryRowContext(ctx context.Context, query string, args ...interface{}) *Row {
+	ctx, span := tx.db.startSpan(ctx, query)
+	defer span.End()
+	ctx, cancel := tx.db.withTimeout(ctx)
+
+	row := tx.Tx.QueryRowContext(ctx, tx.db.Rebind(query), args...)
+	span.RecordError(row.Err())
+	return &Row{Row: row, cancel: cancel}
+}
+
+// InsertID runs an INSERT and returns the generated value of its id column
+func (tx *Tx) InsertID(ctx context.Context, query, idColumn string, args ...interface{}) (int64, error) {
+	return insertID(ctx, tx, tx.Dialect(), query, idColumn, args...)
+}
+
+// execer is implemented by DB and Tx
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *Row
+}
+
+// insertID runs an INSERT, emulating LastInsertId with RETURNING where the driver needs it
+func insertID(ctx context.Context, e execer, d Dialect, query, idColumn string, args ...interface{}) (int64, error) {
+	if d.SupportsReturning() {
+		var id int64
+		err := e.QueryRowContext(ctx, query+" RETURNING "+QuoteIdentifier(d, idColumn), args...).Scan(&id)
+		return id, err
+	}
+
+	result, err := e.ExecContext(ctx, query, args...)
+	if err != nil {
+		return 0, err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get inserted id: %w", err)
+	}
+	return id, nil
+}
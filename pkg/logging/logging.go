@@ -0,0 +1,269 @@
+// Package logging provides structured slog logging with per-plugin levels
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/btassone/obtura/pkg/tracing"
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// Output formats
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
+// Levels lists the level names accepted by ParseLevel, lowest first
+var Levels = []string{"debug", "info", "warn", "error"}
+
+// minLevel lets every record through the base handler; filtering is done per logger
+const minLevel = slog.Level(-100)
+
+// Config holds logging configuration
+type Config struct {
+	Level  string // debug, info, warn, error
+	Format string // text, json
+}
+
+// DefaultConfig returns the default logging configuration
+func DefaultConfig() *Config {
+	return &Config{
+		Level:  "info",
+		Format: FormatText,
+	}
+}
+
+// ParseLevel converts a level name to a slog.Level
+func ParseLevel(name string) (slog.Level, error) {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(strings.TrimSpace(name))); err != nil {
+		return slog.LevelInfo, fmt.Errorf("invalid log level %q", name)
+	}
+	return level, nil
+}
+
+// LevelName returns the lower-case name of a level
+func LevelName(level slog.Level) string {
+	return strings.ToLower(level.String())
+}
+
+// Manager builds loggers that share one output and tracks per-plugin levels
+type Manager struct {
+	handler slog.Handler
+	level   *slog.LevelVar
+
+	mu     sync.RWMutex
+	levels map[string]*slog.LevelVar
+}
+
+// NewManager creates a logging manager writing to w
+func NewManager(config *Config, w io.Writer) (*Manager, error) {
+	if config == nil {
+		config = DefaultConfig()
+	}
+
+	level, err := ParseLevel(config.Level)
+	if err != nil {
+		return nil, err
+	}
+
+	options := &slog.HandlerOptions{Level: minLevel}
+	var handler slog.Handler
+	switch config.Format {
+	case FormatJSON:
+		handler = slog.NewJSONHandler(w, options)
+	case FormatText, "":
+		handler = slog.NewTextHandler(w, options)
+	default:
+		return nil, fmt.Errorf("invalid log format %q", config.Format)
+	}
+
+	m := &Manager{
+		handler: &contextHandler{next: handler},
+		level:   new(slog.LevelVar),
+		levels:  make(map[string]*slog.LevelVar),
+	}
+	m.level.Set(level)
+	return m, nil
+}
+
+// Logger returns the core logger
+func (m *Manager) Logger() *slog.Logger {
+	return slog.New(&levelHandler{leveler: m.level, next: m.handler})
+}
+
+// PluginLogger returns a logger tagged with a plugin ID that honours the plugin's level
+func (m *Manager) PluginLogger(pluginID string) *slog.Logger {
+	handler := &levelHandler{leveler: pluginLeveler{m, pluginID}, next: m.handler}
+	return slog.New(handler).With(slog.String("plugin", pluginID))
+}
+
+// Level returns the global log level
+func (m *Manager) Level() slog.Level {
+	return m.level.Level()
+}
+
+// SetLevel sets the global log level
+func (m *Manager) SetLevel(level slog.Level) {
+	m.level.Set(level)
+}
+
+// PluginLevel returns a plugin's effective level and whether it overrides the global level
+func (m *Manager) PluginLevel(pluginID string) (slog.Level, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if v, ok := m.levels[pluginID]; ok {
+		return v.Level(), true
+	}
+	return m.level.Level(), false
+}
+
+// SetPluginLevel overrides the log level for a plugin
+func (m *Manager) SetPluginLevel(pluginID string, level slog.Level) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	v, ok := m.levels[pluginID]
+	if !ok {
+		v = new(slog.LevelVar)
+		m.levels[pluginID] = v
+	}
+	v.Set(level)
+}
+
+// ResetPluginLevel makes a plugin follow the global level again
+func (m *Manager) ResetPluginLevel(pluginID string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.levels, pluginID)
+}
+
+// PluginLevels returns all per-plugin level overrides
+func (m *Manager) PluginLevels() map[string]slog.Level {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	levels := make(map[string]slog.Level, len(m.levels))
+	for id, v := range m.levels {
+		levels[id] = v.Level()
+	}
+	return levels
+}
+
+// pluginLeveler resolves a plugin's level at log time so runtime changes apply immediately
+type pluginLeveler struct {
+	m        *Manager
+	pluginID string
+}
+
+func (l pluginLeveler) Level() slog.Level {
+	level, _ := l.m.PluginLevel(l.pluginID)
+	return level
+}
+
+// levelHandler filters records below a dynamic level
+type levelHandler struct {
+	leveler slog.Leveler
+	next    slog.Handler
+}
+
+func (h *levelHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return level >= h.leveler.Level() && h.next.Enabled(ctx, level)
+}
+
+func (h *levelHandler) Handle(ctx context.Context, r slog.Record) error {
+	return h.next.Handle(ctx, r)
+}
+
+func (h *levelHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &levelHandler{leveler: h.leveler, next: h.next.WithAttrs(attrs)}
+}
+
+func (h *levelHandler) WithGroup(name string) slog.Handler {
+	return &levelHandler{leveler: h.leveler, next: h.next.WithGroup(name)}
+}
+
+// contextHandler adds the request ID and trace IDs from the context to every record
+type contextHandler struct {
+	next slog.Handler
+}
+
+func (h *contextHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+func (h *contextHandler) Handle(ctx context.Context, r slog.Record) error {
+	if ctx != nil {
+		if id := middleware.GetReqID(ctx); id != "" {
+			r.AddAttrs(slog.String("request_id", id))
+		}
+		if sc := tracing.SpanContextFromContext(ctx); sc.IsValid() {
+			r.AddAttrs(slog.String("trace_id", sc.TraceID.String()), slog.String("span_id", sc.SpanID.String()))
+		}
+	}
+	return h.next.Handle(ctx, r)
+}
+
+func (h *contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &contextHandler{next: h.next.WithAttrs(attrs)}
+}
+
+func (h *contextHandler) WithGroup(name string) slog.Handler {
+	return &contextHandler{next: h.next.WithGroup(name)}
+}
+
+var (
+	defaultMu      sync.RWMutex
+	defaultManager = mustManager(NewManager(DefaultConfig(), os.Stderr))
+)
+
+func mustManager(m *Manager, err error) *Manager {
+	if err != nil {
+		panic(err)
+	}
+	return m
+}
+
+// Setup configures the default manager and installs its logger as the slog default
+func Setup(config *Config) (*Manager, error) {
+	m, err := NewManager(config, os.Stderr)
+	if err != nil {
+		return nil, err
+	}
+
+	defaultMu.Lock()
+	defaultManager = m
+	defaultMu.Unlock()
+
+	slog.SetDefault(m.Logger())
+	return m, nil
+}
+
+// Default returns the default logging manager
+func Default() *Manager {
+	defaultMu.RLock()
+	defer defaultMu.RUnlock()
+	return defaultManager
+}
+
+type contextKey string
+
+const loggerContextKey contextKey = "logger"
+
+// WithLogger returns a context carrying a logger
+func WithLogger(ctx context.Context, logger *slog.Logger) context.Context {
+	return context.WithValue(ctx, loggerContextKey, logger)
+}
+
+// FromContext returns the logger carried by ctx, or the default logger if none is set
+func FromContext(ctx context.Context) *slog.Logger {
+	if logger, ok := ctx.Value(loggerContextKey).(*slog.Logger); ok {
+		return logger
+	}
+	return slog.Default()
+}
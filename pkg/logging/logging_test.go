@@ -0,0 +1,100 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func decodeLines(t *testing.T, buf *bytes.Buffer) []map[string]interface{} {
+	var records []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var record map[string]interface{}
+		require.NoError(t, json.Unmarshal([]byte(line), &record))
+		records = append(records, record)
+	}
+	return records
+}
+
+func TestNewManager_InvalidConfig(t *testing.T) {
+	_, err := NewManager(&Config{Level: "loud", Format: FormatText}, &bytes.Buffer{})
+	assert.Error(t, err)
+
+	_, err = NewManager(&Config{Level: "info", Format: "xml"}, &bytes.Buffer{})
+	assert.Error(t, err)
+}
+
+func TestManager_PluginLevels(t *testing.T) {
+	var buf bytes.Buffer
+	m, err := NewManager(&Config{Level: "info", Format: FormatJSON}, &buf)
+	require.NoError(t, err)
+
+	logger := m.PluginLogger("test.plugin")
+	logger.Debug("hidden")
+	logger.Info("shown")
+
+	records := decodeLines(t, &buf)
+	require.Len(t, records, 1)
+	assert.Equal(t, "shown", records[0]["msg"])
+	assert.Equal(t, "test.plugin", records[0]["plugin"])
+
+	// Raising verbosity at runtime affects existing loggers for that plugin only
+	m.SetPluginLevel("test.plugin", slog.LevelDebug)
+	buf.Reset()
+	logger.Debug("now shown")
+	m.PluginLogger("other.plugin").Debug("still hidden")
+	records = decodeLines(t, &buf)
+	require.Len(t, records, 1)
+	assert.Equal(t, "now shown", records[0]["msg"])
+
+	level, overridden := m.PluginLevel("test.plugin")
+	assert.Equal(t, slog.LevelDebug, level)
+	assert.True(t, overridden)
+
+	m.ResetPluginLevel("test.plugin")
+	level, overridden = m.PluginLevel("test.plugin")
+	assert.Equal(t, slog.LevelInfo, level)
+	assert.False(t, overridden)
+}
+
+func TestRequestLogger(t *testing.T) {
+	var buf bytes.Buffer
+	m, err := NewManager(&Config{Level: "info", Format: FormatJSON}, &buf)
+	require.NoError(t, err)
+
+	handler := middleware.RequestID(RequestLogger(m.Logger())(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		FromContext(r.Context()).InfoContext(r.Context(), "handling")
+		w.WriteHeader(http.StatusTeapot)
+	})))
+
+	req := httptest.NewRequest(http.MethodGet, "/brew", nil)
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	records := decodeLines(t, &buf)
+	require.Len(t, records, 2)
+
+	requestID := records[0]["request_id"]
+	assert.NotEmpty(t, requestID)
+	assert.Equal(t, requestID, records[1]["request_id"])
+
+	assert.Equal(t, "request", records[1]["msg"])
+	assert.Equal(t, "WARN", records[1]["level"])
+	assert.Equal(t, float64(http.StatusTeapot), records[1]["status"])
+	assert.Equal(t, "/brew", records[1]["path"])
+}
+
+func TestFromContext_FallsBackToDefault(t *testing.T) {
+	assert.Equal(t, slog.Default(), FromContext(context.Background()))
+}
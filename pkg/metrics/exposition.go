@@ -0,0 +1,126 @@
+package metrics
+
+import (
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ContentType is the Prometheus text exposition format content type
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// WriteText writes all metrics in Prometheus text exposition format
+func (r *Registry) WriteText(w io.Writer) error {
+	r.mu.RLock()
+	collectors := make([]collector, 0, len(r.metrics))
+	for _, c := range r.metrics {
+		collectors = append(collectors, c)
+	}
+	r.mu.RUnlock()
+
+	sort.Slice(collectors, func(i, j int) bool {
+		return collectors[i].describe().name < collectors[j].describe().name
+	})
+
+	var b strings.Builder
+	for _, c := range collectors {
+		d := c.describe()
+		b.WriteString("# HELP " + d.name + " " + escapeHelp(d.help) + "\n")
+		b.WriteString("# TYPE " + d.name + " " + d.typ + "\n")
+		c.write(&b)
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func (v *valueVec) write(b *strings.Builder) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	for _, key := range sortedKeys(v.series) {
+		s := v.series[key]
+		writeSample(b, v.d.name, v.d.labels, s.labels, "", "", s.value)
+	}
+}
+
+func (h *Histogram) write(b *strings.Builder) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for _, key := range sortedKeys(h.series) {
+		s := h.series[key]
+		var cumulative uint64
+		for i, upper := range h.buckets {
+			cumulative += s.counts[i]
+			writeSample(b, h.name+"_bucket", h.labels, s.labels, "le", formatFloat(upper), float64(cumulative))
+		}
+		writeSample(b, h.name+"_bucket", h.labels, s.labels, "le", "+Inf", float64(s.count))
+		writeSample(b, h.name+"_sum", h.labels, s.labels, "", "", s.sum)
+		writeSample(b, h.name+"_count", h.labels, s.labels, "", "", float64(s.count))
+	}
+}
+
+func (f *funcMetric) write(b *strings.Builder) {
+	writeSample(b, f.name, nil, nil, "", "", f.value())
+}
+
+// writeSample writes one sample line, with an optional extra label (used for le)
+func writeSample(b *strings.Builder, name string, labelNames, labelValues []string, extraName, extraValue string, value float64) {
+	b.WriteString(name)
+	if len(labelNames) > 0 || extraName != "" {
+		b.WriteByte('{')
+		for i, label := range labelNames {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			b.WriteString(label + `="` + escapeLabelValue(labelValues[i]) + `"`)
+		}
+		if extraName != "" {
+			if len(labelNames) > 0 {
+				b.WriteByte(',')
+			}
+			b.WriteString(extraName + `="` + extraValue + `"`)
+		}
+		b.WriteByte('}')
+	}
+	b.WriteByte(' ')
+	b.WriteString(formatFloat(value))
+	b.WriteByte('\n')
+}
+
+func sortedKeys[T any](m map[string]T) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+var (
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	labelEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+)
+
+func escapeHelp(s string) string {
+	return helpEscaper.Replace(s)
+}
+
+func escapeLabelValue(s string) string {
+	return labelEscaper.Replace(s)
+}
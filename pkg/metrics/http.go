@@ -0,0 +1,182 @@
+package metrics
+
+import (
+	"context"
+	"crypto/subtle"
+	"database/sql"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// HandlerOptions controls access to the metrics endpoint
+type HandlerOptions struct {
+	// Token, if set, is accepted as "Authorization: Bearer <token>"
+	Token string
+	// AllowedIPs lists IPs or CIDR ranges allowed to scrape without a token. They are matched
+	// against the connection's peer address recorded by PeerAddr, never against proxy headers.
+	AllowedIPs []string
+}
+
+// peerAddrKey is the context key for the address recorded by PeerAddr
+type peerAddrKey struct{}
+
+// PeerAddr records the address of the connection's peer before middleware such as chi's RealIP
+// replaces RemoteAddr with one taken from request headers, which clients can forge. Use it ahead
+// of such middleware so the metrics allowlist checks the peer that actually connected.
+func PeerAddr(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		ctx := context.WithValue(req.Context(), peerAddrKey{}, req.RemoteAddr)
+		next.ServeHTTP(w, req.WithContext(ctx))
+	})
+}
+
+// peerAddr returns the address recorded by PeerAddr, or RemoteAddr without it
+func peerAddr(req *http.Request) string {
+	if addr, ok := req.Context().Value(peerAddrKey{}).(string); ok {
+		return addr
+	}
+	return req.RemoteAddr
+}
+
+// Handler serves the registry in Prometheus text format.
+// When a token or allowlist is configured, a request must match at least one of them.
+func Handler(r *Registry, options HandlerOptions) (http.Handler, error) {
+	allowed, err := parseAllowlist(options.AllowedIPs)
+	if err != nil {
+		return nil, err
+	}
+	protected := options.Token != "" || len(allowed) > 0
+
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if protected && !tokenMatches(req, options.Token) && !ipAllowed(req, allowed) {
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+
+		w.Header().Set("Content-Type", ContentType)
+		r.WriteText(w)
+	}), nil
+}
+
+// parseAllowlist parses IPs and CIDR ranges
+func parseAllowlist(entries []string) ([]*net.IPNet, error) {
+	var nets []*net.IPNet
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if !strings.Contains(entry, "/") {
+			ip := net.ParseIP(entry)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid IP in metrics allowlist: %s", entry)
+			}
+			bits := 32
+			if ip.To4() == nil {
+				bits = 128
+			}
+			entry = fmt.Sprintf("%s/%d", entry, bits)
+		}
+		_, n, err := net.ParseCIDR(entry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CIDR in metrics allowlist: %s", entry)
+		}
+		nets = append(nets, n)
+	}
+	return nets, nil
+}
+
+func tokenMatches(req *http.Request, token string) bool {
+	if token == "" {
+		return false
+	}
+	given, ok := strings.CutPrefix(req.Header.Get("Authorization"), "Bearer ")
+	return ok && subtle.ConstantTimeCompare([]byte(given), []byte(token)) == 1
+}
+
+func ipAllowed(req *http.Request, allowed []*net.IPNet) bool {
+	addr := peerAddr(req)
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		host = addr
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false
+	}
+	for _, n := range allowed {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// HTTPMiddleware records request counts, latencies and in-flight requests.
+// Requests are labelled by chi route pattern to keep cardinality bounded.
+func HTTPMiddleware(r *Registry) func(http.Handler) http.Handler {
+	requests := r.Counter("http_requests_total", "Total HTTP requests.", "method", "route", "status")
+	duration := r.Histogram("http_request_duration_seconds", "HTTP request latency in seconds.", DefaultBuckets, "method", "route")
+	inFlight := r.Gauge("http_requests_in_flight", "HTTP requests currently being served.")
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			start := time.Now()
+			inFlight.Inc()
+			defer inFlight.Dec()
+
+			ww := middleware.NewWrapResponseWriter(w, req.ProtoMajor)
+			next.ServeHTTP(ww, req)
+
+			status := ww.Status()
+			if status == 0 {
+				status = http.StatusOK
+			}
+
+			route := "unmatched"
+			if rctx := chi.RouteContext(req.Context()); rctx != nil {
+				if pattern := rctx.RoutePattern(); pattern != "" {
+					route = pattern
+				}
+			}
+
+			requests.Inc(req.Method, route, strconv.Itoa(status))
+			duration.Observe(time.Since(start).Seconds(), req.Method, route)
+		})
+	}
+}
+
+// RegisterDBStats exposes connection pool statistics from stats
+func RegisterDBStats(r *Registry, stats func() sql.DBStats) {
+	r.GaugeFunc("db_max_open_connections", "Maximum number of open database connections.", func() float64 {
+		return float64(stats().MaxOpenConnections)
+	})
+	r.GaugeFunc("db_open_connections", "Established database connections, in use and idle.", func() float64 {
+		return float64(stats().OpenConnections)
+	})
+	r.GaugeFunc("db_in_use_connections", "Database connections currently in use.", func() float64 {
+		return float64(stats().InUse)
+	})
+	r.GaugeFunc("db_idle_connections", "Idle database connections.", func() float64 {
+		return float64(stats().Idle)
+	})
+	r.CounterFunc("db_wait_count_total", "Total connections waited for.", func() float64 {
+		return float64(stats().WaitCount)
+	})
+	r.CounterFunc("db_wait_duration_seconds_total", "Total time blocked waiting for a new connection.", func() float64 {
+		return stats().WaitDuration.Seconds()
+	})
+	r.CounterFunc("db_max_idle_closed_total", "Connections closed due to SetMaxIdleConns.", func() float64 {
+		return float64(stats().MaxIdleClosed)
+	})
+	r.CounterFunc("db_max_lifetime_closed_total", "Connections closed due to SetConnMaxLifetime.", func() float64 {
+		return float64(stats().MaxLifetimeClosed)
+	})
+}
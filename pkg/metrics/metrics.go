@@ -0,0 +1,370 @@
+// Package metrics provides counters, gauges and histograms exposed in Prometheus text format
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Metric types
+const (
+	TypeCounter   = "counter"
+	TypeGauge     = "gauge"
+	TypeHistogram = "histogram"
+)
+
+// DefaultBuckets are histogram buckets suited to request latencies in seconds
+var DefaultBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+var (
+	metricNamePattern = regexp.MustCompile(`^[a-zA-Z_:][a-zA-Z0-9_:]*$`)
+	labelNamePattern  = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+)
+
+// collector is implemented by every registered metric
+type collector interface {
+	describe() *desc
+	write(b *strings.Builder)
+}
+
+// desc describes a metric family
+type desc struct {
+	name   string
+	help   string
+	typ    string
+	labels []string
+}
+
+func (d *desc) describe() *desc { return d }
+
+// sameAs reports whether two descriptions register the same metric
+func (d *desc) sameAs(other *desc) bool {
+	if d.typ != other.typ || len(d.labels) != len(other.labels) {
+		return false
+	}
+	for i := range d.labels {
+		if d.labels[i] != other.labels[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// key joins label values into a series key, checking the label count
+func (d *desc) key(values []string) string {
+	if len(values) != len(d.labels) {
+		panic(fmt.Sprintf("metrics: %s expects %d label values, got %d", d.name, len(d.labels), len(values)))
+	}
+	return strings.Join(values, "\xff")
+}
+
+// Registry holds a set of metrics
+type Registry struct {
+	mu      sync.RWMutex
+	metrics map[string]collector
+}
+
+// NewRegistry creates an empty metrics registry
+func NewRegistry() *Registry {
+	return &Registry{
+		metrics: make(map[string]collector),
+	}
+}
+
+// register adds a metric, returning the existing one if an identical metric is already registered.
+// Registering a different metric under the same name panics.
+func (r *Registry) register(c collector) collector {
+	d := c.describe()
+	if !metricNamePattern.MatchString(d.name) {
+		panic(fmt.Sprintf("metrics: invalid metric name %q", d.name))
+	}
+	for _, label := range d.labels {
+		if !labelNamePattern.MatchString(label) || label == "le" {
+			panic(fmt.Sprintf("metrics: invalid label name %q for %s", label, d.name))
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if existing, ok := r.metrics[d.name]; ok {
+		if existing.describe().sameAs(d) {
+			return existing
+		}
+		panic(fmt.Sprintf("metrics: %s already registered with a different type or labels", d.name))
+	}
+	r.metrics[d.name] = c
+	return c
+}
+
+// Counter registers (or returns) a counter
+func (r *Registry) Counter(name, help string, labels ...string) *Counter {
+	c := &Counter{values: newValueVec(&desc{name: name, help: help, typ: TypeCounter, labels: labels})}
+	return r.register(c).(*Counter)
+}
+
+// Gauge registers (or returns) a gauge
+func (r *Registry) Gauge(name, help string, labels ...string) *Gauge {
+	g := &Gauge{values: newValueVec(&desc{name: name, help: help, typ: TypeGauge, labels: labels})}
+	return r.register(g).(*Gauge)
+}
+
+// Histogram registers (or returns) a histogram. Nil buckets use DefaultBuckets.
+func (r *Registry) Histogram(name, help string, buckets []float64, labels ...string) *Histogram {
+	if buckets == nil {
+		buckets = DefaultBuckets
+	}
+	buckets = append([]float64(nil), buckets...)
+	sort.Float64s(buckets)
+
+	h := &Histogram{
+		desc:    &desc{name: name, help: help, typ: TypeHistogram, labels: labels},
+		buckets: buckets,
+		series:  make(map[string]*histogramSeries),
+	}
+	return r.register(h).(*Histogram)
+}
+
+// GaugeFunc registers a gauge whose value is read from fn at collection time.
+// Registering the same gauge again replaces fn, so the value comes from the latest owner.
+func (r *Registry) GaugeFunc(name, help string, fn func() float64) {
+	r.registerFunc(&desc{name: name, help: help, typ: TypeGauge}, fn)
+}
+
+// CounterFunc registers a counter whose value is read from fn at collection time.
+// Registering the same counter again replaces fn.
+func (r *Registry) CounterFunc(name, help string, fn func() float64) {
+	r.registerFunc(&desc{name: name, help: help, typ: TypeCounter}, fn)
+}
+
+// registerFunc registers a callback metric, or points an existing one at fn
+func (r *Registry) registerFunc(d *desc, fn func() float64) {
+	f := &funcMetric{desc: d, fn: fn}
+	if existing := r.register(f).(*funcMetric); existing != f {
+		existing.setFunc(fn)
+	}
+}
+
+// Unregister removes a metric by name
+func (r *Registry) Unregister(name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.metrics, name)
+}
+
+// Names returns the names of all registered metrics, sorted
+func (r *Registry) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	names := make([]string, 0, len(r.metrics))
+	for name := range r.metrics {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Counter is a monotonically increasing value
+type Counter struct {
+	values *valueVec
+}
+
+func (c *Counter) describe() *desc          { return c.values.d }
+func (c *Counter) write(b *strings.Builder) { c.values.write(b) }
+
+// Inc adds one to the counter
+func (c *Counter) Inc(labelValues ...string) {
+	c.values.add(1, labelValues)
+}
+
+// Add adds a non-negative value to the counter
+func (c *Counter) Add(v float64, labelValues ...string) {
+	if v < 0 {
+		panic(fmt.Sprintf("metrics: counter %s cannot decrease", c.values.d.name))
+	}
+	c.values.add(v, labelValues)
+}
+
+// Value returns the current value for a set of label values
+func (c *Counter) Value(labelValues ...string) float64 {
+	return c.values.get(labelValues)
+}
+
+// Gauge is a value that can go up and down
+type Gauge struct {
+	values *valueVec
+}
+
+func (g *Gauge) describe() *desc          { return g.values.d }
+func (g *Gauge) write(b *strings.Builder) { g.values.write(b) }
+
+// Set sets the gauge value
+func (g *Gauge) Set(v float64, labelValues ...string) {
+	g.values.set(v, labelValues)
+}
+
+// Inc adds one to the gauge
+func (g *Gauge) Inc(labelValues ...string) {
+	g.values.add(1, labelValues)
+}
+
+// Dec subtracts one from the gauge
+func (g *Gauge) Dec(labelValues ...string) {
+	g.values.add(-1, labelValues)
+}
+
+// Add adds v to the gauge
+func (g *Gauge) Add(v float64, labelValues ...string) {
+	g.values.add(v, labelValues)
+}
+
+// Value returns the current value for a set of label values
+func (g *Gauge) Value(labelValues ...string) float64 {
+	return g.values.get(labelValues)
+}
+
+// valueVec stores one float per label combination
+type valueVec struct {
+	d      *desc
+	mu     sync.Mutex
+	series map[string]*valueSeries
+}
+
+type valueSeries struct {
+	labels []string
+	value  float64
+}
+
+func newValueVec(d *desc) *valueVec {
+	return &valueVec{d: d, series: make(map[string]*valueSeries)}
+}
+
+func (v *valueVec) lookup(labelValues []string) *valueSeries {
+	key := v.d.key(labelValues)
+	s, ok := v.series[key]
+	if !ok {
+		s = &valueSeries{labels: append([]string(nil), labelValues...)}
+		v.series[key] = s
+	}
+	return s
+}
+
+func (v *valueVec) add(delta float64, labelValues []string) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.lookup(labelValues).value += delta
+}
+
+func (v *valueVec) set(value float64, labelValues []string) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.lookup(labelValues).value = value
+}
+
+func (v *valueVec) get(labelValues []string) float64 {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if s, ok := v.series[v.d.key(labelValues)]; ok {
+		return s.value
+	}
+	return 0
+}
+
+// Histogram counts observations into buckets
+type Histogram struct {
+	*desc
+	buckets []float64
+
+	mu     sync.Mutex
+	series map[string]*histogramSeries
+}
+
+type histogramSeries struct {
+	labels []string
+	counts []uint64 // per bucket, not cumulative
+	count  uint64
+	sum    float64
+}
+
+// Observe records a value
+func (h *Histogram) Observe(v float64, labelValues ...string) {
+	key := h.key(labelValues)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	s, ok := h.series[key]
+	if !ok {
+		s = &histogramSeries{
+			labels: append([]string(nil), labelValues...),
+			counts: make([]uint64, len(h.buckets)),
+		}
+		h.series[key] = s
+	}
+
+	if i := sort.SearchFloat64s(h.buckets, v); i < len(h.buckets) {
+		s.counts[i]++
+	}
+	s.count++
+	s.sum += v
+}
+
+// Count returns the number of observations for a set of label values
+func (h *Histogram) Count(labelValues ...string) uint64 {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if s, ok := h.series[h.key(labelValues)]; ok {
+		return s.count
+	}
+	return 0
+}
+
+// funcMetric reads its value from a callback at collection time
+type funcMetric struct {
+	*desc
+	mu sync.RWMutex
+	fn func() float64
+}
+
+// setFunc replaces the callback
+func (f *funcMetric) setFunc(fn func() float64) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.fn = fn
+}
+
+// value calls the current callback
+func (f *funcMetric) value() float64 {
+	f.mu.RLock()
+	fn := f.fn
+	f.mu.RUnlock()
+	return fn()
+}
+
+type contextKey string
+
+const registryContextKey contextKey = "metrics.registry"
+
+// WithRegistry returns a context carrying a metrics registry
+func WithRegistry(ctx context.Context, r *Registry) context.Context {
+	return context.WithValue(ctx, registryContextKey, r)
+}
+
+// FromContext returns the metrics registry carried by ctx, or the default registry if none is set
+func FromContext(ctx context.Context) *Registry {
+	if r, ok := ctx.Value(registryContextKey).(*Registry); ok {
+		return r
+	}
+	return Default()
+}
+
+var defaultRegistry = NewRegistry()
+
+// Default returns the default metrics registry
+func Default() *Registry {
+	return defaultRegistry
+}
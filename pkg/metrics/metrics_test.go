@@ -0,0 +1,185 @@
+package metrics
+
+import (
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func render(t *testing.T, r *Registry) string {
+	var b strings.Builder
+	require.NoError(t, r.WriteText(&b))
+	return b.String()
+}
+
+func TestRegistry_TextFormat(t *testing.T) {
+	r := NewRegistry()
+
+	requests := r.Counter("requests_total", "Total requests.", "method", "code")
+	requests.Inc("GET", "200")
+	requests.Add(2, "GET", "200")
+	requests.Inc("POST", "500")
+
+	queue := r.Gauge("queue_depth", "Items in queue.")
+	queue.Set(7)
+	queue.Dec()
+
+	latency := r.Histogram("latency_seconds", "Latency.", []float64{0.1, 1}, "route")
+	latency.Observe(0.05, "/a")
+	latency.Observe(0.5, "/a")
+	latency.Observe(3, "/a")
+
+	r.GaugeFunc("answer", "The answer.", func() float64 { return 42 })
+
+	expected := `# HELP answer The answer.
+# TYPE answer gauge
+answer 42
+# HELP latency_seconds Latency.
+# TYPE latency_seconds histogram
+latency_seconds_bucket{route="/a",le="0.1"} 1
+latency_seconds_bucket{route="/a",le="1"} 2
+latency_seconds_bucket{route="/a",le="+Inf"} 3
+latency_seconds_sum{route="/a"} 3.55
+latency_seconds_count{route="/a"} 3
+# HELP queue_depth Items in queue.
+# TYPE queue_depth gauge
+queue_depth 6
+# HELP requests_total Total requests.
+# TYPE requests_total counter
+requests_total{method="GET",code="200"} 3
+requests_total{method="POST",code="500"} 1
+`
+	assert.Equal(t, expected, render(t, r))
+}
+
+func TestRegistry_Registration(t *testing.T) {
+	r := NewRegistry()
+
+	a := r.Counter("hits_total", "Hits.", "path")
+	b := r.Counter("hits_total", "Hits.", "path")
+	assert.Same(t, a, b, "identical registrations return the existing metric")
+
+	assert.Panics(t, func() { r.Gauge("hits_total", "Hits.", "path") })
+	assert.Panics(t, func() { r.Counter("bad-name", "Bad.") })
+	assert.Panics(t, func() { r.Counter("ok_total", "Bad label.", "le") })
+	assert.Panics(t, func() { a.Inc() }, "label count must match")
+	assert.Panics(t, func() { a.Add(-1, "/") })
+
+	r.GaugeFunc("queue_depth", "Queue depth.", func() float64 { return 1 })
+	r.GaugeFunc("queue_depth", "Queue depth.", func() float64 { return 2 })
+	assert.Contains(t, render(t, r), "queue_depth 2\n", "registering a func metric again replaces its function")
+}
+
+func TestRegistry_EscapesLabelValues(t *testing.T) {
+	r := NewRegistry()
+	r.Counter("paths_total", "Paths.", "path").Inc("a\"b\\c\nd")
+
+	assert.Contains(t, render(t, r), `paths_total{path="a\"b\\c\nd"} 1`)
+}
+
+func TestHandler_AccessControl(t *testing.T) {
+	r := NewRegistry()
+	r.Counter("up_total", "Up.").Inc()
+
+	tests := []struct {
+		name    string
+		options HandlerOptions
+		remote  string
+		auth    string
+		want    int
+	}{
+		{"open", HandlerOptions{}, "203.0.113.9:1234", "", http.StatusOK},
+		{"token ok", HandlerOptions{Token: "secret"}, "203.0.113.9:1234", "Bearer secret", http.StatusOK},
+		{"token wrong", HandlerOptions{Token: "secret"}, "203.0.113.9:1234", "Bearer nope", http.StatusForbidden},
+		{"token missing", HandlerOptions{Token: "secret"}, "203.0.113.9:1234", "", http.StatusForbidden},
+		{"ip allowed", HandlerOptions{AllowedIPs: []string{"10.0.0.0/8"}}, "10.1.2.3:1234", "", http.StatusOK},
+		{"single ip allowed", HandlerOptions{AllowedIPs: []string{"127.0.0.1"}}, "127.0.0.1:1234", "", http.StatusOK},
+		{"ip denied", HandlerOptions{AllowedIPs: []string{"10.0.0.0/8"}}, "203.0.113.9:1234", "", http.StatusForbidden},
+		{"token or ip", HandlerOptions{Token: "secret", AllowedIPs: []string{"10.0.0.0/8"}}, "203.0.113.9:1234", "Bearer secret", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler, err := Handler(r, tt.options)
+			require.NoError(t, err)
+
+			req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+			req.RemoteAddr = tt.remote
+			if tt.auth != "" {
+				req.Header.Set("Authorization", tt.auth)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.want, rec.Code)
+			if tt.want == http.StatusOK {
+				assert.Equal(t, ContentType, rec.Header().Get("Content-Type"))
+				assert.Contains(t, rec.Body.String(), "up_total 1")
+			}
+		})
+	}
+
+	_, err := Handler(r, HandlerOptions{AllowedIPs: []string{"not-an-ip"}})
+	assert.Error(t, err)
+}
+
+func TestHandler_IgnoresForwardedHeaders(t *testing.T) {
+	handler, err := Handler(NewRegistry(), HandlerOptions{AllowedIPs: []string{"127.0.0.1"}})
+	require.NoError(t, err)
+	chain := PeerAddr(middleware.RealIP(handler))
+
+	for _, header := range []string{"X-Real-IP", "X-Forwarded-For", "True-Client-IP"} {
+		req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+		req.RemoteAddr = "203.0.113.9:1234"
+		req.Header.Set(header, "127.0.0.1")
+		rec := httptest.NewRecorder()
+		chain.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusForbidden, rec.Code, header)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	req.RemoteAddr = "127.0.0.1:1234"
+	req.Header.Set("X-Forwarded-For", "203.0.113.9")
+	rec := httptest.NewRecorder()
+	chain.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code, "the peer is allowed whatever the headers say")
+}
+
+func TestHTTPMiddleware(t *testing.T) {
+	r := NewRegistry()
+	router := chi.NewRouter()
+	router.Use(HTTPMiddleware(r))
+	router.Get("/users/{id}", func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+	})
+
+	for _, path := range []string{"/users/1", "/users/2", "/missing"} {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	requests := r.Counter("http_requests_total", "Total HTTP requests.", "method", "route", "status")
+	assert.Equal(t, float64(2), requests.Value("GET", "/users/{id}", "201"))
+	assert.Equal(t, float64(1), requests.Value("GET", "unmatched", "404"))
+
+	duration := r.Histogram("http_request_duration_seconds", "HTTP request latency in seconds.", DefaultBuckets, "method", "route")
+	assert.Equal(t, uint64(2), duration.Count("GET", "/users/{id}"))
+}
+
+func TestRegisterDBStats(t *testing.T) {
+	r := NewRegistry()
+	RegisterDBStats(r, func() sql.DBStats {
+		return sql.DBStats{MaxOpenConnections: 25, OpenConnections: 3, InUse: 1, Idle: 2, WaitCount: 4}
+	})
+
+	out := render(t, r)
+	assert.Contains(t, out, "db_max_open_connections 25\n")
+	assert.Contains(t, out, "db_in_use_connections 1\n")
+	assert.Contains(t, out, "db_wait_count_total 4\n")
+}
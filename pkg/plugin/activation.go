@@ -0,0 +1,49 @@
+package plugin
+
+import "fmt"
+
+// ActivationStore persists which plugins an administrator has disabled, so they stay disabled across restarts
+type ActivationStore interface {
+	// DisabledPlugins returns the IDs of disabled plugins
+	DisabledPlugins() ([]string, error)
+	// SetPluginEnabled records whether a plugin is enabled
+	SetPluginEnabled(pluginID string, enabled bool) error
+}
+
+// SetActivationStore sets where plugin activation is persisted.
+// Call it before registering plugins; plugins disabled in the store are registered disabled.
+func (r *Registry) SetActivationStore(store ActivationStore) error {
+	disabled, err := store.DisabledPlugins()
+	if err != nil {
+		return fmt.Errorf("failed to load disabled plugins: %w", err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.activation = store
+	r.disabled = make(map[string]bool, len(disabled))
+	for _, id := range disabled {
+		r.disabled[id] = true
+	}
+	return nil
+}
+
+// Running reports whether the registry has started its plugins
+func (r *Registry) Running() bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.running
+}
+
+// persistActivation records a plugin's activation in the store, if one is set.
+// Callers must hold r.mu.
+func (r *Registry) persistActivation(pluginID string, enabled bool) error {
+	r.disabled[pluginID] = !enabled
+	if r.activation == nil {
+		return nil
+	}
+	if err := r.activation.SetPluginEnabled(pluginID, enabled); err != nil {
+		return fmt.Errorf("failed to save activation of plugin %s: %w", pluginID, err)
+	}
+	return nil
+}
@@ -67,10 +67,10 @@ type AuthPlugin interface {
 
 // AuthConfig represents auth plugin configuration
 type AuthConfig struct {
-	ActiveProvider string                 `json:"active_provider"`
-	SessionSecret  string                 `json:"session_secret"`
-	SessionMaxAge  int                    `json:"session_max_age"`
-	Providers      map[string]interface{} `json:"providers"`
+	ActiveProvider string                 `json:"active_provider" label:"Active Provider" description:"Authentication provider used for logins"`
+	SessionSecret  string                 `json:"session_secret" label:"Session Secret" description:"Key used to sign session cookies" secret:"true" minLen:"16"`
+	SessionMaxAge  int                    `json:"session_max_age" label:"Session Max Age" description:"Session lifetime in seconds" min:"0"`
+	Providers      map[string]interface{} `json:"providers" label:"Providers" description:"Provider-specific settings keyed by provider name"`
 }
 
 // NoAuthProvider implements a provider that allows all access
@@ -2,15 +2,43 @@ package plugin
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"net/mail"
+	"net/url"
 	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unicode/utf8"
 )
 
-// ConfigManager manages plugin configurations
+// ConfigManager manages plugin configurations.
+// It is safe for concurrent use; plugins read consistent configs through Snapshot and Watch.
 type ConfigManager struct {
+	// mu guards the fields below; it is never held while calling storage,
+	// which may call back into the manager for secret paths
+	mu      sync.RWMutex
 	storage ConfigStorage
 	schemas map[string]*ConfigSchema
-	cache   map[string]interface{}
+	cache   map[string]map[string]interface{}
+	secrets map[string][]string // Secret paths marked outside the schema
+	
+	// Layers around the stored config, see EffectiveConfig
+	defaults      map[string]map[string]interface{}
+	fileOverrides map[string]map[string]interface{}
+	fileSource    string
+	env           map[string]string
+	
+	// Published configs and their subscribers, see Snapshot and Watch
+	snapshots map[string]*atomic.Pointer[ConfigSnapshot]
+	watchers  map[string]map[chan *ConfigSnapshot]struct{}
+	
+	// saveMu orders changes so storage, history and snapshots are updated in the same order
+	saveMu sync.Mutex
 }
 
 // NewConfigManager creates a new config manager
@@ -21,9 +49,13 @@ func NewConfigManager() *ConfigManager {
 // NewConfigManagerWithStorage creates a new config manager with a specific storage backend
 func NewConfigManagerWithStorage(storage ConfigStorage) *ConfigManager {
 	return &ConfigManager{
-		storage: storage,
-		schemas: make(map[string]*ConfigSchema),
-		cache:   make(map[string]interface{}),
+		storage:   storage,
+		schemas:   make(map[string]*ConfigSchema),
+		cache:     make(map[string]map[string]interface{}),
+		secrets:   make(map[string][]string),
+		defaults:  make(map[string]map[string]interface{}),
+		snapshots: make(map[string]*atomic.Pointer[ConfigSnapshot]),
+		watchers:  make(map[string]map[chan *ConfigSnapshot]struct{}),
 	}
 }
 
@@ -34,16 +66,28 @@ type ConfigSchema struct {
 
 // ConfigField represents a single configuration field
 type ConfigField struct {
-	Name        string      `json:"name"`
-	Type        string      `json:"type"` // string, number, boolean, select, multiselect
-	Label       string      `json:"label"`
-	Description string      `json:"description"`
-	Default     interface{} `json:"default"`
-	Required    bool        `json:"required"`
-	Options     []Option    `json:"options,omitempty"` // For select/multiselect
-	Validation  *Validation `json:"validation,omitempty"`
+	Name        string        `json:"name"`
+	Type        string        `json:"type"` // string, number, boolean, select, multiselect, object, array, map
+	Label       string        `json:"label"`
+	Description string        `json:"description"`
+	Default     interface{}   `json:"default"`
+	Required    bool          `json:"required"`
+	Options     []Option      `json:"options,omitempty"` // For select/multiselect
+	Validation  *Validation   `json:"validation,omitempty"`
+	Format      string        `json:"format,omitempty"` // email, url, color, duration
+	Secret      bool          `json:"secret,omitempty"`
+	Fields      []ConfigField `json:"fields,omitempty"` // For object
+	Items       *ConfigField  `json:"items,omitempty"`  // Element schema for array and map
 }
 
+// Config field formats
+const (
+	FormatEmail    = "email"
+	FormatURL      = "url"
+	FormatColor    = "color"
+	FormatDuration = "duration"
+)
+
 // Option represents a select option
 type Option struct {
 	Value string `json:"value"`
@@ -61,67 +105,163 @@ type Validation struct {
 
 // RegisterSchema registers a configuration schema for a plugin
 func (cm *ConfigManager) RegisterSchema(pluginID string, schema *ConfigSchema) {
+	cm.mu.Lock()
 	cm.schemas[pluginID] = schema
+	cm.mu.Unlock()
 }
 
 // GetSchema returns the schema for a plugin
 func (cm *ConfigManager) GetSchema(pluginID string) (*ConfigSchema, bool) {
+	cm.mu.RLock()
+	defer cm.mu.RUnlock()
 	schema, ok := cm.schemas[pluginID]
 	return schema, ok
 }
 
+// configStorage returns the current storage backend
+func (cm *ConfigManager) configStorage() ConfigStorage {
+	cm.mu.RLock()
+	defer cm.mu.RUnlock()
+	return cm.storage
+}
+
 // SetConfig sets configuration for a plugin
 func (cm *ConfigManager) SetConfig(pluginID string, config interface{}) error {
-	// Validate against schema if available
-	if schema, ok := cm.schemas[pluginID]; ok {
+	return cm.saveConfig(pluginID, config, "", "")
+}
+
+// SetConfigWithAuthor sets configuration for a plugin, recording who made the change
+func (cm *ConfigManager) SetConfigWithAuthor(pluginID string, config interface{}, author string) error {
+	return cm.saveConfig(pluginID, config, author, "")
+}
+
+// ValidateConfig checks a config against the plugin's schema, if it has one
+func (cm *ConfigManager) ValidateConfig(pluginID string, config interface{}) error {
+	if schema, ok := cm.GetSchema(pluginID); ok {
 		if err := cm.validateConfig(config, schema); err != nil {
 			return fmt.Errorf("invalid configuration: %w", err)
 		}
 	}
+	return nil
+}
+
+// saveConfig validates and stores a config, records a revision if the storage keeps history,
+// and publishes the new effective config to watchers. If the revision can't be recorded the
+// previous config is put back, so a failed save leaves no trace.
+func (cm *ConfigManager) saveConfig(pluginID string, config interface{}, author, message string) error {
+	// Validate against schema if available
+	if err := cm.ValidateConfig(pluginID, config); err != nil {
+		return err
+	}
 	
-	// Convert to map for storage
+	// Convert to map for storage, copying so later changes by the caller don't leak in
 	configMap, err := cm.toMap(config)
 	if err != nil {
 		return fmt.Errorf("failed to convert config to map: %w", err)
 	}
+	configMap = copyConfig(configMap)
+	
+	cm.saveMu.Lock()
+	defer cm.saveMu.Unlock()
 	
-	// Save to storage
-	if err := cm.storage.Save(pluginID, configMap); err != nil {
+	// Save to storage, keeping the previous config in case the revision fails
+	storage := cm.configStorage()
+	previous, loadErr := storage.Load(pluginID)
+	if err := storage.Save(pluginID, configMap); err != nil {
 		return fmt.Errorf("failed to save config: %w", err)
 	}
 	
+	if err := cm.recordRevision(pluginID, configMap, author, message); err != nil {
+		var restoreErr error
+		if loadErr == nil {
+			restoreErr = storage.Save(pluginID, previous)
+		} else {
+			restoreErr = storage.Delete(pluginID)
+		}
+		if restoreErr != nil {
+			return errors.Join(err, fmt.Errorf("failed to restore previous config: %w", restoreErr))
+		}
+		return err
+	}
+	
 	// Update cache
-	cm.cache[pluginID] = config
+	cm.mu.Lock()
+	cm.cache[pluginID] = configMap
+	cm.mu.Unlock()
 	
+	cm.publish(pluginID)
 	return nil
 }
 
-// GetConfig returns configuration for a plugin
+// DeleteConfig removes the stored configuration for a plugin, leaving its defaults and overrides.
+// Revisions already recorded are kept.
+func (cm *ConfigManager) DeleteConfig(pluginID string) error {
+	cm.saveMu.Lock()
+	defer cm.saveMu.Unlock()
+	
+	if err := cm.configStorage().Delete(pluginID); err != nil {
+		return fmt.Errorf("failed to delete config: %w", err)
+	}
+	
+	cm.mu.Lock()
+	delete(cm.cache, pluginID)
+	cm.mu.Unlock()
+	
+	cm.publish(pluginID)
+	return nil
+}
+
+// GetConfig returns a copy of the stored configuration for a plugin
 func (cm *ConfigManager) GetConfig(pluginID string) (interface{}, bool) {
+	config, ok := cm.GetConfigMap(pluginID)
+	if !ok {
+		return nil, false
+	}
+	return config, true
+}
+
+// GetConfigMap returns a copy of the stored configuration for a plugin as a map
+func (cm *ConfigManager) GetConfigMap(pluginID string) (map[string]interface{}, bool) {
 	// Check cache first
-	if config, ok := cm.cache[pluginID]; ok {
-		return config, true
+	cm.mu.RLock()
+	config, ok := cm.cache[pluginID]
+	cm.mu.RUnlock()
+	if ok {
+		return copyConfig(config), true
 	}
 	
 	// Load from storage
-	configMap, err := cm.storage.Load(pluginID)
+	config, err := cm.configStorage().Load(pluginID)
 	if err != nil {
 		return nil, false
 	}
 	
-	// Cache and return
-	cm.cache[pluginID] = configMap
-	return configMap, true
+	// Cache and return, unless a save cached a newer config meanwhile
+	cm.mu.Lock()
+	if cached, ok := cm.cache[pluginID]; ok {
+		config = cached
+	} else {
+		cm.cache[pluginID] = config
+	}
+	cm.mu.Unlock()
+	return copyConfig(config), true
 }
 
-// LoadConfig loads configuration into a struct
+// LoadConfig loads the effective configuration, including overrides, into a struct
 func (cm *ConfigManager) LoadConfig(pluginID string, target interface{}) error {
-	config, ok := cm.GetConfig(pluginID)
-	if !ok {
+	if _, ok := cm.GetConfig(pluginID); !ok {
 		return fmt.Errorf("no configuration found for plugin %s", pluginID)
 	}
 	
-	// Convert to JSON and back to handle type conversions
+	config, _, err := cm.EffectiveConfig(pluginID)
+	if err != nil {
+		return err
+	}
+	return decodeConfig(config, target)
+}
+
+// decodeConfig copies a config into a struct, converting types via JSON
+func decodeConfig(config interface{}, target interface{}) error {
 	jsonData, err := json.Marshal(config)
 	if err != nil {
 		return err
@@ -150,8 +290,12 @@ func (cm *ConfigManager) validateConfig(config interface{}, schema *ConfigSchema
 		}
 	}
 	
-	// Validate each field
-	for _, field := range schema.Fields {
+	return cm.validateFields(configMap, schema.Fields)
+}
+
+// validateFields validates the fields of a config object
+func (cm *ConfigManager) validateFields(configMap map[string]interface{}, fields []ConfigField) error {
+	for _, field := range fields {
 		value, exists := configMap[field.Name]
 		
 		// Check required fields
@@ -160,7 +304,7 @@ func (cm *ConfigManager) validateConfig(config interface{}, schema *ConfigSchema
 		}
 		
 		// Skip validation if field doesn't exist and isn't required
-		if !exists {
+		if !exists || value == nil {
 			continue
 		}
 		
@@ -177,20 +321,32 @@ func (cm *ConfigManager) validateConfig(config interface{}, schema *ConfigSchema
 func (cm *ConfigManager) validateField(value interface{}, field ConfigField) error {
 	// Type validation
 	switch field.Type {
-	case "string":
+	case "string", "select":
 		str, ok := value.(string)
 		if !ok {
 			return fmt.Errorf("expected string, got %T", value)
 		}
+		if len(field.Options) > 0 && !cm.isValidOption(str, field.Options) {
+			return fmt.Errorf("invalid option: %s", str)
+		}
+		if str == "" && !field.Required {
+			return nil
+		}
 		if field.Validation != nil {
-			if field.Validation.MinLen != nil && len(str) < *field.Validation.MinLen {
-				return fmt.Errorf("minimum length is %d", *field.Validation.MinLen)
+			if err := validateLength(utf8.RuneCountInString(str), field.Validation); err != nil {
+				return err
 			}
-			if field.Validation.MaxLen != nil && len(str) > *field.Validation.MaxLen {
-				return fmt.Errorf("maximum length is %d", *field.Validation.MaxLen)
+			if field.Validation.Regex != "" {
+				re, err := regexp.Compile(field.Validation.Regex)
+				if err != nil {
+					return fmt.Errorf("invalid pattern %s: %w", field.Validation.Regex, err)
+				}
+				if !re.MatchString(str) {
+					return fmt.Errorf("must match pattern %s", field.Validation.Regex)
+				}
 			}
-			// TODO: Add regex validation
 		}
+		return validateFormat(str, field.Format)
 		
 	case "number":
 		num, ok := toFloat64(value)
@@ -199,10 +355,10 @@ func (cm *ConfigManager) validateField(value interface{}, field ConfigField) err
 		}
 		if field.Validation != nil {
 			if field.Validation.Min != nil && num < *field.Validation.Min {
-				return fmt.Errorf("minimum value is %f", *field.Validation.Min)
+				return fmt.Errorf("minimum value is %s", formatBound(*field.Validation.Min))
 			}
 			if field.Validation.Max != nil && num > *field.Validation.Max {
-				return fmt.Errorf("maximum value is %f", *field.Validation.Max)
+				return fmt.Errorf("maximum value is %s", formatBound(*field.Validation.Max))
 			}
 		}
 		
@@ -211,28 +367,114 @@ func (cm *ConfigManager) validateField(value interface{}, field ConfigField) err
 			return fmt.Errorf("expected boolean, got %T", value)
 		}
 		
-	case "select":
-		// Validate against options
-		if !cm.isValidOption(value, field.Options) {
-			return fmt.Errorf("invalid option")
-		}
-		
 	case "multiselect":
 		// Validate each selected option
-		values, ok := value.([]interface{})
+		values, ok := toSlice(value)
 		if !ok {
 			return fmt.Errorf("expected array for multiselect")
 		}
 		for _, v := range values {
-			if !cm.isValidOption(v, field.Options) {
+			if len(field.Options) > 0 && !cm.isValidOption(v, field.Options) {
 				return fmt.Errorf("invalid option: %v", v)
 			}
 		}
+		if field.Validation != nil {
+			return validateLength(len(values), field.Validation)
+		}
+		
+	case "object":
+		obj, ok := value.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("expected object, got %T", value)
+		}
+		return cm.validateFields(obj, field.Fields)
+		
+	case "array":
+		items, ok := toSlice(value)
+		if !ok {
+			return fmt.Errorf("expected array, got %T", value)
+		}
+		if field.Validation != nil {
+			if err := validateLength(len(items), field.Validation); err != nil {
+				return err
+			}
+		}
+		if field.Items != nil {
+			for i, item := range items {
+				if err := cm.validateField(item, *field.Items); err != nil {
+					return fmt.Errorf("item %d: %w", i, err)
+				}
+			}
+		}
+		
+	case "map":
+		entries, ok := value.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("expected object, got %T", value)
+		}
+		if field.Items != nil {
+			for key, entry := range entries {
+				if err := cm.validateField(entry, *field.Items); err != nil {
+					return fmt.Errorf("key %s: %w", key, err)
+				}
+			}
+		}
 	}
 	
 	return nil
 }
 
+// validateLength checks a string length or item count against MinLen and MaxLen
+func validateLength(n int, v *Validation) error {
+	if v.MinLen != nil && n < *v.MinLen {
+		return fmt.Errorf("minimum length is %d", *v.MinLen)
+	}
+	if v.MaxLen != nil && n > *v.MaxLen {
+		return fmt.Errorf("maximum length is %d", *v.MaxLen)
+	}
+	return nil
+}
+
+// validateFormat checks a string against a well-known format
+func validateFormat(value, format string) error {
+	switch format {
+	case FormatEmail:
+		addr, err := mail.ParseAddress(value)
+		if err != nil || addr.Address != value {
+			return fmt.Errorf("must be a valid email address")
+		}
+	case FormatURL:
+		u, err := url.Parse(value)
+		if err != nil || u.Scheme == "" || u.Host == "" {
+			return fmt.Errorf("must be an absolute URL")
+		}
+	case FormatColor:
+		if !colorPattern.MatchString(value) {
+			return fmt.Errorf("must be a hex color such as #1a2b3c")
+		}
+	case FormatDuration:
+		if _, err := time.ParseDuration(value); err != nil {
+			return fmt.Errorf("must be a duration such as 30s or 5m")
+		}
+	}
+	return nil
+}
+
+// toSlice converts decoded JSON arrays and string slices to []interface{}
+func toSlice(value interface{}) ([]interface{}, bool) {
+	switch v := value.(type) {
+	case []interface{}:
+		return v, true
+	case []string:
+		items := make([]interface{}, len(v))
+		for i, s := range v {
+			items[i] = s
+		}
+		return items, true
+	}
+	return nil, false
+}
+
 // isValidOption checks if a value is in the options list
 func (cm *ConfigManager) isValidOption(value interface{}, options []Option) bool {
 	str, ok := value.(string)
@@ -262,16 +504,35 @@ func toFloat64(value interface{}) (float64, bool) {
 	return 0, false
 }
 
-// GenerateSchemaFromStruct generates a config schema from a struct
+var (
+	durationType = reflect.TypeOf(time.Duration(0))
+	timeType     = reflect.TypeOf(time.Time{})
+)
+
+// GenerateSchemaFromStruct generates a config schema from a struct.
+// Fields are described by the label, description, required, default, enum, min, max,
+// minLen, maxLen, pattern, format and secret struct tags. Nested structs become object
+// fields, slices array fields and maps map fields.
 func GenerateSchemaFromStruct(v interface{}) *ConfigSchema {
-	schema := &ConfigSchema{
-		Fields: []ConfigField{},
-	}
-	
 	t := reflect.TypeOf(v)
+	if t == nil {
+		return nil
+	}
 	if t.Kind() == reflect.Ptr {
 		t = t.Elem()
 	}
+	if t.Kind() != reflect.Struct {
+		return nil
+	}
+	
+	return &ConfigSchema{
+		Fields: structFields(t),
+	}
+}
+
+// structFields describes the exported fields of a struct type
+func structFields(t reflect.Type) []ConfigField {
+	fields := []ConfigField{}
 	
 	for i := 0; i < t.NumField(); i++ {
 		field := t.Field(i)
@@ -286,24 +547,166 @@ func GenerateSchemaFromStruct(v interface{}) *ConfigSchema {
 		if jsonTag == "-" {
 			continue
 		}
+		name, _, _ := strings.Cut(jsonTag, ",")
 		
-		configField := ConfigField{
-			Name:        jsonTag,
-			Type:        getFieldType(field.Type),
-			Label:       field.Tag.Get("label"),
-			Description: field.Tag.Get("description"),
-			Required:    field.Tag.Get("required") == "true",
+		// Untagged embedded structs are flattened, as encoding/json does
+		if field.Anonymous && name == "" && indirectType(field.Type).Kind() == reflect.Struct {
+			fields = append(fields, structFields(indirectType(field.Type))...)
+			continue
 		}
-		
-		// Set default from tag
-		if defaultTag := field.Tag.Get("default"); defaultTag != "" {
-			configField.Default = defaultTag
+		if name == "" {
+			name = field.Name
 		}
 		
-		schema.Fields = append(schema.Fields, configField)
+		fields = append(fields, describeField(name, field))
+	}
+	
+	return fields
+}
+
+// describeField builds a config field from a struct field and its tags
+func describeField(name string, field reflect.StructField) ConfigField {
+	configField := ConfigField{
+		Name:        name,
+		Label:       field.Tag.Get("label"),
+		Description: field.Tag.Get("description"),
+		Required:    field.Tag.Get("required") == "true",
+		Format:      field.Tag.Get("format"),
+		Secret:      field.Tag.Get("secret") == "true",
+		Validation:  tagValidation(field.Tag),
+	}
+	if configField.Label == "" {
+		configField.Label = field.Name
+	}
+	describeType(&configField, field.Type)
+	
+	if enum := field.Tag.Get("enum"); enum != "" {
+		for _, value := range strings.Split(enum, ",") {
+			value = strings.TrimSpace(value)
+			configField.Options = append(configField.Options, Option{Value: value, Label: value})
+		}
+		if configField.Type == "string" {
+			configField.Type = "select"
+		}
+	}
+	
+	// Set default from tag, converted to the field's type
+	if defaultTag := field.Tag.Get("default"); defaultTag != "" {
+		configField.Default = parseDefault(field.Type, defaultTag)
+	}
+	
+	return configField
+}
+
+// describeType sets the field type, and nested fields or items for composite types
+func describeType(field *ConfigField, t reflect.Type) {
+	t = indirectType(t)
+	
+	switch t.Kind() {
+	case reflect.Struct:
+		if t == timeType {
+			field.Type = "string"
+			return
+		}
+		field.Type = "object"
+		field.Fields = structFields(t)
+	case reflect.Slice, reflect.Array:
+		elem := indirectType(t.Elem())
+		if elem.Kind() == reflect.String {
+			field.Type = "multiselect"
+			return
+		}
+		field.Type = "array"
+		field.Items = &ConfigField{Name: "item"}
+		describeType(field.Items, elem)
+	case reflect.Map:
+		field.Type = "map"
+		if t.Elem().Kind() != reflect.Interface {
+			field.Items = &ConfigField{Name: "value"}
+			describeType(field.Items, t.Elem())
+		}
+	default:
+		field.Type = getFieldType(t)
+	}
+}
+
+// tagValidation reads validation rules from struct tags
+func tagValidation(tag reflect.StructTag) *Validation {
+	v := &Validation{Regex: tag.Get("pattern")}
+	if f, err := strconv.ParseFloat(tag.Get("min"), 64); err == nil {
+		v.Min = &f
+	}
+	if f, err := strconv.ParseFloat(tag.Get("max"), 64); err == nil {
+		v.Max = &f
+	}
+	if n, err := strconv.Atoi(tag.Get("minLen")); err == nil {
+		v.MinLen = &n
 	}
+	if n, err := strconv.Atoi(tag.Get("maxLen")); err == nil {
+		v.MaxLen = &n
+	}
+	
+	if v.Min == nil && v.Max == nil && v.MinLen == nil && v.MaxLen == nil && v.Regex == "" {
+		return nil
+	}
+	return v
+}
+
+// parseDefault converts a default tag to the field's Go type, keeping the raw string if it doesn't parse
+func parseDefault(t reflect.Type, raw string) interface{} {
+	t = indirectType(t)
 	
-	return schema
+	if t == durationType {
+		if d, err := time.ParseDuration(raw); err == nil {
+			return d
+		}
+		return raw
+	}
+	
+	switch t.Kind() {
+	case reflect.String:
+		return reflect.ValueOf(raw).Convert(t).Interface()
+	case reflect.Bool:
+		if b, err := strconv.ParseBool(raw); err == nil {
+			return reflect.ValueOf(b).Convert(t).Interface()
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if n, err := strconv.ParseInt(raw, 10, t.Bits()); err == nil {
+			return reflect.ValueOf(n).Convert(t).Interface()
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if n, err := strconv.ParseUint(raw, 10, t.Bits()); err == nil {
+			return reflect.ValueOf(n).Convert(t).Interface()
+		}
+	case reflect.Float32, reflect.Float64:
+		if f, err := strconv.ParseFloat(raw, t.Bits()); err == nil {
+			return reflect.ValueOf(f).Convert(t).Interface()
+		}
+	case reflect.Slice:
+		// Comma-separated lists of strings, JSON otherwise
+		if t.Elem().Kind() == reflect.String && !strings.HasPrefix(strings.TrimSpace(raw), "[") {
+			values := reflect.MakeSlice(t, 0, 0)
+			for _, s := range strings.Split(raw, ",") {
+				values = reflect.Append(values, reflect.ValueOf(strings.TrimSpace(s)).Convert(t.Elem()))
+			}
+			return values.Interface()
+		}
+		fallthrough
+	default:
+		target := reflect.New(t)
+		if err := json.Unmarshal([]byte(raw), target.Interface()); err == nil {
+			return target.Elem().Interface()
+		}
+	}
+	return raw
+}
+
+// indirectType dereferences pointer types
+func indirectType(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t
 }
 
 // getFieldType returns the config field type for a Go type
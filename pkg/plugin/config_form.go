@@ -0,0 +1,98 @@
+package plugin
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// ParseForm converts submitted form values into a config map following the schema.
+// Nested object fields use dotted names ("parent.child"); arrays and maps are submitted as JSON.
+// Blank secret fields are left out so the stored secret can be kept.
+func (s *ConfigSchema) ParseForm(form url.Values) (map[string]interface{}, error) {
+	return parseFormFields(form, s.Fields, "")
+}
+
+func parseFormFields(form url.Values, fields []ConfigField, prefix string) (map[string]interface{}, error) {
+	config := make(map[string]interface{})
+
+	for _, field := range fields {
+		name := prefix + field.Name
+		raw := strings.TrimSpace(form.Get(name))
+
+		// Secrets are write-only; a blank field keeps the stored value (see KeepSecrets)
+		if field.Secret && raw == "" {
+			continue
+		}
+
+		switch field.Type {
+		case "boolean":
+			b, err := parseFormBool(form.Get(name))
+			if err != nil {
+				return nil, fmt.Errorf("field %s: %w", name, err)
+			}
+			config[field.Name] = b
+
+		case "number":
+			if raw == "" {
+				continue
+			}
+			n, err := strconv.ParseFloat(raw, 64)
+			if err != nil {
+				return nil, fmt.Errorf("field %s: expected number", name)
+			}
+			config[field.Name] = n
+
+		case "multiselect":
+			// Without options the list is edited one value per line
+			submitted := form[name]
+			if len(field.Options) == 0 {
+				submitted = strings.Split(form.Get(name), "\n")
+			}
+			values := make([]interface{}, 0, len(submitted))
+			for _, v := range submitted {
+				if v = strings.TrimSpace(v); v != "" {
+					values = append(values, v)
+				}
+			}
+			config[field.Name] = values
+
+		case "object":
+			nested, err := parseFormFields(form, field.Fields, name+".")
+			if err != nil {
+				return nil, err
+			}
+			config[field.Name] = nested
+
+		case "array", "map":
+			if raw == "" {
+				continue
+			}
+			var decoded interface{}
+			if err := json.Unmarshal([]byte(raw), &decoded); err != nil {
+				return nil, fmt.Errorf("field %s: invalid JSON: %w", name, err)
+			}
+			config[field.Name] = decoded
+
+		default:
+			if _, ok := form[name]; ok {
+				config[field.Name] = form.Get(name)
+			}
+		}
+	}
+
+	return config, nil
+}
+
+// parseFormBool interprets checkbox and boolean select values
+func parseFormBool(value string) (bool, error) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "", "off", "false", "0":
+		return false, nil
+	case "on", "true", "1":
+		return true, nil
+	}
+	return false, fmt.Errorf("expected boolean")
+}
@@ -2,10 +2,13 @@ package plugin
 
 import (
 	"encoding/json"
+	"net/url"
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
+	"github.com/btassone/obtura/pkg/database"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -115,6 +118,114 @@ func TestJSONFileConfigStorage(t *testing.T) {
 	assert.ElementsMatch(t, plugins, list)
 }
 
+// newConfigTestDB creates an in-memory database with the plugin config tables
+func newConfigTestDB(t *testing.T) *database.DB {
+	db := newTestDB(t)
+	_, err := db.Exec(`
+		CREATE TABLE plugin_configs (
+			plugin_id VARCHAR(255) NOT NULL PRIMARY KEY,
+			config TEXT NOT NULL,
+			updated_at TIMESTAMP NOT NULL
+		)
+	`)
+	require.NoError(t, err)
+	_, err = db.Exec(`
+		CREATE TABLE plugin_config_revisions (
+			plugin_id VARCHAR(255) NOT NULL,
+			version INTEGER NOT NULL,
+			author VARCHAR(255) NOT NULL DEFAULT '',
+			message TEXT NOT NULL,
+			config TEXT NOT NULL,
+			created_at TIMESTAMP NOT NULL,
+			PRIMARY KEY (plugin_id, version)
+		)
+	`)
+	require.NoError(t, err)
+	return db
+}
+
+func TestDBConfigStorage(t *testing.T) {
+	storage := NewDBConfigStorage(newConfigTestDB(t))
+
+	config := map[string]interface{}{
+		"host":   "localhost",
+		"port":   8080,
+		"nested": map[string]interface{}{"level": "info"},
+	}
+	require.NoError(t, storage.Save("test-plugin", config))
+
+	loaded, err := storage.Load("test-plugin")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"host":   "localhost",
+		"port":   float64(8080),
+		"nested": map[string]interface{}{"level": "info"},
+	}, loaded)
+
+	// Saving again replaces the stored config
+	require.NoError(t, storage.Save("test-plugin", map[string]interface{}{"host": "db"}))
+	loaded, err = storage.Load("test-plugin")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"host": "db"}, loaded)
+
+	_, err = storage.Load("non-existent")
+	assert.Error(t, err)
+	assert.Error(t, storage.Save("../evil", config))
+
+	require.NoError(t, storage.Save("other-plugin", config))
+	list, err := storage.List()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"other-plugin", "test-plugin"}, list)
+
+	require.NoError(t, storage.Delete("test-plugin"))
+	_, err = storage.Load("test-plugin")
+	assert.Error(t, err)
+}
+
+func TestNewConfigStorage(t *testing.T) {
+	storage, err := NewConfigStorage(ConfigStorageFile, t.TempDir(), nil)
+	require.NoError(t, err)
+	assert.IsType(t, &JSONFileConfigStorage{}, storage)
+
+	storage, err = NewConfigStorage(ConfigStorageDB, "", newConfigTestDB(t))
+	require.NoError(t, err)
+	assert.IsType(t, &DBConfigStorage{}, storage)
+
+	_, err = NewConfigStorage(ConfigStorageDB, "", nil)
+	assert.Error(t, err)
+	_, err = NewConfigStorage("s3", "", nil)
+	assert.Error(t, err)
+}
+
+func TestCopyConfigs(t *testing.T) {
+	src, err := NewJSONFileConfigStorage(t.TempDir())
+	require.NoError(t, err)
+	dst := NewDBConfigStorage(newConfigTestDB(t))
+
+	cm := NewConfigManagerWithStorage(src)
+	require.NoError(t, cm.SetConfig("plugin-a", map[string]interface{}{"limit": 1}))
+	require.NoError(t, cm.SetConfigWithAuthor("plugin-a", map[string]interface{}{"limit": 2}, "admin"))
+	require.NoError(t, cm.SetConfig("plugin-b", map[string]interface{}{"name": "b"}))
+
+	for i := 0; i < 2; i++ {
+		copied, err := CopyConfigs(src, dst)
+		require.NoError(t, err)
+		assert.Equal(t, 2, copied)
+	}
+
+	loaded, err := dst.Load("plugin-a")
+	require.NoError(t, err)
+	assert.Equal(t, float64(2), loaded["limit"])
+
+	// History is copied once, keeping versions and authors
+	history, err := dst.(RevisionStorage).Revisions("plugin-a")
+	require.NoError(t, err)
+	require.Len(t, history, 2)
+	assert.Equal(t, 2, history[0].Version)
+	assert.Equal(t, "admin", history[0].Author)
+	assert.Equal(t, float64(1), history[1].Config["limit"])
+}
+
 func TestConfigManager(t *testing.T) {
 	storage := NewMemoryConfigStorage()
 	manager := NewConfigManagerWithStorage(storage)
@@ -275,14 +386,17 @@ func TestGenerateSchemaFromStruct(t *testing.T) {
 	portField := findField(schema.Fields, "port")
 	require.NotNil(t, portField)
 	assert.Equal(t, "number", portField.Type)
-	// Default values in schema are returned as strings
-	assert.Equal(t, "8080", portField.Default)
+	// Default values are converted to the field's type
+	assert.Equal(t, 8080, portField.Default)
+	require.NotNil(t, portField.Validation)
+	assert.Equal(t, 1.0, *portField.Validation.Min)
+	assert.Equal(t, 65535.0, *portField.Validation.Max)
 
 	// Check debug field
 	debugField := findField(schema.Fields, "debug")
 	require.NotNil(t, debugField)
 	assert.Equal(t, "boolean", debugField.Type)
-	assert.Equal(t, "false", debugField.Default)
+	assert.Equal(t, false, debugField.Default)
 
 	// Check timeout field
 	timeoutField := findField(schema.Fields, "timeout")
@@ -297,7 +411,192 @@ func TestGenerateSchemaFromStruct(t *testing.T) {
 	// Check metadata field
 	metadataField := findField(schema.Fields, "metadata")
 	require.NotNil(t, metadataField)
-	assert.Equal(t, "string", metadataField.Type)
+	assert.Equal(t, "map", metadataField.Type)
+	assert.Nil(t, metadataField.Items)
+}
+
+func TestGenerateSchemaFromStruct_Tags(t *testing.T) {
+	type Endpoint struct {
+		URL     string `json:"url" format:"url" required:"true"`
+		Retries int    `json:"retries" min:"0" max:"5" default:"3"`
+	}
+	type Database struct {
+		Driver string `json:"driver" enum:"sqlite,mysql,postgres" default:"sqlite"`
+		DSN    string `json:"dsn" secret:"true"`
+	}
+	type TestConfig struct {
+		Email     string              `json:"email,omitempty" format:"email"`
+		Slug      string              `json:"slug" pattern:"^[a-z-]+$" minLen:"2" maxLen:"20"`
+		Color     string              `json:"color" format:"color" default:"#336699"`
+		Timeout   time.Duration       `json:"timeout" default:"30s"`
+		Interval  string              `json:"interval" format:"duration"`
+		Tags      []string            `json:"tags" enum:"a,b,c" default:"a,b" maxLen:"2"`
+		Database  Database            `json:"database"`
+		Endpoints []Endpoint          `json:"endpoints" minLen:"1"`
+		Limits    map[string]int      `json:"limits"`
+		Backends  map[string]Endpoint `json:"backends"`
+		Untagged  *Database
+	}
+
+	schema := GenerateSchemaFromStruct(TestConfig{})
+	require.NotNil(t, schema)
+
+	email := findField(schema.Fields, "email")
+	require.NotNil(t, email, "json tag options are stripped from the name")
+	assert.Equal(t, FormatEmail, email.Format)
+	assert.Equal(t, "Email", email.Label)
+
+	slug := findField(schema.Fields, "slug")
+	require.NotNil(t, slug.Validation)
+	assert.Equal(t, "^[a-z-]+$", slug.Validation.Regex)
+	assert.Equal(t, 2, *slug.Validation.MinLen)
+	assert.Equal(t, 20, *slug.Validation.MaxLen)
+
+	assert.Equal(t, "#336699", findField(schema.Fields, "color").Default)
+	assert.Equal(t, 30*time.Second, findField(schema.Fields, "timeout").Default)
+
+	tags := findField(schema.Fields, "tags")
+	assert.Equal(t, "multiselect", tags.Type)
+	assert.Len(t, tags.Options, 3)
+	assert.Equal(t, []string{"a", "b"}, tags.Default)
+
+	database := findField(schema.Fields, "database")
+	assert.Equal(t, "object", database.Type)
+	driver := findField(database.Fields, "driver")
+	assert.Equal(t, "select", driver.Type)
+	assert.Equal(t, "sqlite", driver.Default)
+	assert.True(t, findField(database.Fields, "dsn").Secret)
+
+	endpoints := findField(schema.Fields, "endpoints")
+	assert.Equal(t, "array", endpoints.Type)
+	require.NotNil(t, endpoints.Items)
+	assert.Equal(t, "object", endpoints.Items.Type)
+	assert.Equal(t, 3, findField(endpoints.Items.Fields, "retries").Default)
+
+	limits := findField(schema.Fields, "limits")
+	assert.Equal(t, "map", limits.Type)
+	assert.Equal(t, "number", limits.Items.Type)
+	assert.Equal(t, "object", findField(schema.Fields, "backends").Items.Type)
+
+	assert.Equal(t, "object", findField(schema.Fields, "Untagged").Type)
+	assert.Nil(t, GenerateSchemaFromStruct(nil))
+	assert.Nil(t, GenerateSchemaFromStruct(map[string]interface{}{}))
+}
+
+func TestConfigSchema_NestedValidation(t *testing.T) {
+	type Endpoint struct {
+		URL     string `json:"url" format:"url" required:"true"`
+		Retries int    `json:"retries" min:"0" max:"5"`
+	}
+	type TestConfig struct {
+		Email     string              `json:"email" format:"email"`
+		Slug      string              `json:"slug" pattern:"^[a-z-]+$"`
+		Interval  string              `json:"interval" format:"duration"`
+		Mode      string              `json:"mode" enum:"fast,safe"`
+		Tags      []string            `json:"tags" enum:"a,b,c" maxLen:"2"`
+		Free      []string            `json:"free"`
+		Endpoints []Endpoint          `json:"endpoints" minLen:"1"`
+		Backends  map[string]Endpoint `json:"backends"`
+	}
+	schema := GenerateSchemaFromStruct(TestConfig{})
+	manager := NewConfigManager()
+
+	valid := map[string]interface{}{
+		"email":     "admin@example.com",
+		"slug":      "my-plugin",
+		"interval":  "5m",
+		"mode":      "safe",
+		"tags":      []interface{}{"a", "c"},
+		"free":      []interface{}{"anything"},
+		"endpoints": []interface{}{map[string]interface{}{"url": "https://example.com", "retries": float64(2)}},
+		"backends":  map[string]interface{}{"primary": map[string]interface{}{"url": "https://db.example.com"}},
+	}
+	require.NoError(t, manager.validateConfig(valid, schema))
+
+	// Empty optional strings skip format checks
+	require.NoError(t, manager.validateConfig(map[string]interface{}{"email": ""}, schema))
+
+	tests := []struct {
+		field string
+		value interface{}
+		want  string
+	}{
+		{"email", "not an email", "valid email"},
+		{"email", "Admin <admin@example.com>", "valid email"},
+		{"slug", "Bad Slug", "must match pattern"},
+		{"interval", "soon", "duration"},
+		{"mode", "slow", "invalid option"},
+		{"tags", []interface{}{"a", "d"}, "invalid option"},
+		{"tags", []interface{}{"a", "b", "c"}, "maximum length is 2"},
+		{"endpoints", []interface{}{}, "minimum length is 1"},
+		{"endpoints", []interface{}{map[string]interface{}{"url": "/relative"}}, "item 0: field url: must be an absolute URL"},
+		{"endpoints", []interface{}{map[string]interface{}{"url": "https://x.io", "retries": float64(9)}}, "maximum value is 5"},
+		{"endpoints", []interface{}{map[string]interface{}{}}, "field url is required"},
+		{"backends", map[string]interface{}{"primary": "https://db"}, "key primary: expected object"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.field, func(t *testing.T) {
+			config := make(map[string]interface{})
+			for k, v := range valid {
+				config[k] = v
+			}
+			config[tt.field] = tt.value
+
+			err := manager.validateConfig(config, schema)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), "field "+tt.field)
+			assert.Contains(t, err.Error(), tt.want)
+		})
+	}
+}
+
+func TestConfigSchema_ParseForm(t *testing.T) {
+	type Database struct {
+		Driver string `json:"driver" enum:"sqlite,mysql"`
+		Port   int    `json:"port"`
+		Debug  bool   `json:"debug"`
+	}
+	type TestConfig struct {
+		Name      string         `json:"name"`
+		Tags      []string       `json:"tags" enum:"a,b,c"`
+		Hosts     []string       `json:"hosts"`
+		Database  Database       `json:"database"`
+		Endpoints []struct{}     `json:"endpoints"`
+		Limits    map[string]int `json:"limits"`
+	}
+	schema := GenerateSchemaFromStruct(TestConfig{})
+
+	form := url.Values{
+		"name":            {"demo"},
+		"tags":            {"a", "c"},
+		"hosts":           {"one.example.com\r\n\r\ntwo.example.com\n"},
+		"database.driver": {"mysql"},
+		"database.port":   {"3306"},
+		"database.debug":  {"on"},
+		"limits":          {`{"requests": 10}`},
+	}
+	config, err := schema.ParseForm(form)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"name":  "demo",
+		"tags":  []interface{}{"a", "c"},
+		"hosts": []interface{}{"one.example.com", "two.example.com"},
+		"database": map[string]interface{}{
+			"driver": "mysql",
+			"port":   float64(3306),
+			"debug":  true,
+		},
+		"limits": map[string]interface{}{"requests": float64(10)},
+	}, config)
+
+	manager := NewConfigManager()
+	require.NoError(t, manager.validateConfig(config, schema))
+
+	_, err = schema.ParseForm(url.Values{"database.port": {"many"}})
+	assert.ErrorContains(t, err, "database.port")
+
+	_, err = schema.ParseForm(url.Values{"limits": {"{"}})
+	assert.ErrorContains(t, err, "invalid JSON")
 }
 
 // TestValidateFieldValue - Commented out as validateFieldValue function is not implemented
@@ -315,10 +614,195 @@ func findField(fields []ConfigField, name string) *ConfigField {
 	return nil
 }
 
+func TestSecretCipher(t *testing.T) {
+	oldCipher, err := NewSecretCipher("old-key")
+	require.NoError(t, err)
+	sealed, err := oldCipher.Encrypt("s3cret")
+	require.NoError(t, err)
+	assert.True(t, IsEncryptedSecret(sealed))
+	assert.NotContains(t, sealed, "s3cret")
+
+	plain, err := oldCipher.Decrypt(sealed)
+	require.NoError(t, err)
+	assert.Equal(t, "s3cret", plain)
+
+	// Non-string values round-trip through JSON
+	sealedNumber, err := oldCipher.Encrypt(42)
+	require.NoError(t, err)
+	plain, err = oldCipher.Decrypt(sealedNumber)
+	require.NoError(t, err)
+	assert.Equal(t, float64(42), plain)
+
+	newCipher, err := NewSecretCipher("new-key")
+	require.NoError(t, err)
+	_, err = newCipher.Decrypt(sealed)
+	assert.ErrorIs(t, err, ErrSecretUndecryptable)
+
+	rotating, err := NewSecretCipher("new-key", "old-key")
+	require.NoError(t, err)
+	plain, err = rotating.Decrypt(sealed)
+	require.NoError(t, err)
+	assert.Equal(t, "s3cret", plain)
+
+	_, err = NewSecretCipher("")
+	assert.Error(t, err)
+}
+
 func TestEncryptedConfigStorage(t *testing.T) {
-	// This test would require implementing encryption functionality
-	// For now, we'll skip it but leave a placeholder
-	t.Skip("Encryption not yet implemented")
+	raw := NewMemoryConfigStorage()
+	c, err := NewSecretCipher("app-key")
+	require.NoError(t, err)
+
+	cm := NewConfigManagerWithStorage(raw)
+	cm.SetCipher(c)
+	cm.RegisterSchema("test-plugin", &ConfigSchema{Fields: []ConfigField{
+		{Name: "host", Type: "string"},
+		{Name: "password", Type: "string", Secret: true},
+		{Name: "smtp", Type: "object", Fields: []ConfigField{
+			{Name: "token", Type: "string", Secret: true},
+		}},
+	}})
+	cm.MarkSecret("test-plugin", "api_key")
+
+	config := map[string]interface{}{
+		"host":     "localhost",
+		"password": "hunter2",
+		"api_key":  "abc",
+		"smtp":     map[string]interface{}{"token": "t0ken"},
+	}
+	require.NoError(t, cm.SetConfigWithAuthor("test-plugin", config, "admin"))
+	assert.Equal(t, "hunter2", config["password"], "the caller's config must not be modified")
+
+	// Secrets are encrypted at rest, other fields are not
+	stored, err := raw.Load("test-plugin")
+	require.NoError(t, err)
+	assert.Equal(t, "localhost", stored["host"])
+	assert.True(t, IsEncryptedSecret(stored["password"]))
+	assert.True(t, IsEncryptedSecret(stored["api_key"]))
+	assert.True(t, IsEncryptedSecret(stored["smtp"].(map[string]interface{})["token"]))
+
+	rawHistory, err := raw.(RevisionStorage).Revisions("test-plugin")
+	require.NoError(t, err)
+	require.Len(t, rawHistory, 1)
+	assert.True(t, IsEncryptedSecret(rawHistory[0].Config["password"]))
+
+	// Reading back through the manager decrypts them
+	reader := NewConfigManagerWithStorage(raw)
+	reader.SetCipher(c)
+	loaded, ok := reader.GetConfigMap("test-plugin")
+	require.True(t, ok)
+	assert.Equal(t, config, loaded)
+
+	history, err := reader.History("test-plugin")
+	require.NoError(t, err)
+	assert.Equal(t, "hunter2", history[0].Config["password"])
+}
+
+func TestConfigSchema_Secrets(t *testing.T) {
+	schema := &ConfigSchema{Fields: []ConfigField{
+		{Name: "host", Type: "string"},
+		{Name: "password", Type: "string", Secret: true},
+		{Name: "smtp", Type: "object", Fields: []ConfigField{
+			{Name: "token", Type: "string", Secret: true},
+		}},
+	}}
+	assert.Equal(t, []string{"password", "smtp.token"}, schema.SecretPaths())
+
+	// Blank secret fields are left out and then kept from the current config
+	config, err := schema.ParseForm(url.Values{"host": {"example.com"}, "password": {""}, "smtp.token": {"new"}})
+	require.NoError(t, err)
+	assert.NotContains(t, config, "password")
+
+	schema.KeepSecrets(config, map[string]interface{}{
+		"password": "old",
+		"smtp":     map[string]interface{}{"token": "old"},
+	})
+	assert.Equal(t, "old", config["password"])
+	assert.Equal(t, "new", config["smtp"].(map[string]interface{})["token"])
+
+	changes := schema.MaskSecretChanges([]ConfigChange{
+		{Path: "host", Kind: ChangeModified, Old: "a", New: "b"},
+		{Path: "smtp.token", Kind: ChangeModified, Old: "old", New: "new"},
+		{Path: "password", Kind: ChangeAdded, New: "x"},
+	})
+	assert.Equal(t, "b", changes[0].New)
+	assert.Equal(t, SecretMask, changes[1].Old)
+	assert.Equal(t, SecretMask, changes[1].New)
+	assert.Nil(t, changes[2].Old)
+	assert.Equal(t, SecretMask, changes[2].New)
+}
+
+func TestRotateSecrets(t *testing.T) {
+	raw, err := NewJSONFileConfigStorage(t.TempDir())
+	require.NoError(t, err)
+	schema := &ConfigSchema{Fields: []ConfigField{{Name: "password", Type: "string", Secret: true}}}
+
+	oldCipher, err := NewSecretCipher("old-key")
+	require.NoError(t, err)
+	cm := NewConfigManagerWithStorage(raw)
+	cm.SetCipher(oldCipher)
+	cm.RegisterSchema("test-plugin", schema)
+	require.NoError(t, cm.SetConfig("test-plugin", map[string]interface{}{"password": "first"}))
+	require.NoError(t, cm.SetConfig("test-plugin", map[string]interface{}{"password": "second"}))
+
+	rotating, err := NewSecretCipher("new-key", "old-key")
+	require.NoError(t, err)
+	rotated, err := RotateSecrets(raw, rotating, cm.SecretPaths)
+	require.NoError(t, err)
+	assert.Equal(t, 3, rotated)
+
+	// Only the new key is needed afterwards, for the config and its history
+	newCipher, err := NewSecretCipher("new-key")
+	require.NoError(t, err)
+	reader := NewConfigManagerWithStorage(raw)
+	reader.SetCipher(newCipher)
+	loaded, ok := reader.GetConfigMap("test-plugin")
+	require.True(t, ok)
+	assert.Equal(t, "second", loaded["password"])
+
+	history, err := reader.History("test-plugin")
+	require.NoError(t, err)
+	require.Len(t, history, 2)
+	assert.Equal(t, 2, history[0].Version)
+	assert.Equal(t, "first", history[1].Config["password"])
+
+	// Rotating without the old key fails instead of losing secrets
+	_, err = RotateSecrets(raw, oldCipher, nil)
+	assert.ErrorIs(t, err, ErrSecretUndecryptable)
+}
+
+func TestRotateSecrets_EncryptsPlaintext(t *testing.T) {
+	raw, err := NewJSONFileConfigStorage(t.TempDir())
+	require.NoError(t, err)
+
+	// Saved before APP_KEY was set
+	cm := NewConfigManagerWithStorage(raw)
+	cm.RegisterSchema("test-plugin", &ConfigSchema{Fields: []ConfigField{
+		{Name: "password", Type: "string", Secret: true},
+		{Name: "host", Type: "string"},
+	}})
+	require.NoError(t, cm.SetConfig("test-plugin", map[string]interface{}{"password": "plain", "host": "example.com"}))
+
+	c, err := NewSecretCipher("new-key")
+	require.NoError(t, err)
+	rotated, err := RotateSecrets(raw, c, cm.SecretPaths)
+	require.NoError(t, err)
+	assert.Equal(t, 2, rotated, "the config and its revision")
+
+	stored, err := raw.Load("test-plugin")
+	require.NoError(t, err)
+	assert.True(t, IsEncryptedSecret(stored["password"]))
+	assert.Equal(t, "example.com", stored["host"])
+
+	reader := NewConfigManagerWithStorage(raw)
+	reader.SetCipher(c)
+	loaded, ok := reader.GetConfigMap("test-plugin")
+	require.True(t, ok)
+	assert.Equal(t, "plain", loaded["password"])
+	history, err := reader.History("test-plugin")
+	require.NoError(t, err)
+	require.Len(t, history, 1)
+	assert.Equal(t, "plain", history[0].Config["password"])
 }
 
 func TestConfigWatching(t *testing.T) {
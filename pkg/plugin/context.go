@@ -0,0 +1,45 @@
+package plugin
+
+import (
+	"context"
+
+	"github.com/btassone/obtura/pkg/tracing"
+)
+
+const (
+	storeContextKey  contextKey = "plugin.store"
+	configContextKey contextKey = "plugin.config"
+)
+
+// WithPluginID returns a context carrying the calling plugin's ID.
+// Spans started from the context are attributed to the plugin.
+func WithPluginID(ctx context.Context, pluginID string) context.Context {
+	return tracing.WithPluginID(ctx, pluginID)
+}
+
+// PluginIDFromContext returns the plugin ID carried by ctx
+func PluginIDFromContext(ctx context.Context) (string, bool) {
+	return tracing.PluginIDFromContext(ctx)
+}
+
+// WithStore returns a context carrying a plugin's data store
+func WithStore(ctx context.Context, store Store) context.Context {
+	return context.WithValue(ctx, storeContextKey, store)
+}
+
+// StoreFromContext returns the plugin data store carried by ctx, or nil if none is set
+func StoreFromContext(ctx context.Context) Store {
+	store, _ := ctx.Value(storeContextKey).(Store)
+	return store
+}
+
+// WithConfigManager returns a context carrying the config manager, so plugins can watch their config
+func WithConfigManager(ctx context.Context, cm *ConfigManager) context.Context {
+	return context.WithValue(ctx, configContextKey, cm)
+}
+
+// ConfigManagerFromContext returns the config manager carried by ctx, or nil if none is set
+func ConfigManagerFromContext(ctx context.Context) *ConfigManager {
+	cm, _ := ctx.Value(configContextKey).(*ConfigManager)
+	return cm
+}
@@ -0,0 +1,144 @@
+package plugin
+
+import (
+	"fmt"
+	"time"
+)
+
+// State represents a plugin's position in its lifecycle
+type State string
+
+const (
+	StateRegistered  State = "registered"  // Added to the registry
+	StateResolved    State = "resolved"    // Dependencies found and resolved
+	StateInitialized State = "initialized" // Init completed successfully
+	StateStarted     State = "started"     // Start completed, plugin is running
+	StateStopping    State = "stopping"    // Stop in progress
+	StateStopped     State = "stopped"     // Stop completed
+	StateFailed      State = "failed"      // A lifecycle call returned an error
+	StateDisabled    State = "disabled"    // Disabled by an administrator
+)
+
+// EventPluginStateChanged is emitted whenever a plugin changes state
+const EventPluginStateChanged = "plugin.state_changed"
+
+// validTransitions lists the states each state may move to
+var validTransitions = map[State][]State{
+	StateRegistered:  {StateResolved, StateFailed, StateDisabled},
+	StateResolved:    {StateInitialized, StateFailed, StateDisabled},
+	StateInitialized: {StateStarted, StateFailed, StateDisabled},
+	StateStarted:     {StateStopping, StateFailed},
+	StateStopping:    {StateStopped, StateFailed},
+	StateStopped:     {StateStarted, StateDisabled},
+	StateFailed:      {StateRegistered, StateDisabled},
+	StateDisabled:    {StateRegistered},
+}
+
+// CanTransition reports whether a plugin may move from one state to another
+func CanTransition(from, to State) bool {
+	for _, s := range validTransitions[from] {
+		if s == to {
+			return true
+		}
+	}
+	return false
+}
+
+// StateChange describes a single lifecycle transition
+type StateChange struct {
+	PluginID string    `json:"plugin_id"`
+	From     State     `json:"from"`
+	To       State     `json:"to"`
+	At       time.Time `json:"at"`
+	Error    string    `json:"error,omitempty"`
+}
+
+// PluginStatus is a snapshot of a plugin's lifecycle state
+type PluginStatus struct {
+	ID         string              `json:"id"`
+	State      State               `json:"state"`
+	Since      time.Time           `json:"since"`
+	LastError  string              `json:"last_error,omitempty"`
+	ErrorAt    *time.Time          `json:"error_at,omitempty"`
+	History    []StateChange       `json:"history"`
+	Timestamps map[State]time.Time `json:"timestamps"`
+}
+
+// IsActive reports whether the plugin is currently running
+func (s PluginStatus) IsActive() bool {
+	return s.State == StateStarted
+}
+
+// maxStateHistory caps the number of transitions kept per plugin
+const maxStateHistory = 20
+
+// pluginState tracks the lifecycle of a single plugin
+type pluginState struct {
+	state      State
+	since      time.Time
+	lastError  error
+	errorAt    time.Time
+	history    []StateChange
+	timestamps map[State]time.Time
+}
+
+// newPluginState creates state tracking for a freshly registered plugin
+func newPluginState() *pluginState {
+	now := time.Now()
+	return &pluginState{
+		state:      StateRegistered,
+		since:      now,
+		timestamps: map[State]time.Time{StateRegistered: now},
+	}
+}
+
+// transition moves the plugin to a new state, enforcing valid transitions
+func (s *pluginState) transition(pluginID string, to State, cause error) (StateChange, error) {
+	if !CanTransition(s.state, to) {
+		return StateChange{}, fmt.Errorf("plugin %s: invalid state transition from %s to %s", pluginID, s.state, to)
+	}
+
+	now := time.Now()
+	change := StateChange{
+		PluginID: pluginID,
+		From:     s.state,
+		To:       to,
+		At:       now,
+	}
+	if cause != nil {
+		change.Error = cause.Error()
+		s.lastError = cause
+		s.errorAt = now
+	}
+
+	s.state = to
+	s.since = now
+	s.timestamps[to] = now
+	s.history = append(s.history, change)
+	if len(s.history) > maxStateHistory {
+		s.history = s.history[len(s.history)-maxStateHistory:]
+	}
+
+	return change, nil
+}
+
+// status returns an immutable snapshot of the plugin state
+func (s *pluginState) status(pluginID string) PluginStatus {
+	status := PluginStatus{
+		ID:         pluginID,
+		State:      s.state,
+		Since:      s.since,
+		History:    make([]StateChange, len(s.history)),
+		Timestamps: make(map[State]time.Time, len(s.timestamps)),
+	}
+	copy(status.History, s.history)
+	for state, at := range s.timestamps {
+		status.Timestamps[state] = at
+	}
+	if s.lastError != nil {
+		status.LastError = s.lastError.Error()
+		errorAt := s.errorAt
+		status.ErrorAt = &errorAt
+	}
+	return status
+}
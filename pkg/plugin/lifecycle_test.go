@@ -0,0 +1,254 @@
+package plugin
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCanTransition(t *testing.T) {
+	tests := []struct {
+		from State
+		to   State
+		want bool
+	}{
+		{StateRegistered, StateResolved, true},
+		{StateResolved, StateInitialized, true},
+		{StateInitialized, StateStarted, true},
+		{StateStarted, StateStopping, true},
+		{StateStopping, StateStopped, true},
+		{StateStopped, StateStarted, true},
+		{StateFailed, StateRegistered, true},
+		{StateDisabled, StateRegistered, true},
+		{StateRegistered, StateStarted, false},
+		{StateStarted, StateDisabled, false},
+		{StateDisabled, StateStarted, false},
+		{StateStopped, StateInitialized, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.from)+"->"+string(tt.to), func(t *testing.T) {
+			assert.Equal(t, tt.want, CanTransition(tt.from, tt.to))
+		})
+	}
+}
+
+func TestRegistry_LifecycleStates(t *testing.T) {
+	registry := NewRegistry(chi.NewRouter(), WithConfigStorage(NewMemoryConfigStorage()))
+	p := &TestPlugin{id: "test.plugin.1"}
+	require.NoError(t, registry.Register(p))
+
+	status, err := registry.Status("test.plugin.1")
+	require.NoError(t, err)
+	assert.Equal(t, StateRegistered, status.State)
+
+	ctx := context.Background()
+	require.NoError(t, registry.Initialize(ctx))
+	status, _ = registry.Status("test.plugin.1")
+	assert.Equal(t, StateInitialized, status.State)
+	assert.Contains(t, status.Timestamps, StateResolved)
+
+	require.NoError(t, registry.Start(ctx))
+	status, _ = registry.Status("test.plugin.1")
+	assert.Equal(t, StateStarted, status.State)
+	assert.True(t, status.IsActive())
+	assert.True(t, registry.IsEnabled("test.plugin.1"))
+
+	require.NoError(t, registry.Stop(ctx))
+	status, _ = registry.Status("test.plugin.1")
+	assert.Equal(t, StateStopped, status.State)
+	assert.True(t, p.stopped)
+
+	var path []State
+	for _, change := range status.History {
+		path = append(path, change.To)
+	}
+	assert.Equal(t, []State{StateResolved, StateInitialized, StateStarted, StateStopping, StateStopped}, path)
+
+	_, err = registry.Status("test.plugin.missing")
+	assert.Error(t, err)
+}
+
+func TestRegistry_LifecycleFailure(t *testing.T) {
+	registry := NewRegistry(chi.NewRouter(), WithConfigStorage(NewMemoryConfigStorage()))
+	require.NoError(t, registry.Register(&TestPlugin{id: "test.plugin.1", initError: errors.New("boom")}))
+	require.NoError(t, registry.Register(&TestPlugin{id: "test.plugin.2", dependencies: []string{"test.plugin.1"}}))
+
+	err := registry.Initialize(context.Background())
+	require.Error(t, err)
+
+	status, _ := registry.Status("test.plugin.1")
+	assert.Equal(t, StateFailed, status.State)
+	assert.Contains(t, status.LastError, "boom")
+	assert.NotNil(t, status.ErrorAt)
+
+	statuses := registry.Statuses()
+	require.Len(t, statuses, 2)
+	assert.Equal(t, "test.plugin.1", statuses[0].ID)
+}
+
+func TestRegistry_DisableEnable(t *testing.T) {
+	registry := NewRegistry(chi.NewRouter(), WithConfigStorage(NewMemoryConfigStorage()))
+	require.NoError(t, registry.Register(&TestPlugin{id: "test.plugin.1"}))
+	require.NoError(t, registry.Register(&TestPlugin{id: "test.plugin.2", dependencies: []string{"test.plugin.1"}}))
+
+	ctx := context.Background()
+	require.NoError(t, registry.Initialize(ctx))
+	require.NoError(t, registry.Start(ctx))
+
+	// A dependency of a running plugin cannot be disabled
+	assert.Error(t, registry.Disable(ctx, "test.plugin.1"))
+
+	require.NoError(t, registry.Disable(ctx, "test.plugin.2"))
+	status, _ := registry.Status("test.plugin.2")
+	assert.Equal(t, StateDisabled, status.State)
+	assert.False(t, registry.IsEnabled("test.plugin.2"))
+
+	require.NoError(t, registry.Enable(ctx, "test.plugin.2"))
+	status, _ = registry.Status("test.plugin.2")
+	assert.Equal(t, StateStarted, status.State)
+
+	require.NoError(t, registry.Stop(ctx))
+}
+
+func TestRegistry_DisabledPluginsStopResponding(t *testing.T) {
+	router := chi.NewRouter()
+	registry := NewRegistry(router, WithConfigStorage(NewMemoryConfigStorage()))
+	require.NoError(t, registry.Register(&TestRoutablePlugin{
+		TestPlugin: TestPlugin{id: "test.routable"},
+		routes: []Route{{
+			Method: http.MethodGet,
+			Path:   "/test",
+			Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			}),
+		}},
+	}))
+	require.NoError(t, registry.Register(&TestHookablePlugin{
+		TestPlugin: TestPlugin{id: "test.hookable"},
+		hooks: map[string]HookHandler{
+			"content.render": func(ctx context.Context, data interface{}) (interface{}, error) {
+				return data.(string) + "!", nil
+			},
+		},
+	}))
+	received := make(chan Event, 1)
+	require.NoError(t, registry.Register(&TestEventPlugin{
+		TestPlugin: TestPlugin{id: "test.events"},
+		handlers: map[string]EventHandler{
+			"test.event": func(ctx context.Context, event Event) error {
+				received <- event
+				return nil
+			},
+		},
+	}))
+
+	ctx := context.Background()
+	require.NoError(t, registry.Initialize(ctx))
+	require.NoError(t, registry.Start(ctx))
+	defer registry.Stop(ctx)
+
+	serve := func() int {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/test", nil))
+		return rec.Code
+	}
+	assert.Equal(t, http.StatusOK, serve())
+	result, err := registry.ExecuteHook(ctx, "content.render", "hello")
+	require.NoError(t, err)
+	assert.Equal(t, "hello!", result)
+	registry.handleEvent(Event{Name: "test.event", Context: ctx})
+	select {
+	case <-received:
+	case <-time.After(time.Second):
+		t.Fatal("event was not delivered to the started plugin")
+	}
+
+	for _, id := range []string{"test.routable", "test.hookable", "test.events"} {
+		require.NoError(t, registry.Disable(ctx, id))
+	}
+
+	assert.Equal(t, http.StatusNotFound, serve())
+	result, err = registry.ExecuteHook(ctx, "content.render", "hello")
+	require.NoError(t, err)
+	assert.Equal(t, "hello", result)
+	registry.handleEvent(Event{Name: "test.event", Context: ctx})
+	select {
+	case <-received:
+		t.Fatal("event was delivered to a disabled plugin")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	// Re-enabled plugins respond again
+	require.NoError(t, registry.Enable(ctx, "test.routable"))
+	assert.Equal(t, http.StatusOK, serve())
+}
+
+// memoryActivationStore records plugin activation in memory
+type memoryActivationStore struct {
+	enabled map[string]bool
+}
+
+func (s *memoryActivationStore) DisabledPlugins() ([]string, error) {
+	var ids []string
+	for id, enabled := range s.enabled {
+		if !enabled {
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+func (s *memoryActivationStore) SetPluginEnabled(pluginID string, enabled bool) error {
+	s.enabled[pluginID] = enabled
+	return nil
+}
+
+func TestRegistry_ActivationStore(t *testing.T) {
+	store := &memoryActivationStore{enabled: map[string]bool{"test.plugin.2": false}}
+	registry := NewRegistry(chi.NewRouter(), WithConfigStorage(NewMemoryConfigStorage()))
+	require.NoError(t, registry.SetActivationStore(store))
+	require.NoError(t, registry.Register(&TestPlugin{id: "test.plugin.1"}))
+	require.NoError(t, registry.Register(&TestPlugin{id: "test.plugin.2"}))
+
+	// A plugin disabled in the store is registered disabled and not started
+	ctx := context.Background()
+	require.NoError(t, registry.Initialize(ctx))
+	require.NoError(t, registry.Start(ctx))
+	assert.False(t, registry.IsEnabled("test.plugin.2"))
+	assert.True(t, registry.Running())
+
+	require.NoError(t, registry.Enable(ctx, "test.plugin.2"))
+	assert.True(t, store.enabled["test.plugin.2"])
+	require.NoError(t, registry.Disable(ctx, "test.plugin.1"))
+	assert.False(t, store.enabled["test.plugin.1"])
+
+	require.NoError(t, registry.Stop(ctx))
+}
+
+func TestRegistry_StateChangeEvents(t *testing.T) {
+	registry := NewRegistry(chi.NewRouter(), WithConfigStorage(NewMemoryConfigStorage()))
+	require.NoError(t, registry.Register(&TestPlugin{id: "test.plugin.1"}))
+	require.NoError(t, registry.Initialize(context.Background()))
+
+	// Events are queued until the registry starts processing them
+	var changes []StateChange
+	for len(registry.events) > 0 {
+		event := <-registry.events
+		assert.Equal(t, EventPluginStateChanged, event.Name)
+		assert.Equal(t, "test.plugin.1", event.Source)
+		changes = append(changes, event.Data.(StateChange))
+	}
+
+	require.Len(t, changes, 2)
+	assert.Equal(t, StateRegistered, changes[0].From)
+	assert.Equal(t, StateResolved, changes[0].To)
+	assert.Equal(t, StateInitialized, changes[1].To)
+}
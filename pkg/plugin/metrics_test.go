@@ -0,0 +1,73 @@
+package plugin
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/btassone/obtura/pkg/metrics"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegistry_HookMetrics(t *testing.T) {
+	m := metrics.NewRegistry()
+	registry := NewRegistryWithMetrics(chi.NewRouter(), m, WithConfigStorage(NewMemoryConfigStorage()))
+
+	require.NoError(t, registry.Register(&TestHookablePlugin{
+		TestPlugin: TestPlugin{id: "test.plugin.1"},
+		hooks: map[string]HookHandler{
+			"content.render": func(ctx context.Context, data interface{}) (interface{}, error) {
+				return strings.ToUpper(data.(string)), nil
+			},
+		},
+	}))
+	require.NoError(t, registry.Register(&TestHookablePlugin{
+		TestPlugin: TestPlugin{id: "test.plugin.2"},
+		hooks: map[string]HookHandler{
+			"content.save": func(ctx context.Context, data interface{}) (interface{}, error) {
+				return nil, errors.New("rejected")
+			},
+		},
+	}))
+
+	require.NoError(t, registry.Initialize(context.Background()))
+	require.NoError(t, registry.Start(context.Background()))
+	defer registry.Stop(context.Background())
+
+	result, err := registry.ExecuteHook(context.Background(), "content.render", "hello")
+	require.NoError(t, err)
+	assert.Equal(t, "HELLO", result)
+
+	_, err = registry.ExecuteHook(context.Background(), "content.save", "hello")
+	assert.Error(t, err)
+
+	assert.Equal(t, uint64(1), registry.instruments.hookDuration.Count("content.render", "test.plugin.1"))
+	assert.Equal(t, float64(1), registry.instruments.hookErrors.Value("content.save", "test.plugin.2"))
+	assert.Equal(t, float64(0), registry.instruments.hookErrors.Value("content.render", "test.plugin.1"))
+}
+
+func TestRegistry_EventMetrics(t *testing.T) {
+	m := metrics.NewRegistry()
+	registry := NewRegistryWithMetrics(chi.NewRouter(), m, WithConfigStorage(NewMemoryConfigStorage()))
+
+	// Nothing drains the queue before Start, so overflowing it drops events
+	for i := 0; i < cap(registry.events)+3; i++ {
+		registry.EmitEvent(Event{Name: "test.event", Context: context.Background()})
+	}
+
+	assert.Equal(t, float64(cap(registry.events)), registry.instruments.eventsEmitted.Value("test.event"))
+	assert.Equal(t, float64(3), registry.instruments.eventsDropped.Value("test.event"))
+
+	var out strings.Builder
+	require.NoError(t, m.WriteText(&out))
+	assert.Contains(t, out.String(), "plugin_event_queue_depth 100\n")
+
+	// A newer registry sharing the metrics registry reports its own queue
+	NewRegistryWithMetrics(chi.NewRouter(), m, WithConfigStorage(NewMemoryConfigStorage()))
+	out.Reset()
+	require.NoError(t, m.WriteText(&out))
+	assert.Contains(t, out.String(), "plugin_event_queue_depth 0\n")
+}
@@ -0,0 +1,40 @@
+package plugin
+
+import (
+	"context"
+	"reflect"
+
+	"github.com/btassone/obtura/pkg/database"
+)
+
+// ModelEventSource is the Source of events published for model writes
+const ModelEventSource = "database"
+
+// PublishModelEvents emits an event named model.<table>.<action>, such as model.posts.created,
+// for each model write on db once it commits. The event's Data is the database.ModelEvent.
+// Event plugins subscribe by listing the names in EventHandlers.
+//
+// Handlers run while the writer goes on using its model, so the event carries a shallow copy
+// of it. Slices and maps in the model are still shared, and handlers must not change them.
+func (r *Registry) PublishModelEvents(db *database.DB) {
+	db.OnModelEvent(func(event database.ModelEvent) {
+		event.Model = copyModel(event.Model)
+		r.EmitEvent(Event{
+			Name:    event.Name(),
+			Source:  ModelEventSource,
+			Data:    event,
+			Context: context.Background(),
+		})
+	})
+}
+
+// copyModel returns a shallow copy of a model that is a pointer to a struct, or the model itself
+func copyModel(model database.Model) database.Model {
+	v := reflect.ValueOf(model)
+	if v.Kind() != reflect.Pointer || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return model
+	}
+	clone := reflect.New(v.Elem().Type())
+	clone.Elem().Set(v.Elem())
+	return clone.Interface().(database.Model)
+}
@@ -0,0 +1,59 @@
+package plugin
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/btassone/obtura/pkg/database"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type modelEventPage struct {
+	ID    int64  `db:"id"`
+	Title string `db:"title"`
+}
+
+func (p *modelEventPage) TableName() string  { return "pages" }
+func (p *modelEventPage) PrimaryKey() string { return "id" }
+
+func TestRegistry_PublishModelEvents(t *testing.T) {
+	db := newTestDB(t)
+	_, err := db.Exec(`CREATE TABLE pages (id INTEGER PRIMARY KEY AUTOINCREMENT, title TEXT NOT NULL)`)
+	require.NoError(t, err)
+
+	received := make(chan Event, 1)
+	registry := NewRegistry(chi.NewRouter(), WithConfigStorage(NewMemoryConfigStorage()))
+	require.NoError(t, registry.Register(&TestEventPlugin{
+		TestPlugin: TestPlugin{id: "test.search"},
+		handlers: map[string]EventHandler{
+			"model.pages.created": func(ctx context.Context, event Event) error {
+				received <- event
+				return nil
+			},
+		},
+	}))
+	ctx := context.Background()
+	require.NoError(t, registry.Initialize(ctx))
+	require.NoError(t, registry.Start(ctx))
+	t.Cleanup(func() { registry.Stop(ctx) })
+
+	registry.PublishModelEvents(db)
+	page := &modelEventPage{Title: "Home"}
+	require.NoError(t, database.NewRepository[*modelEventPage](db).Create(page))
+	page.Title = "Changed after the write"
+
+	select {
+	case event := <-received:
+		assert.Equal(t, ModelEventSource, event.Source)
+		modelEvent, ok := event.Data.(database.ModelEvent)
+		require.True(t, ok)
+		assert.Equal(t, database.ModelCreated, modelEvent.Action)
+		assert.NotSame(t, page, modelEvent.Model, "handlers get a copy of the model")
+		assert.Equal(t, &modelEventPage{ID: page.ID, Title: "Home"}, modelEvent.Model)
+	case <-time.After(time.Second):
+		t.Fatal("model event was not delivered")
+	}
+}
@@ -0,0 +1,360 @@
+package plugin
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// Config layers, from lowest to highest precedence
+const (
+	LayerDefault = "default"
+	LayerStored  = "stored"
+	LayerFile    = "file"
+	LayerEnv     = "env"
+)
+
+// EnvPrefix starts environment variables that override plugin config, e.g.
+// OBTURA_PLUGIN_COM_OBTURA_AUTH__SESSION_MAX_AGE sets session_max_age for com.obtura.auth.
+// Nested object fields are separated by a further double underscore.
+const EnvPrefix = "OBTURA_PLUGIN_"
+
+// ConfigOrigin records which layer a config value came from
+type ConfigOrigin struct {
+	Layer  string
+	Source string // Config file name or environment variable, for overrides
+}
+
+// Locked reports whether the value is overridden outside of stored config and cannot be edited
+func (o ConfigOrigin) Locked() bool {
+	return o.Layer == LayerFile || o.Layer == LayerEnv
+}
+
+// String describes the origin for display
+func (o ConfigOrigin) String() string {
+	switch o.Layer {
+	case LayerFile:
+		return "config file " + o.Source
+	case LayerEnv:
+		return "environment variable " + o.Source
+	}
+	return o.Layer
+}
+
+// ConfigOrigins maps dotted config paths to the layer their value came from
+type ConfigOrigins map[string]ConfigOrigin
+
+// Locked returns the overriding origin of a path, or of any value nested under it
+func (o ConfigOrigins) Locked(path string) (ConfigOrigin, bool) {
+	if origin, ok := o[path]; ok && origin.Locked() {
+		return origin, true
+	}
+	for _, p := range o.lockedPaths() {
+		if strings.HasPrefix(p, path+".") {
+			return o[p], true
+		}
+	}
+	return ConfigOrigin{}, false
+}
+
+// lockedPaths returns the overridden paths in order
+func (o ConfigOrigins) lockedPaths() []string {
+	var paths []string
+	for path, origin := range o {
+		if origin.Locked() {
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// SetDefaults records a plugin's default config as the lowest layer
+func (cm *ConfigManager) SetDefaults(pluginID string, defaults interface{}) error {
+	config, err := cm.toMap(defaults)
+	if err != nil {
+		return fmt.Errorf("failed to convert defaults to map: %w", err)
+	}
+	// Copy so later changes to the plugin's config struct don't alter the defaults
+	config = normalizeConfig(config)
+
+	cm.saveMu.Lock()
+	defer cm.saveMu.Unlock()
+	cm.mu.Lock()
+	cm.defaults[pluginID] = config
+	cm.mu.Unlock()
+	cm.publish(pluginID)
+	return nil
+}
+
+// LoadConfigFile reads overrides from a YAML (.yaml, .yml) or TOML (.toml) file with
+// a top-level "plugins" table keyed by plugin ID
+func (cm *ConfigManager) LoadConfigFile(path string) error {
+	data, err := readFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	var file struct {
+		Plugins map[string]map[string]interface{} `yaml:"plugins" toml:"plugins"`
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &file)
+	case ".toml":
+		err = toml.Unmarshal(data, &file)
+	default:
+		return fmt.Errorf("unsupported config file type: %s", path)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+
+	overrides := make(map[string]map[string]interface{}, len(file.Plugins))
+	for pluginID, config := range file.Plugins {
+		overrides[pluginID] = normalizeConfig(config)
+	}
+
+	cm.saveMu.Lock()
+	defer cm.saveMu.Unlock()
+	cm.mu.Lock()
+	cm.fileSource = filepath.Base(path)
+	cm.fileOverrides = overrides
+	cm.mu.Unlock()
+	cm.publishAll()
+	return nil
+}
+
+// LoadEnvOverrides keeps the OBTURA_PLUGIN_ variables from environ (as returned by os.Environ).
+// They are matched to plugins and typed by schema when configs are resolved.
+func (cm *ConfigManager) LoadEnvOverrides(environ []string) {
+	env := make(map[string]string)
+	for _, entry := range environ {
+		key, value, ok := strings.Cut(entry, "=")
+		if ok && strings.HasPrefix(key, EnvPrefix) {
+			env[key] = value
+		}
+	}
+
+	cm.saveMu.Lock()
+	defer cm.saveMu.Unlock()
+	cm.mu.Lock()
+	cm.env = env
+	cm.mu.Unlock()
+	cm.publishAll()
+}
+
+// EffectiveConfig resolves a plugin's config from its defaults, stored config, config file
+// and environment, in increasing precedence, and reports where each value came from
+func (cm *ConfigManager) EffectiveConfig(pluginID string) (map[string]interface{}, ConfigOrigins, error) {
+	stored, _ := cm.GetConfigMap(pluginID)
+	return cm.resolveConfig(pluginID, stored)
+}
+
+// configLayers holds the default and override layers of one plugin's config
+type configLayers struct {
+	defaults   map[string]interface{}
+	file       map[string]interface{}
+	fileSource string
+	env        map[string]string
+	fields     []ConfigField
+}
+
+// layers copies out a plugin's layers so they can be merged without holding cm.mu.
+// The maps are replaced rather than modified when layers change, so sharing them is safe.
+func (cm *ConfigManager) layers(pluginID string) configLayers {
+	cm.mu.RLock()
+	defer cm.mu.RUnlock()
+	layers := configLayers{
+		defaults:   cm.defaults[pluginID],
+		file:       cm.fileOverrides[pluginID],
+		fileSource: cm.fileSource,
+		env:        cm.env,
+	}
+	if schema, ok := cm.schemas[pluginID]; ok {
+		layers.fields = schema.Fields
+	}
+	return layers
+}
+
+// resolveConfig layers defaults and overrides around a stored config
+func (cm *ConfigManager) resolveConfig(pluginID string, stored map[string]interface{}) (map[string]interface{}, ConfigOrigins, error) {
+	layers := cm.layers(pluginID)
+	config := make(map[string]interface{})
+	origins := make(ConfigOrigins)
+
+	mergeLayer(config, origins, layers.defaults, "", func(string) ConfigOrigin {
+		return ConfigOrigin{Layer: LayerDefault}
+	})
+	mergeLayer(config, origins, normalizeConfig(stored), "", func(string) ConfigOrigin {
+		return ConfigOrigin{Layer: LayerStored}
+	})
+	mergeLayer(config, origins, layers.file, "", func(string) ConfigOrigin {
+		return ConfigOrigin{Layer: LayerFile, Source: layers.fileSource}
+	})
+
+	env, sources, err := envOverrides(pluginID, layers)
+	if err != nil {
+		return nil, nil, err
+	}
+	mergeLayer(config, origins, env, "", func(path string) ConfigOrigin {
+		return ConfigOrigin{Layer: LayerEnv, Source: sources[path]}
+	})
+
+	return config, origins, nil
+}
+
+// RestoreOverridden replaces overridden values in a submitted config with the stored ones,
+// so saving a form never persists values that come from the config file or environment
+func (cm *ConfigManager) RestoreOverridden(pluginID string, config map[string]interface{}) error {
+	_, origins, err := cm.EffectiveConfig(pluginID)
+	if err != nil {
+		return err
+	}
+	stored, _ := cm.GetConfigMap(pluginID)
+	for _, path := range origins.lockedPaths() {
+		if value, ok := lookupPath(stored, path); ok {
+			setPath(config, path, value)
+		} else {
+			deletePath(config, path)
+		}
+	}
+	return nil
+}
+
+// envOverrides parses the plugin's environment variables into a nested config,
+// returning the variable each path came from
+func envOverrides(pluginID string, layers configLayers) (map[string]interface{}, map[string]string, error) {
+	prefix := EnvPrefix + envName(pluginID) + "__"
+	config := make(map[string]interface{})
+	sources := make(map[string]string)
+
+	for key, raw := range layers.env {
+		if !strings.HasPrefix(key, prefix) {
+			continue
+		}
+		path, field := envPath(layers.fields, strings.Split(strings.TrimPrefix(key, prefix), "__"))
+		value, err := parseEnvValue(field, raw)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s: %w", key, err)
+		}
+		setPath(config, path, value)
+		sources[path] = key
+	}
+	return config, sources, nil
+}
+
+// envPath maps variable name segments to a dotted config path, following schema field names
+// where they match so that SESSION_MAX_AGE resolves to session_max_age
+func envPath(fields []ConfigField, segments []string) (string, *ConfigField) {
+	var names []string
+	var field *ConfigField
+	for _, segment := range segments {
+		field = nil
+		for i := range fields {
+			if envName(fields[i].Name) == segment {
+				field = &fields[i]
+				break
+			}
+		}
+		if field == nil {
+			names = append(names, strings.ToLower(segment))
+			fields = nil
+			continue
+		}
+		names = append(names, field.Name)
+		fields = field.Fields
+	}
+	return strings.Join(names, "."), field
+}
+
+// parseEnvValue converts an environment variable to the field's type; unknown fields stay strings
+func parseEnvValue(field *ConfigField, raw string) (interface{}, error) {
+	if field == nil {
+		return raw, nil
+	}
+	switch field.Type {
+	case "number":
+		n, err := strconv.ParseFloat(strings.TrimSpace(raw), 64)
+		if err != nil {
+			return nil, fmt.Errorf("expected number")
+		}
+		return n, nil
+	case "boolean":
+		return parseFormBool(raw)
+	case "multiselect":
+		values := []interface{}{}
+		for _, v := range strings.Split(raw, ",") {
+			if v = strings.TrimSpace(v); v != "" {
+				values = append(values, v)
+			}
+		}
+		return values, nil
+	case "object", "array", "map":
+		var decoded interface{}
+		if err := json.Unmarshal([]byte(raw), &decoded); err != nil {
+			return nil, fmt.Errorf("invalid JSON: %w", err)
+		}
+		return decoded, nil
+	}
+	return raw, nil
+}
+
+// envName converts a plugin ID or field name to its environment variable form
+func envName(name string) string {
+	var b strings.Builder
+	for _, r := range strings.ToUpper(name) {
+		if (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			b.WriteRune(r)
+		} else {
+			b.WriteRune('_')
+		}
+	}
+	return b.String()
+}
+
+// mergeLayer copies src over dst, merging nested objects and recording the origin of each value
+func mergeLayer(dst map[string]interface{}, origins ConfigOrigins, src map[string]interface{}, prefix string, origin func(path string) ConfigOrigin) {
+	for key, value := range src {
+		path := prefix + key
+		if nested, ok := value.(map[string]interface{}); ok {
+			existing, ok := dst[key].(map[string]interface{})
+			if !ok {
+				existing = make(map[string]interface{})
+			} else {
+				existing = copyConfig(existing)
+			}
+			delete(origins, path)
+			mergeLayer(existing, origins, nested, path+".", origin)
+			dst[key] = existing
+			continue
+		}
+		for p := range origins {
+			if strings.HasPrefix(p, path+".") {
+				delete(origins, p)
+			}
+		}
+		dst[key] = copyConfigValue(value)
+		origins[path] = origin(path)
+	}
+}
+
+// deletePath removes the value at a dotted path
+func deletePath(config map[string]interface{}, path string) {
+	keys := strings.Split(path, ".")
+	current := config
+	for _, key := range keys[:len(keys)-1] {
+		next, ok := current[key].(map[string]interface{})
+		if !ok {
+			return
+		}
+		current = next
+	}
+	delete(current, keys[len(keys)-1])
+}
@@ -0,0 +1,155 @@
+package plugin
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var overrideSchema = &ConfigSchema{Fields: []ConfigField{
+	{Name: "host", Type: "string"},
+	{Name: "port", Type: "number"},
+	{Name: "debug", Type: "boolean"},
+	{Name: "session_max_age", Type: "number"},
+	{Name: "smtp", Type: "object", Fields: []ConfigField{
+		{Name: "user", Type: "string"},
+		{Name: "tls", Type: "boolean"},
+	}},
+}}
+
+func writeConfigFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	return path
+}
+
+func TestConfigManager_EffectiveConfig(t *testing.T) {
+	cm := NewConfigManager()
+	cm.RegisterSchema("com.example.mail", overrideSchema)
+	require.NoError(t, cm.SetDefaults("com.example.mail", map[string]interface{}{
+		"host": "localhost", "port": 25, "debug": false, "session_max_age": 60,
+		"smtp": map[string]interface{}{"user": "default", "tls": false},
+	}))
+	require.NoError(t, cm.SetConfig("com.example.mail", map[string]interface{}{
+		"host": "stored.example.com", "port": 2525,
+		"smtp": map[string]interface{}{"user": "stored"},
+	}))
+
+	require.NoError(t, cm.LoadConfigFile(writeConfigFile(t, "obtura.yaml", `
+plugins:
+  com.example.mail:
+    port: 587
+    smtp:
+      tls: true
+`)))
+	cm.LoadEnvOverrides([]string{
+		"OBTURA_PLUGIN_COM_EXAMPLE_MAIL__SESSION_MAX_AGE=3600",
+		"OBTURA_PLUGIN_COM_EXAMPLE_MAIL__DEBUG=true",
+		"OBTURA_PLUGIN_COM_EXAMPLE_MAIL__SMTP__USER=env-user",
+		"OBTURA_PLUGIN_OTHER__HOST=ignored",
+		"PATH=/usr/bin",
+	})
+
+	config, origins, err := cm.EffectiveConfig("com.example.mail")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"host":            "stored.example.com",
+		"port":            float64(587),
+		"debug":           true,
+		"session_max_age": float64(3600),
+		"smtp":            map[string]interface{}{"user": "env-user", "tls": true},
+	}, config)
+
+	assert.Equal(t, ConfigOrigin{Layer: LayerStored}, origins["host"])
+	assert.Equal(t, ConfigOrigin{Layer: LayerFile, Source: "obtura.yaml"}, origins["port"])
+	assert.Equal(t, ConfigOrigin{Layer: LayerFile, Source: "obtura.yaml"}, origins["smtp.tls"])
+	assert.Equal(t, ConfigOrigin{Layer: LayerEnv, Source: "OBTURA_PLUGIN_COM_EXAMPLE_MAIL__SESSION_MAX_AGE"}, origins["session_max_age"])
+	assert.Equal(t, ConfigOrigin{Layer: LayerEnv, Source: "OBTURA_PLUGIN_COM_EXAMPLE_MAIL__SMTP__USER"}, origins["smtp.user"])
+
+	_, locked := origins.Locked("host")
+	assert.False(t, locked)
+	origin, locked := origins.Locked("smtp")
+	assert.True(t, locked, "an object is locked when a nested value is overridden")
+	assert.True(t, origin.Locked())
+
+	// Overrides are applied when loading, but never stored
+	var target struct {
+		Port          int  `json:"port"`
+		SessionMaxAge int  `json:"session_max_age"`
+		Debug         bool `json:"debug"`
+	}
+	require.NoError(t, cm.LoadConfig("com.example.mail", &target))
+	assert.Equal(t, 587, target.Port)
+	assert.Equal(t, 3600, target.SessionMaxAge)
+	assert.True(t, target.Debug)
+
+	stored, err := cm.storage.Load("com.example.mail")
+	require.NoError(t, err)
+	assert.Equal(t, 2525, stored["port"])
+	assert.NotContains(t, stored, "session_max_age")
+}
+
+func TestConfigManager_EnvOverrideErrors(t *testing.T) {
+	cm := NewConfigManager()
+	cm.RegisterSchema("com.example.mail", overrideSchema)
+	cm.LoadEnvOverrides([]string{"OBTURA_PLUGIN_COM_EXAMPLE_MAIL__PORT=not-a-number"})
+
+	_, _, err := cm.EffectiveConfig("com.example.mail")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "OBTURA_PLUGIN_COM_EXAMPLE_MAIL__PORT")
+}
+
+func TestConfigManager_LoadConfigFile(t *testing.T) {
+	cm := NewConfigManager()
+	require.NoError(t, cm.LoadConfigFile(writeConfigFile(t, "obtura.toml", `
+[plugins."com.example.mail"]
+host = "toml.example.com"
+
+[plugins."com.example.mail".smtp]
+tls = true
+`)))
+
+	config, origins, err := cm.EffectiveConfig("com.example.mail")
+	require.NoError(t, err)
+	assert.Equal(t, "toml.example.com", config["host"])
+	assert.Equal(t, true, config["smtp"].(map[string]interface{})["tls"])
+	assert.Equal(t, ConfigOrigin{Layer: LayerFile, Source: "obtura.toml"}, origins["host"])
+
+	assert.Error(t, cm.LoadConfigFile(writeConfigFile(t, "obtura.json", `{}`)))
+	assert.Error(t, cm.LoadConfigFile(writeConfigFile(t, "obtura.yaml", "plugins: [")))
+}
+
+func TestConfigManager_RestoreOverridden(t *testing.T) {
+	cm := NewConfigManager()
+	cm.RegisterSchema("com.example.mail", overrideSchema)
+	require.NoError(t, cm.SetConfig("com.example.mail", map[string]interface{}{"host": "stored", "port": 25}))
+	cm.LoadEnvOverrides([]string{
+		"OBTURA_PLUGIN_COM_EXAMPLE_MAIL__PORT=587",
+		"OBTURA_PLUGIN_COM_EXAMPLE_MAIL__DEBUG=true",
+	})
+
+	// A submitted form carries disabled fields as missing or zero values
+	submitted := map[string]interface{}{"host": "edited", "port": float64(587), "debug": false}
+	require.NoError(t, cm.RestoreOverridden("com.example.mail", submitted))
+	assert.Equal(t, map[string]interface{}{"host": "edited", "port": 25}, submitted)
+}
+
+func TestRegistry_ConfigOverrides(t *testing.T) {
+	r := NewRegistry(chi.NewRouter(), WithConfigStorage(NewMemoryConfigStorage()))
+	r.GetConfigManager().LoadEnvOverrides([]string{"OBTURA_PLUGIN_TEST_REVISIONS__LIMIT=9"})
+
+	p := &RevisionTestPlugin{TestPlugin: TestPlugin{id: "test.revisions"}, config: &revisionTestConfig{}}
+	require.NoError(t, r.Register(p))
+	assert.Equal(t, "default", p.config.Name)
+	assert.Equal(t, 9, p.config.Limit)
+
+	// Saving keeps the override applied
+	require.NoError(t, r.SetConfig("test.revisions", map[string]interface{}{"name": "saved", "limit": 2}))
+	assert.Equal(t, "saved", p.config.Name)
+	assert.Equal(t, 9, p.config.Limit)
+}
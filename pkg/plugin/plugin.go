@@ -2,6 +2,7 @@ package plugin
 
 import (
 	"context"
+	"io/fs"
 	"net/http"
 )
 
@@ -93,6 +94,14 @@ type MigrationPlugin interface {
 	Migrations() []Migration
 }
 
+// MigrationFSPlugin ships SQL migrations, NNN_name.up.sql files with optional .down.sql rollbacks
+// and per-dialect variants, usually embedded with go:embed. They run with the core migrations,
+// with versions prefixed by the plugin ID.
+type MigrationFSPlugin interface {
+	Plugin
+	MigrationFS() fs.FS
+}
+
 // Migration represents a database migration
 type Migration struct {
 	Version     string
@@ -248,8 +257,10 @@ type Setting struct {
 	Default     interface{}
 	Options     []SettingOption // For select/radio types
 	Validation  SettingValidation
-	Group       string // Settings group
-	Order       int    // Display order
+	Group       string   // Settings group
+	Order       int      // Display order
+	Secret      bool     // Encrypted at rest, masked and write-only in forms
+	Accept      []string // For file types: allowed extensions such as ".pdf", DefaultFileAccept if empty
 }
 
 // SettingType defines the type of setting
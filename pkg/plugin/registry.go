@@ -3,9 +3,17 @@ package plugin
 import (
 	"context"
 	"fmt"
+	"io/fs"
+	"log/slog"
 	"net/http"
+	"reflect"
+	"sort"
 	"sync"
+	"time"
 
+	"github.com/btassone/obtura/pkg/logging"
+	"github.com/btassone/obtura/pkg/metrics"
+	"github.com/btassone/obtura/pkg/tracing"
 	"github.com/go-chi/chi/v5"
 )
 
@@ -14,39 +22,120 @@ type Registry struct {
 	mu       sync.RWMutex
 	plugins  map[string]Plugin
 	services map[string]interface{}
-	hooks    map[string][]HookHandler
+	hooks    map[string][]hookEntry
 	events   chan Event
 	router   *chi.Mux
-	routes   []Route // Store routes until router is set
+	routes   []pluginRoute // Store routes until router is set
 	
-	// Plugin states
-	initialized map[string]bool
-	started     map[string]bool
+	// Plugin lifecycle states
+	states     map[string]*pluginState
+	startOrder []string
+	running    bool
 	
-	// Configuration
+	// stopEvents cancels the background event processor
+	stopEvents context.CancelFunc
+	
+	// Configuration; configMu orders config changes so plugins' config structs are written by one at a time
 	configManager *ConfigManager
+	configMu      sync.Mutex
+	
+	// Per-plugin data stores
+	storeProvider StoreProvider
+	
+	// Persisted plugin activation, see SetActivationStore
+	activation ActivationStore
+	disabled   map[string]bool
+	
+	// Logging
+	logManager *logging.Manager
+	
+	// Metrics
+	metrics     *metrics.Registry
+	instruments *registryInstruments
 }
 
-// NewRegistry creates a new plugin registry
-func NewRegistry(router *chi.Mux) *Registry {
-	// Create config manager with file storage
-	configStorage, err := NewJSONFileConfigStorage("./configs/plugins")
-	if err != nil {
-		// Fall back to memory storage
-		configStorage = NewMemoryConfigStorage()
+// pluginRoute is a route and the plugin that registered it
+type pluginRoute struct {
+	pluginID string
+	route    Route
+}
+
+// hookEntry is a hook handler and the plugin that registered it
+type hookEntry struct {
+	pluginID string
+	handler  HookHandler
+}
+
+// registryInstruments are the metrics recorded by the registry
+type registryInstruments struct {
+	hookDuration       *metrics.Histogram
+	hookErrors         *metrics.Counter
+	eventsEmitted      *metrics.Counter
+	eventsDropped      *metrics.Counter
+	eventHandlerErrors *metrics.Counter
+}
+
+// RegistryOption configures a registry as it is created
+type RegistryOption func(*Registry)
+
+// WithConfigStorage stores plugin configurations in storage instead of JSON files under
+// ./configs/plugins, which are then never created
+func WithConfigStorage(storage ConfigStorage) RegistryOption {
+	return func(r *Registry) {
+		r.configManager = NewConfigManagerWithStorage(storage)
 	}
-	
-	return &Registry{
+}
+
+// NewRegistry creates a new plugin registry with its own metrics registry
+func NewRegistry(router *chi.Mux, opts ...RegistryOption) *Registry {
+	return NewRegistryWithMetrics(router, metrics.NewRegistry(), opts...)
+}
+
+// NewRegistryWithMetrics creates a new plugin registry that records metrics into m.
+// Plugin configs are stored as JSON files under ./configs/plugins unless WithConfigStorage is given.
+func NewRegistryWithMetrics(router *chi.Mux, m *metrics.Registry, opts ...RegistryOption) *Registry {
+	r := &Registry{
 		plugins:       make(map[string]Plugin),
 		services:      make(map[string]interface{}),
-		hooks:         make(map[string][]HookHandler),
+		hooks:         make(map[string][]hookEntry),
 		events:        make(chan Event, 100),
 		router:        router,
-		routes:        make([]Route, 0),
-		initialized:   make(map[string]bool),
-		started:       make(map[string]bool),
-		configManager: NewConfigManagerWithStorage(configStorage),
+		routes:        make([]pluginRoute, 0),
+		states:        make(map[string]*pluginState),
+		disabled:      make(map[string]bool),
+		storeProvider: NewMemoryStoreProvider(),
+		logManager:    logging.Default(),
+		metrics:       m,
+	}
+	for _, opt := range opts {
+		opt(r)
 	}
+	
+	if r.configManager == nil {
+		configStorage, err := NewJSONFileConfigStorage("./configs/plugins")
+		if err != nil {
+			// Fall back to memory storage; use WithConfigStorage to choose another persistent storage
+			r.logManager.Logger().Warn("Plugin configs will not persist, using memory storage", "error", err)
+			configStorage = NewMemoryConfigStorage()
+		}
+		r.configManager = NewConfigManagerWithStorage(configStorage)
+	}
+	
+	r.instruments = &registryInstruments{
+		hookDuration:       m.Histogram("plugin_hook_duration_seconds", "Hook handler execution time in seconds.", metrics.DefaultBuckets, "hook", "plugin"),
+		hookErrors:         m.Counter("plugin_hook_errors_total", "Hook handler errors.", "hook", "plugin"),
+		eventsEmitted:      m.Counter("plugin_events_emitted_total", "Events queued for delivery.", "event"),
+		eventsDropped:      m.Counter("plugin_events_dropped_total", "Events dropped because the queue was full.", "event"),
+		eventHandlerErrors: m.Counter("plugin_event_handler_errors_total", "Event handler errors.", "event", "plugin"),
+	}
+	m.GaugeFunc("plugin_event_queue_depth", "Events waiting in the queue.", func() float64 {
+		return float64(len(r.events))
+	})
+	m.GaugeFunc("plugin_event_queue_capacity", "Capacity of the event queue.", func() float64 {
+		return float64(cap(r.events))
+	})
+	
+	return r
 }
 
 // Register adds a plugin to the registry
@@ -61,12 +150,41 @@ func (r *Registry) Register(p Plugin) error {
 	
 	// Don't check dependencies during registration - defer to Initialize
 	r.plugins[id] = p
+	r.states[id] = newPluginState()
 	
-	// Register default config and schema
-	r.configManager.SetConfig(id, p.DefaultConfig())
+	// Plugins disabled by an administrator stay disabled
+	if r.disabled[id] {
+		if err := r.setState(id, StateDisabled, nil); err != nil {
+			return err
+		}
+	}
+	
+	// Register the schema and secret settings first so defaults are saved with secrets encrypted
 	if schema := GenerateSchemaFromStruct(p.Config()); schema != nil {
 		r.configManager.RegisterSchema(id, schema)
 	}
+	if sp, ok := p.(SettingsPlugin); ok {
+		for _, setting := range sp.Settings() {
+			if setting.Secret {
+				r.configManager.MarkSecret(id, setting.Key)
+			}
+		}
+	}
+	
+	// Keep a stored config, which may be shared with other instances; otherwise store the defaults
+	if err := r.configManager.SetDefaults(id, p.DefaultConfig()); err != nil {
+		r.logManager.Logger().Warn("Ignoring plugin default config", "plugin", id, "error", err)
+	}
+	if _, ok := r.configManager.GetConfig(id); !ok {
+		if err := r.configManager.SetConfig(id, p.DefaultConfig()); err != nil {
+			r.logManager.Logger().Warn("Failed to save default plugin config", "plugin", id, "error", err)
+		}
+	}
+	
+	// Load the effective config, including config file and environment overrides, into the plugin
+	if err := loadEffectiveConfig(r.configManager, p); err != nil {
+		r.logManager.Logger().Warn("Ignoring stored plugin config", "plugin", id, "error", err)
+	}
 	
 	// Register services if this is a service plugin
 	if sp, ok := p.(ServicePlugin); ok {
@@ -76,14 +194,14 @@ func (r *Registry) Register(p Plugin) error {
 	// Register hooks if this is a hookable plugin
 	if hp, ok := p.(HookablePlugin); ok {
 		for hookName, handler := range hp.Hooks() {
-			r.hooks[hookName] = append(r.hooks[hookName], handler)
+			r.hooks[hookName] = append(r.hooks[hookName], hookEntry{pluginID: id, handler: handler})
 		}
 	}
 	
 	// Register routes if this is a routable plugin
 	if rp, ok := p.(RoutablePlugin); ok {
 		for _, route := range rp.Routes() {
-			r.registerRoute(route)
+			r.registerRoute(id, route)
 		}
 	}
 	
@@ -92,7 +210,7 @@ func (r *Registry) Register(p Plugin) error {
 		for _, route := range ap.AdminRoutes() {
 			// Prefix admin routes
 			route.Path = "/admin" + route.Path
-			r.registerRoute(route)
+			r.registerRoute(id, route)
 		}
 	}
 	
@@ -100,10 +218,10 @@ func (r *Registry) Register(p Plugin) error {
 }
 
 // registerRoute registers a single route
-func (r *Registry) registerRoute(route Route) {
+func (r *Registry) registerRoute(pluginID string, route Route) {
 	// If router is not set, store routes for later
 	if r.router == nil {
-		r.routes = append(r.routes, route)
+		r.routes = append(r.routes, pluginRoute{pluginID: pluginID, route: route})
 		return
 	}
 	
@@ -114,9 +232,15 @@ func (r *Registry) registerRoute(route Route) {
 		handler = route.Middlewares[i](handler)
 	}
 	
-	// Convert back to HandlerFunc for chi
+	// Convert back to HandlerFunc for chi, attributing the request to the plugin.
+	// Routes of a plugin that isn't started, for example one disabled at runtime, are not found.
 	handlerFunc := func(w http.ResponseWriter, req *http.Request) {
-		handler.ServeHTTP(w, req)
+		if !r.IsEnabled(pluginID) {
+			http.NotFound(w, req)
+			return
+		}
+		tracing.SpanFromContext(req.Context()).SetAttributes(tracing.Attr("plugin.id", pluginID))
+		handler.ServeHTTP(w, req.WithContext(WithPluginID(req.Context(), pluginID)))
 	}
 	
 	switch route.Method {
@@ -143,8 +267,8 @@ func (r *Registry) SetRouter(router *chi.Mux) {
 	r.router = router
 	
 	// Register all stored routes
-	for _, route := range r.routes {
-		r.registerRoute(route)
+	for _, pr := range r.routes {
+		r.registerRoute(pr.pluginID, pr.route)
 	}
 	
 	// Clear stored routes
@@ -166,7 +290,8 @@ func (r *Registry) IsEnabled(pluginID string) bool {
 	defer r.mu.RUnlock()
 	
 	// A plugin is considered enabled if it's started
-	return r.started[pluginID]
+	ps, ok := r.states[pluginID]
+	return ok && ps.state == StateStarted
 }
 
 // Get returns a plugin by ID
@@ -193,6 +318,20 @@ func (r *Registry) List() []Plugin {
 	return list
 }
 
+// MigrationFS returns the SQL migrations of plugins that ship them, keyed by plugin ID
+func (r *Registry) MigrationFS() map[string]fs.FS {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	
+	migrations := make(map[string]fs.FS)
+	for id, p := range r.plugins {
+		if mp, ok := p.(MigrationFSPlugin); ok {
+			migrations[id] = mp.MigrationFS()
+		}
+	}
+	return migrations
+}
+
 // Initialize initializes all plugins
 func (r *Registry) Initialize(ctx context.Context) error {
 	r.mu.Lock()
@@ -201,6 +340,9 @@ func (r *Registry) Initialize(ctx context.Context) error {
 	// Initialize in dependency order
 	visiting := make(map[string]bool)
 	for _, p := range r.plugins {
+		if r.states[p.ID()].state == StateDisabled {
+			continue
+		}
 		if err := r.initializePluginWithCycleCheck(ctx, p, visiting); err != nil {
 			return err
 		}
@@ -212,10 +354,19 @@ func (r *Registry) Initialize(ctx context.Context) error {
 // initializePluginWithCycleCheck initializes a plugin with circular dependency detection
 func (r *Registry) initializePluginWithCycleCheck(ctx context.Context, p Plugin, visiting map[string]bool) error {
 	id := p.ID()
+	ps := r.states[id]
 	
-	// Already initialized
-	if r.initialized[id] {
+	switch ps.state {
+	case StateInitialized, StateStarted, StateStopping, StateStopped:
+		// Already initialized
 		return nil
+	case StateDisabled:
+		return fmt.Errorf("plugin %s is disabled", id)
+	case StateFailed:
+		// Allow a failed plugin to be retried from scratch
+		if err := r.setState(id, StateRegistered, nil); err != nil {
+			return err
+		}
 	}
 	
 	// Check for circular dependency
@@ -230,9 +381,14 @@ func (r *Registry) initializePluginWithCycleCheck(ctx context.Context, p Plugin,
 	for _, depID := range p.Dependencies() {
 		dep, ok := r.plugins[depID]
 		if !ok {
-			return fmt.Errorf("plugin %s requires %s which is not registered", id, depID)
+			err := fmt.Errorf("plugin %s requires %s which is not registered", id, depID)
+			r.setState(id, StateFailed, err)
+			return err
 		}
 		if err := r.initializePluginWithCycleCheck(ctx, dep, visiting); err != nil {
+			if ps.state != StateFailed {
+				r.setState(id, StateFailed, err)
+			}
 			return err
 		}
 	}
@@ -240,42 +396,18 @@ func (r *Registry) initializePluginWithCycleCheck(ctx context.Context, p Plugin,
 	// Mark as no longer visiting
 	delete(visiting, id)
 	
-	// Initialize this plugin
-	if err := p.Init(ctx); err != nil {
-		return fmt.Errorf("failed to initialize plugin %s: %w", id, err)
-	}
-	
-	r.initialized[id] = true
-	return nil
-}
-
-// initializePlugin initializes a single plugin and its dependencies
-func (r *Registry) initializePlugin(ctx context.Context, p Plugin) error {
-	id := p.ID()
-	
-	// Already initialized
-	if r.initialized[id] {
-		return nil
-	}
-	
-	// Check and initialize dependencies first
-	for _, depID := range p.Dependencies() {
-		dep, ok := r.plugins[depID]
-		if !ok {
-			return fmt.Errorf("plugin %s requires %s which is not registered", id, depID)
-		}
-		if err := r.initializePlugin(ctx, dep); err != nil {
-			return err
-		}
+	if err := r.setState(id, StateResolved, nil); err != nil {
+		return err
 	}
 	
 	// Initialize this plugin
-	if err := p.Init(ctx); err != nil {
-		return fmt.Errorf("failed to initialize plugin %s: %w", id, err)
+	if err := p.Init(r.pluginContext(ctx, id)); err != nil {
+		err = fmt.Errorf("failed to initialize plugin %s: %w", id, err)
+		r.setState(id, StateFailed, err)
+		return err
 	}
 	
-	r.initialized[id] = true
-	return nil
+	return r.setState(id, StateInitialized, nil)
 }
 
 // Start starts all plugins
@@ -284,13 +416,22 @@ func (r *Registry) Start(ctx context.Context) error {
 	defer r.mu.Unlock()
 	
 	for _, p := range r.plugins {
+		if r.states[p.ID()].state == StateDisabled {
+			continue
+		}
 		if err := r.startPlugin(ctx, p); err != nil {
 			return err
 		}
 	}
 	
-	// Start event processor
-	go r.processEvents(ctx)
+	r.running = true
+	
+	// Start event processor; it runs until ctx is done or the registry stops
+	if r.stopEvents == nil {
+		eventCtx, cancel := context.WithCancel(ctx)
+		r.stopEvents = cancel
+		go r.processEvents(eventCtx)
+	}
 	
 	return nil
 }
@@ -298,28 +439,40 @@ func (r *Registry) Start(ctx context.Context) error {
 // startPlugin starts a single plugin
 func (r *Registry) startPlugin(ctx context.Context, p Plugin) error {
 	id := p.ID()
+	ps := r.states[id]
 	
-	// Already started
-	if r.started[id] {
+	switch ps.state {
+	case StateStarted:
+		// Already started
 		return nil
+	case StateInitialized, StateStopped:
+		// Ready to start
+	case StateDisabled:
+		return fmt.Errorf("plugin %s is disabled", id)
+	default:
+		return fmt.Errorf("plugin %s cannot be started from state %s", id, ps.state)
 	}
 	
 	// Start dependencies first
 	for _, depID := range p.Dependencies() {
 		if dep, ok := r.plugins[depID]; ok {
 			if err := r.startPlugin(ctx, dep); err != nil {
+				err = fmt.Errorf("plugin %s requires %s: %w", id, depID, err)
+				r.setState(id, StateFailed, err)
 				return err
 			}
 		}
 	}
 	
 	// Start this plugin
-	if err := p.Start(ctx); err != nil {
-		return fmt.Errorf("failed to start plugin %s: %w", id, err)
+	if err := p.Start(r.pluginContext(ctx, id)); err != nil {
+		err = fmt.Errorf("failed to start plugin %s: %w", id, err)
+		r.setState(id, StateFailed, err)
+		return err
 	}
 	
-	r.started[id] = true
-	return nil
+	r.startOrder = append(r.startOrder, id)
+	return r.setState(id, StateStarted, nil)
 }
 
 // Stop stops all plugins
@@ -327,16 +480,20 @@ func (r *Registry) Stop(ctx context.Context) error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 	
-	// Stop in reverse order
+	// Stop in reverse start order so dependents stop before their dependencies
 	var errs []error
-	for _, p := range r.plugins {
-		if r.started[p.ID()] {
-			if err := p.Stop(ctx); err != nil {
-				errs = append(errs, fmt.Errorf("failed to stop plugin %s: %w", p.ID(), err))
-			}
-			r.started[p.ID()] = false
+	for i := len(r.startOrder) - 1; i >= 0; i-- {
+		if err := r.stopPlugin(ctx, r.plugins[r.startOrder[i]]); err != nil {
+			errs = append(errs, err)
 		}
 	}
+	r.startOrder = nil
+	r.running = false
+	
+	if r.stopEvents != nil {
+		r.stopEvents()
+		r.stopEvents = nil
+	}
 	
 	if len(errs) > 0 {
 		return fmt.Errorf("errors stopping plugins: %v", errs)
@@ -345,17 +502,173 @@ func (r *Registry) Stop(ctx context.Context) error {
 	return nil
 }
 
+// stopPlugin stops a single started plugin
+func (r *Registry) stopPlugin(ctx context.Context, p Plugin) error {
+	id := p.ID()
+	if r.states[id].state != StateStarted {
+		return nil
+	}
+	
+	if err := r.setState(id, StateStopping, nil); err != nil {
+		return err
+	}
+	
+	if err := p.Stop(ctx); err != nil {
+		err = fmt.Errorf("failed to stop plugin %s: %w", id, err)
+		r.setState(id, StateFailed, err)
+		return err
+	}
+	
+	r.removeFromStartOrder(id)
+	return r.setState(id, StateStopped, nil)
+}
+
+// removeFromStartOrder drops a plugin from the recorded start order
+func (r *Registry) removeFromStartOrder(id string) {
+	for i, startedID := range r.startOrder {
+		if startedID == id {
+			r.startOrder = append(r.startOrder[:i], r.startOrder[i+1:]...)
+			return
+		}
+	}
+}
+
+// Disable stops a plugin if it is running and marks it disabled
+func (r *Registry) Disable(ctx context.Context, pluginID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	
+	p, ok := r.plugins[pluginID]
+	if !ok {
+		return fmt.Errorf("plugin not found: %s", pluginID)
+	}
+	
+	// Refuse to disable a plugin that running plugins depend on
+	for _, other := range r.plugins {
+		if r.states[other.ID()].state != StateStarted {
+			continue
+		}
+		for _, depID := range other.Dependencies() {
+			if depID == pluginID {
+				return fmt.Errorf("plugin %s is required by %s", pluginID, other.ID())
+			}
+		}
+	}
+	
+	if err := r.stopPlugin(ctx, p); err != nil {
+		return err
+	}
+	
+	if err := r.setState(pluginID, StateDisabled, nil); err != nil {
+		return err
+	}
+	return r.persistActivation(pluginID, false)
+}
+
+// Enable re-enables a disabled plugin, initializing and starting it if the registry is running
+func (r *Registry) Enable(ctx context.Context, pluginID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	
+	p, ok := r.plugins[pluginID]
+	if !ok {
+		return fmt.Errorf("plugin not found: %s", pluginID)
+	}
+	
+	if err := r.setState(pluginID, StateRegistered, nil); err != nil {
+		return err
+	}
+	
+	if err := r.persistActivation(pluginID, true); err != nil {
+		return err
+	}
+	
+	if err := r.initializePluginWithCycleCheck(ctx, p, make(map[string]bool)); err != nil {
+		return err
+	}
+	
+	if r.running {
+		return r.startPlugin(ctx, p)
+	}
+	
+	return nil
+}
+
+// setState transitions a plugin to a new state and emits a state change event.
+// Callers must hold r.mu.
+func (r *Registry) setState(pluginID string, to State, cause error) error {
+	change, err := r.states[pluginID].transition(pluginID, to, cause)
+	if err != nil {
+		return err
+	}
+	
+	logger := r.logManager.PluginLogger(pluginID)
+	if cause != nil {
+		logger.Error("plugin state changed", slog.String("from", string(change.From)), slog.String("to", string(to)), slog.String("error", cause.Error()))
+	} else {
+		logger.Debug("plugin state changed", slog.String("from", string(change.From)), slog.String("to", string(to)))
+	}
+	
+	r.EmitEvent(Event{
+		Name:    EventPluginStateChanged,
+		Source:  pluginID,
+		Data:    change,
+		Context: context.Background(),
+	})
+	
+	return nil
+}
+
+// Status returns the lifecycle status of a plugin
+func (r *Registry) Status(pluginID string) (PluginStatus, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	
+	ps, ok := r.states[pluginID]
+	if !ok {
+		return PluginStatus{}, fmt.Errorf("plugin not found: %s", pluginID)
+	}
+	return ps.status(pluginID), nil
+}
+
+// Statuses returns the lifecycle status of every registered plugin, sorted by ID
+func (r *Registry) Statuses() []PluginStatus {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	
+	statuses := make([]PluginStatus, 0, len(r.states))
+	for id, ps := range r.states {
+		statuses = append(statuses, ps.status(id))
+	}
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].ID < statuses[j].ID
+	})
+	return statuses
+}
+
 // ExecuteHook executes all handlers for a hook
 func (r *Registry) ExecuteHook(ctx context.Context, hookName string, data interface{}) (interface{}, error) {
+	// Only started plugins take part in the hook chain
 	r.mu.RLock()
-	handlers := r.hooks[hookName]
+	entries := make([]hookEntry, 0, len(r.hooks[hookName]))
+	for _, entry := range r.hooks[hookName] {
+		if ps, ok := r.states[entry.pluginID]; ok && ps.state == StateStarted {
+			entries = append(entries, entry)
+		}
+	}
 	r.mu.RUnlock()
 	
 	result := data
-	for _, handler := range handlers {
+	for _, entry := range entries {
+		hookCtx, span := tracing.Start(WithPluginID(ctx, entry.pluginID), "hook "+hookName, tracing.Attr("hook", hookName))
+		start := time.Now()
 		var err error
-		result, err = handler(ctx, result)
+		result, err = entry.handler(hookCtx, result)
+		r.instruments.hookDuration.Observe(time.Since(start).Seconds(), hookName, entry.pluginID)
+		span.RecordError(err)
+		span.End()
 		if err != nil {
+			r.instruments.hookErrors.Inc(hookName, entry.pluginID)
 			return nil, err
 		}
 	}
@@ -367,9 +680,11 @@ func (r *Registry) ExecuteHook(ctx context.Context, hookName string, data interf
 func (r *Registry) EmitEvent(event Event) {
 	select {
 	case r.events <- event:
+		r.instruments.eventsEmitted.Inc(event.Name)
 	default:
 		// Event queue full, drop event
-		// In production, you might want to log this
+		r.instruments.eventsDropped.Inc(event.Name)
+		r.logManager.Logger().Warn("Event queue full, dropping event", "event", event.Name, "source", event.Source)
 	}
 }
 
@@ -390,35 +705,67 @@ func (r *Registry) handleEvent(event Event) {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 	
-	// Notify all event plugins
-	for _, p := range r.plugins {
+	// Notify all started event plugins
+	for id, p := range r.plugins {
+		if r.states[id].state != StateStarted {
+			continue
+		}
 		if ep, ok := p.(EventPlugin); ok {
 			if handler, exists := ep.EventHandlers()[event.Name]; exists {
-				go handler(event.Context, event)
+				go r.runEventHandler(p.ID(), handler, event)
 			}
 		}
 	}
 }
 
+// runEventHandler runs a single event handler and records its failure
+func (r *Registry) runEventHandler(pluginID string, handler EventHandler, event Event) {
+	ctx := event.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	
+	ctx, span := tracing.Start(WithPluginID(ctx, pluginID), "event "+event.Name,
+		tracing.Attr("event", event.Name),
+		tracing.Attr("event.source", event.Source),
+	)
+	defer span.End()
+	
+	if err := handler(ctx, event); err != nil {
+		span.RecordError(err)
+		r.instruments.eventHandlerErrors.Inc(event.Name, pluginID)
+		r.logManager.PluginLogger(pluginID).Error("Event handler failed", "event", event.Name, "error", err)
+	}
+}
+
 // GetConfig gets plugin configuration
 func (r *Registry) GetConfig(pluginID string) (interface{}, bool) {
-	return r.configManager.GetConfig(pluginID)
+	return r.GetConfigManager().GetConfig(pluginID)
 }
 
 // SetConfig sets plugin configuration
 func (r *Registry) SetConfig(pluginID string, config interface{}) error {
-	p, ok := r.plugins[pluginID]
-	if !ok {
-		return fmt.Errorf("plugin %s not found", pluginID)
+	return r.SetConfigWithAuthor(pluginID, config, "")
+}
+
+// SetConfigWithAuthor sets plugin configuration, recording who made the change in the config history
+func (r *Registry) SetConfigWithAuthor(pluginID string, config interface{}, author string) error {
+	p, err := r.Get(pluginID)
+	if err != nil {
+		return err
 	}
 	
+	r.configMu.Lock()
+	defer r.configMu.Unlock()
+	
 	// Update plugin's config
-	if err := r.configManager.SetConfig(pluginID, config); err != nil {
+	configManager := r.GetConfigManager()
+	if err := configManager.SetConfigWithAuthor(pluginID, config, author); err != nil {
 		return err
 	}
 	
 	// Load config into plugin
-	if err := r.configManager.LoadConfig(pluginID, p.Config()); err != nil {
+	if err := configManager.LoadConfig(pluginID, p.Config()); err != nil {
 		return err
 	}
 	
@@ -430,7 +777,177 @@ func (r *Registry) SetConfig(pluginID string, config interface{}) error {
 	return nil
 }
 
+// DeleteConfig removes a plugin's stored configuration and loads its defaults, with overrides, into the plugin
+func (r *Registry) DeleteConfig(pluginID string) error {
+	p, err := r.Get(pluginID)
+	if err != nil {
+		return err
+	}
+	
+	r.configMu.Lock()
+	defer r.configMu.Unlock()
+	
+	configManager := r.GetConfigManager()
+	if err := configManager.DeleteConfig(pluginID); err != nil {
+		return err
+	}
+	return loadEffectiveConfig(configManager, p)
+}
+
+// RollbackConfig restores a plugin's configuration from an earlier revision.
+// The restored config is checked against the schema and the plugin's ValidateConfig, and
+// settings plugins are notified of each changed setting; any failure leaves the current config in place.
+func (r *Registry) RollbackConfig(pluginID string, version int, author string) error {
+	p, err := r.Get(pluginID)
+	if err != nil {
+		return err
+	}
+	
+	r.configMu.Lock()
+	defer r.configMu.Unlock()
+	
+	rev, err := r.GetConfigManager().Revision(pluginID, version)
+	if err != nil {
+		return err
+	}
+	return r.replaceConfig(p, rev.Config, author, fmt.Sprintf("Rolled back to revision %d", rev.Version))
+}
+
+// ReplaceConfig replaces a plugin's whole configuration, checked and applied as RollbackConfig does.
+// The message is recorded with the new revision.
+func (r *Registry) ReplaceConfig(pluginID string, config map[string]interface{}, author, message string) error {
+	p, err := r.Get(pluginID)
+	if err != nil {
+		return err
+	}
+	
+	r.configMu.Lock()
+	defer r.configMu.Unlock()
+	return r.replaceConfig(p, config, author, message)
+}
+
+// replaceConfig notifies settings plugins of changed settings before committing a new config
+func (r *Registry) replaceConfig(p Plugin, config map[string]interface{}, author, message string) error {
+	current, ok := r.GetConfigManager().GetConfigMap(p.ID())
+	if !ok {
+		current = make(map[string]interface{})
+	}
+	
+	commit := func(map[string]interface{}) error {
+		return r.commitConfig(p, config, author, message)
+	}
+	if sp, ok := p.(SettingsPlugin); ok {
+		return ApplySettings(sp, current, config, commit)
+	}
+	return commit(nil)
+}
+
+// commitConfig validates and saves a whole config, reverting the plugin's loaded config on failure
+func (r *Registry) commitConfig(p Plugin, config map[string]interface{}, author, message string) error {
+	pluginID := p.ID()
+	configManager := r.GetConfigManager()
+	if err := configManager.ValidateConfig(pluginID, config); err != nil {
+		return err
+	}
+	
+	// Overrides still apply on top of the new config
+	effective, _, err := configManager.resolveConfig(pluginID, config)
+	if err != nil {
+		return err
+	}
+	if err := loadPluginConfig(p, effective); err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	if err := p.ValidateConfig(); err != nil {
+		loadEffectiveConfig(configManager, p)
+		return err
+	}
+	
+	if err := configManager.saveConfig(pluginID, config, author, message); err != nil {
+		loadEffectiveConfig(configManager, p)
+		return err
+	}
+	return nil
+}
+
+// loadEffectiveConfig loads the plugin's resolved config into it
+func loadEffectiveConfig(cm *ConfigManager, p Plugin) error {
+	config, _, err := cm.EffectiveConfig(p.ID())
+	if err != nil {
+		return err
+	}
+	return loadPluginConfig(p, config)
+}
+
+// loadPluginConfig decodes a config into the plugin's config struct.
+// Plugins whose Config is not a pointer hold nothing to load into and are skipped.
+func loadPluginConfig(p Plugin, config map[string]interface{}) error {
+	target := p.Config()
+	if target == nil || reflect.ValueOf(target).Kind() != reflect.Ptr {
+		return nil
+	}
+	return decodeConfig(config, target)
+}
+
+// SetConfigStorage replaces where plugin configurations are stored.
+// It must be called before plugins are registered, as registration loads and saves their configs.
+// Prefer WithConfigStorage, which keeps the registry from creating the default file storage.
+func (r *Registry) SetConfigStorage(storage ConfigStorage) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.configManager = NewConfigManagerWithStorage(storage)
+}
+
+// SetStoreProvider sets how plugin data stores are created
+func (r *Registry) SetStoreProvider(provider StoreProvider) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.storeProvider = provider
+}
+
+// Store returns the data store scoped to a plugin
+func (r *Registry) Store(pluginID string) Store {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.storeProvider(pluginID)
+}
+
+// SetLogManager sets the logging manager used to create plugin loggers
+func (r *Registry) SetLogManager(m *logging.Manager) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.logManager = m
+}
+
+// LogManager returns the logging manager
+func (r *Registry) LogManager() *logging.Manager {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.logManager
+}
+
+// Logger returns a logger tagged with a plugin's ID
+func (r *Registry) Logger(pluginID string) *slog.Logger {
+	return r.LogManager().PluginLogger(pluginID)
+}
+
+// Metrics returns the metrics registry plugins can register their own metrics with
+func (r *Registry) Metrics() *metrics.Registry {
+	return r.metrics
+}
+
+// pluginContext scopes ctx to a plugin, carrying its ID, data store, logger, metrics registry and config manager
+func (r *Registry) pluginContext(ctx context.Context, pluginID string) context.Context {
+	ctx = WithPluginID(ctx, pluginID)
+	ctx = logging.WithLogger(ctx, r.logManager.PluginLogger(pluginID))
+	ctx = metrics.WithRegistry(ctx, r.metrics)
+	ctx = WithConfigManager(ctx, r.configManager)
+	return WithStore(ctx, r.storeProvider(pluginID))
+}
+
 // GetConfigManager returns the config manager
 func (r *Registry) GetConfigManager() *ConfigManager {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
 	return r.configManager
 }
\ No newline at end of file
@@ -0,0 +1,324 @@
+package plugin
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"time"
+)
+
+// ErrRevisionsUnsupported is returned when the config storage keeps no history
+var ErrRevisionsUnsupported = errors.New("config storage does not support revisions")
+
+// ErrRevisionNotFound is returned when a revision does not exist
+var ErrRevisionNotFound = errors.New("config revision not found")
+
+// ConfigRevision is a saved version of a plugin's configuration
+type ConfigRevision struct {
+	Version   int                    `json:"version"`
+	PluginID  string                 `json:"plugin_id"`
+	Author    string                 `json:"author,omitempty"`
+	Message   string                 `json:"message,omitempty"`
+	CreatedAt time.Time              `json:"created_at"`
+	Config    map[string]interface{} `json:"config"`
+}
+
+// RevisionStorage is implemented by config storages that keep a history of saves
+type RevisionStorage interface {
+	// AppendRevision stores a revision, assigning it the next version number
+	AppendRevision(rev ConfigRevision) (ConfigRevision, error)
+
+	// Revisions returns a plugin's revisions, newest first
+	Revisions(pluginID string) ([]ConfigRevision, error)
+
+	// ReplaceRevisions rewrites a plugin's history, keeping the given version numbers.
+	// It is used to re-encrypt secrets; revisions are passed newest first.
+	ReplaceRevisions(pluginID string, revisions []ConfigRevision) error
+}
+
+// Change kinds reported by DiffConfig
+const (
+	ChangeAdded    = "added"
+	ChangeRemoved  = "removed"
+	ChangeModified = "modified"
+)
+
+// ConfigChange is a single field-level difference between two configs
+type ConfigChange struct {
+	Path string // Dotted path of the field, e.g. "database.port"
+	Kind string
+	Old  interface{}
+	New  interface{}
+}
+
+// DiffConfig returns the field-level changes from old to new, sorted by path.
+// Nested objects are compared field by field; other values are compared whole.
+func DiffConfig(old, new map[string]interface{}) []ConfigChange {
+	changes := diffMaps(normalizeConfig(old), normalizeConfig(new), "")
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Path < changes[j].Path
+	})
+	return changes
+}
+
+func diffMaps(old, new map[string]interface{}, prefix string) []ConfigChange {
+	var changes []ConfigChange
+
+	for key, oldValue := range old {
+		path := prefix + key
+		newValue, exists := new[key]
+		if !exists {
+			changes = append(changes, ConfigChange{Path: path, Kind: ChangeRemoved, Old: oldValue})
+			continue
+		}
+
+		oldMap, oldIsMap := oldValue.(map[string]interface{})
+		newMap, newIsMap := newValue.(map[string]interface{})
+		if oldIsMap && newIsMap {
+			changes = append(changes, diffMaps(oldMap, newMap, path+".")...)
+			continue
+		}
+		if !reflect.DeepEqual(oldValue, newValue) {
+			changes = append(changes, ConfigChange{Path: path, Kind: ChangeModified, Old: oldValue, New: newValue})
+		}
+	}
+
+	for key, newValue := range new {
+		if _, exists := old[key]; !exists {
+			changes = append(changes, ConfigChange{Path: prefix + key, Kind: ChangeAdded, New: newValue})
+		}
+	}
+
+	return changes
+}
+
+// normalizeConfig round-trips a config through JSON so values compare the same
+// whether they came from memory (int, []string) or from storage (float64, []interface{})
+func normalizeConfig(config map[string]interface{}) map[string]interface{} {
+	if config == nil {
+		return map[string]interface{}{}
+	}
+	data, err := json.Marshal(config)
+	if err != nil {
+		return config
+	}
+	var normalized map[string]interface{}
+	if err := json.Unmarshal(data, &normalized); err != nil {
+		return config
+	}
+	return normalized
+}
+
+// History returns a plugin's config revisions, newest first
+func (cm *ConfigManager) History(pluginID string) ([]ConfigRevision, error) {
+	rs, ok := cm.configStorage().(RevisionStorage)
+	if !ok {
+		return nil, ErrRevisionsUnsupported
+	}
+	return rs.Revisions(pluginID)
+}
+
+// Revision returns a single config revision
+func (cm *ConfigManager) Revision(pluginID string, version int) (ConfigRevision, error) {
+	revisions, err := cm.History(pluginID)
+	if err != nil {
+		return ConfigRevision{}, err
+	}
+	for _, rev := range revisions {
+		if rev.Version == version {
+			return rev, nil
+		}
+	}
+	return ConfigRevision{}, fmt.Errorf("%w: %s version %d", ErrRevisionNotFound, pluginID, version)
+}
+
+// recordRevision appends a revision if the storage keeps history
+func (cm *ConfigManager) recordRevision(pluginID string, config map[string]interface{}, author, message string) error {
+	rs, ok := cm.configStorage().(RevisionStorage)
+	if !ok {
+		return nil
+	}
+	_, err := rs.AppendRevision(ConfigRevision{
+		PluginID:  pluginID,
+		Author:    author,
+		Message:   message,
+		CreatedAt: time.Now().UTC(),
+		Config:    config,
+	})
+	if err != nil && !errors.Is(err, ErrRevisionsUnsupported) {
+		return fmt.Errorf("failed to record config revision: %w", err)
+	}
+	return nil
+}
+
+// AppendRevision stores a revision in memory
+func (s *MemoryConfigStorage) AppendRevision(rev ConfigRevision) (ConfigRevision, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.revisions == nil {
+		s.revisions = make(map[string][]ConfigRevision)
+	}
+
+	configCopy := make(map[string]interface{}, len(rev.Config))
+	for k, v := range rev.Config {
+		configCopy[k] = v
+	}
+	rev.Config = configCopy
+	rev.Version = len(s.revisions[rev.PluginID]) + 1
+
+	s.revisions[rev.PluginID] = append(s.revisions[rev.PluginID], rev)
+	return rev, nil
+}
+
+// Revisions returns a plugin's revisions, newest first
+func (s *MemoryConfigStorage) Revisions(pluginID string) ([]ConfigRevision, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	stored := s.revisions[pluginID]
+	revisions := make([]ConfigRevision, 0, len(stored))
+	for i := len(stored) - 1; i >= 0; i-- {
+		revisions = append(revisions, stored[i])
+	}
+	return revisions, nil
+}
+
+// ReplaceRevisions replaces a plugin's revisions in memory
+func (s *MemoryConfigStorage) ReplaceRevisions(pluginID string, revisions []ConfigRevision) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.revisions == nil {
+		s.revisions = make(map[string][]ConfigRevision)
+	}
+	stored := make([]ConfigRevision, 0, len(revisions))
+	for i := len(revisions) - 1; i >= 0; i-- {
+		stored = append(stored, revisions[i])
+	}
+	s.revisions[pluginID] = stored
+	return nil
+}
+
+// AppendRevision appends a revision to the plugin's history file
+func (s *JSONFileConfigStorage) AppendRevision(rev ConfigRevision) (ConfigRevision, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := validatePluginID(rev.PluginID); err != nil {
+		return ConfigRevision{}, err
+	}
+
+	existing, err := s.readRevisions(rev.PluginID)
+	if err != nil {
+		return ConfigRevision{}, err
+	}
+	rev.Version = 1
+	if len(existing) > 0 {
+		rev.Version = existing[len(existing)-1].Version + 1
+	}
+
+	data, err := json.Marshal(rev)
+	if err != nil {
+		return ConfigRevision{}, fmt.Errorf("failed to marshal revision: %w", err)
+	}
+	if err := ensureDir(s.historyDir()); err != nil {
+		return ConfigRevision{}, fmt.Errorf("failed to create history directory: %w", err)
+	}
+	if err := appendFile(s.historyPath(rev.PluginID), append(data, '\n')); err != nil {
+		return ConfigRevision{}, fmt.Errorf("failed to write revision: %w", err)
+	}
+
+	return rev, nil
+}
+
+// Revisions returns a plugin's revisions from its history file, newest first
+func (s *JSONFileConfigStorage) Revisions(pluginID string) ([]ConfigRevision, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if err := validatePluginID(pluginID); err != nil {
+		return nil, err
+	}
+
+	revisions, err := s.readRevisions(pluginID)
+	if err != nil {
+		return nil, err
+	}
+	for i, j := 0, len(revisions)-1; i < j; i, j = i+1, j-1 {
+		revisions[i], revisions[j] = revisions[j], revisions[i]
+	}
+	return revisions, nil
+}
+
+// ReplaceRevisions rewrites a plugin's history file
+func (s *JSONFileConfigStorage) ReplaceRevisions(pluginID string, revisions []ConfigRevision) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := validatePluginID(pluginID); err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	for i := len(revisions) - 1; i >= 0; i-- {
+		data, err := json.Marshal(revisions[i])
+		if err != nil {
+			return fmt.Errorf("failed to marshal revision: %w", err)
+		}
+		buf.Write(data)
+		buf.WriteByte('\n')
+	}
+	if err := ensureDir(s.historyDir()); err != nil {
+		return fmt.Errorf("failed to create history directory: %w", err)
+	}
+	if err := writeFile(s.historyPath(pluginID), buf.Bytes()); err != nil {
+		return fmt.Errorf("failed to write config history: %w", err)
+	}
+	return nil
+}
+
+// readRevisions reads a plugin's history file, oldest first
+func (s *JSONFileConfigStorage) readRevisions(pluginID string) ([]ConfigRevision, error) {
+	data, err := readFile(s.historyPath(pluginID))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return []ConfigRevision{}, nil
+		}
+		return nil, fmt.Errorf("failed to read config history: %w", err)
+	}
+
+	revisions := []ConfigRevision{}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		var rev ConfigRevision
+		if err := json.Unmarshal(scanner.Bytes(), &rev); err != nil {
+			return nil, fmt.Errorf("failed to parse config history: %w", err)
+		}
+		revisions = append(revisions, rev)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read config history: %w", err)
+	}
+	return revisions, nil
+}
+
+// historyDir holds one JSON-lines history file per plugin
+func (s *JSONFileConfigStorage) historyDir() string {
+	return filepath.Join(s.basePath, "history")
+}
+
+// historyPath returns the history file path for a plugin
+func (s *JSONFileConfigStorage) historyPath(pluginID string) string {
+	return filepath.Join(s.historyDir(), pluginID+".jsonl")
+}
@@ -0,0 +1,214 @@
+package plugin
+
+import (
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type revisionTestConfig struct {
+	Name  string `json:"name"`
+	Limit int    `json:"limit"`
+}
+
+// RevisionTestPlugin has a pointer config that rejects negative limits
+type RevisionTestPlugin struct {
+	TestPlugin
+	config *revisionTestConfig
+}
+
+func (p *RevisionTestPlugin) Config() interface{} { return p.config }
+func (p *RevisionTestPlugin) DefaultConfig() interface{} {
+	return &revisionTestConfig{Name: "default", Limit: 1}
+}
+func (p *RevisionTestPlugin) ValidateConfig() error {
+	if p.config.Limit < 0 {
+		return assert.AnError
+	}
+	return nil
+}
+
+func newRevisionRegistry(t *testing.T, p Plugin) *Registry {
+	t.Helper()
+	r := NewRegistry(chi.NewRouter(), WithConfigStorage(NewMemoryConfigStorage()))
+	require.NoError(t, r.Register(p))
+	return r
+}
+
+func TestConfigRevisions(t *testing.T) {
+	storages := map[string]func(t *testing.T) ConfigStorage{
+		"memory": func(t *testing.T) ConfigStorage { return NewMemoryConfigStorage() },
+		"json file": func(t *testing.T) ConfigStorage {
+			storage, err := NewJSONFileConfigStorage(t.TempDir())
+			require.NoError(t, err)
+			return storage
+		},
+		"database": func(t *testing.T) ConfigStorage { return NewDBConfigStorage(newConfigTestDB(t)) },
+	}
+
+	for name, newStorage := range storages {
+		t.Run(name, func(t *testing.T) {
+			cm := NewConfigManagerWithStorage(newStorage(t))
+
+			require.NoError(t, cm.SetConfig("test.plugin", map[string]interface{}{"limit": 1}))
+			require.NoError(t, cm.SetConfigWithAuthor("test.plugin", map[string]interface{}{"limit": 2}, "admin@example.com"))
+			require.NoError(t, cm.SetConfig("other.plugin", map[string]interface{}{"limit": 9}))
+
+			history, err := cm.History("test.plugin")
+			require.NoError(t, err)
+			require.Len(t, history, 2)
+			assert.Equal(t, 2, history[0].Version)
+			assert.Equal(t, "admin@example.com", history[0].Author)
+			assert.EqualValues(t, 2, history[0].Config["limit"])
+			assert.Equal(t, 1, history[1].Version)
+			assert.Empty(t, history[1].Author)
+			assert.False(t, history[1].CreatedAt.IsZero())
+
+			rev, err := cm.Revision("test.plugin", 1)
+			require.NoError(t, err)
+			assert.EqualValues(t, 1, rev.Config["limit"])
+
+			_, err = cm.Revision("test.plugin", 5)
+			assert.ErrorIs(t, err, ErrRevisionNotFound)
+
+			// Rewriting history keeps version numbers
+			history[1].Config = map[string]interface{}{"limit": "rewritten"}
+			require.NoError(t, cm.storage.(RevisionStorage).ReplaceRevisions("test.plugin", history))
+			rewritten, err := cm.History("test.plugin")
+			require.NoError(t, err)
+			require.Len(t, rewritten, 2)
+			assert.Equal(t, []int{2, 1}, []int{rewritten[0].Version, rewritten[1].Version})
+			assert.Equal(t, "rewritten", rewritten[1].Config["limit"])
+			assert.Equal(t, "admin@example.com", rewritten[0].Author)
+		})
+	}
+}
+
+// failingRevisionStorage stores configs in memory but can't record revisions
+type failingRevisionStorage struct {
+	*MemoryConfigStorage
+}
+
+func (s failingRevisionStorage) AppendRevision(rev ConfigRevision) (ConfigRevision, error) {
+	return ConfigRevision{}, assert.AnError
+}
+
+func TestConfigManager_FailedRevisionLeavesConfig(t *testing.T) {
+	storage := failingRevisionStorage{NewMemoryConfigStorage().(*MemoryConfigStorage)}
+	require.NoError(t, storage.Save("test.plugin", map[string]interface{}{"name": "stored"}))
+	cm := NewConfigManagerWithStorage(storage)
+	updates, stop := cm.Watch("test.plugin")
+	defer stop()
+	<-updates
+
+	err := cm.SetConfig("test.plugin", map[string]interface{}{"name": "changed"})
+	assert.ErrorIs(t, err, assert.AnError)
+
+	config, ok := cm.GetConfigMap("test.plugin")
+	require.True(t, ok)
+	assert.Equal(t, "stored", config["name"])
+	select {
+	case <-updates:
+		t.Fatal("a failed save was published")
+	default:
+	}
+
+	// A plugin without a config before the failed save still has none
+	assert.Error(t, cm.SetConfig("test.other", map[string]interface{}{"name": "new"}))
+	_, ok = cm.GetConfigMap("test.other")
+	assert.False(t, ok)
+}
+
+func TestDiffConfig(t *testing.T) {
+	old := map[string]interface{}{
+		"name":    "site",
+		"port":    8080,
+		"removed": true,
+		"db":      map[string]interface{}{"host": "localhost", "port": 5432},
+		"tags":    []string{"a"},
+	}
+	new := map[string]interface{}{
+		"name":  "site",
+		"port":  float64(8080),
+		"added": "x",
+		"db":    map[string]interface{}{"host": "db.internal", "port": 5432},
+		"tags":  []interface{}{"a", "b"},
+	}
+
+	changes := DiffConfig(old, new)
+	assert.Equal(t, []ConfigChange{
+		{Path: "added", Kind: ChangeAdded, New: "x"},
+		{Path: "db.host", Kind: ChangeModified, Old: "localhost", New: "db.internal"},
+		{Path: "removed", Kind: ChangeRemoved, Old: true},
+		{Path: "tags", Kind: ChangeModified, Old: []interface{}{"a"}, New: []interface{}{"a", "b"}},
+	}, changes)
+
+	assert.Empty(t, DiffConfig(nil, map[string]interface{}{}))
+}
+
+func TestRegistry_RollbackConfig(t *testing.T) {
+	t.Run("restores an earlier revision", func(t *testing.T) {
+		p := &RevisionTestPlugin{TestPlugin: TestPlugin{id: "test.revisions"}, config: &revisionTestConfig{}}
+		r := newRevisionRegistry(t, p)
+
+		require.NoError(t, r.SetConfigWithAuthor("test.revisions", map[string]interface{}{"name": "changed", "limit": 5}, "editor"))
+		assert.Equal(t, 5, p.config.Limit)
+
+		require.NoError(t, r.RollbackConfig("test.revisions", 1, "admin"))
+		assert.Equal(t, "default", p.config.Name)
+		assert.Equal(t, 1, p.config.Limit)
+
+		history, err := r.GetConfigManager().History("test.revisions")
+		require.NoError(t, err)
+		require.Len(t, history, 3)
+		assert.Equal(t, "admin", history[0].Author)
+		assert.Equal(t, "Rolled back to revision 1", history[0].Message)
+	})
+
+	t.Run("keeps the current config when validation fails", func(t *testing.T) {
+		p := &RevisionTestPlugin{TestPlugin: TestPlugin{id: "test.revisions"}, config: &revisionTestConfig{}}
+		r := newRevisionRegistry(t, p)
+
+		// Store an invalid revision directly, bypassing the plugin's validation
+		require.NoError(t, r.GetConfigManager().SetConfig("test.revisions", map[string]interface{}{"name": "bad", "limit": -1}))
+		require.NoError(t, r.SetConfig("test.revisions", map[string]interface{}{"name": "good", "limit": 3}))
+
+		err := r.RollbackConfig("test.revisions", 2, "admin")
+		require.Error(t, err)
+		assert.Equal(t, "good", p.config.Name)
+		assert.Equal(t, 3, p.config.Limit)
+
+		history, err := r.GetConfigManager().History("test.revisions")
+		require.NoError(t, err)
+		assert.Len(t, history, 3)
+	})
+
+	t.Run("notifies settings plugins and rolls back rejected changes", func(t *testing.T) {
+		p := &TestSettingsPlugin{
+			TestPlugin: TestPlugin{id: "test.settings"},
+			settings:   []Setting{{Key: "theme", Type: SettingTypeString}},
+			values:     map[string]interface{}{},
+		}
+		r := newRevisionRegistry(t, p)
+		cm := r.GetConfigManager()
+
+		require.NoError(t, cm.SetConfig("test.settings", map[string]interface{}{"theme": "light"}))
+		require.NoError(t, cm.SetConfig("test.settings", map[string]interface{}{"theme": "dark"}))
+
+		require.NoError(t, r.RollbackConfig("test.settings", 2, ""))
+		assert.Equal(t, "light", p.values["theme"])
+
+		p.reject = "theme"
+		require.Error(t, r.RollbackConfig("test.settings", 3, ""))
+		config, _ := cm.GetConfig("test.settings")
+		assert.Equal(t, "light", config.(map[string]interface{})["theme"])
+	})
+
+	t.Run("unknown revision", func(t *testing.T) {
+		p := &RevisionTestPlugin{TestPlugin: TestPlugin{id: "test.revisions"}, config: &revisionTestConfig{}}
+		r := newRevisionRegistry(t, p)
+		assert.ErrorIs(t, r.RollbackConfig("test.revisions", 42, ""), ErrRevisionNotFound)
+	})
+}
@@ -0,0 +1,524 @@
+package plugin
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// secretPrefix marks an encrypted config value; the version allows the format to change later
+const secretPrefix = "enc:v1:"
+
+// ErrSecretUndecryptable is returned when no configured key can decrypt a secret
+var ErrSecretUndecryptable = errors.New("secret cannot be decrypted with the configured keys")
+
+// SecretCipher encrypts config secrets with AES-256-GCM.
+// Values are encrypted with the primary key and decrypted with any known key,
+// so previous keys keep working until secrets are rotated.
+type SecretCipher struct {
+	aeads []cipher.AEAD
+}
+
+// NewSecretCipher creates a cipher from the app key and any previous keys.
+// Keys are hashed with SHA-256, so any long random string can be used.
+func NewSecretCipher(key string, previous ...string) (*SecretCipher, error) {
+	if key == "" {
+		return nil, fmt.Errorf("secret key cannot be empty")
+	}
+
+	c := &SecretCipher{}
+	for _, k := range append([]string{key}, previous...) {
+		if k == "" {
+			continue
+		}
+		sum := sha256.Sum256([]byte(k))
+		block, err := aes.NewCipher(sum[:])
+		if err != nil {
+			return nil, fmt.Errorf("failed to create cipher: %w", err)
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create cipher: %w", err)
+		}
+		c.aeads = append(c.aeads, aead)
+	}
+	return c, nil
+}
+
+// Encrypt encrypts a config value with the primary key
+func (c *SecretCipher) Encrypt(value interface{}) (string, error) {
+	plaintext, err := json.Marshal(value)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode secret: %w", err)
+	}
+
+	aead := c.aeads[0]
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	sealed := aead.Seal(nonce, nonce, plaintext, nil)
+	return secretPrefix + base64.RawStdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt decrypts a value produced by Encrypt
+func (c *SecretCipher) Decrypt(value string) (interface{}, error) {
+	if !IsEncryptedSecret(value) {
+		return nil, fmt.Errorf("value is not an encrypted secret")
+	}
+	sealed, err := base64.RawStdEncoding.DecodeString(strings.TrimPrefix(value, secretPrefix))
+	if err != nil {
+		return nil, fmt.Errorf("malformed secret: %w", err)
+	}
+
+	for _, aead := range c.aeads {
+		if len(sealed) < aead.NonceSize() {
+			break
+		}
+		nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+		plaintext, err := aead.Open(nil, nonce, ciphertext, nil)
+		if err != nil {
+			continue
+		}
+		var decoded interface{}
+		if err := json.Unmarshal(plaintext, &decoded); err != nil {
+			return nil, fmt.Errorf("failed to decode secret: %w", err)
+		}
+		return decoded, nil
+	}
+	return nil, ErrSecretUndecryptable
+}
+
+// IsEncryptedSecret reports whether a config value is an encrypted secret
+func IsEncryptedSecret(value interface{}) bool {
+	s, ok := value.(string)
+	return ok && strings.HasPrefix(s, secretPrefix)
+}
+
+// SecretPaths returns the dotted paths of fields marked secret, including nested object fields
+func (s *ConfigSchema) SecretPaths() []string {
+	if s == nil {
+		return nil
+	}
+	return secretPaths(s.Fields, "")
+}
+
+func secretPaths(fields []ConfigField, prefix string) []string {
+	var paths []string
+	for _, field := range fields {
+		path := prefix + field.Name
+		if field.Secret {
+			paths = append(paths, path)
+			continue
+		}
+		if field.Type == "object" {
+			paths = append(paths, secretPaths(field.Fields, path+".")...)
+		}
+	}
+	return paths
+}
+
+// KeepSecrets copies secret values from current into config where the form left them out,
+// so secret fields are write-only: submitting a blank field keeps the stored value
+func (s *ConfigSchema) KeepSecrets(config, current map[string]interface{}) {
+	keepSecrets(s.SecretPaths(), config, current)
+}
+
+func keepSecrets(paths []string, config, current map[string]interface{}) {
+	for _, path := range paths {
+		if _, ok := lookupPath(config, path); ok {
+			continue
+		}
+		if value, ok := lookupPath(current, path); ok {
+			setPath(config, path, value)
+		}
+	}
+}
+
+// MaskSecretChanges hides secret values in a config diff, keeping which fields changed
+func (s *ConfigSchema) MaskSecretChanges(changes []ConfigChange) []ConfigChange {
+	return maskSecretChanges(s.SecretPaths(), changes)
+}
+
+func maskSecretChanges(paths []string, changes []ConfigChange) []ConfigChange {
+	masked := make([]ConfigChange, len(changes))
+	for i, change := range changes {
+		masked[i] = change
+		for _, path := range paths {
+			if change.Path == path || strings.HasPrefix(change.Path, path+".") {
+				masked[i].Old = maskSecret(change.Old)
+				masked[i].New = maskSecret(change.New)
+				break
+			}
+		}
+	}
+	return masked
+}
+
+// SecretMask replaces secret values wherever they are displayed
+const SecretMask = "••••••••"
+
+func maskSecret(value interface{}) interface{} {
+	if value == nil {
+		return nil
+	}
+	return SecretMask
+}
+
+// MarkSecret marks config paths as secret in addition to those in the plugin's schema,
+// such as settings declared with Secret
+func (cm *ConfigManager) MarkSecret(pluginID string, paths ...string) {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+	cm.secrets[pluginID] = append(cm.secrets[pluginID], paths...)
+}
+
+// SetCipher encrypts secret fields at rest. Call it before configs are loaded or saved.
+func (cm *ConfigManager) SetCipher(c *SecretCipher) {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+	cm.storage = NewEncryptedConfigStorage(cm.storage, c, cm.secretPaths)
+}
+
+// SecretPaths returns the dotted paths of a plugin's secret fields, from its schema and MarkSecret
+func (cm *ConfigManager) SecretPaths(pluginID string) []string {
+	return cm.secretPaths(pluginID)
+}
+
+// StripSecrets removes a plugin's secret fields from a config, such as one about to be exported
+func (cm *ConfigManager) StripSecrets(pluginID string, config map[string]interface{}) {
+	for _, path := range cm.secretPaths(pluginID) {
+		deletePath(config, path)
+	}
+}
+
+// KeepSecrets copies a plugin's secret values from current into config where config leaves them out
+func (cm *ConfigManager) KeepSecrets(pluginID string, config, current map[string]interface{}) {
+	keepSecrets(cm.secretPaths(pluginID), config, current)
+}
+
+// MaskSecretChanges hides a plugin's secret values in a config diff
+func (cm *ConfigManager) MaskSecretChanges(pluginID string, changes []ConfigChange) []ConfigChange {
+	return maskSecretChanges(cm.secretPaths(pluginID), changes)
+}
+
+// secretPaths returns every secret path for a plugin
+func (cm *ConfigManager) secretPaths(pluginID string) []string {
+	cm.mu.RLock()
+	defer cm.mu.RUnlock()
+	paths := append([]string{}, cm.secrets[pluginID]...)
+	if schema, ok := cm.schemas[pluginID]; ok {
+		paths = append(paths, schema.SecretPaths()...)
+	}
+	return paths
+}
+
+// EncryptedConfigStorage wraps a ConfigStorage, encrypting secret fields before they are stored.
+// Any value carrying the encrypted prefix is decrypted on load, whether or not it is still marked secret.
+type EncryptedConfigStorage struct {
+	storage ConfigStorage
+	cipher  *SecretCipher
+	secrets func(pluginID string) []string
+}
+
+// NewEncryptedConfigStorage creates a storage that encrypts the paths returned by secrets
+func NewEncryptedConfigStorage(storage ConfigStorage, c *SecretCipher, secrets func(pluginID string) []string) ConfigStorage {
+	return &EncryptedConfigStorage{
+		storage: storage,
+		cipher:  c,
+		secrets: secrets,
+	}
+}
+
+// Load retrieves and decrypts configuration for a plugin
+func (s *EncryptedConfigStorage) Load(pluginID string) (map[string]interface{}, error) {
+	config, err := s.storage.Load(pluginID)
+	if err != nil {
+		return nil, err
+	}
+	return s.decrypt(config)
+}
+
+// Save encrypts secret fields and stores configuration for a plugin
+func (s *EncryptedConfigStorage) Save(pluginID string, config map[string]interface{}) error {
+	encrypted, err := s.encrypt(pluginID, config)
+	if err != nil {
+		return err
+	}
+	return s.storage.Save(pluginID, encrypted)
+}
+
+// Delete removes configuration for a plugin
+func (s *EncryptedConfigStorage) Delete(pluginID string) error {
+	return s.storage.Delete(pluginID)
+}
+
+// List returns all stored plugin IDs
+func (s *EncryptedConfigStorage) List() ([]string, error) {
+	return s.storage.List()
+}
+
+// AppendRevision encrypts a revision's secrets before storing it
+func (s *EncryptedConfigStorage) AppendRevision(rev ConfigRevision) (ConfigRevision, error) {
+	rs, ok := s.storage.(RevisionStorage)
+	if !ok {
+		return ConfigRevision{}, ErrRevisionsUnsupported
+	}
+	plain := rev.Config
+	encrypted, err := s.encrypt(rev.PluginID, rev.Config)
+	if err != nil {
+		return ConfigRevision{}, err
+	}
+	rev.Config = encrypted
+	stored, err := rs.AppendRevision(rev)
+	stored.Config = plain
+	return stored, err
+}
+
+// Revisions returns a plugin's decrypted revisions, newest first
+func (s *EncryptedConfigStorage) Revisions(pluginID string) ([]ConfigRevision, error) {
+	rs, ok := s.storage.(RevisionStorage)
+	if !ok {
+		return nil, ErrRevisionsUnsupported
+	}
+	revisions, err := rs.Revisions(pluginID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range revisions {
+		if revisions[i].Config, err = s.decrypt(revisions[i].Config); err != nil {
+			return nil, fmt.Errorf("revision %d: %w", revisions[i].Version, err)
+		}
+	}
+	return revisions, nil
+}
+
+// ReplaceRevisions encrypts and replaces a plugin's revisions
+func (s *EncryptedConfigStorage) ReplaceRevisions(pluginID string, revisions []ConfigRevision) error {
+	rs, ok := s.storage.(RevisionStorage)
+	if !ok {
+		return ErrRevisionsUnsupported
+	}
+	encrypted := make([]ConfigRevision, len(revisions))
+	for i, rev := range revisions {
+		config, err := s.encrypt(pluginID, rev.Config)
+		if err != nil {
+			return err
+		}
+		rev.Config = config
+		encrypted[i] = rev
+	}
+	return rs.ReplaceRevisions(pluginID, encrypted)
+}
+
+// encrypt returns a copy of config with the plugin's secret paths encrypted
+func (s *EncryptedConfigStorage) encrypt(pluginID string, config map[string]interface{}) (map[string]interface{}, error) {
+	encrypted := copyConfig(config)
+	for _, path := range s.secrets(pluginID) {
+		value, ok := lookupPath(encrypted, path)
+		if !ok || value == nil || IsEncryptedSecret(value) {
+			continue
+		}
+		sealed, err := s.cipher.Encrypt(value)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encrypt %s: %w", path, err)
+		}
+		setPath(encrypted, path, sealed)
+	}
+	return encrypted, nil
+}
+
+// decrypt returns a copy of config with every encrypted value decrypted
+func (s *EncryptedConfigStorage) decrypt(config map[string]interface{}) (map[string]interface{}, error) {
+	var err error
+	decrypted := mapSecrets(copyConfig(config), func(value string) (interface{}, error) {
+		plain, decryptErr := s.cipher.Decrypt(value)
+		if decryptErr != nil && err == nil {
+			err = decryptErr
+		}
+		return plain, decryptErr
+	})
+	if err != nil {
+		return nil, err
+	}
+	return decrypted, nil
+}
+
+// RotateSecrets re-encrypts every stored secret, including those in config history, with the
+// cipher's primary key. The cipher must also know the old keys. Plaintext values at the paths
+// secrets returns for a plugin, such as ones saved before APP_KEY was set, are encrypted too;
+// secrets may be nil. It returns how many secrets were rewritten.
+// storage must be the underlying storage, not one wrapped by EncryptedConfigStorage.
+func RotateSecrets(storage ConfigStorage, c *SecretCipher, secrets func(pluginID string) []string) (int, error) {
+	ids, err := storage.List()
+	if err != nil {
+		return 0, err
+	}
+	sort.Strings(ids)
+
+	rotated := 0
+	var rotateErr error
+	reencrypt := func(value string) (interface{}, error) {
+		plain, err := c.Decrypt(value)
+		if err == nil {
+			var sealed string
+			if sealed, err = c.Encrypt(plain); err == nil {
+				rotated++
+				return sealed, nil
+			}
+		}
+		if rotateErr == nil {
+			rotateErr = err
+		}
+		return value, err
+	}
+	encryptPlain := func(id string, config map[string]interface{}) error {
+		if secrets == nil {
+			return nil
+		}
+		for _, path := range secrets(id) {
+			value, ok := lookupPath(config, path)
+			if !ok || value == nil || IsEncryptedSecret(value) {
+				continue
+			}
+			sealed, err := c.Encrypt(value)
+			if err != nil {
+				return fmt.Errorf("failed to encrypt %s: %w", path, err)
+			}
+			setPath(config, path, sealed)
+			rotated++
+		}
+		return nil
+	}
+
+	for _, id := range ids {
+		config, err := storage.Load(id)
+		if err != nil {
+			return rotated, fmt.Errorf("failed to load config for %s: %w", id, err)
+		}
+		config = mapSecrets(config, reencrypt)
+		if rotateErr == nil {
+			rotateErr = encryptPlain(id, config)
+		}
+		if rotateErr != nil {
+			return rotated, fmt.Errorf("failed to rotate secrets for %s: %w", id, rotateErr)
+		}
+		if err := storage.Save(id, config); err != nil {
+			return rotated, fmt.Errorf("failed to save config for %s: %w", id, err)
+		}
+
+		rs, ok := storage.(RevisionStorage)
+		if !ok {
+			continue
+		}
+		revisions, err := rs.Revisions(id)
+		if err != nil {
+			return rotated, fmt.Errorf("failed to load history for %s: %w", id, err)
+		}
+		for i := range revisions {
+			revisions[i].Config = mapSecrets(revisions[i].Config, reencrypt)
+			if rotateErr == nil {
+				rotateErr = encryptPlain(id, revisions[i].Config)
+			}
+		}
+		if rotateErr != nil {
+			return rotated, fmt.Errorf("failed to rotate secrets in history for %s: %w", id, rotateErr)
+		}
+		if err := rs.ReplaceRevisions(id, revisions); err != nil {
+			return rotated, fmt.Errorf("failed to save history for %s: %w", id, err)
+		}
+	}
+
+	return rotated, nil
+}
+
+// mapSecrets replaces every encrypted string in a config, at any depth, with fn's result.
+// Values fn fails on are left unchanged.
+func mapSecrets(config map[string]interface{}, fn func(string) (interface{}, error)) map[string]interface{} {
+	for key, value := range config {
+		config[key] = mapSecretValue(value, fn)
+	}
+	return config
+}
+
+func mapSecretValue(value interface{}, fn func(string) (interface{}, error)) interface{} {
+	switch v := value.(type) {
+	case string:
+		if IsEncryptedSecret(v) {
+			if replaced, err := fn(v); err == nil {
+				return replaced
+			}
+		}
+	case map[string]interface{}:
+		return mapSecrets(v, fn)
+	case []interface{}:
+		for i := range v {
+			v[i] = mapSecretValue(v[i], fn)
+		}
+	}
+	return value
+}
+
+// copyConfig deep-copies nested config maps and lists so they can be modified safely
+func copyConfig(config map[string]interface{}) map[string]interface{} {
+	copied := make(map[string]interface{}, len(config))
+	for key, value := range config {
+		copied[key] = copyConfigValue(value)
+	}
+	return copied
+}
+
+func copyConfigValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return copyConfig(v)
+	case []interface{}:
+		copied := make([]interface{}, len(v))
+		for i := range v {
+			copied[i] = copyConfigValue(v[i])
+		}
+		return copied
+	}
+	return value
+}
+
+// lookupPath returns the value at a dotted path in nested config maps
+func lookupPath(config map[string]interface{}, path string) (interface{}, bool) {
+	keys := strings.Split(path, ".")
+	current := config
+	for i, key := range keys {
+		value, ok := current[key]
+		if !ok {
+			return nil, false
+		}
+		if i == len(keys)-1 {
+			return value, true
+		}
+		if current, ok = value.(map[string]interface{}); !ok {
+			return nil, false
+		}
+	}
+	return nil, false
+}
+
+// setPath sets the value at a dotted path, creating nested maps as needed
+func setPath(config map[string]interface{}, path string, value interface{}) {
+	keys := strings.Split(path, ".")
+	current := config
+	for _, key := range keys[:len(keys)-1] {
+		next, ok := current[key].(map[string]interface{})
+		if !ok {
+			next = make(map[string]interface{})
+			current[key] = next
+		}
+		current = next
+	}
+	current[keys[len(keys)-1]] = value
+}
@@ -0,0 +1,275 @@
+package plugin
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SettingDateTimeLayouts are the accepted input formats for datetime settings
+var SettingDateTimeLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04", // HTML datetime-local
+	"2006-01-02",
+}
+
+// DefaultFileAccept are the extensions a file setting accepts unless it sets Accept. Uploads are
+// served as static files, so they leave out types browsers run scripts in, such as HTML and SVG.
+var DefaultFileAccept = []string{".png", ".jpg", ".jpeg", ".gif", ".webp", ".ico"}
+
+var colorPattern = regexp.MustCompile(`^#([0-9a-fA-F]{3}|[0-9a-fA-F]{6})$`)
+
+// SettingErrors maps setting keys to validation messages
+type SettingErrors map[string]string
+
+// Error summarises the failed settings
+func (e SettingErrors) Error() string {
+	keys := make([]string, 0, len(e))
+	for key := range e {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	parts := make([]string, 0, len(keys))
+	for _, key := range keys {
+		parts = append(parts, fmt.Sprintf("%s: %s", key, e[key]))
+	}
+	return "invalid settings: " + strings.Join(parts, "; ")
+}
+
+// ParseSettingValue converts submitted form values to the setting's Go type.
+// Empty input for int, JSON and datetime settings yields nil.
+func ParseSettingValue(setting Setting, values []string) (interface{}, error) {
+	value := ""
+	if len(values) > 0 {
+		value = values[0]
+	}
+
+	switch setting.Type {
+	case SettingTypeBool:
+		b, err := parseFormBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("must be true or false")
+		}
+		return b, nil
+
+	case SettingTypeInt:
+		value = strings.TrimSpace(value)
+		if value == "" {
+			return nil, nil
+		}
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, fmt.Errorf("must be a whole number")
+		}
+		return n, nil
+
+	case SettingTypeSelect:
+		if value != "" && len(setting.Options) > 0 && !hasSettingOption(setting.Options, value) {
+			return nil, fmt.Errorf("%q is not a valid option", value)
+		}
+		return value, nil
+
+	case SettingTypeMulti:
+		selected := make([]string, 0, len(values))
+		for _, v := range values {
+			if v == "" {
+				continue
+			}
+			if len(setting.Options) > 0 && !hasSettingOption(setting.Options, v) {
+				return nil, fmt.Errorf("%q is not a valid option", v)
+			}
+			selected = append(selected, v)
+		}
+		return selected, nil
+
+	case SettingTypeJSON:
+		if strings.TrimSpace(value) == "" {
+			return nil, nil
+		}
+		var decoded interface{}
+		if err := json.Unmarshal([]byte(value), &decoded); err != nil {
+			return nil, fmt.Errorf("must be valid JSON: %v", err)
+		}
+		return decoded, nil
+
+	case SettingTypeColor:
+		value = strings.TrimSpace(value)
+		if value != "" && !colorPattern.MatchString(value) {
+			return nil, fmt.Errorf("must be a hex color such as #1a2b3c")
+		}
+		return strings.ToLower(value), nil
+
+	case SettingTypeDateTime:
+		value = strings.TrimSpace(value)
+		if value == "" {
+			return nil, nil
+		}
+		for _, layout := range SettingDateTimeLayouts {
+			if t, err := time.Parse(layout, value); err == nil {
+				return t, nil
+			}
+		}
+		return nil, fmt.Errorf("must be a date and time")
+
+	default:
+		// Strings, and file settings whose value is the stored file's path
+		return value, nil
+	}
+}
+
+// ValidateSetting checks a parsed value against the setting's validation rules.
+// Min and Max bound numbers, and the length of strings, lists and JSON text.
+func ValidateSetting(setting Setting, value interface{}) error {
+	rules := setting.Validation
+
+	if isEmptySetting(value) {
+		if rules.Required {
+			return fmt.Errorf("is required")
+		}
+		return nil
+	}
+
+	size, unit := settingSize(setting, value)
+	if min, ok := toFloat64(rules.Min); ok && size < min {
+		return fmt.Errorf("must be at least %s%s", formatBound(min), unit)
+	}
+	if max, ok := toFloat64(rules.Max); ok && size > max {
+		return fmt.Errorf("must be at most %s%s", formatBound(max), unit)
+	}
+
+	if rules.Pattern != "" {
+		re, err := regexp.Compile(rules.Pattern)
+		if err != nil {
+			return fmt.Errorf("invalid validation pattern: %w", err)
+		}
+		if s, ok := value.(string); ok && !re.MatchString(s) {
+			return fmt.Errorf("must match pattern %s", rules.Pattern)
+		}
+	}
+
+	if rules.Custom != nil {
+		if err := rules.Custom(value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AcceptedFiles returns the file extensions a file setting accepts
+func AcceptedFiles(setting Setting) []string {
+	if len(setting.Accept) > 0 {
+		return setting.Accept
+	}
+	return DefaultFileAccept
+}
+
+// AcceptsFile reports whether a file setting accepts an upload, by its file name's extension
+func AcceptsFile(setting Setting, filename string) bool {
+	ext := strings.ToLower(filepath.Ext(filename))
+	if ext == "" {
+		return false
+	}
+	for _, accept := range AcceptedFiles(setting) {
+		if strings.ToLower(accept) == ext {
+			return true
+		}
+	}
+	return false
+}
+
+// ApplySettings notifies a plugin of each changed setting, then persists the merged values with commit.
+// If any OnSettingChange call or the commit fails, the changes already applied are reverted in reverse
+// order so the plugin is left with its previous settings.
+func ApplySettings(sp SettingsPlugin, current, updates map[string]interface{}, commit func(map[string]interface{}) error) error {
+	merged := make(map[string]interface{}, len(current)+len(updates))
+	for key, value := range current {
+		merged[key] = value
+	}
+
+	var applied []string
+	rollback := func() {
+		for i := len(applied) - 1; i >= 0; i-- {
+			key := applied[i]
+			// Best effort: the plugin already accepted the old value once
+			sp.OnSettingChange(key, updates[key], current[key])
+		}
+	}
+
+	for _, setting := range sp.Settings() {
+		value, ok := updates[setting.Key]
+		if !ok {
+			continue
+		}
+		merged[setting.Key] = value
+		if reflect.DeepEqual(current[setting.Key], value) {
+			continue
+		}
+		if err := sp.OnSettingChange(setting.Key, current[setting.Key], value); err != nil {
+			rollback()
+			return fmt.Errorf("failed to update setting %s: %w", setting.Key, err)
+		}
+		applied = append(applied, setting.Key)
+	}
+
+	if err := commit(merged); err != nil {
+		rollback()
+		return fmt.Errorf("failed to save settings: %w", err)
+	}
+	return nil
+}
+
+// settingSize returns the number Min/Max compare against and its unit
+func settingSize(setting Setting, value interface{}) (float64, string) {
+	switch v := value.(type) {
+	case int:
+		return float64(v), ""
+	case float64:
+		return v, ""
+	case string:
+		return float64(len([]rune(v))), " characters"
+	case []string:
+		return float64(len(v)), " selections"
+	case time.Time:
+		return math.NaN(), ""
+	default:
+		if setting.Type == SettingTypeJSON {
+			data, _ := json.Marshal(v)
+			return float64(len(data)), " characters"
+		}
+		return math.NaN(), ""
+	}
+}
+
+func isEmptySetting(value interface{}) bool {
+	switch v := value.(type) {
+	case nil:
+		return true
+	case string:
+		return v == ""
+	case []string:
+		return len(v) == 0
+	}
+	return false
+}
+
+func hasSettingOption(options []SettingOption, value string) bool {
+	for _, option := range options {
+		if option.Value == value {
+			return true
+		}
+	}
+	return false
+}
+
+func formatBound(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
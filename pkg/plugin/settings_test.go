@@ -0,0 +1,190 @@
+package plugin
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestSettingsPlugin records setting changes and can reject one key
+type TestSettingsPlugin struct {
+	TestPlugin
+	settings []Setting
+	values   map[string]interface{}
+	reject   string
+}
+
+func (p *TestSettingsPlugin) Settings() []Setting { return p.settings }
+
+func (p *TestSettingsPlugin) OnSettingChange(key string, oldValue, newValue interface{}) error {
+	if key == p.reject {
+		return errors.New("rejected")
+	}
+	p.values[key] = newValue
+	return nil
+}
+
+func TestParseSettingValue(t *testing.T) {
+	options := []SettingOption{{Value: "a", Label: "A"}, {Value: "b", Label: "B"}}
+
+	tests := []struct {
+		name    string
+		setting Setting
+		values  []string
+		want    interface{}
+		wantErr bool
+	}{
+		{"string", Setting{Type: SettingTypeString}, []string{"hello"}, "hello", false},
+		{"bool checked", Setting{Type: SettingTypeBool}, []string{"on"}, true, false},
+		{"bool unchecked", Setting{Type: SettingTypeBool}, nil, false, false},
+		{"bool invalid", Setting{Type: SettingTypeBool}, []string{"maybe"}, nil, true},
+		{"int", Setting{Type: SettingTypeInt}, []string{" 42 "}, 42, false},
+		{"int empty", Setting{Type: SettingTypeInt}, []string{""}, nil, false},
+		{"int invalid", Setting{Type: SettingTypeInt}, []string{"4.2"}, nil, true},
+		{"select", Setting{Type: SettingTypeSelect, Options: options}, []string{"b"}, "b", false},
+		{"select unknown", Setting{Type: SettingTypeSelect, Options: options}, []string{"c"}, nil, true},
+		{"multi", Setting{Type: SettingTypeMulti, Options: options}, []string{"a", "b"}, []string{"a", "b"}, false},
+		{"multi none", Setting{Type: SettingTypeMulti, Options: options}, nil, []string{}, false},
+		{"multi unknown", Setting{Type: SettingTypeMulti, Options: options}, []string{"a", "z"}, nil, true},
+		{"json", Setting{Type: SettingTypeJSON}, []string{`{"a":[1,2]}`}, map[string]interface{}{"a": []interface{}{float64(1), float64(2)}}, false},
+		{"json invalid", Setting{Type: SettingTypeJSON}, []string{`{"a":`}, nil, true},
+		{"color", Setting{Type: SettingTypeColor}, []string{"#FFaa00"}, "#ffaa00", false},
+		{"color short", Setting{Type: SettingTypeColor}, []string{"#fff"}, "#fff", false},
+		{"color invalid", Setting{Type: SettingTypeColor}, []string{"red"}, nil, true},
+		{"datetime local", Setting{Type: SettingTypeDateTime}, []string{"2024-03-01T09:30"}, time.Date(2024, 3, 1, 9, 30, 0, 0, time.UTC), false},
+		{"datetime invalid", Setting{Type: SettingTypeDateTime}, []string{"tomorrow"}, nil, true},
+		{"file", Setting{Type: SettingTypeFile}, []string{"/static/uploads/logo.png"}, "/static/uploads/logo.png", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseSettingValue(tt.setting, tt.values)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestValidateSetting(t *testing.T) {
+	tests := []struct {
+		name    string
+		setting Setting
+		value   interface{}
+		wantErr string
+	}{
+		{"required missing", Setting{Validation: SettingValidation{Required: true}}, "", "is required"},
+		{"required nil", Setting{Type: SettingTypeInt, Validation: SettingValidation{Required: true}}, nil, "is required"},
+		{"optional empty", Setting{Validation: SettingValidation{Min: 3}}, "", ""},
+		{"string too short", Setting{Validation: SettingValidation{Min: 3}}, "ab", "must be at least 3 characters"},
+		{"string too long", Setting{Validation: SettingValidation{Max: 3}}, "abcd", "must be at most 3 characters"},
+		{"int below min", Setting{Type: SettingTypeInt, Validation: SettingValidation{Min: 1}}, 0, "must be at least 1"},
+		{"int above max", Setting{Type: SettingTypeInt, Validation: SettingValidation{Max: 10.5}}, 11, "must be at most 10.5"},
+		{"int in range", Setting{Type: SettingTypeInt, Validation: SettingValidation{Min: 1, Max: 10}}, 5, ""},
+		{"multi too few", Setting{Type: SettingTypeMulti, Validation: SettingValidation{Min: 2}}, []string{"a"}, "must be at least 2 selections"},
+		{"multi required", Setting{Type: SettingTypeMulti, Validation: SettingValidation{Required: true}}, []string{}, "is required"},
+		{"pattern", Setting{Validation: SettingValidation{Pattern: `^[a-z]+$`}}, "ABC", "must match pattern ^[a-z]+$"},
+		{"pattern ok", Setting{Validation: SettingValidation{Pattern: `^[a-z]+$`}}, "abc", ""},
+		{"custom", Setting{Validation: SettingValidation{Custom: func(v interface{}) error {
+			return errors.New("is not allowed")
+		}}}, "x", "is not allowed"},
+		{"datetime ignores bounds", Setting{Type: SettingTypeDateTime, Validation: SettingValidation{Min: 1}}, time.Now(), ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateSetting(tt.setting, tt.value)
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			assert.Equal(t, tt.wantErr, err.Error())
+		})
+	}
+}
+
+func TestAcceptsFile(t *testing.T) {
+	images := Setting{Type: SettingTypeFile}
+	assert.True(t, AcceptsFile(images, "logo.png"))
+	assert.True(t, AcceptsFile(images, "Photo.JPG"))
+	assert.False(t, AcceptsFile(images, "page.html"))
+	assert.False(t, AcceptsFile(images, "drawing.svg"))
+	assert.False(t, AcceptsFile(images, "logo"))
+
+	pdf := Setting{Type: SettingTypeFile, Accept: []string{".pdf"}}
+	assert.True(t, AcceptsFile(pdf, "terms.PDF"))
+	assert.False(t, AcceptsFile(pdf, "logo.png"))
+}
+
+func TestSettingErrors(t *testing.T) {
+	err := SettingErrors{"b": "is required", "a": "must be a whole number"}
+	assert.Equal(t, "invalid settings: a: must be a whole number; b: is required", err.Error())
+}
+
+func TestApplySettings(t *testing.T) {
+	newPlugin := func(reject string) *TestSettingsPlugin {
+		return &TestSettingsPlugin{
+			TestPlugin: TestPlugin{id: "test.settings"},
+			settings: []Setting{
+				{Key: "first", Type: SettingTypeString},
+				{Key: "second", Type: SettingTypeInt},
+				{Key: "third", Type: SettingTypeBool},
+			},
+			values: map[string]interface{}{"first": "old", "second": 1, "third": false},
+			reject: reject,
+		}
+	}
+	current := map[string]interface{}{"first": "old", "second": 1, "third": false, "other": "kept"}
+	updates := map[string]interface{}{"first": "new", "second": 2, "third": true}
+
+	t.Run("commits all changes", func(t *testing.T) {
+		p := newPlugin("")
+		var saved map[string]interface{}
+		err := ApplySettings(p, current, updates, func(values map[string]interface{}) error {
+			saved = values
+			return nil
+		})
+		require.NoError(t, err)
+		assert.Equal(t, map[string]interface{}{"first": "new", "second": 2, "third": true, "other": "kept"}, saved)
+		assert.Equal(t, map[string]interface{}{"first": "new", "second": 2, "third": true}, p.values)
+		assert.Equal(t, "old", current["first"], "current values must not be modified")
+	})
+
+	t.Run("rolls back when a plugin rejects a change", func(t *testing.T) {
+		p := newPlugin("third")
+		committed := false
+		err := ApplySettings(p, current, updates, func(values map[string]interface{}) error {
+			committed = true
+			return nil
+		})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "third")
+		assert.False(t, committed)
+		assert.Equal(t, map[string]interface{}{"first": "old", "second": 1, "third": false}, p.values)
+	})
+
+	t.Run("rolls back when the commit fails", func(t *testing.T) {
+		p := newPlugin("")
+		err := ApplySettings(p, current, updates, func(values map[string]interface{}) error {
+			return errors.New("disk full")
+		})
+		require.Error(t, err)
+		assert.Equal(t, map[string]interface{}{"first": "old", "second": 1, "third": false}, p.values)
+	})
+
+	t.Run("skips unchanged settings", func(t *testing.T) {
+		p := newPlugin("first")
+		err := ApplySettings(p, current, map[string]interface{}{"first": "old", "second": 3}, func(map[string]interface{}) error {
+			return nil
+		})
+		require.NoError(t, err)
+		assert.Equal(t, 3, p.values["second"])
+	})
+}
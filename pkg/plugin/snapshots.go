@@ -0,0 +1,152 @@
+package plugin
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// ConfigSnapshot is an immutable view of a plugin's effective config.
+// A new snapshot replaces the previous one whenever the config or its overrides change;
+// its maps are shared between readers and must not be modified.
+type ConfigSnapshot struct {
+	PluginID string
+	Version  uint64 // Increases with every snapshot published for the plugin
+	Config   map[string]interface{}
+	Origins  ConfigOrigins
+}
+
+// Decode copies the snapshot's config into a struct
+func (s *ConfigSnapshot) Decode(target interface{}) error {
+	return decodeConfig(s.Config, target)
+}
+
+// Snapshot returns the current effective config of a plugin
+func (cm *ConfigManager) Snapshot(pluginID string) (*ConfigSnapshot, error) {
+	cm.mu.RLock()
+	current := cm.snapshots[pluginID]
+	cm.mu.RUnlock()
+	if current != nil {
+		if snapshot := current.Load(); snapshot != nil {
+			return snapshot, nil
+		}
+	}
+
+	// Nothing published yet; resolve the config in order with any concurrent change
+	cm.saveMu.Lock()
+	defer cm.saveMu.Unlock()
+	if snapshot := cm.currentSnapshot(pluginID); snapshot != nil {
+		return snapshot, nil
+	}
+	return cm.refreshSnapshot(pluginID)
+}
+
+// Watch subscribes to a plugin's config. The channel first receives the current snapshot,
+// then each new one. A slow receiver only misses intermediate snapshots, never the latest.
+// Call the returned function to unsubscribe and close the channel.
+func (cm *ConfigManager) Watch(pluginID string) (<-chan *ConfigSnapshot, func()) {
+	ch := make(chan *ConfigSnapshot, 1)
+
+	cm.saveMu.Lock()
+	if snapshot := cm.currentSnapshot(pluginID); snapshot != nil {
+		ch <- snapshot
+	} else if snapshot, err := cm.refreshSnapshot(pluginID); err == nil {
+		ch <- snapshot
+	}
+	cm.mu.Lock()
+	if cm.watchers[pluginID] == nil {
+		cm.watchers[pluginID] = make(map[chan *ConfigSnapshot]struct{})
+	}
+	cm.watchers[pluginID][ch] = struct{}{}
+	cm.mu.Unlock()
+	cm.saveMu.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			cm.mu.Lock()
+			defer cm.mu.Unlock()
+			delete(cm.watchers[pluginID], ch)
+			close(ch)
+		})
+	}
+}
+
+// currentSnapshot returns the published snapshot of a plugin, or nil
+func (cm *ConfigManager) currentSnapshot(pluginID string) *ConfigSnapshot {
+	cm.mu.RLock()
+	defer cm.mu.RUnlock()
+	if current := cm.snapshots[pluginID]; current != nil {
+		return current.Load()
+	}
+	return nil
+}
+
+// refreshSnapshot resolves and stores a new snapshot of a plugin's config.
+// Callers hold saveMu, so versions increase in the order changes were made.
+func (cm *ConfigManager) refreshSnapshot(pluginID string) (*ConfigSnapshot, error) {
+	config, origins, err := cm.EffectiveConfig(pluginID)
+	if err != nil {
+		return nil, err
+	}
+
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+	current := cm.snapshots[pluginID]
+	if current == nil {
+		current = new(atomic.Pointer[ConfigSnapshot])
+		cm.snapshots[pluginID] = current
+	}
+	snapshot := &ConfigSnapshot{PluginID: pluginID, Config: config, Origins: origins, Version: 1}
+	if previous := current.Load(); previous != nil {
+		snapshot.Version = previous.Version + 1
+	}
+	current.Store(snapshot)
+	return snapshot, nil
+}
+
+// publish refreshes a plugin's snapshot and notifies its watchers. Callers hold saveMu.
+// Plugins nobody has read or watched yet are resolved lazily by Snapshot instead.
+func (cm *ConfigManager) publish(pluginID string) {
+	cm.mu.RLock()
+	_, published := cm.snapshots[pluginID]
+	watched := len(cm.watchers[pluginID]) > 0
+	cm.mu.RUnlock()
+	if !published && !watched {
+		return
+	}
+
+	snapshot, err := cm.refreshSnapshot(pluginID)
+	if err != nil {
+		// Overrides that don't parse keep the last good snapshot in place
+		return
+	}
+
+	// Hold the read lock while sending so unsubscribing can't close a channel mid-send
+	cm.mu.RLock()
+	defer cm.mu.RUnlock()
+	for ch := range cm.watchers[pluginID] {
+		// Replace an unread snapshot so the receiver always gets the latest
+		select {
+		case <-ch:
+		default:
+		}
+		ch <- snapshot
+	}
+}
+
+// publishAll refreshes every published snapshot, after overrides shared by all plugins change
+func (cm *ConfigManager) publishAll() {
+	cm.mu.RLock()
+	ids := make(map[string]struct{}, len(cm.snapshots)+len(cm.watchers))
+	for id := range cm.snapshots {
+		ids[id] = struct{}{}
+	}
+	for id := range cm.watchers {
+		ids[id] = struct{}{}
+	}
+	cm.mu.RUnlock()
+
+	for id := range ids {
+		cm.publish(id)
+	}
+}
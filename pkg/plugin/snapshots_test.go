@@ -0,0 +1,206 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func receiveSnapshot(t *testing.T, updates <-chan *ConfigSnapshot) *ConfigSnapshot {
+	t.Helper()
+	select {
+	case snapshot := <-updates:
+		return snapshot
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for config snapshot")
+		return nil
+	}
+}
+
+func TestConfigManager_Snapshot(t *testing.T) {
+	cm := NewConfigManager()
+	require.NoError(t, cm.SetConfig("test.plugin", map[string]interface{}{"name": "first"}))
+
+	first, err := cm.Snapshot("test.plugin")
+	require.NoError(t, err)
+	assert.Equal(t, "first", first.Config["name"])
+	assert.Equal(t, ConfigOrigin{Layer: LayerStored}, first.Origins["name"])
+
+	same, err := cm.Snapshot("test.plugin")
+	require.NoError(t, err)
+	assert.Same(t, first, same, "snapshots are reused until the config changes")
+
+	require.NoError(t, cm.SetConfig("test.plugin", map[string]interface{}{"name": "second"}))
+	second, err := cm.Snapshot("test.plugin")
+	require.NoError(t, err)
+	assert.Equal(t, "second", second.Config["name"])
+	assert.Greater(t, second.Version, first.Version)
+	assert.Equal(t, "first", first.Config["name"], "earlier snapshots are not modified")
+
+	// Overrides publish a new snapshot too
+	cm.LoadEnvOverrides([]string{"OBTURA_PLUGIN_TEST_PLUGIN__NAME=env"})
+	third, err := cm.Snapshot("test.plugin")
+	require.NoError(t, err)
+	assert.Equal(t, "env", third.Config["name"])
+
+	var decoded struct {
+		Name string `json:"name"`
+	}
+	require.NoError(t, third.Decode(&decoded))
+	assert.Equal(t, "env", decoded.Name)
+}
+
+func TestConfigManager_ConfigIsCopied(t *testing.T) {
+	cm := NewConfigManager()
+	config := map[string]interface{}{"name": "saved", "nested": map[string]interface{}{"a": 1}}
+	require.NoError(t, cm.SetConfig("test.plugin", config))
+
+	// Neither the saved map nor a returned one can change the stored config
+	config["name"] = "changed"
+	got, _ := cm.GetConfigMap("test.plugin")
+	got["nested"].(map[string]interface{})["a"] = 2
+
+	got, _ = cm.GetConfigMap("test.plugin")
+	assert.Equal(t, "saved", got["name"])
+	assert.Equal(t, 1, got["nested"].(map[string]interface{})["a"])
+}
+
+func TestConfigManager_Watch(t *testing.T) {
+	cm := NewConfigManager()
+	require.NoError(t, cm.SetConfig("test.plugin", map[string]interface{}{"name": "first"}))
+
+	updates, stop := cm.Watch("test.plugin")
+	assert.Equal(t, "first", receiveSnapshot(t, updates).Config["name"], "the current config is sent on subscribe")
+
+	require.NoError(t, cm.SetConfig("test.plugin", map[string]interface{}{"name": "second"}))
+	assert.Equal(t, "second", receiveSnapshot(t, updates).Config["name"])
+
+	// A slow watcher gets the latest snapshot, not a backlog
+	require.NoError(t, cm.SetConfig("test.plugin", map[string]interface{}{"name": "third"}))
+	require.NoError(t, cm.SetConfig("test.plugin", map[string]interface{}{"name": "fourth"}))
+	assert.Equal(t, "fourth", receiveSnapshot(t, updates).Config["name"])
+
+	// Other plugins' changes are not delivered
+	require.NoError(t, cm.SetConfig("test.other", map[string]interface{}{"name": "other"}))
+	select {
+	case snapshot := <-updates:
+		t.Fatalf("unexpected snapshot for %s", snapshot.PluginID)
+	default:
+	}
+
+	stop()
+	stop()
+	_, open := <-updates
+	assert.False(t, open, "stopping closes the channel")
+	require.NoError(t, cm.SetConfig("test.plugin", map[string]interface{}{"name": "fifth"}))
+}
+
+func TestConfigManager_ConcurrentAccess(t *testing.T) {
+	cm := NewConfigManager()
+	cm.RegisterSchema("test.plugin", &ConfigSchema{Fields: []ConfigField{{Name: "count", Type: "number"}}})
+	updates, stop := cm.Watch("test.plugin")
+	defer stop()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 25; j++ {
+				switch j % 5 {
+				case 0:
+					assert.NoError(t, cm.SetConfig("test.plugin", map[string]interface{}{"count": i*100 + j}))
+				case 1:
+					cm.GetConfig("test.plugin")
+				case 2:
+					cm.Snapshot("test.plugin")
+				case 3:
+					cm.EffectiveConfig("test.plugin")
+				case 4:
+					cm.MarkSecret(fmt.Sprintf("test.plugin.%d", i), "token")
+				}
+			}
+		}(i)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		for snapshot := range updates {
+			_ = snapshot.Config["count"]
+		}
+		close(done)
+	}()
+	wg.Wait()
+	stop()
+	<-done
+
+	// The published snapshot matches the last save
+	snapshot, err := cm.Snapshot("test.plugin")
+	require.NoError(t, err)
+	stored, _ := cm.GetConfigMap("test.plugin")
+	assert.EqualValues(t, stored["count"], snapshot.Config["count"])
+}
+
+func TestConfigManager_ConcurrentEffectiveConfigAndRegistration(t *testing.T) {
+	schema := &ConfigSchema{Fields: []ConfigField{{Name: "count", Type: "number"}}}
+	cm := NewConfigManager()
+	cm.RegisterSchema("test.plugin", schema)
+	cm.LoadEnvOverrides([]string{"OBTURA_PLUGIN_TEST_PLUGIN__COUNT=3"})
+
+	// Registering while configs are resolved must not race; the environment always wins
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 25; j++ {
+				cm.RegisterSchema("test.plugin", schema)
+				assert.NoError(t, cm.SetDefaults("test.plugin", map[string]interface{}{"count": i*100 + j}))
+				cm.LoadEnvOverrides([]string{"OBTURA_PLUGIN_TEST_PLUGIN__COUNT=3"})
+			}
+		}(i)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 25; j++ {
+				config, _, err := cm.EffectiveConfig("test.plugin")
+				assert.NoError(t, err)
+				assert.EqualValues(t, 3, config["count"])
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestRegistry_ConcurrentSetConfig(t *testing.T) {
+	r := NewRegistry(chi.NewRouter(), WithConfigStorage(NewMemoryConfigStorage()))
+	p := &RevisionTestPlugin{TestPlugin: TestPlugin{id: "test.revisions"}, config: &revisionTestConfig{}}
+	require.NoError(t, r.Register(p))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			assert.NoError(t, r.SetConfig("test.revisions", map[string]interface{}{"name": "concurrent", "limit": i}))
+			r.GetConfig("test.revisions")
+		}(i)
+	}
+	wg.Wait()
+
+	snapshot, err := r.GetConfigManager().Snapshot("test.revisions")
+	require.NoError(t, err)
+	assert.Equal(t, float64(p.config.Limit), snapshot.Config["limit"])
+}
+
+func TestRegistry_PluginContextCarriesConfigManager(t *testing.T) {
+	r := NewRegistry(chi.NewRouter(), WithConfigStorage(NewMemoryConfigStorage()))
+	ctx := r.pluginContext(context.Background(), "test.plugin")
+	assert.Same(t, r.GetConfigManager(), ConfigManagerFromContext(ctx))
+	assert.Nil(t, ConfigManagerFromContext(context.Background()))
+}
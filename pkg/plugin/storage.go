@@ -4,8 +4,11 @@ import (
 	"encoding/json"
 	"fmt"
 	"path/filepath"
+	"sort"
 	"strings"
 	"sync"
+
+	"github.com/btassone/obtura/pkg/database"
 )
 
 // ConfigStorage defines the interface for storing plugin configurations
@@ -23,10 +26,90 @@ type ConfigStorage interface {
 	List() ([]string, error)
 }
 
+// Config storage kinds accepted by NewConfigStorage
+const (
+	ConfigStorageFile   = "file"
+	ConfigStorageDB     = "db"
+	ConfigStorageMemory = "memory"
+)
+
+// NewConfigStorage creates the config storage of the given kind.
+// File storage keeps configs under path; database storage requires db.
+func NewConfigStorage(kind, path string, db *database.DB) (ConfigStorage, error) {
+	switch kind {
+	case ConfigStorageFile, "":
+		return NewJSONFileConfigStorage(path)
+	case ConfigStorageDB:
+		if db == nil {
+			return nil, fmt.Errorf("database config storage requires a database connection")
+		}
+		return NewDBConfigStorage(db), nil
+	case ConfigStorageMemory:
+		return NewMemoryConfigStorage(), nil
+	}
+	return nil, fmt.Errorf("unknown config storage %q (expected file, db or memory)", kind)
+}
+
+// CopyConfigs copies every plugin config from src to dst and returns how many were copied.
+// When both storages keep history, revisions are copied oldest first for plugins that
+// have no history in dst yet, so running the copy twice does not duplicate them.
+func CopyConfigs(src, dst ConfigStorage) (int, error) {
+	ids, err := src.List()
+	if err != nil {
+		return 0, err
+	}
+	sort.Strings(ids)
+	
+	srcRevisions, srcHasHistory := src.(RevisionStorage)
+	dstRevisions, dstHasHistory := dst.(RevisionStorage)
+	
+	for i, id := range ids {
+		config, err := src.Load(id)
+		if err != nil {
+			return i, fmt.Errorf("failed to load config for %s: %w", id, err)
+		}
+		if err := dst.Save(id, config); err != nil {
+			return i, fmt.Errorf("failed to save config for %s: %w", id, err)
+		}
+		
+		if !srcHasHistory || !dstHasHistory {
+			continue
+		}
+		if err := copyRevisions(srcRevisions, dstRevisions, id); err != nil {
+			return i, fmt.Errorf("failed to copy history for %s: %w", id, err)
+		}
+	}
+	
+	return len(ids), nil
+}
+
+// copyRevisions copies a plugin's history into an empty destination history
+func copyRevisions(src, dst RevisionStorage, pluginID string) error {
+	existing, err := dst.Revisions(pluginID)
+	if err != nil {
+		return err
+	}
+	if len(existing) > 0 {
+		return nil
+	}
+	
+	revisions, err := src.Revisions(pluginID)
+	if err != nil {
+		return err
+	}
+	for i := len(revisions) - 1; i >= 0; i-- {
+		if _, err := dst.AppendRevision(revisions[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // MemoryConfigStorage is an in-memory implementation of ConfigStorage
 type MemoryConfigStorage struct {
-	mu      sync.RWMutex
-	configs map[string]map[string]interface{}
+	mu        sync.RWMutex
+	configs   map[string]map[string]interface{}
+	revisions map[string][]ConfigRevision
 }
 
 // NewMemoryConfigStorage creates a new memory-based config storage
@@ -209,6 +292,7 @@ var (
 	ensureDir  = defaultEnsureDir
 	readFile   = defaultReadFile
 	writeFile  = defaultWriteFile
+	appendFile = defaultAppendFile
 	removeFile = defaultRemoveFile
 	listFiles  = defaultListFiles
 	filepathHelper   = defaultFilepath{}
@@ -0,0 +1,179 @@
+package plugin
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/btassone/obtura/pkg/database"
+)
+
+// Config tables backing DBConfigStorage
+const (
+	ConfigTable         = "plugin_configs"
+	ConfigRevisionTable = "plugin_config_revisions"
+)
+
+// DBConfigStorage stores plugin configurations and their revisions in the database,
+// so several instances can share configuration without a writable filesystem
+type DBConfigStorage struct {
+	db *database.DB
+}
+
+// NewDBConfigStorage creates a database-backed config storage
+func NewDBConfigStorage(db *database.DB) ConfigStorage {
+	return &DBConfigStorage{db: db}
+}
+
+// Load retrieves configuration for a plugin
+func (s *DBConfigStorage) Load(pluginID string) (map[string]interface{}, error) {
+	var data string
+	err := s.db.QueryRow(`SELECT config FROM plugin_configs WHERE plugin_id = ?`, pluginID).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("no configuration found for plugin: %s", pluginID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load config: %w", err)
+	}
+
+	var config map[string]interface{}
+	if err := json.Unmarshal([]byte(data), &config); err != nil {
+		return nil, fmt.Errorf("failed to parse config JSON: %w", err)
+	}
+	return config, nil
+}
+
+// Save stores configuration for a plugin
+func (s *DBConfigStorage) Save(pluginID string, config map[string]interface{}) error {
+	if err := validatePluginID(pluginID); err != nil {
+		return err
+	}
+	data, err := json.Marshal(config)
+	if err != nil {
+		return fmt.Errorf("failed to marshal config: %w", err)
+	}
+
+	var query string
+	switch s.db.Driver() {
+	case "mysql":
+		query = `INSERT INTO plugin_configs (plugin_id, config, updated_at) VALUES (?, ?, ?)
+			ON DUPLICATE KEY UPDATE config = VALUES(config), updated_at = VALUES(updated_at)`
+	default:
+		query = `INSERT INTO plugin_configs (plugin_id, config, updated_at) VALUES (?, ?, ?)
+			ON CONFLICT (plugin_id) DO UPDATE SET config = excluded.config, updated_at = excluded.updated_at`
+	}
+
+	if _, err := s.db.Exec(query, pluginID, string(data), time.Now().UTC()); err != nil {
+		return fmt.Errorf("failed to save config: %w", err)
+	}
+	return nil
+}
+
+// Delete removes configuration for a plugin. Its revisions are kept.
+func (s *DBConfigStorage) Delete(pluginID string) error {
+	if _, err := s.db.Exec(`DELETE FROM plugin_configs WHERE plugin_id = ?`, pluginID); err != nil {
+		return fmt.Errorf("failed to delete config: %w", err)
+	}
+	return nil
+}
+
+// List returns all stored plugin IDs
+func (s *DBConfigStorage) List() ([]string, error) {
+	rows, err := s.db.Query(`SELECT plugin_id FROM plugin_configs ORDER BY plugin_id`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list configs: %w", err)
+	}
+	defer rows.Close()
+
+	ids := []string{}
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to list configs: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// AppendRevision stores a revision, assigning it the next version number.
+// Concurrent saves from other instances fail on the primary key rather than sharing a version.
+func (s *DBConfigStorage) AppendRevision(rev ConfigRevision) (ConfigRevision, error) {
+	if err := validatePluginID(rev.PluginID); err != nil {
+		return ConfigRevision{}, err
+	}
+	data, err := json.Marshal(rev.Config)
+	if err != nil {
+		return ConfigRevision{}, fmt.Errorf("failed to marshal revision: %w", err)
+	}
+
+	err = s.db.Transaction(context.Background(), func(tx *database.Tx) error {
+		var latest int
+		query := `SELECT COALESCE(MAX(version), 0) FROM plugin_config_revisions WHERE plugin_id = ?`
+		if err := tx.QueryRow(query, rev.PluginID).Scan(&latest); err != nil {
+			return err
+		}
+		rev.Version = latest + 1
+
+		_, err := tx.Exec(`INSERT INTO plugin_config_revisions
+			(plugin_id, version, author, message, config, created_at) VALUES (?, ?, ?, ?, ?, ?)`,
+			rev.PluginID, rev.Version, rev.Author, rev.Message, string(data), rev.CreatedAt)
+		return err
+	})
+	if err != nil {
+		return ConfigRevision{}, fmt.Errorf("failed to write revision: %w", err)
+	}
+	return rev, nil
+}
+
+// Revisions returns a plugin's revisions, newest first
+func (s *DBConfigStorage) Revisions(pluginID string) ([]ConfigRevision, error) {
+	rows, err := s.db.Query(`SELECT version, author, message, config, created_at
+		FROM plugin_config_revisions WHERE plugin_id = ? ORDER BY version DESC`, pluginID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config history: %w", err)
+	}
+	defer rows.Close()
+
+	revisions := []ConfigRevision{}
+	for rows.Next() {
+		rev := ConfigRevision{PluginID: pluginID}
+		var data string
+		if err := rows.Scan(&rev.Version, &rev.Author, &rev.Message, &data, &rev.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to read config history: %w", err)
+		}
+		if err := json.Unmarshal([]byte(data), &rev.Config); err != nil {
+			return nil, fmt.Errorf("failed to parse config history: %w", err)
+		}
+		revisions = append(revisions, rev)
+	}
+	return revisions, rows.Err()
+}
+
+// ReplaceRevisions rewrites a plugin's revisions in one transaction
+func (s *DBConfigStorage) ReplaceRevisions(pluginID string, revisions []ConfigRevision) error {
+	err := s.db.Transaction(context.Background(), func(tx *database.Tx) error {
+		if _, err := tx.Exec(`DELETE FROM plugin_config_revisions WHERE plugin_id = ?`, pluginID); err != nil {
+			return err
+		}
+		insert := `INSERT INTO plugin_config_revisions
+			(plugin_id, version, author, message, config, created_at) VALUES (?, ?, ?, ?, ?, ?)`
+		for _, rev := range revisions {
+			data, err := json.Marshal(rev.Config)
+			if err != nil {
+				return err
+			}
+			if _, err := tx.Exec(insert, pluginID, rev.Version, rev.Author, rev.Message, string(data), rev.CreatedAt); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to replace config history: %w", err)
+	}
+	return nil
+}
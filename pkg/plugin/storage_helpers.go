@@ -28,6 +28,19 @@ func defaultWriteFile(path string, data []byte) error {
 	return os.WriteFile(path, data, 0644)
 }
 
+// defaultAppendFile appends data to a file, creating it if needed
+func defaultAppendFile(path string, data []byte) error {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
 // defaultRemoveFile removes a file
 func defaultRemoveFile(path string) error {
 	err := os.Remove(path)
@@ -0,0 +1,208 @@
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrKeyNotFound is returned when a store key does not exist or has expired
+var ErrKeyNotFound = errors.New("key not found")
+
+// Store is a key-value data store scoped to a single plugin
+type Store interface {
+	// Get decodes the value stored under key into dest
+	Get(ctx context.Context, key string, dest interface{}) error
+
+	// Set stores value under key. A ttl of zero means the value never expires.
+	Set(ctx context.Context, key string, value interface{}, ttl time.Duration) error
+
+	// Delete removes a key
+	Delete(ctx context.Context, key string) error
+
+	// Increment atomically adds delta to an integer value and returns the result.
+	// Missing or expired keys start from zero.
+	Increment(ctx context.Context, key string, delta int64) (int64, error)
+
+	// Scan returns all live items whose key starts with prefix, ordered by key
+	Scan(ctx context.Context, prefix string) ([]StoreItem, error)
+}
+
+// StoreItem is a single entry returned by Store.Scan
+type StoreItem struct {
+	Key       string
+	Value     json.RawMessage
+	ExpiresAt *time.Time
+}
+
+// Decode decodes the item value into dest
+func (i StoreItem) Decode(dest interface{}) error {
+	return json.Unmarshal(i.Value, dest)
+}
+
+// GetAs retrieves a typed value from a store
+func GetAs[T any](ctx context.Context, s Store, key string) (T, error) {
+	var value T
+	err := s.Get(ctx, key, &value)
+	return value, err
+}
+
+// encodeStoreValue encodes a value for storage
+func encodeStoreValue(value interface{}) (string, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode value: %w", err)
+	}
+	return string(data), nil
+}
+
+// expiryFor returns the expiry time for a ttl, or nil if it never expires
+func expiryFor(ttl time.Duration) *time.Time {
+	if ttl <= 0 {
+		return nil
+	}
+	expires := time.Now().UTC().Add(ttl)
+	return &expires
+}
+
+// validateStoreKey rejects empty keys
+func validateStoreKey(key string) error {
+	if key == "" {
+		return fmt.Errorf("store key cannot be empty")
+	}
+	return nil
+}
+
+// MemoryStore is an in-memory implementation of Store
+type MemoryStore struct {
+	mu    sync.Mutex
+	items map[string]memoryStoreItem
+}
+
+type memoryStoreItem struct {
+	value     string
+	expiresAt *time.Time
+}
+
+func (i memoryStoreItem) expired(now time.Time) bool {
+	return i.expiresAt != nil && !i.expiresAt.After(now)
+}
+
+// NewMemoryStore creates a new memory-based store
+func NewMemoryStore() Store {
+	return &MemoryStore{
+		items: make(map[string]memoryStoreItem),
+	}
+}
+
+// Get decodes the value stored under key into dest
+func (s *MemoryStore) Get(ctx context.Context, key string, dest interface{}) error {
+	s.mu.Lock()
+	item, ok := s.items[key]
+	s.mu.Unlock()
+
+	if !ok || item.expired(time.Now().UTC()) {
+		return ErrKeyNotFound
+	}
+	return json.Unmarshal([]byte(item.value), dest)
+}
+
+// Set stores value under key
+func (s *MemoryStore) Set(ctx context.Context, key string, value interface{}, ttl time.Duration) error {
+	if err := validateStoreKey(key); err != nil {
+		return err
+	}
+	encoded, err := encodeStoreValue(value)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.items[key] = memoryStoreItem{value: encoded, expiresAt: expiryFor(ttl)}
+	return nil
+}
+
+// Delete removes a key
+func (s *MemoryStore) Delete(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.items, key)
+	return nil
+}
+
+// Increment atomically adds delta to an integer value
+func (s *MemoryStore) Increment(ctx context.Context, key string, delta int64) (int64, error) {
+	if err := validateStoreKey(key); err != nil {
+		return 0, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var current int64
+	item, ok := s.items[key]
+	if ok && !item.expired(time.Now().UTC()) {
+		n, err := strconv.ParseInt(item.value, 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("value for key %s is not an integer", key)
+		}
+		current = n
+	} else {
+		item = memoryStoreItem{}
+	}
+
+	current += delta
+	item.value = strconv.FormatInt(current, 10)
+	s.items[key] = item
+	return current, nil
+}
+
+// Scan returns all live items whose key starts with prefix
+func (s *MemoryStore) Scan(ctx context.Context, prefix string) ([]StoreItem, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now().UTC()
+	var items []StoreItem
+	for key, item := range s.items {
+		if !strings.HasPrefix(key, prefix) || item.expired(now) {
+			continue
+		}
+		items = append(items, StoreItem{
+			Key:       key,
+			Value:     json.RawMessage(item.value),
+			ExpiresAt: item.expiresAt,
+		})
+	}
+
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].Key < items[j].Key
+	})
+	return items, nil
+}
+
+// StoreProvider creates a store scoped to a plugin ID
+type StoreProvider func(pluginID string) Store
+
+// NewMemoryStoreProvider returns a provider that hands each plugin its own memory store
+func NewMemoryStoreProvider() StoreProvider {
+	var mu sync.Mutex
+	stores := make(map[string]Store)
+	return func(pluginID string) Store {
+		mu.Lock()
+		defer mu.Unlock()
+		if s, ok := stores[pluginID]; ok {
+			return s
+		}
+		s := NewMemoryStore()
+		stores[pluginID] = s
+		return s
+	}
+}
@@ -0,0 +1,208 @@
+package plugin
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/btassone/obtura/pkg/database"
+)
+
+// StoreTable is the table backing DBStore
+const StoreTable = "plugin_data"
+
+// DBStore is a database-backed implementation of Store scoped to one plugin
+type DBStore struct {
+	db       *database.DB
+	pluginID string
+}
+
+// NewDBStore creates a database-backed store for a plugin
+func NewDBStore(db *database.DB, pluginID string) Store {
+	return &DBStore{
+		db:       db,
+		pluginID: pluginID,
+	}
+}
+
+// NewDBStoreProvider returns a provider that hands each plugin a database-backed store
+func NewDBStoreProvider(db *database.DB) StoreProvider {
+	return func(pluginID string) Store {
+		return NewDBStore(db, pluginID)
+	}
+}
+
+// Get decodes the value stored under key into dest
+func (s *DBStore) Get(ctx context.Context, key string, dest interface{}) error {
+	query := `SELECT value, expires_at FROM plugin_data WHERE plugin_id = ? AND item_key = ?`
+
+	var value string
+	var expiresAt sql.NullTime
+	err := s.db.QueryRowContext(ctx, query, s.pluginID, key).Scan(&value, &expiresAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrKeyNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get key %s: %w", key, err)
+	}
+
+	if expiresAt.Valid && !expiresAt.Time.After(time.Now().UTC()) {
+		return ErrKeyNotFound
+	}
+	return json.Unmarshal([]byte(value), dest)
+}
+
+// Set stores value under key
+func (s *DBStore) Set(ctx context.Context, key string, value interface{}, ttl time.Duration) error {
+	if err := validateStoreKey(key); err != nil {
+		return err
+	}
+	encoded, err := encodeStoreValue(value)
+	if err != nil {
+		return err
+	}
+
+	var query string
+	switch s.db.Driver() {
+	case "mysql":
+		query = `INSERT INTO plugin_data (plugin_id, item_key, value, expires_at) VALUES (?, ?, ?, ?)
+			ON DUPLICATE KEY UPDATE value = VALUES(value), expires_at = VALUES(expires_at)`
+	default:
+		query = `INSERT INTO plugin_data (plugin_id, item_key, value, expires_at) VALUES (?, ?, ?, ?)
+			ON CONFLICT (plugin_id, item_key) DO UPDATE SET value = excluded.value, expires_at = excluded.expires_at`
+	}
+
+	if _, err := s.db.ExecContext(ctx, query, s.pluginID, key, encoded, expiryFor(ttl)); err != nil {
+		return fmt.Errorf("failed to set key %s: %w", key, err)
+	}
+	return nil
+}
+
+// Delete removes a key
+func (s *DBStore) Delete(ctx context.Context, key string) error {
+	query := `DELETE FROM plugin_data WHERE plugin_id = ? AND item_key = ?`
+	if _, err := s.db.ExecContext(ctx, query, s.pluginID, key); err != nil {
+		return fmt.Errorf("failed to delete key %s: %w", key, err)
+	}
+	return nil
+}
+
+// Increment atomically adds delta to an integer value.
+// The upsert takes a row lock, so the read that follows in the same
+// transaction sees the value written by this call. MySQL and SQLite cast
+// text that isn't a number to 0, so they leave a value that doesn't cast
+// back to itself unchanged, and the read reports it isn't an integer.
+func (s *DBStore) Increment(ctx context.Context, key string, delta int64) (int64, error) {
+	if err := validateStoreKey(key); err != nil {
+		return 0, err
+	}
+
+	var query string
+	switch s.db.Driver() {
+	case "mysql":
+		// MySQL evaluates assignments left to right, so value must be updated before expires_at
+		query = `INSERT INTO plugin_data (plugin_id, item_key, value, expires_at) VALUES (?, ?, ?, NULL)
+			ON DUPLICATE KEY UPDATE
+				value = CASE WHEN expires_at IS NOT NULL AND expires_at <= ? THEN VALUES(value)
+					WHEN CAST(CAST(value AS SIGNED) AS CHAR) = value THEN CAST(CAST(value AS SIGNED) + ? AS CHAR)
+					ELSE value END,
+				expires_at = CASE WHEN expires_at IS NOT NULL AND expires_at <= ? THEN NULL ELSE expires_at END`
+	case "postgres", "postgresql":
+		query = `INSERT INTO plugin_data (plugin_id, item_key, value, expires_at) VALUES (?, ?, ?, NULL)
+			ON CONFLICT (plugin_id, item_key) DO UPDATE SET
+				value = CASE WHEN plugin_data.expires_at IS NOT NULL AND plugin_data.expires_at <= ? THEN excluded.value
+					ELSE CAST(CAST(plugin_data.value AS BIGINT) + ? AS TEXT) END,
+				expires_at = CASE WHEN plugin_data.expires_at IS NOT NULL AND plugin_data.expires_at <= ? THEN NULL
+					ELSE plugin_data.expires_at END`
+	default:
+		query = `INSERT INTO plugin_data (plugin_id, item_key, value, expires_at) VALUES (?, ?, ?, NULL)
+			ON CONFLICT (plugin_id, item_key) DO UPDATE SET
+				value = CASE WHEN plugin_data.expires_at IS NOT NULL AND plugin_data.expires_at <= ? THEN excluded.value
+					WHEN CAST(CAST(plugin_data.value AS INTEGER) AS TEXT) = plugin_data.value
+						THEN CAST(CAST(plugin_data.value AS INTEGER) + ? AS TEXT)
+					ELSE plugin_data.value END,
+				expires_at = CASE WHEN plugin_data.expires_at IS NOT NULL AND plugin_data.expires_at <= ? THEN NULL
+					ELSE plugin_data.expires_at END`
+	}
+
+	now := time.Now().UTC()
+	var result int64
+	err := s.db.Transaction(ctx, func(tx *database.Tx) error {
+		if _, err := tx.ExecContext(ctx, query,
+			s.pluginID, key, strconv.FormatInt(delta, 10), now, delta, now); err != nil {
+			return err
+		}
+
+		var value string
+		selectQuery := `SELECT value FROM plugin_data WHERE plugin_id = ? AND item_key = ?`
+		if err := tx.QueryRowContext(ctx, selectQuery, s.pluginID, key).Scan(&value); err != nil {
+			return err
+		}
+
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return fmt.Errorf("value for key %s is not an integer", key)
+		}
+		result = n
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to increment key %s: %w", key, err)
+	}
+	return result, nil
+}
+
+// Scan returns all live items whose key starts with prefix.
+// LIKE ignores case under SQLite and MySQL's default collations, so matches are rechecked case-sensitively.
+func (s *DBStore) Scan(ctx context.Context, prefix string) ([]StoreItem, error) {
+	query := `SELECT item_key, value, expires_at FROM plugin_data
+		WHERE plugin_id = ? AND item_key LIKE ? ESCAPE '!' AND (expires_at IS NULL OR expires_at > ?)
+		ORDER BY item_key`
+
+	rows, err := s.db.QueryContext(ctx, query, s.pluginID, escapeLike(prefix)+"%", time.Now().UTC())
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan prefix %s: %w", prefix, err)
+	}
+	defer rows.Close()
+
+	var items []StoreItem
+	for rows.Next() {
+		var item StoreItem
+		var value string
+		var expiresAt sql.NullTime
+		if err := rows.Scan(&item.Key, &value, &expiresAt); err != nil {
+			return nil, fmt.Errorf("failed to scan prefix %s: %w", prefix, err)
+		}
+		if !strings.HasPrefix(item.Key, prefix) {
+			continue
+		}
+		item.Value = json.RawMessage(value)
+		if expiresAt.Valid {
+			t := expiresAt.Time
+			item.ExpiresAt = &t
+		}
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
+
+// DeleteExpired removes this plugin's expired keys and returns how many were removed
+func (s *DBStore) DeleteExpired(ctx context.Context) (int64, error) {
+	query := `DELETE FROM plugin_data WHERE plugin_id = ? AND expires_at IS NOT NULL AND expires_at <= ?`
+	result, err := s.db.ExecContext(ctx, query, s.pluginID, time.Now().UTC())
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete expired keys: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+// escapeLike escapes LIKE wildcards using ! as the escape character
+func escapeLike(s string) string {
+	replacer := strings.NewReplacer("!", "!!", "%", "!%", "_", "!_")
+	return replacer.Replace(s)
+}
@@ -0,0 +1,206 @@
+package plugin
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/btassone/obtura/pkg/database"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestDB(t *testing.T) *database.DB {
+	db, err := database.New(&database.Config{
+		Driver:       "sqlite",
+		SQLitePath:   ":memory:",
+		MaxOpenConns: 1,
+		MaxIdleConns: 1,
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	_, err = db.Exec(`
+		CREATE TABLE plugin_data (
+			plugin_id VARCHAR(255) NOT NULL,
+			item_key VARCHAR(255) NOT NULL,
+			value TEXT NOT NULL,
+			expires_at TIMESTAMP NULL,
+			PRIMARY KEY (plugin_id, item_key)
+		)
+	`)
+	require.NoError(t, err)
+	return db
+}
+
+func TestStores(t *testing.T) {
+	stores := map[string]func(t *testing.T, pluginID string) Store{
+		"memory": func(t *testing.T, pluginID string) Store {
+			return NewMemoryStore()
+		},
+		"database": func(t *testing.T, pluginID string) Store {
+			return NewDBStore(newTestDB(t), pluginID)
+		},
+	}
+
+	for name, newStore := range stores {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+
+			t.Run("get set delete", func(t *testing.T) {
+				s := newStore(t, "test.plugin")
+
+				type point struct{ X, Y int }
+				require.NoError(t, s.Set(ctx, "point", point{1, 2}, 0))
+
+				got, err := GetAs[point](ctx, s, "point")
+				require.NoError(t, err)
+				assert.Equal(t, point{1, 2}, got)
+
+				require.NoError(t, s.Set(ctx, "point", point{3, 4}, 0))
+				got, _ = GetAs[point](ctx, s, "point")
+				assert.Equal(t, point{3, 4}, got)
+
+				require.NoError(t, s.Delete(ctx, "point"))
+				_, err = GetAs[point](ctx, s, "point")
+				assert.ErrorIs(t, err, ErrKeyNotFound)
+
+				assert.Error(t, s.Set(ctx, "", "value", 0))
+			})
+
+			t.Run("ttl", func(t *testing.T) {
+				s := newStore(t, "test.plugin")
+
+				require.NoError(t, s.Set(ctx, "short", "gone", 10*time.Millisecond))
+				require.NoError(t, s.Set(ctx, "long", "kept", time.Hour))
+				time.Sleep(20 * time.Millisecond)
+
+				_, err := GetAs[string](ctx, s, "short")
+				assert.ErrorIs(t, err, ErrKeyNotFound)
+
+				value, err := GetAs[string](ctx, s, "long")
+				require.NoError(t, err)
+				assert.Equal(t, "kept", value)
+
+				// Expired counters restart from zero
+				_, err = s.Increment(ctx, "short", 5)
+				require.NoError(t, err)
+				n, _ := GetAs[int64](ctx, s, "short")
+				assert.Equal(t, int64(5), n)
+			})
+
+			t.Run("increment", func(t *testing.T) {
+				s := newStore(t, "test.plugin")
+
+				n, err := s.Increment(ctx, "counter", 2)
+				require.NoError(t, err)
+				assert.Equal(t, int64(2), n)
+
+				n, err = s.Increment(ctx, "counter", -5)
+				require.NoError(t, err)
+				assert.Equal(t, int64(-3), n)
+
+				// Values that aren't integers are left alone
+				require.NoError(t, s.Set(ctx, "name", "alice", 0))
+				_, err = s.Increment(ctx, "name", 1)
+				assert.ErrorContains(t, err, "not an integer")
+				name, err := GetAs[string](ctx, s, "name")
+				require.NoError(t, err)
+				assert.Equal(t, "alice", name)
+
+				var wg sync.WaitGroup
+				for i := 0; i < 20; i++ {
+					wg.Add(1)
+					go func() {
+						defer wg.Done()
+						_, err := s.Increment(ctx, "concurrent", 1)
+						assert.NoError(t, err)
+					}()
+				}
+				wg.Wait()
+
+				total, _ := GetAs[int64](ctx, s, "concurrent")
+				assert.Equal(t, int64(20), total)
+			})
+
+			t.Run("scan", func(t *testing.T) {
+				s := newStore(t, "test.plugin")
+
+				require.NoError(t, s.Set(ctx, "user:2", "bob", 0))
+				require.NoError(t, s.Set(ctx, "user:1", "alice", 0))
+				require.NoError(t, s.Set(ctx, "user_x", "wildcard", 0))
+				require.NoError(t, s.Set(ctx, "USER:4", "other case", 0))
+				require.NoError(t, s.Set(ctx, "user:3", "expired", time.Nanosecond))
+				require.NoError(t, s.Set(ctx, "session:1", "other", 0))
+				time.Sleep(time.Millisecond)
+
+				items, err := s.Scan(ctx, "user:")
+				require.NoError(t, err)
+				require.Len(t, items, 2)
+				assert.Equal(t, "user:1", items[0].Key)
+
+				var name string
+				require.NoError(t, items[1].Decode(&name))
+				assert.Equal(t, "bob", name)
+			})
+		})
+	}
+}
+
+func TestDBStore_ScopedToPlugin(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+
+	a := NewDBStore(db, "plugin.a")
+	b := NewDBStore(db, "plugin.b")
+
+	require.NoError(t, a.Set(ctx, "key", "from a", 0))
+	require.NoError(t, b.Set(ctx, "key", "from b", 0))
+
+	value, _ := GetAs[string](ctx, a, "key")
+	assert.Equal(t, "from a", value)
+
+	items, err := b.Scan(ctx, "")
+	require.NoError(t, err)
+	require.Len(t, items, 1)
+
+	require.NoError(t, a.Set(ctx, "stale", "x", time.Nanosecond))
+	time.Sleep(time.Millisecond)
+	removed, err := a.(*DBStore).DeleteExpired(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), removed)
+}
+
+func TestRegistry_StoreInPluginContext(t *testing.T) {
+	registry := NewRegistry(chi.NewRouter(), WithConfigStorage(NewMemoryConfigStorage()))
+	p := &storePlugin{TestPlugin: TestPlugin{id: "test.plugin.1"}}
+	require.NoError(t, registry.Register(p))
+	require.NoError(t, registry.Initialize(context.Background()))
+
+	assert.Equal(t, "test.plugin.1", p.pluginID)
+	require.NotNil(t, p.store)
+
+	// The store handed to Init is the one the registry exposes for the plugin
+	require.NoError(t, p.store.Set(context.Background(), "key", "value", 0))
+	value, err := GetAs[string](context.Background(), registry.Store("test.plugin.1"), "key")
+	require.NoError(t, err)
+	assert.Equal(t, "value", value)
+
+	_, err = GetAs[string](context.Background(), registry.Store("test.plugin.2"), "key")
+	assert.ErrorIs(t, err, ErrKeyNotFound)
+}
+
+// storePlugin records the store and plugin ID it receives during Init
+type storePlugin struct {
+	TestPlugin
+	store    Store
+	pluginID string
+}
+
+func (p *storePlugin) Init(ctx context.Context) error {
+	p.store = StoreFromContext(ctx)
+	p.pluginID, _ = PluginIDFromContext(ctx)
+	return nil
+}
@@ -0,0 +1,59 @@
+package plugin
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/btassone/obtura/pkg/tracing"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegistry_HookSpans(t *testing.T) {
+	buffer := tracing.NewRingBuffer(10)
+	tracer := tracing.NewTracer(buffer)
+	registry := NewRegistry(chi.NewRouter(), WithConfigStorage(NewMemoryConfigStorage()))
+
+	var hookPluginID string
+	require.NoError(t, registry.Register(&TestHookablePlugin{
+		TestPlugin: TestPlugin{id: "test.plugin.1"},
+		hooks: map[string]HookHandler{
+			"content.save": func(ctx context.Context, data interface{}) (interface{}, error) {
+				hookPluginID, _ = PluginIDFromContext(ctx)
+				return data, nil
+			},
+		},
+	}))
+	require.NoError(t, registry.Register(&TestHookablePlugin{
+		TestPlugin: TestPlugin{id: "test.plugin.2"},
+		hooks: map[string]HookHandler{
+			"content.save": func(ctx context.Context, data interface{}) (interface{}, error) {
+				return nil, errors.New("rejected")
+			},
+		},
+	}))
+
+	require.NoError(t, registry.Initialize(context.Background()))
+	require.NoError(t, registry.Start(context.Background()))
+	defer registry.Stop(context.Background())
+
+	ctx, root := tracer.Start(context.Background(), "request")
+	_, err := registry.ExecuteHook(ctx, "content.save", "hello")
+	root.End()
+	assert.Error(t, err)
+	assert.Equal(t, "test.plugin.1", hookPluginID)
+
+	spans := buffer.Spans()
+	require.Len(t, spans, 3)
+	for i, pluginID := range []string{"test.plugin.1", "test.plugin.2"} {
+		span := spans[i]
+		assert.Equal(t, "hook content.save", span.Name)
+		assert.Equal(t, root.SpanContext().SpanID, span.ParentID)
+		id, _ := span.Attribute("plugin.id")
+		assert.Equal(t, pluginID, id)
+	}
+	assert.Empty(t, spans[0].Error)
+	assert.Equal(t, "rejected", spans[1].Error)
+}
@@ -0,0 +1,302 @@
+package tracing
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// RingBuffer keeps the most recent finished spans in memory
+type RingBuffer struct {
+	mu    sync.RWMutex
+	spans []SpanData
+	next  int
+	full  bool
+}
+
+// NewRingBuffer creates a ring buffer holding up to capacity spans
+func NewRingBuffer(capacity int) *RingBuffer {
+	if capacity <= 0 {
+		capacity = 1000
+	}
+	return &RingBuffer{spans: make([]SpanData, capacity)}
+}
+
+// Export stores a span, overwriting the oldest once full
+func (b *RingBuffer) Export(span SpanData) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.spans[b.next] = span
+	b.next = (b.next + 1) % len(b.spans)
+	if b.next == 0 {
+		b.full = true
+	}
+	return nil
+}
+
+// Spans returns the buffered spans, oldest first
+func (b *RingBuffer) Spans() []SpanData {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	if !b.full {
+		return append([]SpanData(nil), b.spans[:b.next]...)
+	}
+	spans := make([]SpanData, 0, len(b.spans))
+	spans = append(spans, b.spans[b.next:]...)
+	return append(spans, b.spans[:b.next]...)
+}
+
+// Trace groups the buffered spans of one trace
+type Trace struct {
+	ID    TraceID
+	Root  SpanData
+	Spans []SpanData // ordered by start time
+	Start time.Time
+	End   time.Time
+	Error bool
+}
+
+// Duration returns the time between the first span starting and the last ending
+func (t Trace) Duration() time.Duration {
+	return t.End.Sub(t.Start)
+}
+
+// Depth returns how deeply a span is nested within the trace
+func (t Trace) Depth(span SpanData) int {
+	parents := make(map[SpanID]SpanID, len(t.Spans))
+	for _, s := range t.Spans {
+		parents[s.SpanID] = s.ParentID
+	}
+
+	depth := 0
+	for id := span.ParentID; id.IsValid(); id = parents[id] {
+		if _, ok := parents[id]; !ok {
+			break
+		}
+		depth++
+	}
+	return depth
+}
+
+// Traces returns buffered spans grouped by trace, most recent first
+func (b *RingBuffer) Traces() []Trace {
+	byID := make(map[TraceID]*Trace)
+	for _, span := range b.Spans() {
+		t, ok := byID[span.TraceID]
+		if !ok {
+			t = &Trace{ID: span.TraceID, Start: span.Start, End: span.End}
+			byID[span.TraceID] = t
+		}
+		t.Spans = append(t.Spans, span)
+		if span.Start.Before(t.Start) {
+			t.Start = span.Start
+		}
+		if span.End.After(t.End) {
+			t.End = span.End
+		}
+		if span.Error != "" {
+			t.Error = true
+		}
+	}
+
+	traces := make([]Trace, 0, len(byID))
+	for _, t := range byID {
+		sort.Slice(t.Spans, func(i, j int) bool {
+			return t.Spans[i].Start.Before(t.Spans[j].Start)
+		})
+		// The root is the earliest span whose parent is not in the buffer
+		t.Root = t.Spans[0]
+		present := make(map[SpanID]bool, len(t.Spans))
+		for _, s := range t.Spans {
+			present[s.SpanID] = true
+		}
+		for _, s := range t.Spans {
+			if !present[s.ParentID] {
+				t.Root = s
+				break
+			}
+		}
+		traces = append(traces, *t)
+	}
+
+	sort.Slice(traces, func(i, j int) bool {
+		return traces[i].Start.After(traces[j].Start)
+	})
+	return traces
+}
+
+// Trace returns a single buffered trace
+func (b *RingBuffer) Trace(id TraceID) (Trace, bool) {
+	for _, t := range b.Traces() {
+		if t.ID == id {
+			return t, true
+		}
+	}
+	return Trace{}, false
+}
+
+// OTLPFileExporter appends spans to a file as OTLP/JSON, one ExportTraceServiceRequest per line
+type OTLPFileExporter struct {
+	mu          sync.Mutex
+	file        *os.File
+	serviceName string
+}
+
+// NewOTLPFileExporter creates an exporter appending to path
+func NewOTLPFileExporter(path, serviceName string) (*OTLPFileExporter, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create trace directory: %w", err)
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open trace file: %w", err)
+	}
+	return &OTLPFileExporter{file: file, serviceName: serviceName}, nil
+}
+
+// Export writes a span as an OTLP/JSON line
+func (e *OTLPFileExporter) Export(span SpanData) error {
+	data, err := json.Marshal(otlpRequest(e.serviceName, []SpanData{span}))
+	if err != nil {
+		return err
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	_, err = e.file.Write(append(data, '\n'))
+	return err
+}
+
+// Close closes the underlying file
+func (e *OTLPFileExporter) Close() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.file.Close()
+}
+
+// OTLP/JSON encoding, see https://opentelemetry.io/docs/specs/otlp/#json-protobuf-encoding
+
+type otlpExportRequest struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+type otlpResourceSpans struct {
+	Resource   otlpResource     `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpResource struct {
+	Attributes []otlpKeyValue `json:"attributes"`
+}
+
+type otlpScopeSpans struct {
+	Scope otlpScope  `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpScope struct {
+	Name string `json:"name"`
+}
+
+type otlpSpan struct {
+	TraceID           string         `json:"traceId"`
+	SpanID            string         `json:"spanId"`
+	ParentSpanID      string         `json:"parentSpanId,omitempty"`
+	Name              string         `json:"name"`
+	Kind              int            `json:"kind"`
+	StartTimeUnixNano string         `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string         `json:"endTimeUnixNano"`
+	Attributes        []otlpKeyValue `json:"attributes,omitempty"`
+	Status            *otlpStatus    `json:"status,omitempty"`
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+
+type otlpKeyValue struct {
+	Key   string                 `json:"key"`
+	Value map[string]interface{} `json:"value"`
+}
+
+// OTLP span kinds and status codes
+const (
+	otlpKindInternal = 1
+	otlpKindServer   = 2
+	otlpKindClient   = 3
+	otlpStatusError  = 2
+)
+
+func otlpRequest(serviceName string, spans []SpanData) otlpExportRequest {
+	encoded := make([]otlpSpan, 0, len(spans))
+	for _, s := range spans {
+		span := otlpSpan{
+			TraceID:           s.TraceID.String(),
+			SpanID:            s.SpanID.String(),
+			Name:              s.Name,
+			Kind:              otlpKind(s.Kind),
+			StartTimeUnixNano: strconv.FormatInt(s.Start.UnixNano(), 10),
+			EndTimeUnixNano:   strconv.FormatInt(s.End.UnixNano(), 10),
+		}
+		if s.ParentID.IsValid() {
+			span.ParentSpanID = s.ParentID.String()
+		}
+		for _, attr := range s.Attributes {
+			span.Attributes = append(span.Attributes, otlpAttribute(attr))
+		}
+		if s.Error != "" {
+			span.Status = &otlpStatus{Code: otlpStatusError, Message: s.Error}
+		}
+		encoded = append(encoded, span)
+	}
+
+	return otlpExportRequest{
+		ResourceSpans: []otlpResourceSpans{{
+			Resource: otlpResource{Attributes: []otlpKeyValue{
+				otlpAttribute(Attr("service.name", serviceName)),
+			}},
+			ScopeSpans: []otlpScopeSpans{{
+				Scope: otlpScope{Name: "github.com/btassone/obtura/pkg/tracing"},
+				Spans: encoded,
+			}},
+		}},
+	}
+}
+
+func otlpKind(kind string) int {
+	switch kind {
+	case KindServer:
+		return otlpKindServer
+	case KindClient:
+		return otlpKindClient
+	default:
+		return otlpKindInternal
+	}
+}
+
+func otlpAttribute(attr Attribute) otlpKeyValue {
+	var value map[string]interface{}
+	switch v := attr.Value.(type) {
+	case string:
+		value = map[string]interface{}{"stringValue": v}
+	case bool:
+		value = map[string]interface{}{"boolValue": v}
+	case int:
+		value = map[string]interface{}{"intValue": strconv.Itoa(v)}
+	case int64:
+		value = map[string]interface{}{"intValue": strconv.FormatInt(v, 10)}
+	case float64:
+		value = map[string]interface{}{"doubleValue": v}
+	default:
+		value = map[string]interface{}{"stringValue": fmt.Sprint(v)}
+	}
+	return otlpKeyValue{Key: attr.Key, Value: value}
+}
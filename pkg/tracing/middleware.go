@@ -0,0 +1,60 @@
+package tracing
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// Middleware starts a server span for each request, continuing any incoming traceparent.
+// The span is named after the chi route pattern once routing has completed.
+func Middleware(t *Tracer) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := r.Context()
+			if header := r.Header.Get(TraceparentHeader); header != "" {
+				if sc, err := ParseTraceparent(header); err == nil {
+					ctx = WithRemoteSpanContext(ctx, sc)
+				}
+			}
+
+			ctx, span := t.StartKind(ctx, r.Method+" "+r.URL.Path, KindServer,
+				Attr("http.method", r.Method),
+				Attr("http.target", r.URL.Path),
+			)
+			defer span.End()
+
+			if id := middleware.GetReqID(ctx); id != "" {
+				span.SetAttributes(Attr("request_id", id))
+			}
+			w.Header().Set(TraceparentHeader, span.SpanContext().Traceparent())
+
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+			next.ServeHTTP(ww, r.WithContext(ctx))
+
+			status := ww.Status()
+			if status == 0 {
+				status = http.StatusOK
+			}
+			span.SetAttributes(Attr("http.status_code", status))
+			if status >= 500 {
+				span.RecordError(errorStatus(status))
+			}
+
+			if rctx := chi.RouteContext(ctx); rctx != nil {
+				if pattern := rctx.RoutePattern(); pattern != "" {
+					span.SetName(r.Method + " " + pattern)
+					span.SetAttributes(Attr("http.route", pattern))
+				}
+			}
+		})
+	}
+}
+
+// errorStatus reports a server error response as a span error
+type errorStatus int
+
+func (e errorStatus) Error() string {
+	return http.StatusText(int(e))
+}
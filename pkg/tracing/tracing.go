@@ -0,0 +1,325 @@
+// Package tracing provides lightweight, W3C traceparent compatible request tracing
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Span kinds
+const (
+	KindInternal = "internal"
+	KindServer   = "server"
+	KindClient   = "client"
+)
+
+// TraceID identifies a trace
+type TraceID [16]byte
+
+// String returns the lower-case hex encoding of the ID
+func (id TraceID) String() string { return hex.EncodeToString(id[:]) }
+
+// IsValid reports whether the ID is non-zero
+func (id TraceID) IsValid() bool { return id != TraceID{} }
+
+// SpanID identifies a span within a trace
+type SpanID [8]byte
+
+// String returns the lower-case hex encoding of the ID
+func (id SpanID) String() string { return hex.EncodeToString(id[:]) }
+
+// IsValid reports whether the ID is non-zero
+func (id SpanID) IsValid() bool { return id != SpanID{} }
+
+// SpanContext identifies a span and is propagated across process boundaries
+type SpanContext struct {
+	TraceID TraceID
+	SpanID  SpanID
+	Sampled bool
+}
+
+// IsValid reports whether both IDs are set
+func (sc SpanContext) IsValid() bool {
+	return sc.TraceID.IsValid() && sc.SpanID.IsValid()
+}
+
+// Attribute is a key-value pair recorded on a span
+type Attribute struct {
+	Key   string
+	Value interface{}
+}
+
+// Attr creates an attribute
+func Attr(key string, value interface{}) Attribute {
+	return Attribute{Key: key, Value: value}
+}
+
+// SpanData is an immutable snapshot of a finished span handed to exporters
+type SpanData struct {
+	Name       string
+	Kind       string
+	TraceID    TraceID
+	SpanID     SpanID
+	ParentID   SpanID
+	Start      time.Time
+	End        time.Time
+	Attributes []Attribute
+	Error      string
+}
+
+// Duration returns how long the span took
+func (d SpanData) Duration() time.Duration {
+	return d.End.Sub(d.Start)
+}
+
+// Attribute returns the value of an attribute, if set
+func (d SpanData) Attribute(key string) (interface{}, bool) {
+	for i := len(d.Attributes) - 1; i >= 0; i-- {
+		if d.Attributes[i].Key == key {
+			return d.Attributes[i].Value, true
+		}
+	}
+	return nil, false
+}
+
+// Span is an in-progress unit of work. A nil Span is valid and records nothing.
+type Span struct {
+	tracer *Tracer
+	sc     SpanContext
+
+	mu    sync.Mutex
+	data  SpanData
+	ended bool
+}
+
+// SpanContext returns the span's identifiers
+func (s *Span) SpanContext() SpanContext {
+	if s == nil {
+		return SpanContext{}
+	}
+	return s.sc
+}
+
+// SetName renames the span
+func (s *Span) SetName(name string) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data.Name = name
+}
+
+// SetAttributes records attributes on the span
+func (s *Span) SetAttributes(attrs ...Attribute) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data.Attributes = append(s.data.Attributes, attrs...)
+}
+
+// RecordError marks the span as failed
+func (s *Span) RecordError(err error) {
+	if s == nil || err == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data.Error = err.Error()
+}
+
+// End finishes the span and hands it to the tracer's exporters. Only the first call has an effect.
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	s.data.End = time.Now()
+	data := s.data
+	data.Attributes = append([]Attribute(nil), s.data.Attributes...)
+	s.mu.Unlock()
+
+	if s.sc.Sampled {
+		s.tracer.export(data)
+	}
+}
+
+// Exporter receives finished spans
+type Exporter interface {
+	Export(span SpanData) error
+}
+
+// Tracer creates spans and sends finished ones to its exporters
+type Tracer struct {
+	mu        sync.RWMutex
+	exporters []Exporter
+	onError   func(error)
+}
+
+// NewTracer creates a tracer exporting to the given exporters
+func NewTracer(exporters ...Exporter) *Tracer {
+	return &Tracer{exporters: exporters}
+}
+
+// AddExporter adds an exporter
+func (t *Tracer) AddExporter(e Exporter) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.exporters = append(t.exporters, e)
+}
+
+// OnError sets a callback for exporter failures
+func (t *Tracer) OnError(fn func(error)) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.onError = fn
+}
+
+func (t *Tracer) export(data SpanData) {
+	t.mu.RLock()
+	exporters := t.exporters
+	onError := t.onError
+	t.mu.RUnlock()
+
+	for _, e := range exporters {
+		if err := e.Export(data); err != nil && onError != nil {
+			onError(fmt.Errorf("failed to export span %s: %w", data.Name, err))
+		}
+	}
+}
+
+// Start begins a span as a child of the span (or remote span context) carried by ctx
+func (t *Tracer) Start(ctx context.Context, name string, attrs ...Attribute) (context.Context, *Span) {
+	return t.start(ctx, name, KindInternal, attrs)
+}
+
+// StartKind begins a span of a specific kind
+func (t *Tracer) StartKind(ctx context.Context, name, kind string, attrs ...Attribute) (context.Context, *Span) {
+	return t.start(ctx, name, kind, attrs)
+}
+
+func (t *Tracer) start(ctx context.Context, name, kind string, attrs []Attribute) (context.Context, *Span) {
+	parent := SpanContextFromContext(ctx)
+
+	sc := SpanContext{SpanID: newSpanID(), Sampled: true}
+	if parent.IsValid() {
+		sc.TraceID = parent.TraceID
+		sc.Sampled = parent.Sampled
+	} else {
+		sc.TraceID = newTraceID()
+	}
+
+	span := &Span{
+		tracer: t,
+		sc:     sc,
+		data: SpanData{
+			Name:     name,
+			Kind:     kind,
+			TraceID:  sc.TraceID,
+			SpanID:   sc.SpanID,
+			ParentID: parent.SpanID,
+			Start:    time.Now(),
+		},
+	}
+	if id, ok := PluginIDFromContext(ctx); ok {
+		span.data.Attributes = append(span.data.Attributes, Attr("plugin.id", id))
+	}
+	span.data.Attributes = append(span.data.Attributes, attrs...)
+
+	return context.WithValue(ctx, spanContextKey, span), span
+}
+
+func newTraceID() TraceID {
+	var id TraceID
+	rand.Read(id[:])
+	return id
+}
+
+func newSpanID() SpanID {
+	var id SpanID
+	rand.Read(id[:])
+	return id
+}
+
+type contextKey string
+
+const (
+	spanContextKey     contextKey = "tracing.span"
+	remoteContextKey   contextKey = "tracing.remote"
+	pluginIDContextKey contextKey = "tracing.plugin_id"
+)
+
+// SpanFromContext returns the active span, or nil if there is none
+func SpanFromContext(ctx context.Context) *Span {
+	span, _ := ctx.Value(spanContextKey).(*Span)
+	return span
+}
+
+// SpanContextFromContext returns the active span's context, falling back to a remote parent
+func SpanContextFromContext(ctx context.Context) SpanContext {
+	if span := SpanFromContext(ctx); span != nil {
+		return span.sc
+	}
+	sc, _ := ctx.Value(remoteContextKey).(SpanContext)
+	return sc
+}
+
+// WithRemoteSpanContext returns a context whose next span continues a remote trace
+func WithRemoteSpanContext(ctx context.Context, sc SpanContext) context.Context {
+	return context.WithValue(ctx, remoteContextKey, sc)
+}
+
+// WithPluginID returns a context whose spans are attributed to a plugin
+func WithPluginID(ctx context.Context, pluginID string) context.Context {
+	return context.WithValue(ctx, pluginIDContextKey, pluginID)
+}
+
+// PluginIDFromContext returns the plugin ID spans started from ctx are attributed to
+func PluginIDFromContext(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(pluginIDContextKey).(string)
+	return id, ok
+}
+
+var (
+	defaultMu     sync.RWMutex
+	defaultTracer = NewTracer()
+)
+
+// Default returns the default tracer
+func Default() *Tracer {
+	defaultMu.RLock()
+	defer defaultMu.RUnlock()
+	return defaultTracer
+}
+
+// SetDefault replaces the default tracer
+func SetDefault(t *Tracer) {
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+	defaultTracer = t
+}
+
+// Start begins a span using the tracer of the active span, or the default tracer
+func Start(ctx context.Context, name string, attrs ...Attribute) (context.Context, *Span) {
+	return StartKind(ctx, name, KindInternal, attrs...)
+}
+
+// StartKind begins a span of a specific kind using the tracer of the active span, or the default tracer
+func StartKind(ctx context.Context, name, kind string, attrs ...Attribute) (context.Context, *Span) {
+	tracer := Default()
+	if span := SpanFromContext(ctx); span != nil {
+		tracer = span.tracer
+	}
+	return tracer.start(ctx, name, kind, attrs)
+}
@@ -0,0 +1,240 @@
+package tracing
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseTraceparent(t *testing.T) {
+	sc, err := ParseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	require.NoError(t, err)
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", sc.TraceID.String())
+	assert.Equal(t, "00f067aa0ba902b7", sc.SpanID.String())
+	assert.True(t, sc.Sampled)
+	assert.Equal(t, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", sc.Traceparent())
+
+	sc, err = ParseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00")
+	require.NoError(t, err)
+	assert.False(t, sc.Sampled)
+
+	invalid := []string{
+		"",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7",
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",
+		"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra",
+		"00-zzf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+	}
+	for _, value := range invalid {
+		_, err := ParseTraceparent(value)
+		assert.Error(t, err, value)
+	}
+
+	// Future versions may carry extra fields
+	_, err = ParseTraceparent("01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra")
+	assert.NoError(t, err)
+}
+
+func TestTracer_ParentChild(t *testing.T) {
+	buffer := NewRingBuffer(10)
+	tracer := NewTracer(buffer)
+
+	ctx, root := tracer.Start(context.Background(), "root")
+	_, child := Start(ctx, "child", Attr("key", "value"))
+	child.RecordError(errors.New("boom"))
+	child.End()
+	root.End()
+	root.End()
+
+	spans := buffer.Spans()
+	require.Len(t, spans, 2)
+	assert.Equal(t, "child", spans[0].Name)
+	assert.Equal(t, "root", spans[1].Name)
+	assert.Equal(t, spans[1].TraceID, spans[0].TraceID)
+	assert.Equal(t, spans[1].SpanID, spans[0].ParentID)
+	assert.False(t, spans[1].ParentID.IsValid())
+	assert.Equal(t, "boom", spans[0].Error)
+
+	value, ok := spans[0].Attribute("key")
+	assert.True(t, ok)
+	assert.Equal(t, "value", value)
+}
+
+func TestTracer_RemoteParentAndSampling(t *testing.T) {
+	buffer := NewRingBuffer(10)
+	tracer := NewTracer(buffer)
+
+	remote, err := ParseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	require.NoError(t, err)
+	_, span := tracer.Start(WithRemoteSpanContext(context.Background(), remote), "continued")
+	span.End()
+
+	spans := buffer.Spans()
+	require.Len(t, spans, 1)
+	assert.Equal(t, remote.TraceID, spans[0].TraceID)
+	assert.Equal(t, remote.SpanID, spans[0].ParentID)
+
+	// Unsampled traces propagate but are not exported
+	remote.Sampled = false
+	_, span = tracer.Start(WithRemoteSpanContext(context.Background(), remote), "dropped")
+	span.End()
+	assert.Len(t, buffer.Spans(), 1)
+	assert.Equal(t, remote.TraceID, span.SpanContext().TraceID)
+}
+
+func TestTracer_PluginID(t *testing.T) {
+	buffer := NewRingBuffer(10)
+	tracer := NewTracer(buffer)
+
+	ctx, parent := tracer.Start(WithPluginID(context.Background(), "com.example.test"), "hook")
+	_, query := StartKind(ctx, "db SELECT", KindClient)
+	query.End()
+	parent.End()
+
+	for _, span := range buffer.Spans() {
+		id, ok := span.Attribute("plugin.id")
+		assert.True(t, ok, span.Name)
+		assert.Equal(t, "com.example.test", id)
+	}
+	assert.Equal(t, KindClient, buffer.Spans()[0].Kind)
+}
+
+func TestRingBuffer_Traces(t *testing.T) {
+	buffer := NewRingBuffer(3)
+	tracer := NewTracer(buffer)
+
+	for _, name := range []string{"a", "b", "c", "d"} {
+		_, span := tracer.Start(context.Background(), name)
+		span.End()
+	}
+
+	// The oldest span is overwritten once the buffer is full
+	spans := buffer.Spans()
+	require.Len(t, spans, 3)
+	assert.Equal(t, "b", spans[0].Name)
+	assert.Equal(t, "d", spans[2].Name)
+
+	traces := buffer.Traces()
+	require.Len(t, traces, 3)
+	assert.Equal(t, "d", traces[0].Root.Name)
+
+	trace, ok := buffer.Trace(spans[1].TraceID)
+	require.True(t, ok)
+	assert.Equal(t, "c", trace.Root.Name)
+
+	_, ok = buffer.Trace(TraceID{1})
+	assert.False(t, ok)
+}
+
+func TestTrace_Depth(t *testing.T) {
+	buffer := NewRingBuffer(10)
+	tracer := NewTracer(buffer)
+
+	ctx, root := tracer.Start(context.Background(), "root")
+	ctx, child := Start(ctx, "child")
+	_, grandchild := Start(ctx, "grandchild")
+	grandchild.End()
+	child.End()
+	root.End()
+
+	traces := buffer.Traces()
+	require.Len(t, traces, 1)
+	trace := traces[0]
+	assert.Equal(t, "root", trace.Root.Name)
+	require.Len(t, trace.Spans, 3)
+	assert.Equal(t, 0, trace.Depth(trace.Spans[0]))
+	assert.Equal(t, 1, trace.Depth(trace.Spans[1]))
+	assert.Equal(t, 2, trace.Depth(trace.Spans[2]))
+}
+
+func TestOTLPFileExporter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "traces", "spans.jsonl")
+	exporter, err := NewOTLPFileExporter(path, "obtura-test")
+	require.NoError(t, err)
+	tracer := NewTracer(exporter)
+
+	ctx, root := tracer.StartKind(context.Background(), "GET /", KindServer, Attr("http.status_code", 500))
+	_, child := Start(ctx, "child", Attr("ok", true))
+	child.End()
+	root.RecordError(errors.New("Internal Server Error"))
+	root.End()
+	require.NoError(t, exporter.Close())
+
+	file, err := os.Open(path)
+	require.NoError(t, err)
+	defer file.Close()
+
+	var lines []map[string]interface{}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var line map[string]interface{}
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &line))
+		lines = append(lines, line)
+	}
+	require.Len(t, lines, 2)
+
+	resourceSpans := lines[1]["resourceSpans"].([]interface{})[0].(map[string]interface{})
+	resource := resourceSpans["resource"].(map[string]interface{})
+	serviceName := resource["attributes"].([]interface{})[0].(map[string]interface{})
+	assert.Equal(t, "service.name", serviceName["key"])
+	assert.Equal(t, "obtura-test", serviceName["value"].(map[string]interface{})["stringValue"])
+
+	span := resourceSpans["scopeSpans"].([]interface{})[0].(map[string]interface{})["spans"].([]interface{})[0].(map[string]interface{})
+	assert.Equal(t, "GET /", span["name"])
+	assert.Equal(t, float64(otlpKindServer), span["kind"])
+	assert.Len(t, span["traceId"], 32)
+	assert.NotContains(t, span, "parentSpanId")
+	assert.Equal(t, float64(otlpStatusError), span["status"].(map[string]interface{})["code"])
+	attr := span["attributes"].([]interface{})[0].(map[string]interface{})
+	assert.Equal(t, "500", attr["value"].(map[string]interface{})["intValue"])
+}
+
+func TestMiddleware(t *testing.T) {
+	buffer := NewRingBuffer(10)
+	tracer := NewTracer(buffer)
+
+	var handlerSpan SpanContext
+	router := chi.NewRouter()
+	router.Use(Middleware(tracer))
+	router.Get("/items/{id}", func(w http.ResponseWriter, r *http.Request) {
+		handlerSpan = SpanContextFromContext(r.Context())
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/items/42", nil)
+	req.Header.Set(TraceparentHeader, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	spans := buffer.Spans()
+	require.Len(t, spans, 1)
+	span := spans[0]
+	assert.Equal(t, "GET /items/{id}", span.Name)
+	assert.Equal(t, KindServer, span.Kind)
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", span.TraceID.String())
+	assert.Equal(t, "00f067aa0ba902b7", span.ParentID.String())
+	assert.Equal(t, span.SpanID, handlerSpan.SpanID)
+	assert.NotEmpty(t, span.Error)
+
+	status, _ := span.Attribute("http.status_code")
+	assert.Equal(t, http.StatusInternalServerError, status)
+	route, _ := span.Attribute("http.route")
+	assert.Equal(t, "/items/{id}", route)
+
+	sc, err := ParseTraceparent(rec.Header().Get(TraceparentHeader))
+	require.NoError(t, err)
+	assert.Equal(t, span.SpanID, sc.SpanID)
+}
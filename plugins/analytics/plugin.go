@@ -3,9 +3,12 @@ package analytics
 import (
 	"context"
 	"fmt"
+	"log/slog"
 	"net/http"
-	"sync"
+	"strings"
 
+	"github.com/btassone/obtura/pkg/logging"
+	"github.com/btassone/obtura/pkg/metrics"
 	"github.com/btassone/obtura/pkg/plugin"
 )
 
@@ -16,11 +19,15 @@ type Config struct {
 	ExcludeAdmin  bool   `json:"exclude_admin" label:"Exclude Admin" description:"Don't track admin page views" default:"true"`
 }
 
+// pageViewPrefix prefixes store keys holding per-path view counts
+const pageViewPrefix = "views:"
+
 // Plugin tracks simple page analytics
 type Plugin struct {
-	config    *Config
-	pageViews map[string]int
-	mu        sync.RWMutex
+	config *Config
+	store  plugin.Store
+	logger *slog.Logger
+	views  *metrics.Counter
 }
 
 // NewPlugin creates a new analytics plugin
@@ -30,7 +37,8 @@ func NewPlugin() *Plugin {
 			Enabled:      true,
 			ExcludeAdmin: true,
 		},
-		pageViews: make(map[string]int),
+		store:  plugin.NewMemoryStore(),
+		logger: slog.Default(),
 	}
 }
 
@@ -43,7 +51,17 @@ func (p *Plugin) Description() string { return "Basic page view analytics" }
 func (p *Plugin) Author() string      { return "Example Author" }
 func (p *Plugin) Dependencies() []string { return []string{} }
 
-func (p *Plugin) Init(ctx context.Context) error    { return nil }
+func (p *Plugin) Init(ctx context.Context) error {
+	p.logger = logging.FromContext(ctx)
+	p.views = metrics.FromContext(ctx).Counter("analytics_page_views_total", "Page views tracked by the analytics plugin.")
+	
+	// Persist page views in the plugin's data store when the registry provides one
+	if store := plugin.StoreFromContext(ctx); store != nil {
+		p.store = store
+	}
+	return nil
+}
+
 func (p *Plugin) Start(ctx context.Context) error   { return nil }
 func (p *Plugin) Stop(ctx context.Context) error    { return nil }
 func (p *Plugin) Destroy(ctx context.Context) error { return nil }
@@ -68,7 +86,7 @@ func (p *Plugin) Middleware() func(http.Handler) http.Handler {
 				}
 				
 				// Track the page view
-				p.trackPageView(path)
+				p.trackPageView(r.Context(), path)
 			}
 			
 			next.ServeHTTP(w, r)
@@ -106,21 +124,30 @@ func (p *Plugin) AdminNavigation() []plugin.NavItem {
 
 // Helper methods
 
-func (p *Plugin) trackPageView(path string) {
-	p.mu.Lock()
-	defer p.mu.Unlock()
-	p.pageViews[path]++
+func (p *Plugin) trackPageView(ctx context.Context, path string) {
+	if p.views != nil {
+		p.views.Inc()
+	}
+	if _, err := p.store.Increment(ctx, pageViewPrefix+path, 1); err != nil {
+		p.logger.WarnContext(ctx, "Failed to track page view", "path", path, "error", err)
+	}
 }
 
-func (p *Plugin) getStats() map[string]int {
-	p.mu.RLock()
-	defer p.mu.RUnlock()
+func (p *Plugin) getStats(ctx context.Context) (map[string]int64, error) {
+	items, err := p.store.Scan(ctx, pageViewPrefix)
+	if err != nil {
+		return nil, err
+	}
 	
-	stats := make(map[string]int)
-	for path, views := range p.pageViews {
-		stats[path] = views
+	stats := make(map[string]int64)
+	for _, item := range items {
+		var views int64
+		if err := item.Decode(&views); err != nil {
+			return nil, err
+		}
+		stats[strings.TrimPrefix(item.Key, pageViewPrefix)] = views
 	}
-	return stats
+	return stats, nil
 }
 
 // HTTP handlers
@@ -155,7 +182,11 @@ func (p *Plugin) handleAnalytics(w http.ResponseWriter, r *http.Request) {
 }
 
 func (p *Plugin) handleAPIStats(w http.ResponseWriter, r *http.Request) {
-	stats := p.getStats()
+	stats, err := p.getStats(r.Context())
+	if err != nil {
+		http.Error(w, "Failed to load stats", http.StatusInternalServerError)
+		return
+	}
 	
 	if len(stats) == 0 {
 		w.Write([]byte(`<p>No page views tracked yet.</p>`))
@@ -178,7 +209,7 @@ func (p *Plugin) EventHandlers() map[string]plugin.EventHandler {
 			// Handle page view events from other plugins
 			if data, ok := event.Data.(map[string]interface{}); ok {
 				if path, ok := data["path"].(string); ok {
-					p.trackPageView(path)
+					p.trackPageView(ctx, path)
 				}
 			}
 			return nil
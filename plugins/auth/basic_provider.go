@@ -5,6 +5,8 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/a-h/templ"
@@ -34,8 +36,12 @@ var (
 type BasicAuthProvider struct {
 	db       *database.DB
 	userRepo *models.UserRepository
-	store    *sessions.CookieStore
-	config   *plugin.AuthConfig
+
+	// The cookie store is replaced when the session settings change, see Configure
+	store  atomic.Pointer[sessions.CookieStore]
+	mu     sync.Mutex
+	secret string
+	maxAge int
 }
 
 // BasicAuthUser wraps a model user to implement AuthUser
@@ -75,7 +81,7 @@ func (b *BasicAuthProvider) Authenticate(ctx context.Context, credentials map[st
 	}
 
 	// Find user by email
-	user, err := b.userRepo.FindByEmail(email)
+	user, err := b.userRepo.FindByEmailContext(ctx, email)
 	if err != nil {
 		return nil, ErrInvalidCredentials
 	}
@@ -100,14 +106,14 @@ func (b *BasicAuthProvider) GetUser(r *http.Request) (plugin.AuthUser, bool) {
 	}
 
 	// Get from session
-	session, _ := b.store.Get(r, SessionName)
+	session, _ := b.store.Load().Get(r, SessionName)
 	userID, ok := session.Values[UserIDKey].(int64)
 	if !ok || userID == 0 {
 		return nil, false
 	}
 
 	// Load user from database
-	user, err := b.userRepo.FindByID(userID)
+	user, err := b.userRepo.FindByIDContext(r.Context(), userID)
 	if err != nil {
 		return nil, false
 	}
@@ -117,7 +123,7 @@ func (b *BasicAuthProvider) GetUser(r *http.Request) (plugin.AuthUser, bool) {
 
 func (b *BasicAuthProvider) Login(w http.ResponseWriter, r *http.Request, user plugin.AuthUser) error {
 	// Create session
-	session, _ := b.store.Get(r, SessionName)
+	session, _ := b.store.Load().Get(r, SessionName)
 
 	// Convert user ID to int64
 	var userID int64
@@ -128,7 +134,7 @@ func (b *BasicAuthProvider) Login(w http.ResponseWriter, r *http.Request, user p
 }
 
 func (b *BasicAuthProvider) Logout(w http.ResponseWriter, r *http.Request) error {
-	session, _ := b.store.Get(r, SessionName)
+	session, _ := b.store.Load().Get(r, SessionName)
 
 	// Delete session values
 	session.Values[UserIDKey] = nil
@@ -138,7 +144,7 @@ func (b *BasicAuthProvider) Logout(w http.ResponseWriter, r *http.Request) error
 }
 
 func (b *BasicAuthProvider) IsAuthenticated(r *http.Request) bool {
-	session, _ := b.store.Get(r, SessionName)
+	session, _ := b.store.Load().Get(r, SessionName)
 	userID, ok := session.Values[UserIDKey].(int64)
 	return ok && userID > 0
 }
@@ -274,6 +280,24 @@ func (b *BasicAuthProvider) CreateInitialAdmin() error {
 
 // NewBasicAuthProvider Initialize the provider with config
 func NewBasicAuthProvider(db *database.DB, userRepo *models.UserRepository, config *plugin.AuthConfig) *BasicAuthProvider {
+	b := &BasicAuthProvider{
+		db:       db,
+		userRepo: userRepo,
+	}
+	b.Configure(*config)
+	return b
+}
+
+// Configure applies session settings, rebuilding the cookie store when the secret or lifetime changes.
+// Sessions signed with a previous secret are no longer accepted.
+func (b *BasicAuthProvider) Configure(config plugin.AuthConfig) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.store.Load() != nil && config.SessionSecret == b.secret && config.SessionMaxAge == b.maxAge {
+		return
+	}
+
 	store := sessions.NewCookieStore([]byte(config.SessionSecret))
 	store.Options = &sessions.Options{
 		Path:     "/",
@@ -283,10 +307,7 @@ func NewBasicAuthProvider(db *database.DB, userRepo *models.UserRepository, conf
 		SameSite: http.SameSiteLaxMode,
 	}
 
-	return &BasicAuthProvider{
-		db:       db,
-		userRepo: userRepo,
-		store:    store,
-		config:   config,
-	}
+	b.store.Store(store)
+	b.secret = config.SessionSecret
+	b.maxAge = config.SessionMaxAge
 }
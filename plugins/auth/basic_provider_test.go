@@ -1,3 +1,42 @@
 // TODO: Fix these tests to match current provider interface
 // Tests have been temporarily disabled to fix compilation errors
-package auth
\ No newline at end of file
+package auth
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/btassone/obtura/internal/models"
+	"github.com/btassone/obtura/pkg/plugin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBasicAuthProvider_Configure(t *testing.T) {
+	config := plugin.AuthConfig{SessionSecret: "first-secret-0123456789", SessionMaxAge: 3600}
+	provider := NewBasicAuthProvider(nil, nil, &config)
+
+	// Log in and carry the session cookie into a new request
+	rec := httptest.NewRecorder()
+	require.NoError(t, provider.Login(rec, httptest.NewRequest(http.MethodPost, "/login", nil), &BasicAuthUser{user: &models.User{ID: 5}}))
+	// Sessions are cached per request, so each check needs a fresh one
+	request := func() *http.Request {
+		req := httptest.NewRequest(http.MethodGet, "/admin", nil)
+		for _, cookie := range rec.Result().Cookies() {
+			req.AddCookie(cookie)
+		}
+		return req
+	}
+	assert.True(t, provider.IsAuthenticated(request()))
+
+	// Unchanged settings keep the store
+	store := provider.store.Load()
+	provider.Configure(config)
+	assert.Same(t, store, provider.store.Load())
+
+	// A new secret invalidates existing sessions
+	config.SessionSecret = "second-secret-0123456789"
+	provider.Configure(config)
+	assert.NotSame(t, store, provider.store.Load())
+	assert.False(t, provider.IsAuthenticated(request()))
+}
@@ -3,10 +3,13 @@ package auth
 import (
 	"context"
 	"fmt"
+	"log/slog"
 	"net/http"
+	"sync"
 
 	"github.com/btassone/obtura/internal/models"
 	"github.com/btassone/obtura/pkg/database"
+	"github.com/btassone/obtura/pkg/logging"
 	"github.com/btassone/obtura/pkg/plugin"
 )
 
@@ -14,9 +17,16 @@ import (
 type Plugin struct {
 	db          *database.DB
 	providers   map[string]plugin.AuthProvider
-	active      string
 	config      *plugin.AuthConfig
 	userRepo    *models.UserRepository
+	
+	// The active provider changes with the config while requests are served
+	mu          sync.RWMutex
+	active      string
+	
+	// Config subscription started by Start
+	configs     *plugin.ConfigManager
+	stopWatch   func()
 }
 
 // NewPlugin creates a new auth plugin
@@ -49,26 +59,41 @@ func (p *Plugin) Init(ctx context.Context) error {
 	p.RegisterProvider(&plugin.NoAuthProvider{})
 	
 	// Set active provider
-	p.active = p.config.ActiveProvider
+	p.setActive(p.config.ActiveProvider)
 	
 	return nil
 }
 
 func (p *Plugin) Start(ctx context.Context) error {
+	// Follow config changes made while running, such as a new session secret
+	if cm := plugin.ConfigManagerFromContext(ctx); cm != nil {
+		updates, stop := cm.Watch(p.ID())
+		p.configs = cm
+		p.stopWatch = stop
+		go p.watchConfig(updates, logging.FromContext(ctx))
+	}
+	
 	// Create initial admin user if needed
-	if p.active == "basic" {
+	if p.activeName() == "basic" {
 		provider, _ := p.GetProvider("basic")
 		if basicAuth, ok := provider.(*BasicAuthProvider); ok {
 			if err := basicAuth.CreateInitialAdmin(); err != nil {
 				// Log error but don't fail startup
-				fmt.Printf("Note: Could not create initial admin: %v\n", err)
+				logging.FromContext(ctx).Warn("Could not create initial admin", "error", err)
 			}
 		}
 	}
 	return nil
 }
 
-func (p *Plugin) Stop(ctx context.Context) error  { return nil }
+func (p *Plugin) Stop(ctx context.Context) error {
+	if p.stopWatch != nil {
+		p.stopWatch()
+		p.stopWatch = nil
+	}
+	return nil
+}
+
 func (p *Plugin) Destroy(ctx context.Context) error { return nil }
 
 func (p *Plugin) Config() interface{}        { return p.config }
@@ -135,7 +160,7 @@ func (p *Plugin) GetProvider(name string) (plugin.AuthProvider, bool) {
 }
 
 func (p *Plugin) GetActiveProvider() plugin.AuthProvider {
-	if provider, ok := p.providers[p.active]; ok {
+	if provider, ok := p.providers[p.activeName()]; ok {
 		return provider
 	}
 	// Fallback to no auth
@@ -146,11 +171,55 @@ func (p *Plugin) SetActiveProvider(name string) error {
 	if _, ok := p.providers[name]; !ok {
 		return fmt.Errorf("provider %s not found", name)
 	}
-	p.active = name
-	p.config.ActiveProvider = name
+	p.setActive(name)
+	
+	// Persist the choice; the config watcher keeps the active provider in sync from then on
+	if p.configs != nil {
+		config, ok := p.configs.GetConfigMap(p.ID())
+		if !ok {
+			config = make(map[string]interface{})
+		}
+		config["active_provider"] = name
+		return p.configs.SetConfig(p.ID(), config)
+	}
 	return nil
 }
 
+// activeName returns the name of the active provider
+func (p *Plugin) activeName() string {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.active
+}
+
+func (p *Plugin) setActive(name string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.active = name
+}
+
+// watchConfig applies config snapshots until the subscription ends
+func (p *Plugin) watchConfig(updates <-chan *plugin.ConfigSnapshot, logger *slog.Logger) {
+	for snapshot := range updates {
+		var config plugin.AuthConfig
+		if err := snapshot.Decode(&config); err != nil {
+			logger.Warn("Ignoring auth config update", "version", snapshot.Version, "error", err)
+			continue
+		}
+		p.applyConfig(config)
+	}
+}
+
+// applyConfig switches the active provider and updates the session settings of the basic provider
+func (p *Plugin) applyConfig(config plugin.AuthConfig) {
+	if _, ok := p.providers[config.ActiveProvider]; ok {
+		p.setActive(config.ActiveProvider)
+	}
+	if provider, ok := p.providers["basic"].(*BasicAuthProvider); ok {
+		provider.Configure(config)
+	}
+}
+
 func (p *Plugin) RegisterProvider(provider plugin.AuthProvider) error {
 	name := provider.Name()
 	if _, exists := p.providers[name]; exists {
@@ -176,7 +245,7 @@ func (p *Plugin) handleLogin(w http.ResponseWriter, r *http.Request) {
 	}
 	
 	// For basic auth, show login page
-	if p.active == "basic" {
+	if p.activeName() == "basic" {
 		if basicAuth, ok := provider.(*BasicAuthProvider); ok {
 			basicAuth.ShowLoginPage(w, r)
 			return
@@ -190,7 +259,7 @@ func (p *Plugin) handleLogin(w http.ResponseWriter, r *http.Request) {
 func (p *Plugin) handleLoginPost(w http.ResponseWriter, r *http.Request) {
 	provider := p.GetActiveProvider()
 	
-	if p.active == "basic" {
+	if p.activeName() == "basic" {
 		if basicAuth, ok := provider.(*BasicAuthProvider); ok {
 			basicAuth.HandleLogin(w, r)
 			return
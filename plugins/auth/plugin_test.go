@@ -1,3 +1,44 @@
 // TODO: Fix these tests to match current plugin interface
 // Tests have been temporarily disabled to fix compilation errors
-package auth
\ No newline at end of file
+package auth
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/btassone/obtura/pkg/plugin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPlugin_WatchesConfig(t *testing.T) {
+	p := NewPlugin(nil)
+	p.config.ActiveProvider = "none"
+
+	cm := plugin.NewConfigManager()
+	require.NoError(t, cm.SetConfig(p.ID(), p.config))
+	ctx := plugin.WithConfigManager(context.Background(), cm)
+	require.NoError(t, p.Init(ctx))
+	require.NoError(t, p.Start(ctx))
+	defer p.Stop(ctx)
+
+	basic, _ := p.GetProvider("basic")
+	store := basic.(*BasicAuthProvider).store.Load()
+
+	require.NoError(t, cm.SetConfig(p.ID(), map[string]interface{}{
+		"active_provider": "basic",
+		"session_secret":  "rotated-secret-0123456789",
+		"session_max_age": 60,
+	}))
+	assert.Eventually(t, func() bool {
+		return p.GetActiveProvider() == basic && basic.(*BasicAuthProvider).store.Load() != store
+	}, time.Second, 5*time.Millisecond)
+
+	// Switching provider from the admin persists the choice
+	require.NoError(t, p.SetActiveProvider("none"))
+	config, _ := cm.GetConfigMap(p.ID())
+	assert.Equal(t, "none", config["active_provider"])
+	assert.Eventually(t, func() bool {
+		return p.GetActiveProvider().Name() == "none"
+	}, time.Second, 5*time.Millisecond)
+}
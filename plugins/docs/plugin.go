@@ -8,12 +8,14 @@ import (
 	"go/doc"
 	"go/parser"
 	"go/token"
+	"log/slog"
 	"net/http"
 	"path/filepath"
 	"sort"
 	"strings"
 
 	"github.com/a-h/templ"
+	"github.com/btassone/obtura/pkg/logging"
 	"github.com/btassone/obtura/pkg/plugin"
 )
 
@@ -25,6 +27,7 @@ type Plugin struct {
 	description string
 	author      string
 	packages    map[string]*PackageDoc
+	logger      *slog.Logger
 }
 
 
@@ -37,6 +40,7 @@ func NewPlugin() *Plugin {
 		description: "Automatically generates documentation from code comments",
 		author:      "Obtura Team",
 		packages:    make(map[string]*PackageDoc),
+		logger:      slog.Default(),
 	}
 }
 
@@ -82,6 +86,8 @@ func (p *Plugin) Destroy(ctx context.Context) error {
 
 // Init sets up the documentation plugin
 func (p *Plugin) Init(ctx context.Context) error {
+	p.logger = logging.FromContext(ctx)
+	
 	// Scan for Go packages
 	if err := p.scanPackages(); err != nil {
 		return fmt.Errorf("failed to scan packages: %w", err)
@@ -169,7 +175,7 @@ func (p *Plugin) scanPackages() error {
 	for _, pkgPath := range packagesToScan {
 		if err := p.parsePackage(pkgPath); err != nil {
 			// Log error but continue scanning other packages
-			fmt.Printf("Error parsing package %s: %v\n", pkgPath, err)
+			p.logger.Warn("Error parsing package", "package", pkgPath, "error", err)
 		}
 	}
 	
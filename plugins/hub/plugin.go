@@ -6,8 +6,10 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"strings"
 
 	"github.com/a-h/templ"
+	"github.com/btassone/obtura/pkg/logging"
 	"github.com/btassone/obtura/pkg/plugin"
 )
 
@@ -19,6 +21,10 @@ type Plugin struct {
 	description string
 	author      string
 	registry    *plugin.Registry
+	
+	// Where file settings are stored and served from
+	uploadDir string
+	uploadURL string
 }
 
 // NewPlugin creates a new plugin hub instance
@@ -30,6 +36,8 @@ func NewPlugin(registry *plugin.Registry) *Plugin {
 		description: "Central dashboard for discovering and managing plugins",
 		author:      "Obtura Team",
 		registry:    registry,
+		uploadDir:   "web/static/uploads/plugins",
+		uploadURL:   "/static/uploads/plugins",
 	}
 }
 
@@ -142,6 +150,11 @@ func (p *Plugin) AdminRoutes() []plugin.Route {
 			Path:    "/hub/plugins/{id}/settings",
 			Handler: p.handleSaveSettings,
 		},
+		{
+			Method:  http.MethodPost,
+			Path:    "/hub/plugins/{id}/log-level",
+			Handler: p.handleSetLogLevel,
+		},
 	}
 }
 
@@ -258,26 +271,23 @@ func (p *Plugin) handlePluginDocs(w http.ResponseWriter, r *http.Request) {
 func (p *Plugin) handlePluginSettings(w http.ResponseWriter, r *http.Request) {
 	pluginID := r.PathValue("id")
 	
-	pluginInfo, err := p.getPluginInfo(pluginID)
+	// Show the effective values, including overrides from the config file and environment
+	form := SettingsForm{Saved: r.URL.Query().Get("success") == "true"}
+	current := p.getPluginSettings(pluginID)
+	values, origins, err := p.registry.GetConfigManager().EffectiveConfig(pluginID)
 	if err != nil {
-		http.Error(w, "Plugin not found", http.StatusNotFound)
+		form.Values = current
+		form.Error = err.Error()
+		p.renderSettingsForm(w, r, pluginID, form, http.StatusInternalServerError)
 		return
 	}
-	
-	// Get current settings values
-	settings := p.getPluginSettings(pluginID)
-	
-	// Check for success message
-	if r.URL.Query().Get("success") == "true" {
-		// Add success flag to context or template data
-		// For now, we'll handle this in the template
-	}
-	
-	component := pluginSettingsPage(pluginInfo, settings)
-	templ.Handler(component).ServeHTTP(w, r)
+	form.Values = values
+	form.Origins = origins
+	p.renderSettingsForm(w, r, pluginID, form, http.StatusOK)
 }
 
-// handleSaveSettings saves plugin settings
+// handleSaveSettings parses, validates and saves plugin settings.
+// Nothing is applied unless every setting is valid, and a failed change rolls back the others.
 func (p *Plugin) handleSaveSettings(w http.ResponseWriter, r *http.Request) {
 	pluginID := r.PathValue("id")
 	
@@ -295,49 +305,154 @@ func (p *Plugin) handleSaveSettings(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	
-	// Parse form data
-	if err := r.ParseForm(); err != nil {
+	// Parse form data, including any uploaded files
+	if err := p.parseSettingsForm(w, r); err != nil {
 		http.Error(w, "Invalid form data", http.StatusBadRequest)
 		return
 	}
 	
-	// Get plugin settings
-	settings := settingsPlugin.Settings()
+	configManager := p.registry.GetConfigManager()
+	current := p.getPluginSettings(pluginID)
+	effective, origins, err := configManager.EffectiveConfig(pluginID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	updates := make(map[string]interface{})
+	form := SettingsForm{
+		Values:  make(map[string]interface{}),
+		Origins: origins,
+		Errors:  plugin.SettingErrors{},
+	}
+	var uploads []string
 	
-	// Process each setting
-	for _, setting := range settings {
-		value := r.FormValue(setting.Key)
-		
-		// Convert value based on type
-		var convertedValue interface{}
-		switch setting.Type {
-		case plugin.SettingTypeBool:
-			convertedValue = value == "on" || value == "true"
-		case plugin.SettingTypeInt:
-			var intVal int
-			fmt.Sscanf(value, "%d", &intVal)
-			convertedValue = intVal
-		default:
-			convertedValue = value
+	// Parse and validate every setting before applying any of them
+	for _, setting := range settingsPlugin.Settings() {
+		// Settings locked by the config file or environment are not editable
+		if _, locked := origins.Locked(setting.Key); locked {
+			form.Values[setting.Key] = effective[setting.Key]
+			continue
 		}
 		
-		// Get old value for comparison
-		oldValue := p.getSettingValue(pluginID, setting.Key)
+		// Secrets are write-only: a blank field keeps the stored value
+		if setting.Secret && strings.TrimSpace(r.FormValue(setting.Key)) == "" {
+			form.Values[setting.Key] = current[setting.Key]
+			continue
+		}
 		
-		// Notify plugin of change
-		if err := settingsPlugin.OnSettingChange(setting.Key, oldValue, convertedValue); err != nil {
-			http.Error(w, fmt.Sprintf("Failed to update setting %s: %v", setting.Key, err), http.StatusInternalServerError)
-			return
+		var value interface{}
+		var err error
+		if setting.Type == plugin.SettingTypeFile {
+			var upload string
+			value, upload, err = p.settingFileValue(r, pluginID, setting, current[setting.Key])
+			if upload != "" {
+				uploads = append(uploads, upload)
+			}
+		} else {
+			value, err = plugin.ParseSettingValue(setting, r.Form[setting.Key])
+		}
+		if err == nil {
+			err = plugin.ValidateSetting(setting, value)
 		}
 		
-		// Save to config manager
-		p.saveSettingValue(pluginID, setting.Key, convertedValue)
+		if err != nil {
+			form.Errors[setting.Key] = err.Error()
+			form.Values[setting.Key] = invalidSettingValue(r, setting, current[setting.Key])
+			continue
+		}
+		form.Values[setting.Key] = value
+		updates[setting.Key] = value
+	}
+	
+	if len(form.Errors) > 0 {
+		p.removeUploads(uploads)
+		p.renderSettingsForm(w, r, pluginID, form, http.StatusUnprocessableEntity)
+		return
+	}
+	
+	// The registry notifies the plugin of each change, then validates, loads and saves the whole config
+	values := make(map[string]interface{}, len(current)+len(updates))
+	for key, value := range current {
+		values[key] = value
+	}
+	for key, value := range updates {
+		values[key] = value
+	}
+	
+	// Overridden values are never written to the stored config
+	err = configManager.RestoreOverridden(pluginID, values)
+	if err == nil {
+		err = p.registry.ReplaceConfig(pluginID, values, settingsAuthor(r), "")
+	}
+	if err != nil {
+		p.removeUploads(uploads)
+		form.Error = err.Error()
+		p.renderSettingsForm(w, r, pluginID, form, http.StatusUnprocessableEntity)
+		return
+	}
+	
+	// Replaced uploads are no longer referenced
+	for _, setting := range settingsPlugin.Settings() {
+		if setting.Type != plugin.SettingTypeFile {
+			continue
+		}
+		old, _ := current[setting.Key].(string)
+		if updated, _ := updates[setting.Key].(string); old != "" && old != updated {
+			if path, ok := p.uploadPath(old); ok {
+				p.removeUploads([]string{path})
+			}
+		}
 	}
 	
 	// Redirect back to settings page with success message
 	http.Redirect(w, r, fmt.Sprintf("/admin/hub/plugins/%s/settings?success=true", pluginID), http.StatusSeeOther)
 }
 
+// renderSettingsForm renders the settings page for a plugin
+func (p *Plugin) renderSettingsForm(w http.ResponseWriter, r *http.Request, pluginID string, form SettingsForm, status int) {
+	pluginInfo, err := p.getPluginInfo(pluginID)
+	if err != nil {
+		http.Error(w, "Plugin not found", http.StatusNotFound)
+		return
+	}
+	
+	component := pluginSettingsPage(pluginInfo, form)
+	templ.Handler(component, templ.WithStatus(status)).ServeHTTP(w, r)
+}
+
+// handleSetLogLevel changes a plugin's log level at runtime
+func (p *Plugin) handleSetLogLevel(w http.ResponseWriter, r *http.Request) {
+	pluginID := r.PathValue("id")
+	
+	if _, err := p.registry.Get(pluginID); err != nil {
+		http.Error(w, "Plugin not found", http.StatusNotFound)
+		return
+	}
+	
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Invalid form data", http.StatusBadRequest)
+		return
+	}
+	
+	logManager := p.registry.LogManager()
+	value := r.FormValue("level")
+	if value == "" || value == "inherit" {
+		logManager.ResetPluginLevel(pluginID)
+	} else {
+		level, err := logging.ParseLevel(value)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		logManager.SetPluginLevel(pluginID, level)
+	}
+	
+	level, _ := logManager.PluginLevel(pluginID)
+	logging.FromContext(r.Context()).Info("Plugin log level changed", "plugin", pluginID, "level", logging.LevelName(level))
+	
+	http.Redirect(w, r, fmt.Sprintf("/admin/hub/plugins/%s", pluginID), http.StatusSeeOther)
+}
+
 // gatherPluginInfo collects information about all plugins
 func (p *Plugin) gatherPluginInfo() []PluginInfo {
 	var plugins []PluginInfo
@@ -356,6 +471,18 @@ func (p *Plugin) gatherPluginInfo() []PluginInfo {
 			Dependencies: plg.Dependencies(),
 		}
 		
+		// Attach lifecycle status
+		if status, err := p.registry.Status(plg.ID()); err == nil {
+			info.State = status.State
+			info.StateSince = status.Since
+			info.LastError = status.LastError
+		}
+		
+		// Attach log level
+		level, overridden := p.registry.LogManager().PluginLevel(plg.ID())
+		info.LogLevel = logging.LevelName(level)
+		info.LogLevelOverridden = overridden
+		
 		// Check for various interfaces
 		if _, ok := plg.(plugin.RoutablePlugin); ok {
 			info.ProvidesRoutes = true
@@ -396,6 +523,9 @@ func (p *Plugin) gatherPluginInfo() []PluginInfo {
 		if _, ok := plg.(plugin.MigrationPlugin); ok {
 			info.ProvidesMigrations = true
 		}
+		if _, ok := plg.(plugin.MigrationFSPlugin); ok {
+			info.ProvidesMigrations = true
+		}
 		
 		if _, ok := plg.(plugin.AssetPlugin); ok {
 			info.ProvidesAssets = true
@@ -458,19 +588,6 @@ func (p *Plugin) getPluginSettings(pluginID string) map[string]interface{} {
 	return make(map[string]interface{})
 }
 
-// getSettingValue gets a specific setting value
-func (p *Plugin) getSettingValue(pluginID, key string) interface{} {
-	settings := p.getPluginSettings(pluginID)
-	return settings[key]
-}
-
-// saveSettingValue saves a specific setting value
-func (p *Plugin) saveSettingValue(pluginID, key string, value interface{}) {
-	settings := p.getPluginSettings(pluginID)
-	settings[key] = value
-	p.registry.GetConfigManager().SetConfig(pluginID, settings)
-}
-
 // convertToMap converts a config interface to a map
 func (p *Plugin) convertToMap(config interface{}) (map[string]interface{}, bool) {
 	// If already a map, return it
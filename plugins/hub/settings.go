@@ -0,0 +1,209 @@
+package hub
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/btassone/obtura/pkg/plugin"
+	authPlugin "github.com/btassone/obtura/plugins/auth"
+)
+
+// maxSettingsUploadSize bounds the size of a settings form including uploaded files
+const maxSettingsUploadSize = 32 << 20
+
+var unsafeFilenameChars = regexp.MustCompile(`[^a-zA-Z0-9._-]+`)
+
+// parseSettingsForm parses url-encoded and multipart settings forms
+func (p *Plugin) parseSettingsForm(w http.ResponseWriter, r *http.Request) error {
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		r.Body = http.MaxBytesReader(w, r.Body, maxSettingsUploadSize)
+		return r.ParseMultipartForm(maxSettingsUploadSize)
+	}
+	return r.ParseForm()
+}
+
+// settingFileValue stores an uploaded file for a file setting and returns its URL.
+// Without an upload the current file is kept, unless the "<key>_clear" box is ticked.
+// Uploads are served as static files, so only the extensions the setting accepts are stored.
+// The stored file's path is returned so it can be removed if the save fails.
+func (p *Plugin) settingFileValue(r *http.Request, pluginID string, setting plugin.Setting, current interface{}) (interface{}, string, error) {
+	file, header, err := r.FormFile(setting.Key)
+	if errors.Is(err, http.ErrMissingFile) || errors.Is(err, http.ErrNotMultipart) {
+		if r.FormValue(setting.Key+"_clear") != "" {
+			return "", "", nil
+		}
+		if current == nil {
+			return "", "", nil
+		}
+		return current, "", nil
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read upload: %w", err)
+	}
+	defer file.Close()
+
+	if !plugin.AcceptsFile(setting, header.Filename) {
+		return nil, "", fmt.Errorf("must be a %s file", strings.Join(plugin.AcceptedFiles(setting), ", "))
+	}
+
+	suffix := make([]byte, 8)
+	if _, err := rand.Read(suffix); err != nil {
+		return nil, "", err
+	}
+	name := hex.EncodeToString(suffix) + "-" + unsafeFilenameChars.ReplaceAllString(filepath.Base(header.Filename), "_")
+
+	dir := filepath.Join(p.uploadDir, pluginID)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, "", fmt.Errorf("failed to create upload directory: %w", err)
+	}
+
+	dest := filepath.Join(dir, name)
+	out, err := os.Create(dest)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to store upload: %w", err)
+	}
+	if _, err := io.Copy(out, file); err != nil {
+		out.Close()
+		os.Remove(dest)
+		return nil, "", fmt.Errorf("failed to store upload: %w", err)
+	}
+	if err := out.Close(); err != nil {
+		os.Remove(dest)
+		return nil, "", fmt.Errorf("failed to store upload: %w", err)
+	}
+
+	return path.Join(p.uploadURL, pluginID, name), dest, nil
+}
+
+// uploadPath maps a stored file URL back to its path on disk
+func (p *Plugin) uploadPath(url string) (string, bool) {
+	rel, ok := strings.CutPrefix(url, p.uploadURL+"/")
+	if !ok || rel != path.Clean(rel) || strings.HasPrefix(rel, "../") {
+		return "", false
+	}
+	return filepath.Join(p.uploadDir, filepath.FromSlash(rel)), true
+}
+
+// removeUploads deletes stored files
+func (p *Plugin) removeUploads(paths []string) {
+	for _, path := range paths {
+		os.Remove(path)
+	}
+}
+
+// invalidSettingValue is what the form shows for a setting that failed validation
+func invalidSettingValue(r *http.Request, setting plugin.Setting, current interface{}) interface{} {
+	switch setting.Type {
+	case plugin.SettingTypeFile:
+		return current
+	case plugin.SettingTypeMulti:
+		return r.Form[setting.Key]
+	}
+	return rawSettingValue(r.FormValue(setting.Key))
+}
+
+// settingInputValue formats a setting value for a form input
+func settingInputValue(setting plugin.Setting, value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case rawSettingValue:
+		return string(v)
+	case time.Time:
+		return v.Format("2006-01-02T15:04")
+	}
+
+	switch setting.Type {
+	case plugin.SettingTypeJSON:
+		data, err := json.MarshalIndent(value, "", "  ")
+		if err != nil {
+			return fmt.Sprint(value)
+		}
+		return string(data)
+	case plugin.SettingTypeDateTime:
+		// Stored values come back from the config storage as RFC 3339 strings
+		if s, ok := value.(string); ok {
+			if t, err := time.Parse(time.RFC3339, s); err == nil {
+				return t.Format("2006-01-02T15:04")
+			}
+		}
+	}
+	return fmt.Sprintf("%v", value)
+}
+
+// settingSelected reports whether an option is chosen in a select or multi value
+func settingSelected(value interface{}, option string) bool {
+	switch v := value.(type) {
+	case []string:
+		for _, s := range v {
+			if s == option {
+				return true
+			}
+		}
+		return false
+	case []interface{}:
+		for _, s := range v {
+			if fmt.Sprint(s) == option {
+				return true
+			}
+		}
+		return false
+	}
+	return value != nil && fmt.Sprintf("%v", value) == option
+}
+
+// settingInputClass highlights inputs with validation errors
+func settingInputClass(fieldError string) string {
+	if fieldError != "" {
+		return "shadow-sm focus:ring-red-500 focus:border-red-500 block w-full sm:text-sm border-red-300 rounded-md"
+	}
+	return "shadow-sm focus:ring-indigo-500 focus:border-indigo-500 block w-full sm:text-sm border-gray-300 rounded-md"
+}
+
+// settingFileAccept lists the extensions a file input accepts
+func settingFileAccept(setting plugin.Setting) string {
+	return strings.Join(plugin.AcceptedFiles(setting), ",")
+}
+
+// hasFileSetting reports whether a settings form needs multipart encoding
+func hasFileSetting(settings []plugin.Setting) bool {
+	for _, setting := range settings {
+		if setting.Type == plugin.SettingTypeFile {
+			return true
+		}
+	}
+	return false
+}
+
+// settingLocked reports whether a setting is overridden by the config file or environment
+func settingLocked(origins plugin.ConfigOrigins, key string) bool {
+	_, locked := origins.Locked(key)
+	return locked
+}
+
+// settingsAuthor identifies the signed-in user saving settings for the config history
+func settingsAuthor(r *http.Request) string {
+	if user, ok := r.Context().Value(authPlugin.UserContextKey).(plugin.AuthUser); ok {
+		return user.Email()
+	}
+	return ""
+}
+
+// secretPlaceholder hints whether a secret setting already has a value without showing it
+func secretPlaceholder(value interface{}) string {
+	if value != nil && value != "" {
+		return plugin.SecretMask
+	}
+	return "Not set"
+}
@@ -0,0 +1,250 @@
+package hub
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/btassone/obtura/pkg/plugin"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// filesConfig is the config loaded into filesPlugin
+type filesConfig struct {
+	Title string `json:"title"`
+	Logo  string `json:"logo"`
+	Terms string `json:"terms"`
+}
+
+// filesPlugin has file settings, fails to change its title to "fail" and rejects the title "invalid"
+type filesPlugin struct {
+	config  filesConfig
+	changes []string
+}
+
+func (p *filesPlugin) ID() string                        { return "test.files" }
+func (p *filesPlugin) Name() string                      { return "Files" }
+func (p *filesPlugin) Version() string                   { return "1.0.0" }
+func (p *filesPlugin) Description() string               { return "Plugin with file settings" }
+func (p *filesPlugin) Author() string                    { return "Test" }
+func (p *filesPlugin) Init(ctx context.Context) error    { return nil }
+func (p *filesPlugin) Start(ctx context.Context) error   { return nil }
+func (p *filesPlugin) Stop(ctx context.Context) error    { return nil }
+func (p *filesPlugin) Destroy(ctx context.Context) error { return nil }
+func (p *filesPlugin) Dependencies() []string            { return nil }
+func (p *filesPlugin) Config() interface{}               { return &p.config }
+func (p *filesPlugin) DefaultConfig() interface{}        { return map[string]interface{}{"title": "Files"} }
+
+func (p *filesPlugin) ValidateConfig() error {
+	if p.config.Title == "invalid" {
+		return fmt.Errorf("title is invalid")
+	}
+	return nil
+}
+
+func (p *filesPlugin) Settings() []plugin.Setting {
+	return []plugin.Setting{
+		{Key: "logo", Name: "Logo", Type: plugin.SettingTypeFile},
+		{Key: "terms", Name: "Terms", Type: plugin.SettingTypeFile, Accept: []string{".pdf"}},
+		{Key: "title", Name: "Title", Type: plugin.SettingTypeString, Validation: plugin.SettingValidation{Required: true}},
+	}
+}
+
+func (p *filesPlugin) OnSettingChange(key string, oldValue, newValue interface{}) error {
+	if newValue == "fail" {
+		return fmt.Errorf("title rejected")
+	}
+	p.changes = append(p.changes, fmt.Sprintf("%s=%v", key, newValue))
+	return nil
+}
+
+// newFilesHub creates a hub whose uploads go to a temporary directory, with configs kept in memory
+func newFilesHub(t *testing.T) (*Plugin, *filesPlugin) {
+	registry := plugin.NewRegistry(chi.NewRouter(), plugin.WithConfigStorage(plugin.NewMemoryConfigStorage()))
+	files := &filesPlugin{}
+	require.NoError(t, registry.Register(files))
+
+	hub := NewPlugin(registry)
+	hub.uploadDir = t.TempDir()
+	return hub, files
+}
+
+// saveSettings posts a multipart settings form, with uploads mapped from field to file name
+func saveSettings(t *testing.T, hub *Plugin, fields, uploads map[string]string) *httptest.ResponseRecorder {
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	for key, value := range fields {
+		require.NoError(t, mw.WriteField(key, value))
+	}
+	for key, name := range uploads {
+		part, err := mw.CreateFormFile(key, name)
+		require.NoError(t, err)
+		_, err = part.Write([]byte("contents of " + name))
+		require.NoError(t, err)
+	}
+	require.NoError(t, mw.Close())
+
+	req := httptest.NewRequest(http.MethodPost, "/admin/hub/plugins/test.files/settings", &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	req.SetPathValue("id", "test.files")
+	rec := httptest.NewRecorder()
+	hub.handleSaveSettings(rec, req)
+	return rec
+}
+
+// storedUploads lists the files stored for the test plugin
+func storedUploads(t *testing.T, hub *Plugin) []string {
+	entries, err := os.ReadDir(filepath.Join(hub.uploadDir, "test.files"))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	require.NoError(t, err)
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	return names
+}
+
+// savedSettings returns the test plugin's stored config
+func savedSettings(t *testing.T, hub *Plugin) map[string]interface{} {
+	config, ok := hub.registry.GetConfigManager().GetConfigMap("test.files")
+	require.True(t, ok)
+	return config
+}
+
+func TestSaveSettings_Uploads(t *testing.T) {
+	hub, files := newFilesHub(t)
+
+	rec := saveSettings(t, hub, map[string]string{"title": "Docs"}, map[string]string{"logo": "My Logo.PNG", "terms": "terms.pdf"})
+	require.Equal(t, http.StatusSeeOther, rec.Code, rec.Body.String())
+
+	config := savedSettings(t, hub)
+	assert.Equal(t, "Docs", config["title"])
+	logo, _ := config["logo"].(string)
+	assert.Regexp(t, `^/static/uploads/plugins/test\.files/[0-9a-f]{16}-My_Logo\.PNG$`, logo)
+
+	path, ok := hub.uploadPath(logo)
+	require.True(t, ok)
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "contents of My Logo.PNG", string(data))
+	assert.Len(t, storedUploads(t, hub), 2)
+	assert.Contains(t, files.changes, "title=Docs")
+}
+
+func TestSaveSettings_RejectsUnacceptedFiles(t *testing.T) {
+	hub, _ := newFilesHub(t)
+
+	for field, name := range map[string]string{"logo": "page.html", "terms": "logo.png"} {
+		rec := saveSettings(t, hub, map[string]string{"title": "Docs"}, map[string]string{field: name})
+		assert.Equal(t, http.StatusUnprocessableEntity, rec.Code, name)
+		assert.Contains(t, rec.Body.String(), "must be a")
+	}
+	rec := saveSettings(t, hub, map[string]string{"title": "Docs"}, map[string]string{"logo": "drawing.svg"})
+	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code, "SVG can run scripts")
+
+	assert.Empty(t, storedUploads(t, hub))
+	assert.Equal(t, "Files", savedSettings(t, hub)["title"])
+}
+
+func TestSaveSettings_InvalidRemovesUploads(t *testing.T) {
+	hub, files := newFilesHub(t)
+
+	rec := saveSettings(t, hub, map[string]string{"title": ""}, map[string]string{"logo": "logo.png"})
+	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+	assert.Contains(t, rec.Body.String(), "is required")
+
+	assert.Empty(t, storedUploads(t, hub))
+	assert.Nil(t, savedSettings(t, hub)["logo"])
+	assert.Empty(t, files.changes)
+}
+
+func TestSaveSettings_RollbackRemovesUploads(t *testing.T) {
+	hub, files := newFilesHub(t)
+
+	rec := saveSettings(t, hub, map[string]string{"title": "fail"}, map[string]string{"logo": "logo.png"})
+	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+	assert.Contains(t, rec.Body.String(), "title rejected")
+
+	// The logo was applied before the title failed, then reverted
+	require.NotEmpty(t, files.changes)
+	assert.Regexp(t, `^logo=/static/uploads/plugins/test\.files/`, files.changes[0])
+	assert.Equal(t, "logo=<nil>", files.changes[len(files.changes)-1])
+
+	assert.Empty(t, storedUploads(t, hub))
+	config := savedSettings(t, hub)
+	assert.Equal(t, "Files", config["title"])
+	assert.Nil(t, config["logo"])
+}
+
+func TestSaveSettings_RemovesReplacedFiles(t *testing.T) {
+	hub, _ := newFilesHub(t)
+
+	rec := saveSettings(t, hub, map[string]string{"title": "Docs"}, map[string]string{"logo": "old.png"})
+	require.Equal(t, http.StatusSeeOther, rec.Code)
+	old := savedSettings(t, hub)["logo"].(string)
+
+	// Saving without an upload keeps the file
+	rec = saveSettings(t, hub, map[string]string{"title": "Docs"}, nil)
+	require.Equal(t, http.StatusSeeOther, rec.Code)
+	assert.Equal(t, old, savedSettings(t, hub)["logo"])
+
+	rec = saveSettings(t, hub, map[string]string{"title": "Docs"}, map[string]string{"logo": "new.png"})
+	require.Equal(t, http.StatusSeeOther, rec.Code)
+	replaced := savedSettings(t, hub)["logo"].(string)
+	assert.NotEqual(t, old, replaced)
+	stored := storedUploads(t, hub)
+	require.Len(t, stored, 1)
+	assert.Equal(t, filepath.Base(replaced), stored[0])
+
+	// Clearing the setting removes its file
+	rec = saveSettings(t, hub, map[string]string{"title": "Docs", "logo_clear": "on"}, nil)
+	require.Equal(t, http.StatusSeeOther, rec.Code)
+	assert.Equal(t, "", savedSettings(t, hub)["logo"])
+	assert.Empty(t, storedUploads(t, hub))
+}
+
+func TestSaveSettings_LoadsAndValidatesConfig(t *testing.T) {
+	hub, files := newFilesHub(t)
+
+	rec := saveSettings(t, hub, map[string]string{"title": "Docs"}, nil)
+	require.Equal(t, http.StatusSeeOther, rec.Code, rec.Body.String())
+	assert.Equal(t, "Docs", files.config.Title, "the running plugin gets the saved config")
+
+	rec = saveSettings(t, hub, map[string]string{"title": "invalid"}, nil)
+	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+	assert.Contains(t, rec.Body.String(), "title is invalid")
+	assert.Equal(t, "Docs", savedSettings(t, hub)["title"])
+	assert.Equal(t, "Docs", files.config.Title)
+}
+
+func TestSettings_OverriddenSettingsAreLocked(t *testing.T) {
+	hub, files := newFilesHub(t)
+	cm := hub.registry.GetConfigManager()
+	cm.LoadEnvOverrides([]string{"OBTURA_PLUGIN_TEST_FILES__TITLE=From env"})
+
+	req := httptest.NewRequest(http.MethodGet, "/admin/hub/plugins/test.files/settings", nil)
+	req.SetPathValue("id", "test.files")
+	rec := httptest.NewRecorder()
+	hub.handlePluginSettings(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), "Locked: set by environment variable OBTURA_PLUGIN_TEST_FILES__TITLE")
+	assert.Contains(t, rec.Body.String(), `value="From env"`)
+
+	rec = saveSettings(t, hub, map[string]string{"title": "Docs"}, map[string]string{"logo": "logo.png"})
+	require.Equal(t, http.StatusSeeOther, rec.Code, rec.Body.String())
+
+	config := savedSettings(t, hub)
+	assert.Equal(t, "Files", config["title"], "overridden values are not stored")
+	assert.NotEmpty(t, config["logo"])
+	assert.Equal(t, "From env", files.config.Title)
+}
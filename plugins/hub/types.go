@@ -1,6 +1,10 @@
 package hub
 
-import "github.com/btassone/obtura/pkg/plugin"
+import (
+	"time"
+
+	"github.com/btassone/obtura/pkg/plugin"
+)
 
 // PluginInfo contains comprehensive information about a plugin
 type PluginInfo struct {
@@ -12,6 +16,15 @@ type PluginInfo struct {
 	Author      string
 	IsActive    bool
 	
+	// Lifecycle
+	State      plugin.State
+	StateSince time.Time
+	LastError  string
+	
+	// Logging
+	LogLevel           string
+	LogLevelOverridden bool
+	
 	// Features
 	ProvidesRoutes      bool
 	ProvidesAdmin       bool
@@ -44,4 +57,16 @@ type PluginInfo struct {
 	Type     string
 	Active   bool
 	Priority int
-}
\ No newline at end of file
+}
+
+// SettingsForm holds the values and validation results shown on a plugin settings page
+type SettingsForm struct {
+	Values  map[string]interface{}
+	Origins plugin.ConfigOrigins // Marks settings locked by the config file or environment
+	Errors  plugin.SettingErrors
+	Error   string // Failure that is not tied to a single field
+	Saved   bool
+}
+
+// rawSettingValue is submitted input that failed to parse and is shown back unchanged
+type rawSettingValue string
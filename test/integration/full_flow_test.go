@@ -29,7 +29,7 @@ type TestServer struct {
 func setupTestServer(t *testing.T) *TestServer {
 	// Create router and registry
 	router := chi.NewRouter()
-	registry := plugin.NewRegistry(router)
+	registry := plugin.NewRegistry(router, plugin.WithConfigStorage(plugin.NewMemoryConfigStorage()))
 
 	// Create test server
 	ts := httptest.NewServer(router)